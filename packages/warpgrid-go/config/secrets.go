@@ -0,0 +1,61 @@
+// Package config provides configuration helpers for WarpGrid modules.
+//
+// Secrets are read from files in a directory the host preopens into the
+// module, one file per secret, mirroring Kubernetes secret volume
+// mounts: the secret DB_PASSWORD lives at /secrets/DB_PASSWORD. This
+// keeps credentials out of the environment, where they are easily
+// leaked through logs and process listings. Environment variables
+// remain as a fallback for local development.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SecretsDir is the directory Secret reads secret files from. Under
+// WASI it must be preopened by the host (for example with
+// --dir /run/warpgrid/secrets::/secrets).
+var SecretsDir = "/secrets"
+
+// ErrSecretNotFound is returned when a secret exists neither as a file
+// in SecretsDir nor as an environment variable.
+var ErrSecretNotFound = errors.New("config: secret not found")
+
+// Secret returns the named secret. The file SecretsDir/name takes
+// precedence; if it does not exist, the environment variable of the
+// same name is used. Trailing newlines (as left by editors and
+// `echo`) are trimmed from file contents.
+//
+// name must be a plain file name: names containing path separators or
+// ".." are rejected. A secret file that exists but cannot be read is
+// an error rather than a silent fall back to the environment.
+func Secret(name string) (string, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("config: invalid secret name %q", name)
+	}
+
+	data, err := os.ReadFile(filepath.Join(SecretsDir, name))
+	switch {
+	case err == nil:
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case !errors.Is(err, fs.ErrNotExist):
+		return "", fmt.Errorf("config: reading secret %s: %w", name, err)
+	}
+
+	if v, ok := os.LookupEnv(name); ok {
+		return v, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+}
+
+// LookupSecret is like Secret but reports a missing secret as ok ==
+// false instead of an error. Other errors also yield ok == false.
+func LookupSecret(name string) (value string, ok bool) {
+	v, err := Secret(name)
+	return v, err == nil
+}
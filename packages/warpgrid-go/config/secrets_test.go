@@ -0,0 +1,90 @@
+package config_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anthropics/warpgrid/packages/warpgrid-go/config"
+)
+
+// ── Secret tests ────────────────────────────────────────────────────
+
+func useSecretsDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	prev := config.SecretsDir
+	config.SecretsDir = dir
+	t.Cleanup(func() { config.SecretsDir = prev })
+	return dir
+}
+
+func writeSecret(t *testing.T, dir, name, value string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(value), 0o600); err != nil {
+		t.Fatalf("failed to write secret: %v", err)
+	}
+}
+
+func TestSecret_FileTakesPrecedenceOverEnv(t *testing.T) {
+	dir := useSecretsDir(t)
+	writeSecret(t, dir, "DB_PASSWORD", "from-file\n")
+	t.Setenv("DB_PASSWORD", "from-env")
+
+	got, err := config.Secret("DB_PASSWORD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "from-file" {
+		t.Fatalf("expected 'from-file', got '%s'", got)
+	}
+}
+
+func TestSecret_TrimsTrailingNewlinesOnly(t *testing.T) {
+	dir := useSecretsDir(t)
+	writeSecret(t, dir, "API_KEY", "  key with spaces \r\n\n")
+
+	got, err := config.Secret("API_KEY")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "  key with spaces " {
+		t.Fatalf("expected newlines trimmed and spaces kept, got %q", got)
+	}
+}
+
+func TestSecret_FallsBackToEnv(t *testing.T) {
+	useSecretsDir(t)
+	t.Setenv("DB_USER", "postgres")
+
+	got, err := config.Secret("DB_USER")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "postgres" {
+		t.Fatalf("expected 'postgres', got '%s'", got)
+	}
+}
+
+func TestSecret_MissingReturnsErrSecretNotFound(t *testing.T) {
+	useSecretsDir(t)
+
+	_, err := config.Secret("WARPGRID_TEST_MISSING_SECRET")
+	if !errors.Is(err, config.ErrSecretNotFound) {
+		t.Fatalf("expected ErrSecretNotFound, got %v", err)
+	}
+	if _, ok := config.LookupSecret("WARPGRID_TEST_MISSING_SECRET"); ok {
+		t.Fatal("expected LookupSecret to report missing secret")
+	}
+}
+
+func TestSecret_RejectsPathTraversal(t *testing.T) {
+	useSecretsDir(t)
+
+	for _, name := range []string{"", "..", "../etc/passwd", "a/b"} {
+		if _, err := config.Secret(name); err == nil || errors.Is(err, config.ErrSecretNotFound) {
+			t.Fatalf("name %q: expected invalid name error, got %v", name, err)
+		}
+	}
+}
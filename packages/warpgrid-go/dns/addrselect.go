@@ -0,0 +1,264 @@
+package dns
+
+import (
+	"net"
+	"sort"
+)
+
+// Destination address ordering per RFC 6724 section 6, used by
+// Resolver when SortRFC6724 is set.
+//
+// Sorting compares each destination together with the source address
+// the host would use to reach it. Rules that need a source (1, 2, 5,
+// and 9) are neutral when no source is known, which is the common case
+// under WASI where local-address enumeration may be unavailable; the
+// remaining rules (precedence and scope) still apply. Rules 3, 4, and 7
+// depend on interface state this package cannot observe and are
+// skipped. Ties keep the resolver's original order (rule 10).
+
+// SortByRFC6724 reorders dsts in place by RFC 6724 destination address
+// selection. srcs, when non-nil, must be the same length as dsts and
+// hold the source address for each destination, or nil where none is
+// usable; it is reordered alongside dsts. Pass a nil srcs when no
+// source information is available.
+func SortByRFC6724(dsts, srcs []net.IP) {
+	if srcs != nil && len(srcs) != len(dsts) {
+		panic("dns: SortByRFC6724 source list length mismatch")
+	}
+	s := &byRFC6724{dsts: dsts, srcs: srcs, haveSrcs: srcs != nil}
+	if srcs == nil {
+		s.srcs = make([]net.IP, len(dsts))
+	}
+	for i := range dsts {
+		s.attrs = append(s.attrs, newAddrAttr(dsts[i], s.srcs[i]))
+	}
+	sort.Stable(s)
+}
+
+// sortIPAddrs is SortByRFC6724 for addresses that carry a zone; srcs
+// must be parallel to addrs.
+func sortIPAddrs(addrs []net.IPAddr, srcs []net.IP) {
+	s := &byRFC6724{dsts: make([]net.IP, len(addrs)), srcs: srcs, haveSrcs: true, addrs: addrs}
+	for i, addr := range addrs {
+		s.dsts[i] = addr.IP
+		s.attrs = append(s.attrs, newAddrAttr(addr.IP, srcs[i]))
+	}
+	sort.Stable(s)
+}
+
+// defaultSourceAddr finds the source address the host would use for
+// dst by connecting a UDP socket, which selects a route without sending
+// any packets. It returns nil when no route exists or the platform does
+// not support the probe.
+func defaultSourceAddr(dst net.IP) net.IP {
+	conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: dst, Port: 9})
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+	if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok {
+		return addr.IP
+	}
+	return nil
+}
+
+type addrAttr struct {
+	scope      uint8
+	precedence uint8
+	label      uint8
+	src        net.IP // nil when unknown or unusable
+	srcScope   uint8
+	srcLabel   uint8
+}
+
+func newAddrAttr(dst, src net.IP) addrAttr {
+	a := addrAttr{
+		scope:      classifyScope(dst),
+		precedence: policyFor(dst).precedence,
+		label:      policyFor(dst).label,
+		src:        src,
+	}
+	if src != nil {
+		a.srcScope = classifyScope(src)
+		a.srcLabel = policyFor(src).label
+	}
+	return a
+}
+
+type byRFC6724 struct {
+	dsts     []net.IP
+	srcs     []net.IP
+	attrs    []addrAttr
+	haveSrcs bool
+	addrs    []net.IPAddr // reordered alongside dsts, when set
+}
+
+func (s *byRFC6724) Len() int { return len(s.dsts) }
+
+func (s *byRFC6724) Swap(i, j int) {
+	s.dsts[i], s.dsts[j] = s.dsts[j], s.dsts[i]
+	s.srcs[i], s.srcs[j] = s.srcs[j], s.srcs[i]
+	s.attrs[i], s.attrs[j] = s.attrs[j], s.attrs[i]
+	if s.addrs != nil {
+		s.addrs[i], s.addrs[j] = s.addrs[j], s.addrs[i]
+	}
+}
+
+// Less reports whether destination i is preferred over destination j.
+func (s *byRFC6724) Less(i, j int) bool {
+	da, db := s.dsts[i], s.dsts[j]
+	a, b := s.attrs[i], s.attrs[j]
+
+	if s.haveSrcs {
+		// Rule 1: avoid unusable destinations.
+		if (a.src != nil) != (b.src != nil) {
+			return a.src != nil
+		}
+		if a.src != nil && b.src != nil {
+			// Rule 2: prefer matching scope.
+			if am, bm := a.scope == a.srcScope, b.scope == b.srcScope; am != bm {
+				return am
+			}
+			// Rule 5: prefer matching label.
+			if am, bm := a.label == a.srcLabel, b.label == b.srcLabel; am != bm {
+				return am
+			}
+		}
+	}
+
+	// Rule 6: prefer higher precedence.
+	if a.precedence != b.precedence {
+		return a.precedence > b.precedence
+	}
+
+	// Rule 8: prefer smaller scope.
+	if a.scope != b.scope {
+		return a.scope < b.scope
+	}
+
+	// Rule 9: use longest matching prefix, within one address family.
+	if a.src != nil && b.src != nil && (da.To4() == nil) == (db.To4() == nil) {
+		if la, lb := commonPrefixLen(a.src, da), commonPrefixLen(b.src, db); la != lb {
+			return la > lb
+		}
+	}
+
+	// Rule 10: otherwise leave the order unchanged.
+	return false
+}
+
+// ── Policy table and scopes ─────────────────────────────────────────
+
+type policyEntry struct {
+	prefix     *net.IPNet
+	precedence uint8
+	label      uint8
+}
+
+// rfc6724PolicyTable is the default policy table from RFC 6724 section
+// 2.1, ordered from longest to shortest prefix.
+var rfc6724PolicyTable = []policyEntry{
+	{mustCIDR("::1/128"), 50, 0},
+	{mustCIDR("::ffff:0:0/96"), 35, 4},
+	{mustCIDR("::/96"), 1, 3},
+	{mustCIDR("2001::/32"), 5, 5},
+	{mustCIDR("2002::/16"), 30, 2},
+	{mustCIDR("3ffe::/16"), 1, 12},
+	{mustCIDR("fec0::/10"), 1, 11},
+	{mustCIDR("fc00::/7"), 3, 13},
+	{mustCIDR("::/0"), 40, 1},
+}
+
+func mustCIDR(s string) *net.IPNet {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// policyFor returns the policy table entry matching ip. IPv4
+// addresses are looked up as IPv4-mapped IPv6 addresses.
+func policyFor(ip net.IP) policyEntry {
+	ip16 := ip.To16()
+	for _, p := range rfc6724PolicyTable {
+		ones, bits := p.prefix.Mask.Size()
+		if bits == 128 && containsIP16(p.prefix.IP, ip16, ones) {
+			return p
+		}
+	}
+	return rfc6724PolicyTable[len(rfc6724PolicyTable)-1]
+}
+
+// containsIP16 reports whether the first ones bits of ip match prefix.
+// Unlike net.IPNet.Contains, it never treats IPv4-mapped addresses as
+// matching IPv4 prefixes.
+func containsIP16(prefix, ip net.IP, ones int) bool {
+	return commonPrefixLen16(prefix.To16(), ip) >= ones
+}
+
+const (
+	scopeInterfaceLocal = 0x1
+	scopeLinkLocal      = 0x2
+	scopeSiteLocal      = 0x5
+	scopeGlobal         = 0xe
+)
+
+// classifyScope returns the RFC 6724 section 3.1 scope of ip. IPv4
+// loopback and link-local addresses have link-local scope; all other
+// IPv4 addresses, including private ones, are global.
+func classifyScope(ip net.IP) uint8 {
+	if ip4 := ip.To4(); ip4 != nil {
+		if ip4.IsLoopback() || ip4.IsLinkLocalUnicast() {
+			return scopeLinkLocal
+		}
+		return scopeGlobal
+	}
+	switch {
+	case ip.IsMulticast():
+		return ip[1] & 0xf
+	case ip.IsLoopback(), ip.IsLinkLocalUnicast():
+		return scopeLinkLocal
+	case ip[0] == 0xfe && ip[1]&0xc0 == 0xc0:
+		return scopeSiteLocal
+	}
+	return scopeGlobal
+}
+
+// commonPrefixLen returns the number of leading bits a and b share,
+// capped at 64 for IPv6 (the usual interface identifier boundary) as
+// RFC 6724 rule 9 recommends. Addresses of different families share 0.
+func commonPrefixLen(a, b net.IP) int {
+	if a4, b4 := a.To4(), b.To4(); a4 != nil || b4 != nil {
+		if a4 == nil || b4 == nil {
+			return 0
+		}
+		return commonPrefixLen16(a4, b4)
+	}
+	n := commonPrefixLen16(a.To16(), b.To16())
+	if n > 64 {
+		n = 64
+	}
+	return n
+}
+
+// commonPrefixLen16 counts shared leading bits of equal-length a and b.
+func commonPrefixLen16(a, b net.IP) int {
+	if len(a) != len(b) {
+		return 0
+	}
+	n := 0
+	for i := range a {
+		x := a[i] ^ b[i]
+		if x == 0 {
+			n += 8
+			continue
+		}
+		for x&0x80 == 0 {
+			n++
+			x <<= 1
+		}
+		break
+	}
+	return n
+}
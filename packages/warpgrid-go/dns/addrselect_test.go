@@ -0,0 +1,119 @@
+package dns_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/anthropics/warpgrid/packages/warpgrid-go/dns"
+)
+
+// ── RFC 6724 address sorting tests ──────────────────────────────────
+
+func parseIPs(ss ...string) []net.IP {
+	ips := make([]net.IP, len(ss))
+	for i, s := range ss {
+		if s != "" {
+			ips[i] = net.ParseIP(s)
+		}
+	}
+	return ips
+}
+
+func assertOrder(t *testing.T, got []net.IP, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if !got[i].Equal(net.ParseIP(want[i])) {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+// The cases below are the worked examples from RFC 6724 section 10.2.
+
+func TestSortByRFC6724_PreferMatchingScope(t *testing.T) {
+	dsts := parseIPs("198.51.100.121", "2001:db8:1::1")
+	srcs := parseIPs("169.254.13.78", "2001:db8:1::2")
+	dns.SortByRFC6724(dsts, srcs)
+	assertOrder(t, dsts, "2001:db8:1::1", "198.51.100.121")
+
+	dsts = parseIPs("2001:db8:1::1", "198.51.100.121")
+	srcs = parseIPs("fe80::1", "198.51.100.117")
+	dns.SortByRFC6724(dsts, srcs)
+	assertOrder(t, dsts, "198.51.100.121", "2001:db8:1::1")
+}
+
+func TestSortByRFC6724_PreferHigherPrecedence(t *testing.T) {
+	dsts := parseIPs("10.1.2.3", "2001:db8:1::1")
+	srcs := parseIPs("10.1.2.4", "2001:db8:1::2")
+	dns.SortByRFC6724(dsts, srcs)
+	assertOrder(t, dsts, "2001:db8:1::1", "10.1.2.3")
+}
+
+func TestSortByRFC6724_PreferSmallerScope(t *testing.T) {
+	dsts := parseIPs("2001:db8:1::1", "fe80::1")
+	srcs := parseIPs("2001:db8:1::2", "fe80::2")
+	dns.SortByRFC6724(dsts, srcs)
+	assertOrder(t, dsts, "fe80::1", "2001:db8:1::1")
+}
+
+func TestSortByRFC6724_LongestMatchingPrefix(t *testing.T) {
+	dsts := parseIPs("2001:db8:3ffe::1", "2001:db8:1::1")
+	srcs := parseIPs("2001:db8:3f44::2", "2001:db8:1::2")
+	dns.SortByRFC6724(dsts, srcs)
+	assertOrder(t, dsts, "2001:db8:1::1", "2001:db8:3ffe::1")
+}
+
+func TestSortByRFC6724_AvoidUnusableDestinations(t *testing.T) {
+	dsts := parseIPs("2001:db8:1::1", "198.51.100.121")
+	srcs := parseIPs("", "198.51.100.117")
+	dns.SortByRFC6724(dsts, srcs)
+	assertOrder(t, dsts, "198.51.100.121", "2001:db8:1::1")
+	if srcs[1] != nil {
+		t.Fatal("expected sources to be reordered alongside destinations")
+	}
+}
+
+func TestSortByRFC6724_WithoutSourcesUsesPrecedence(t *testing.T) {
+	// No source information, as under WASI: precedence still prefers
+	// native IPv6 over IPv4 and ties keep their original order.
+	dsts := parseIPs("198.51.100.1", "198.51.100.2", "2001:db8::1")
+	dns.SortByRFC6724(dsts, nil)
+	assertOrder(t, dsts, "2001:db8::1", "198.51.100.1", "198.51.100.2")
+}
+
+func TestResolve_SortRFC6724IsOptIn(t *testing.T) {
+	answer := parseIPs("198.51.100.1", "2001:db8::1")
+	backend := mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		return answer, nil
+	})
+	sources := map[string]string{"198.51.100.1": "198.51.100.7"} // no IPv6 route
+
+	r := dns.NewResolver(backend)
+	ips, _ := r.Resolve("dual.warp.local")
+	assertOrder(t, ips, "198.51.100.1", "2001:db8::1")
+
+	r.SortRFC6724 = true
+	r.SourceAddr = func(dst net.IP) net.IP {
+		if src, ok := sources[dst.String()]; ok {
+			return net.ParseIP(src)
+		}
+		return nil
+	}
+	ips, err := r.Resolve("dual.warp.local")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertOrder(t, ips, "198.51.100.1", "2001:db8::1")
+
+	// With an IPv6 route available, precedence puts IPv6 first.
+	sources["2001:db8::1"] = "2001:db8::7"
+	ips, _ = r.Resolve("dual.warp.local")
+	assertOrder(t, ips, "2001:db8::1", "198.51.100.1")
+
+	if !answer[0].Equal(net.ParseIP("198.51.100.1")) {
+		t.Fatal("expected the backend's slice not to be reordered")
+	}
+}
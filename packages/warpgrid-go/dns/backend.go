@@ -0,0 +1,210 @@
+// WarpGrid DNS shim backend.
+//
+// WasiBackend holds the record decoding and buffer sizing logic for the
+// warpgrid:shim/dns host function. The host call itself is linked only
+// in WASI builds (shim_wasi.go); elsewhere the backend reports
+// ErrShimUnavailable unless a RawResolveFunc is injected, which lets the
+// buffer handling be exercised natively.
+//
+// ABI contract (matching libc-patches/0001-dns-getaddrinfo):
+//   Input: hostname (ptr, len), family (0 = any, 4 = A only, 6 = AAAA only),
+//          out_buf (ptr), out_buf_cap
+//   Output: count of records available, each record = 17 bytes:
+//     byte 0: family marker (4 = IPv4, 6 = IPv6)
+//     bytes 1-4: IPv4 address (when family=4)
+//     bytes 1-16: IPv6 address (when family=6)
+//
+// Zoned records (WasiBackend.Zones): the guest sets bit 8 (0x100) of
+// the family argument to ask for 33-byte records that also carry the
+// zone of scoped IPv6 addresses such as link-local fe80::/10, without
+// which they cannot be dialed:
+//     bytes 0-16: as above
+//     bytes 17-32: zone (interface name or decimal scope ID), NUL-padded;
+//                  all zero when the address has no zone
+// The 16 bytes fit any Linux interface name (IFNAMSIZ). out_buf_cap and
+// the returned count are in bytes and records exactly as before. Hosts
+// that predate zoned records must not be sent the flag, so it is opt-in.
+
+package dns
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+)
+
+const (
+	familyAny  = 0
+	familyIPv4 = 4
+	familyIPv6 = 6
+	recordSize = 17 // 1 byte family + 16 bytes address
+
+	familyZoned     = 1 << 8 // family flag requesting zoned records
+	zoneSize        = 16     // zone field of a zoned record
+	zonedRecordSize = recordSize + zoneSize
+
+	// DefaultBufferRecords is the number of records WasiBackend sizes
+	// its result buffer for when BufferRecords is unset or invalid.
+	DefaultBufferRecords = 32
+
+	// MaxBufferRecords caps BufferRecords and buffer growth, bounding
+	// the result buffer at about 17 KiB (33 KiB with zoned records).
+	MaxBufferRecords = 1024
+)
+
+// Address families accepted by WasiBackend.Family, matching the family
+// argument of the shim ABI.
+const (
+	FamilyAny  uint32 = familyAny
+	FamilyIPv4 uint32 = familyIPv4
+	FamilyIPv6 uint32 = familyIPv6
+)
+
+// ErrInvalidFamily is returned by WasiBackend.Resolve when Family is not
+// FamilyAny, FamilyIPv4, or FamilyIPv6.
+var ErrInvalidFamily = errors.New("dns: invalid address family")
+
+// RawResolveFunc performs the raw shim call: it writes up to
+// len(buf)/17 records (len(buf)/33 zoned records when family has the
+// zoned flag set) for hostname into buf and returns the number of
+// records the host has available, which may exceed what fit.
+type RawResolveFunc func(hostname string, family uint32, buf []byte) uint32
+
+// WasiBackend implements ResolverBackend by calling the WarpGrid DNS
+// host shim through the //go:wasmimport directive.
+type WasiBackend struct {
+	// BufferRecords is how many records the result buffer holds on the
+	// first call. If the host reports more, the buffer grows to fit
+	// (up to MaxBufferRecords) and the call is retried once, so
+	// deployments with large A-record pools can pre-size it to avoid
+	// the retry. Zero or negative means DefaultBufferRecords; values
+	// above MaxBufferRecords are capped.
+	BufferRecords int
+
+	// Family restricts the query the host performs: FamilyIPv4 for A
+	// records only, FamilyIPv6 for AAAA only, or FamilyAny (the zero
+	// value) for both. Asking the host for one family saves it the
+	// lookups a caller would only discard; any records of the other
+	// family it returns anyway are dropped.
+	Family uint32
+
+	// Zones asks the host for zoned records, so IPv6 link-local answers
+	// come back with the zone needed to dial them (see ResolveIPAddr).
+	// Only set it for hosts that support the zoned record format
+	// described at the top of this file.
+	Zones bool
+
+	// Raw overrides the host call. When nil, the linked shim is used,
+	// or ErrShimUnavailable is returned in builds without it.
+	Raw RawResolveFunc
+}
+
+// EffectiveBufferRecords returns the initial buffer size, in records,
+// that Resolve uses after validating BufferRecords.
+func (b WasiBackend) EffectiveBufferRecords() int {
+	switch {
+	case b.BufferRecords <= 0:
+		return DefaultBufferRecords
+	case b.BufferRecords > MaxBufferRecords:
+		return MaxBufferRecords
+	}
+	return b.BufferRecords
+}
+
+// Resolve calls warpgrid:shim/dns.resolve-address for the given hostname.
+// Zones are dropped; use ResolveIPAddr to keep them.
+func (b WasiBackend) Resolve(hostname string) ([]net.IP, error) {
+	addrs, err := b.ResolveIPAddr(hostname)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, len(addrs))
+	for i, addr := range addrs {
+		ips[i] = addr.IP
+	}
+	return ips, nil
+}
+
+// ResolveIPAddr is like Resolve but returns each address with its zone,
+// which is set only when Zones is enabled and the host reported one.
+func (b WasiBackend) ResolveIPAddr(hostname string) ([]net.IPAddr, error) {
+	if hostname == "" {
+		return nil, fmt.Errorf("dns: empty hostname")
+	}
+
+	switch b.Family {
+	case FamilyAny, FamilyIPv4, FamilyIPv6:
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrInvalidFamily, b.Family)
+	}
+
+	raw := b.Raw
+	if raw == nil {
+		raw = shimResolve
+	}
+	if raw == nil {
+		return nil, ErrShimUnavailable
+	}
+
+	familyArg, size := b.Family, recordSize
+	if b.Zones {
+		familyArg, size = familyArg|familyZoned, zonedRecordSize
+	}
+
+	records := b.EffectiveBufferRecords()
+	buf := make([]byte, records*size)
+	count := raw(hostname, familyArg, buf)
+
+	// Grow and retry once when the host has more records than fit.
+	if count > uint32(records) && records < MaxBufferRecords {
+		records = MaxBufferRecords
+		if count < uint32(records) {
+			records = int(count)
+		}
+		buf = make([]byte, records*size)
+		count = raw(hostname, familyArg, buf)
+	}
+
+	if count == 0 {
+		return nil, fmt.Errorf("dns: host not found: %s", hostname)
+	}
+
+	// Clamp to buffer capacity to prevent out-of-bounds access
+	// if the host returns a count larger than our buffer can hold.
+	bufCap := uint32(len(buf) / size)
+	if count > bufCap {
+		count = bufCap
+	}
+
+	addrs := make([]net.IPAddr, 0, count)
+	for i := uint32(0); i < count; i++ {
+		rec := buf[i*uint32(size) : (i+1)*uint32(size)]
+		family := rec[0]
+		addrBytes := rec[1:recordSize]
+		if b.Family != FamilyAny && uint32(family) != b.Family {
+			continue
+		}
+
+		switch family {
+		case familyIPv4:
+			ip := make(net.IP, 4)
+			copy(ip, addrBytes[:4])
+			addrs = append(addrs, net.IPAddr{IP: ip})
+		case familyIPv6:
+			ip := make(net.IP, 16)
+			copy(ip, addrBytes[:16])
+			var zone string
+			if b.Zones {
+				zone = string(bytes.TrimRight(rec[recordSize:], "\x00"))
+			}
+			addrs = append(addrs, net.IPAddr{IP: ip, Zone: zone})
+		}
+	}
+
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("dns: host not found: %s", hostname)
+	}
+
+	return addrs, nil
+}
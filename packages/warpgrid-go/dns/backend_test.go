@@ -0,0 +1,224 @@
+package dns_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/anthropics/warpgrid/packages/warpgrid-go/dns"
+)
+
+// ── WasiBackend buffer sizing tests ─────────────────────────────────
+
+// fakeShim returns a RawResolveFunc that has n IPv4 records available
+// and records the buffer capacity (in records) of each call.
+func fakeShim(n int, calls *[]int) dns.RawResolveFunc {
+	return func(hostname string, family uint32, buf []byte) uint32 {
+		capacity := len(buf) / 17
+		*calls = append(*calls, capacity)
+		for i := 0; i < n && i < capacity; i++ {
+			rec := buf[i*17:]
+			rec[0] = 4
+			rec[1], rec[2], rec[3], rec[4] = 10, 0, byte(i>>8), byte(i)
+		}
+		return uint32(n)
+	}
+}
+
+func TestWasiBackend_ConfiguredBufferFitsInOneCall(t *testing.T) {
+	var calls []int
+	backend := dns.WasiBackend{BufferRecords: 64, Raw: fakeShim(50, &calls)}
+
+	ips, err := backend.Resolve("pool.warp.local")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ips) != 50 {
+		t.Fatalf("expected 50 IPs, got %d", len(ips))
+	}
+	if len(calls) != 1 || calls[0] != 64 {
+		t.Fatalf("expected a single call with 64 records, got %v", calls)
+	}
+}
+
+func TestWasiBackend_DefaultBufferGrowsAndRetries(t *testing.T) {
+	var calls []int
+	backend := dns.WasiBackend{Raw: fakeShim(50, &calls)}
+
+	ips, err := backend.Resolve("pool.warp.local")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ips) != 50 {
+		t.Fatalf("expected 50 IPs after growing, got %d", len(ips))
+	}
+	if len(calls) != 2 || calls[0] != dns.DefaultBufferRecords || calls[1] != 50 {
+		t.Fatalf("expected calls with [32 50] records, got %v", calls)
+	}
+}
+
+func TestWasiBackend_InvalidBufferRecordsFallsBackToDefault(t *testing.T) {
+	for _, n := range []int{0, -5} {
+		backend := dns.WasiBackend{BufferRecords: n}
+		if got := backend.EffectiveBufferRecords(); got != dns.DefaultBufferRecords {
+			t.Fatalf("BufferRecords %d: expected %d, got %d", n, dns.DefaultBufferRecords, got)
+		}
+	}
+
+	huge := dns.WasiBackend{BufferRecords: 1 << 30}
+	if got := huge.EffectiveBufferRecords(); got != dns.MaxBufferRecords {
+		t.Fatalf("expected cap at %d, got %d", dns.MaxBufferRecords, got)
+	}
+}
+
+func TestWasiBackend_WithoutShimReturnsUnavailable(t *testing.T) {
+	_, err := dns.WasiBackend{}.Resolve("db.warp.local")
+	if !errors.Is(err, dns.ErrShimUnavailable) {
+		t.Fatalf("expected ErrShimUnavailable on native build, got %v", err)
+	}
+}
+
+func TestWasiBackend_ZeroCountIsNotFound(t *testing.T) {
+	var calls []int
+	_, err := dns.WasiBackend{Raw: fakeShim(0, &calls)}.Resolve("missing.warp.local")
+	if err == nil || errors.Is(err, dns.ErrShimUnavailable) {
+		t.Fatalf("expected host not found error, got %v", err)
+	}
+}
+
+// ── WasiBackend family tests ────────────────────────────────────────
+
+// familyShim returns a RawResolveFunc that records the family argument
+// of each call and answers with one IPv4 and one IPv6 record.
+func familyShim(families *[]uint32) dns.RawResolveFunc {
+	return func(hostname string, family uint32, buf []byte) uint32 {
+		*families = append(*families, family)
+		buf[0] = 4
+		buf[1], buf[2], buf[3], buf[4] = 10, 0, 0, 1
+		buf[17] = 6
+		buf[18], buf[19], buf[33] = 0xfd, 0x00, 0x01
+		return 2
+	}
+}
+
+func TestWasiBackend_FamilyForwarded(t *testing.T) {
+	for _, family := range []uint32{dns.FamilyAny, dns.FamilyIPv4, dns.FamilyIPv6} {
+		var families []uint32
+		backend := dns.WasiBackend{Family: family, Raw: familyShim(&families)}
+
+		if _, err := backend.Resolve("db.warp.local"); err != nil {
+			t.Fatalf("family %d: unexpected error: %v", family, err)
+		}
+		if len(families) != 1 || families[0] != family {
+			t.Fatalf("expected family %d forwarded, got %v", family, families)
+		}
+	}
+}
+
+func TestWasiBackend_FamilyFiltersOtherRecords(t *testing.T) {
+	var families []uint32
+	backend := dns.WasiBackend{Family: dns.FamilyIPv6, Raw: familyShim(&families)}
+
+	ips, err := backend.Resolve("db.warp.local")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ips) != 1 || ips[0].To4() != nil {
+		t.Fatalf("expected a single IPv6 address, got %v", ips)
+	}
+}
+
+func TestWasiBackend_InvalidFamily(t *testing.T) {
+	var families []uint32
+	backend := dns.WasiBackend{Family: 5, Raw: familyShim(&families)}
+
+	_, err := backend.Resolve("db.warp.local")
+	if !errors.Is(err, dns.ErrInvalidFamily) {
+		t.Fatalf("expected ErrInvalidFamily, got %v", err)
+	}
+	if len(families) != 0 {
+		t.Fatalf("expected no shim call for an invalid family, got %v", families)
+	}
+}
+
+// ── WasiBackend zone tests ──────────────────────────────────────────
+
+// zonedShim returns a RawResolveFunc that records the family argument
+// of each call and answers with a link-local IPv6 record in zone and
+// an IPv4 record, in the zoned format when the family asks for it.
+func zonedShim(zone string, families *[]uint32) dns.RawResolveFunc {
+	return func(hostname string, family uint32, buf []byte) uint32 {
+		*families = append(*families, family)
+		size := 17
+		if family&0x100 != 0 {
+			size = 33
+		}
+		buf[0] = 6
+		copy(buf[1:17], net.ParseIP("fe80::1"))
+		if size == 33 {
+			copy(buf[17:33], zone)
+		}
+		rec := buf[size:]
+		rec[0] = 4
+		rec[1], rec[2], rec[3], rec[4] = 10, 0, 0, 1
+		return 2
+	}
+}
+
+func TestWasiBackend_ZonesRoundTrip(t *testing.T) {
+	var families []uint32
+	backend := dns.WasiBackend{Zones: true, Raw: zonedShim("wg0", &families)}
+
+	addrs, err := backend.ResolveIPAddr("router.warp.local")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(families) != 1 || families[0] != dns.FamilyAny|0x100 {
+		t.Fatalf("expected the zoned flag in the family argument, got %v", families)
+	}
+	if len(addrs) != 2 || addrs[0].String() != "fe80::1%wg0" || addrs[1].String() != "10.0.0.1" {
+		t.Fatalf("expected [fe80::1%%wg0 10.0.0.1], got %v", addrs)
+	}
+
+	// Resolve keeps the addresses but drops the zone.
+	ips, err := backend.Resolve("router.warp.local")
+	if err != nil || len(ips) != 2 || !ips[0].Equal(net.ParseIP("fe80::1")) {
+		t.Fatalf("expected fe80::1 first from Resolve, got %v (%v)", ips, err)
+	}
+}
+
+func TestWasiBackend_ZonesOffUsesLegacyRecords(t *testing.T) {
+	var families []uint32
+	backend := dns.WasiBackend{Raw: zonedShim("wg0", &families)}
+
+	addrs, err := backend.ResolveIPAddr("router.warp.local")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if families[0] != dns.FamilyAny {
+		t.Fatalf("expected no zoned flag without Zones, got %#x", families[0])
+	}
+	if len(addrs) != 2 || addrs[0].Zone != "" || addrs[1].String() != "10.0.0.1" {
+		t.Fatalf("expected unzoned 17-byte records, got %v", addrs)
+	}
+}
+
+func TestResolver_ResolveIPAddrKeepsZones(t *testing.T) {
+	var families []uint32
+	r := dns.NewResolver(dns.WasiBackend{Zones: true, Raw: zonedShim("3", &families)})
+	r.TTL = time.Minute
+
+	for i := 0; i < 2; i++ { // second answer comes from the cache
+		addrs, err := r.ResolveIPAddr("router.warp.local")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if addrs[0].String() != "fe80::1%3" {
+			t.Fatalf("expected fe80::1%%3, got %v", addrs)
+		}
+	}
+	if len(families) != 1 {
+		t.Fatalf("expected 1 shim call, got %d", len(families))
+	}
+}
@@ -0,0 +1,159 @@
+package dns
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// answerCache holds resolved answers keyed by the hostname passed to
+// Resolve, along with the in-flight lookups used to coalesce concurrent
+// queries for the same name.
+type answerCache struct {
+	mu       sync.Mutex
+	entries  map[string]cacheEntry
+	inflight map[string]*flight
+}
+
+// cacheEntry is a successful answer and the time it was fetched.
+type cacheEntry struct {
+	addrs   []net.IPAddr
+	fetched time.Time
+}
+
+// flight is a backend lookup shared by every caller that asks for the
+// same name while it runs.
+type flight struct {
+	done  chan struct{}
+	addrs []net.IPAddr
+	err   error
+}
+
+// CacheEntry describes one cached answer, as returned by CacheSnapshot.
+type CacheEntry struct {
+	// Addrs is the answer as the backend gave it, before UnmapV4 and
+	// SortRFC6724 are applied.
+	Addrs []net.IPAddr
+
+	// InsertedAt is when the answer was fetched.
+	InsertedAt time.Time
+
+	// ExpiresAt is when the answer stops being fresh, InsertedAt plus
+	// TTL. After it the answer is served stale, with a background
+	// refresh, until StaleUntil.
+	ExpiresAt time.Time
+
+	// StaleUntil is when the answer stops being served at all, bounded
+	// by StaleTTL and MaxCacheAge.
+	StaleUntil time.Time
+}
+
+// CacheSnapshot returns the cached answers keyed by the name they were
+// resolved under, for debug endpoints that need to show what DNS-based
+// routing is working from. Entries past StaleUntil that have not been
+// evicted yet are included. The result is a copy: modifying it, down
+// to the addresses' bytes, does not affect the cache.
+func (r *Resolver) CacheSnapshot() map[string]CacheEntry {
+	r.cache.mu.Lock()
+	defer r.cache.mu.Unlock()
+
+	snapshot := make(map[string]CacheEntry, len(r.cache.entries))
+	for name, e := range r.cache.entries {
+		addrs := make([]net.IPAddr, len(e.addrs))
+		for i, addr := range e.addrs {
+			addrs[i] = net.IPAddr{IP: append(net.IP(nil), addr.IP...), Zone: addr.Zone}
+		}
+		usable := r.TTL + r.StaleTTL
+		if r.MaxCacheAge > 0 && r.MaxCacheAge < usable {
+			usable = r.MaxCacheAge
+		}
+		snapshot[name] = CacheEntry{
+			Addrs:      addrs,
+			InsertedAt: e.fetched,
+			ExpiresAt:  e.fetched.Add(r.TTL),
+			StaleUntil: e.fetched.Add(usable),
+		}
+	}
+	return snapshot
+}
+
+// cachingEnabled reports whether Resolve should consult the cache.
+func (r *Resolver) cachingEnabled() bool {
+	return r.TTL > 0 || r.StaleTTL > 0
+}
+
+func (r *Resolver) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
+
+// tooOld reports whether an entry of the given age may no longer be
+// served, even stale.
+func (r *Resolver) tooOld(age time.Duration) bool {
+	return age >= r.TTL+r.StaleTTL || (r.MaxCacheAge > 0 && age >= r.MaxCacheAge)
+}
+
+// cachedLookup serves hostname from the cache where possible. A fresh
+// answer is returned as is. An answer past TTL but within StaleTTL is
+// returned immediately while a background refresh runs; if that
+// refresh fails the stale answer keeps being served until StaleTTL
+// elapses. Anything older, or older than MaxCacheAge, is looked up in
+// the foreground.
+func (r *Resolver) cachedLookup(hostname string) ([]net.IPAddr, error) {
+	now := r.now()
+
+	r.cache.mu.Lock()
+	entry, ok := r.cache.entries[hostname]
+	r.cache.mu.Unlock()
+
+	if age := now.Sub(entry.fetched); ok && !r.tooOld(age) {
+		if age >= r.TTL {
+			r.startFlight(hostname)
+		}
+		return entry.addrs, nil
+	}
+
+	f := r.startFlight(hostname)
+	<-f.done
+	return f.addrs, f.err
+}
+
+// startFlight returns the in-flight lookup for hostname, starting one
+// if none is running. On success the answer replaces the cached entry;
+// on failure the existing entry is left to age out.
+func (r *Resolver) startFlight(hostname string) *flight {
+	r.cache.mu.Lock()
+	defer r.cache.mu.Unlock()
+
+	if f, ok := r.cache.inflight[hostname]; ok {
+		return f
+	}
+	if r.cache.inflight == nil {
+		r.cache.inflight = make(map[string]*flight)
+	}
+	f := &flight{done: make(chan struct{})}
+	r.cache.inflight[hostname] = f
+
+	go func() {
+		addrs, err := r.lookup(hostname)
+		fetched := r.now()
+
+		r.cache.mu.Lock()
+		if err == nil && len(addrs) > 0 {
+			if r.cache.entries == nil {
+				r.cache.entries = make(map[string]cacheEntry)
+			}
+			r.cache.entries[hostname] = cacheEntry{addrs: addrs, fetched: fetched}
+		} else if e, ok := r.cache.entries[hostname]; ok && r.tooOld(fetched.Sub(e.fetched)) {
+			delete(r.cache.entries, hostname)
+		}
+		delete(r.cache.inflight, hostname)
+		r.cache.mu.Unlock()
+
+		f.addrs, f.err = addrs, err
+		close(f.done)
+	}()
+	return f
+}
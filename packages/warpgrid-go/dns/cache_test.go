@@ -0,0 +1,331 @@
+package dns_test
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anthropics/warpgrid/packages/warpgrid-go/dns"
+)
+
+// ── Helpers ─────────────────────────────────────────────────────────
+
+// fakeClock is a settable clock for Resolver.Now.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// switchableBackend answers with the current IP, or fails while down.
+type switchableBackend struct {
+	mu    sync.Mutex
+	ip    net.IP
+	down  bool
+	calls atomic.Int32
+}
+
+func (b *switchableBackend) Resolve(hostname string) ([]net.IP, error) {
+	b.calls.Add(1)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.down {
+		return nil, errors.New("shim outage")
+	}
+	return []net.IP{b.ip}, nil
+}
+
+func (b *switchableBackend) set(ip string, down bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.ip = net.ParseIP(ip)
+	b.down = down
+}
+
+// waitFor polls cond until it holds or a second passes.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for background refresh")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func resolveOne(t *testing.T, r *dns.Resolver, host string) string {
+	t.Helper()
+	ips, err := r.Resolve(host)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ips) != 1 {
+		t.Fatalf("expected 1 IP, got %v", ips)
+	}
+	return ips[0].String()
+}
+
+// ── Cache tests ─────────────────────────────────────────────────────
+
+func TestResolve_TTLCachesAnswers(t *testing.T) {
+	backend := &switchableBackend{}
+	backend.set("10.0.0.1", false)
+	clock := newFakeClock()
+	r := dns.NewResolver(backend)
+	r.TTL = time.Minute
+	r.Now = clock.Now
+
+	resolveOne(t, r, "db.warp.local")
+	backend.set("10.0.0.2", false)
+	if got := resolveOne(t, r, "db.warp.local"); got != "10.0.0.1" {
+		t.Fatalf("expected cached 10.0.0.1, got %s", got)
+	}
+	if n := backend.calls.Load(); n != 1 {
+		t.Fatalf("expected 1 backend call, got %d", n)
+	}
+
+	clock.Advance(time.Minute)
+	if got := resolveOne(t, r, "db.warp.local"); got != "10.0.0.2" {
+		t.Fatalf("expected fresh 10.0.0.2 after TTL, got %s", got)
+	}
+}
+
+func TestResolve_StaleServedDuringOutage(t *testing.T) {
+	backend := &switchableBackend{}
+	backend.set("10.0.0.1", false)
+	clock := newFakeClock()
+	r := dns.NewResolver(backend)
+	r.TTL = time.Minute
+	r.StaleTTL = 5 * time.Minute
+	r.Now = clock.Now
+
+	resolveOne(t, r, "db.warp.local")
+
+	backend.set("", true)
+	clock.Advance(2 * time.Minute)
+	if got := resolveOne(t, r, "db.warp.local"); got != "10.0.0.1" {
+		t.Fatalf("expected stale 10.0.0.1 during outage, got %s", got)
+	}
+	waitFor(t, func() bool { return backend.calls.Load() >= 2 })
+
+	// The failed refresh must not evict the stale answer.
+	clock.Advance(2 * time.Minute)
+	if got := resolveOne(t, r, "db.warp.local"); got != "10.0.0.1" {
+		t.Fatalf("expected stale 10.0.0.1 after failed refresh, got %s", got)
+	}
+
+	// Past StaleTTL the outage surfaces.
+	clock.Advance(3 * time.Minute)
+	if _, err := r.Resolve("db.warp.local"); err == nil {
+		t.Fatal("expected error once StaleTTL elapsed")
+	}
+}
+
+func TestResolve_StaleUpdatedAfterBackgroundRefresh(t *testing.T) {
+	backend := &switchableBackend{}
+	backend.set("10.0.0.1", false)
+	clock := newFakeClock()
+	r := dns.NewResolver(backend)
+	r.TTL = time.Minute
+	r.StaleTTL = 5 * time.Minute
+	r.Now = clock.Now
+
+	resolveOne(t, r, "db.warp.local")
+
+	backend.set("10.0.0.2", false)
+	clock.Advance(2 * time.Minute)
+	if got := resolveOne(t, r, "db.warp.local"); got != "10.0.0.1" {
+		t.Fatalf("expected stale answer returned immediately, got %s", got)
+	}
+
+	waitFor(t, func() bool { return resolveOne(t, r, "db.warp.local") == "10.0.0.2" })
+	calls := backend.calls.Load()
+	if got := resolveOne(t, r, "db.warp.local"); got != "10.0.0.2" {
+		t.Fatalf("expected refreshed 10.0.0.2, got %s", got)
+	}
+	if n := backend.calls.Load(); n != calls {
+		t.Fatalf("expected refreshed answer to be fresh, got %d extra backend calls", n-calls)
+	}
+}
+
+func TestResolve_ConcurrentMissesCoalesce(t *testing.T) {
+	release := make(chan struct{})
+	var calls atomic.Int32
+	backend := mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		calls.Add(1)
+		<-release
+		return []net.IP{net.ParseIP("10.0.0.1")}, nil
+	})
+	r := dns.NewResolver(backend)
+	r.TTL = time.Minute
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.Resolve("db.warp.local")
+		}()
+	}
+	waitFor(t, func() bool { return calls.Load() >= 1 })
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("expected 1 backend call for concurrent misses, got %d", n)
+	}
+}
+
+func TestResolve_FailuresAreNotCached(t *testing.T) {
+	backend := &switchableBackend{}
+	backend.set("", true)
+	r := dns.NewResolver(backend)
+	r.TTL = time.Minute
+
+	if _, err := r.Resolve("db.warp.local"); err == nil {
+		t.Fatal("expected error from failing backend")
+	}
+	backend.set("10.0.0.1", false)
+	if got := resolveOne(t, r, "db.warp.local"); got != "10.0.0.1" {
+		t.Fatalf("expected 10.0.0.1 once backend recovers, got %s", got)
+	}
+}
+
+// ── MaxCacheAge tests ───────────────────────────────────────────────
+
+func TestResolve_MaxCacheAgeForcesReresolution(t *testing.T) {
+	backend := &switchableBackend{}
+	backend.set("10.0.0.1", false)
+	clock := newFakeClock()
+	r := dns.NewResolver(backend)
+	r.TTL = time.Hour
+	r.MaxCacheAge = 5 * time.Minute
+	r.Now = clock.Now
+
+	resolveOne(t, r, "db.warp.local")
+	backend.set("10.0.0.2", false)
+
+	clock.Advance(4 * time.Minute)
+	if got := resolveOne(t, r, "db.warp.local"); got != "10.0.0.1" {
+		t.Fatalf("expected cached 10.0.0.1 within MaxCacheAge, got %s", got)
+	}
+	if n := backend.calls.Load(); n != 1 {
+		t.Fatalf("expected 1 backend call within MaxCacheAge, got %d", n)
+	}
+
+	clock.Advance(time.Minute)
+	if got := resolveOne(t, r, "db.warp.local"); got != "10.0.0.2" {
+		t.Fatalf("expected re-resolution past MaxCacheAge despite TTL, got %s", got)
+	}
+	if n := backend.calls.Load(); n != 2 {
+		t.Fatalf("expected 2 backend calls, got %d", n)
+	}
+}
+
+func TestResolve_MaxCacheAgeBoundsStaleServing(t *testing.T) {
+	backend := &switchableBackend{}
+	backend.set("10.0.0.1", false)
+	clock := newFakeClock()
+	r := dns.NewResolver(backend)
+	r.TTL = time.Minute
+	r.StaleTTL = time.Hour
+	r.MaxCacheAge = 10 * time.Minute
+	r.Now = clock.Now
+
+	resolveOne(t, r, "db.warp.local")
+	backend.set("", true)
+
+	clock.Advance(5 * time.Minute)
+	if got := resolveOne(t, r, "db.warp.local"); got != "10.0.0.1" {
+		t.Fatalf("expected stale answer within MaxCacheAge, got %s", got)
+	}
+
+	clock.Advance(5 * time.Minute)
+	if _, err := r.Resolve("db.warp.local"); err == nil {
+		t.Fatal("expected stale answer dropped past MaxCacheAge")
+	}
+}
+
+// ── CacheSnapshot tests ─────────────────────────────────────────────
+
+func TestCacheSnapshot_ReflectsCachedAnswers(t *testing.T) {
+	backend := &switchableBackend{}
+	backend.set("10.0.0.1", false)
+	clock := newFakeClock()
+	r := dns.NewResolver(backend)
+	r.TTL = time.Minute
+	r.StaleTTL = 5 * time.Minute
+	r.Now = clock.Now
+
+	start := clock.Now()
+	resolveOne(t, r, "db.warp.local")
+	clock.Advance(10 * time.Second)
+	backend.set("10.0.0.2", false)
+	resolveOne(t, r, "cache.warp.local")
+
+	snap := r.CacheSnapshot()
+	if len(snap) != 2 {
+		t.Fatalf("expected 2 entries, got %v", snap)
+	}
+	db := snap["db.warp.local"]
+	if len(db.Addrs) != 1 || db.Addrs[0].IP.String() != "10.0.0.1" {
+		t.Fatalf("expected db.warp.local -> 10.0.0.1, got %v", db.Addrs)
+	}
+	if !db.InsertedAt.Equal(start) || !db.ExpiresAt.Equal(start.Add(time.Minute)) || !db.StaleUntil.Equal(start.Add(6*time.Minute)) {
+		t.Fatalf("expected timestamps from %v, got inserted %v expires %v stale until %v", start, db.InsertedAt, db.ExpiresAt, db.StaleUntil)
+	}
+	cache := snap["cache.warp.local"]
+	if !cache.InsertedAt.Equal(start.Add(10*time.Second)) || !cache.InsertedAt.Before(cache.ExpiresAt) {
+		t.Fatalf("expected cache.warp.local inserted 10s later, got %v", cache.InsertedAt)
+	}
+}
+
+func TestCacheSnapshot_IsACopy(t *testing.T) {
+	backend := &switchableBackend{}
+	backend.set("10.0.0.1", false)
+	r := dns.NewResolver(backend)
+	r.TTL = time.Minute
+
+	resolveOne(t, r, "db.warp.local")
+	snap := r.CacheSnapshot()
+	snap["db.warp.local"].Addrs[0].IP[len(snap["db.warp.local"].Addrs[0].IP)-1] = 99
+	delete(snap, "db.warp.local")
+
+	if got := resolveOne(t, r, "db.warp.local"); got != "10.0.0.1" {
+		t.Fatalf("expected the cache unaffected by snapshot edits, got %s", got)
+	}
+	if n := backend.calls.Load(); n != 1 {
+		t.Fatalf("expected the answer still cached, got %d backend calls", n)
+	}
+}
+
+func TestCacheSnapshot_EmptyWithoutCaching(t *testing.T) {
+	backend := &switchableBackend{}
+	backend.set("10.0.0.1", false)
+	r := dns.NewResolver(backend)
+
+	resolveOne(t, r, "db.warp.local")
+	if snap := r.CacheSnapshot(); len(snap) != 0 {
+		t.Fatalf("expected an empty snapshot with caching off, got %v", snap)
+	}
+}
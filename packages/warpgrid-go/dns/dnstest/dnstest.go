@@ -0,0 +1,91 @@
+// Package dnstest provides a configurable in-memory dns.ResolverBackend
+// for testing code that resolves through the WarpGrid DNS shim.
+//
+// FakeBackend answers from a per-host table and can inject latency and
+// failures, so Dialer timeout, failover, and Happy Eyeballs behaviour
+// can be exercised deterministically on standard Go. For per-hostname
+// errors set at runtime, see nettest.ControllableBackend.
+package dnstest
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrInjected is the error returned for failures injected by FailAfter
+// or FailEvery when FakeBackend.Err is nil.
+var ErrInjected = errors.New("dnstest: injected failure")
+
+// FakeBackend is a dns.ResolverBackend backed by a host table. Set its
+// fields before the first Resolve; it is then safe for concurrent use.
+type FakeBackend struct {
+	// Hosts maps hostnames to their addresses. Unknown hostnames fail
+	// with a "HostNotFound" error, as the shim reports them.
+	Hosts map[string][]net.IP
+
+	// Latency delays every Resolve call, including failing ones.
+	Latency time.Duration
+
+	// FailAfter, when positive, lets that many calls succeed and fails
+	// every later call, simulating a resolver that goes away.
+	FailAfter int
+
+	// FailEvery, when positive, fails every FailEvery-th call (the
+	// FailEvery-th, 2*FailEvery-th, ...), simulating a flaky resolver.
+	FailEvery int
+
+	// Err is the error returned for injected failures. Defaults to
+	// ErrInjected.
+	Err error
+
+	mu    sync.Mutex
+	calls int
+}
+
+// Resolve implements dns.ResolverBackend.
+func (b *FakeBackend) Resolve(hostname string) ([]net.IP, error) {
+	b.mu.Lock()
+	b.calls++
+	n := b.calls
+	b.mu.Unlock()
+
+	if b.Latency > 0 {
+		time.Sleep(b.Latency)
+	}
+
+	if (b.FailAfter > 0 && n > b.FailAfter) || (b.FailEvery > 0 && n%b.FailEvery == 0) {
+		if b.Err != nil {
+			return nil, b.Err
+		}
+		return nil, ErrInjected
+	}
+
+	ips, ok := b.Hosts[hostname]
+	if !ok {
+		return nil, errors.New("HostNotFound: " + hostname)
+	}
+	out := make([]net.IP, len(ips))
+	copy(out, ips)
+	return out, nil
+}
+
+// Calls returns the number of Resolve calls so far.
+func (b *FakeBackend) Calls() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.calls
+}
+
+// IPs parses addrs into a slice for use in Hosts. It panics if an entry
+// is not a valid IP address.
+func IPs(addrs ...string) []net.IP {
+	ips := make([]net.IP, len(addrs))
+	for i, s := range addrs {
+		if ips[i] = net.ParseIP(s); ips[i] == nil {
+			panic("dnstest: invalid IP " + s)
+		}
+	}
+	return ips
+}
@@ -0,0 +1,93 @@
+package dnstest_test
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/warpgrid/packages/warpgrid-go/dns"
+	"github.com/anthropics/warpgrid/packages/warpgrid-go/dns/dnstest"
+)
+
+var _ dns.ResolverBackend = (*dnstest.FakeBackend)(nil)
+
+// ── FakeBackend tests ───────────────────────────────────────────────
+
+func TestFakeBackend_PerHostMapping(t *testing.T) {
+	backend := &dnstest.FakeBackend{Hosts: map[string][]net.IP{
+		"db.warp.local":    dnstest.IPs("10.0.0.1", "10.0.0.2"),
+		"cache.warp.local": dnstest.IPs("fd00::1"),
+	}}
+	resolver := dns.NewResolver(backend)
+
+	ips, err := resolver.Resolve("db.warp.local")
+	if err != nil || len(ips) != 2 || ips[0].String() != "10.0.0.1" {
+		t.Fatalf("expected [10.0.0.1 10.0.0.2], got %v (err %v)", ips, err)
+	}
+	ips, err = resolver.Resolve("cache.warp.local")
+	if err != nil || len(ips) != 1 || ips[0].String() != "fd00::1" {
+		t.Fatalf("expected [fd00::1], got %v (err %v)", ips, err)
+	}
+	if _, err := resolver.Resolve("missing.warp.local"); err == nil || !strings.Contains(err.Error(), "HostNotFound") {
+		t.Fatalf("expected HostNotFound error, got %v", err)
+	}
+	if backend.Calls() != 3 {
+		t.Fatalf("expected 3 calls, got %d", backend.Calls())
+	}
+}
+
+func TestFakeBackend_Latency(t *testing.T) {
+	backend := &dnstest.FakeBackend{
+		Hosts:   map[string][]net.IP{"slow.warp.local": dnstest.IPs("10.0.0.1")},
+		Latency: 50 * time.Millisecond,
+	}
+
+	start := time.Now()
+	if _, err := backend.Resolve("slow.warp.local"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("expected at least 50ms of latency, got %v", elapsed)
+	}
+}
+
+func TestFakeBackend_FailAfter(t *testing.T) {
+	backend := &dnstest.FakeBackend{
+		Hosts:     map[string][]net.IP{"db.warp.local": dnstest.IPs("10.0.0.1")},
+		FailAfter: 2,
+	}
+
+	for i := 1; i <= 4; i++ {
+		_, err := backend.Resolve("db.warp.local")
+		if i <= 2 && err != nil {
+			t.Fatalf("call %d: unexpected error: %v", i, err)
+		}
+		if i > 2 && !errors.Is(err, dnstest.ErrInjected) {
+			t.Fatalf("call %d: expected ErrInjected, got %v", i, err)
+		}
+	}
+}
+
+func TestFakeBackend_FailEveryIsIntermittent(t *testing.T) {
+	custom := errors.New("SERVFAIL")
+	backend := &dnstest.FakeBackend{
+		Hosts:     map[string][]net.IP{"db.warp.local": dnstest.IPs("10.0.0.1")},
+		FailEvery: 3,
+		Err:       custom,
+	}
+
+	var failed []int
+	for i := 1; i <= 7; i++ {
+		if _, err := backend.Resolve("db.warp.local"); err != nil {
+			if !errors.Is(err, custom) {
+				t.Fatalf("call %d: expected custom error, got %v", i, err)
+			}
+			failed = append(failed, i)
+		}
+	}
+	if len(failed) != 2 || failed[0] != 3 || failed[1] != 6 {
+		t.Fatalf("expected calls 3 and 6 to fail, got %v", failed)
+	}
+}
@@ -0,0 +1,59 @@
+// Fallback resolution for builds without the WarpGrid DNS shim.
+//
+// //go:wasmimport bindings are linked statically: the import is recorded
+// in the module's import section and resolved when the host instantiates
+// the module. There is no way to probe for a missing import at runtime,
+// and a host that does not provide warpgrid_shim.dns_resolve either
+// rejects the module or traps on the first call.
+//
+// Availability is therefore a build-time decision. WASI builds link the
+// shim by default (shim_wasi.go). Building with -tags warpgrid_noshim, or
+// building natively, compiles shim_fallback.go instead, which omits the
+// import and wires DefaultResolver to FallbackBackend so a misconfigured
+// deployment gets a clean ErrShimUnavailable rather than a trap.
+
+package dns
+
+import (
+	"errors"
+	"net"
+)
+
+// ErrShimUnavailable is returned when hostname resolution is attempted
+// in a build that does not link the warpgrid:shim/dns import.
+var ErrShimUnavailable = errors.New("dns: shim unavailable")
+
+// UnavailableBackend implements ResolverBackend by failing every lookup
+// with ErrShimUnavailable. IP literals still resolve because Resolver
+// handles them before consulting the backend.
+type UnavailableBackend struct{}
+
+// Resolve always returns ErrShimUnavailable.
+func (UnavailableBackend) Resolve(hostname string) ([]net.IP, error) {
+	return nil, ErrShimUnavailable
+}
+
+// FallbackBackend is the backend DefaultResolver uses when the DNS shim
+// is not linked into the build. Replace it before the first call to
+// DefaultResolver to supply, for example, a static host table.
+var FallbackBackend ResolverBackend = UnavailableBackend{}
+
+// ShimAvailable reports whether this build links the warpgrid:shim/dns
+// import. It is false on native builds and on WASI builds compiled with
+// the warpgrid_noshim tag.
+func ShimAvailable() bool {
+	return shimAvailable
+}
+
+// SelectBackend chooses the backend for a resolver. When available is
+// true and shim is non-nil, shim is returned. Otherwise fallback is
+// returned, or UnavailableBackend if fallback is nil.
+func SelectBackend(available bool, shim, fallback ResolverBackend) ResolverBackend {
+	if available && shim != nil {
+		return shim
+	}
+	if fallback != nil {
+		return fallback
+	}
+	return UnavailableBackend{}
+}
@@ -8,11 +8,18 @@
 package dns
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"strings"
+	"time"
 )
 
+// ErrCIDRHost is returned, wrapped with the offending name, when a
+// hostname is written in CIDR notation such as "10.0.0.0/24", which
+// names a network rather than a host and is usually a misconfiguration.
+var ErrCIDRHost = errors.New("dns: CIDR notation not supported as host")
+
 // ResolverBackend abstracts the platform-specific DNS resolution call.
 //
 // On WASI, this calls warpgrid:shim/dns.resolve-address via
@@ -21,11 +28,73 @@ type ResolverBackend interface {
 	Resolve(hostname string) ([]net.IP, error)
 }
 
+// ZonedBackend is implemented by backends that can report the zone of
+// scoped IPv6 answers, such as WasiBackend with Zones set. Resolver
+// prefers ResolveIPAddr when the backend has it, so a link-local answer
+// reaches ResolveIPAddr callers, and the Dialer, with its zone intact.
+type ZonedBackend interface {
+	ResolverBackend
+	ResolveIPAddr(hostname string) ([]net.IPAddr, error)
+}
+
 // Resolver wraps a ResolverBackend with IP literal detection and
 // validation logic. When the input is already an IP address, the
 // backend is bypassed entirely.
 type Resolver struct {
 	backend ResolverBackend
+
+	// SearchDomains lists suffixes tried, in order, for short names
+	// such as "postgres", e.g. "default.svc.warp.local". The first
+	// expansion the backend resolves wins; the bare name is tried last.
+	SearchDomains []string
+
+	// NDots is the threshold below which search domains apply: a name
+	// with fewer than NDots dots is expanded first. Zero disables
+	// search entirely.
+	NDots int
+
+	// SortRFC6724 orders multi-address answers by RFC 6724 destination
+	// address selection (see SortByRFC6724) instead of the backend's
+	// order. It is best-effort: where the source address for a
+	// destination cannot be determined, as under WASI, only the
+	// source-independent rules apply.
+	SortRFC6724 bool
+
+	// SourceAddr returns the local address used to reach dst, or nil if
+	// dst is unreachable or unknown. It is consulted only when
+	// SortRFC6724 is set; nil means probe the host's routing table.
+	SourceAddr func(dst net.IP) net.IP
+
+	// UnmapV4 converts IPv4-mapped IPv6 answers such as
+	// ::ffff:192.0.2.1 to their 4-byte IPv4 form (ip.To4()), so code
+	// that tells families apart by address length, and stacks that
+	// cannot dial the mapped form from an IPv4-only host, see plain
+	// IPv4. When false the addresses are returned as the backend sent
+	// them.
+	UnmapV4 bool
+
+	// TTL caches successful answers for this long, keyed by the name
+	// passed to Resolve. Concurrent lookups of the same name share one
+	// backend query. Zero disables caching unless StaleTTL is set.
+	TTL time.Duration
+
+	// StaleTTL keeps serving an answer for this long after TTL expires
+	// while it is refreshed in the background, so a brief shim outage
+	// does not fail dials. A failed refresh leaves the stale answer in
+	// place until StaleTTL elapses.
+	StaleTTL time.Duration
+
+	// MaxCacheAge is a hard limit on how long any cached answer is
+	// used, fresh or stale, bounding staleness after topology changes
+	// even when TTL and StaleTTL would allow longer. Zero means no
+	// limit.
+	MaxCacheAge time.Duration
+
+	// Now returns the current time for cache expiry; nil means
+	// time.Now.
+	Now func() time.Time
+
+	cache answerCache
 }
 
 // NewResolver creates a Resolver with the given backend.
@@ -37,8 +106,29 @@ func NewResolver(backend ResolverBackend) *Resolver {
 //
 // If hostname is an IP literal (IPv4, IPv6, or bracketed IPv6),
 // it is returned directly without calling the backend.
-// Otherwise, the backend is consulted for resolution.
+// Otherwise, the backend is consulted for resolution, expanding
+// short names through SearchDomains as described on Resolver.
+// A trailing dot marks a fully-qualified name that bypasses search.
+// CIDR notation fails with ErrCIDRHost without consulting the backend.
+// Answers are cached as configured by TTL and StaleTTL.
+//
+// Resolve drops IPv6 zones; use ResolveIPAddr to keep them.
 func (r *Resolver) Resolve(hostname string) ([]net.IP, error) {
+	addrs, err := r.ResolveIPAddr(hostname)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, len(addrs))
+	for i, addr := range addrs {
+		ips[i] = addr.IP
+	}
+	return ips, nil
+}
+
+// ResolveIPAddr is like Resolve but keeps the zone of each address, as
+// reported by a ZonedBackend. Answers from other backends, and IP
+// literals, have no zone.
+func (r *Resolver) ResolveIPAddr(hostname string) ([]net.IPAddr, error) {
 	// Fast path: IP literals bypass DNS entirely
 	if IsIPLiteral(hostname) {
 		stripped := strings.TrimPrefix(strings.TrimSuffix(hostname, "]"), "[")
@@ -46,10 +136,99 @@ func (r *Resolver) Resolve(hostname string) ([]net.IP, error) {
 		if ip == nil {
 			return nil, fmt.Errorf("dns: IsIPLiteral matched but ParseIP failed for %q", hostname)
 		}
-		return []net.IP{ip}, nil
+		return r.unmapV4([]net.IPAddr{{IP: ip}}), nil
+	}
+	if _, _, err := net.ParseCIDR(hostname); err == nil {
+		return nil, fmt.Errorf("%w: %q", ErrCIDRHost, hostname)
+	}
+
+	var addrs []net.IPAddr
+	var err error
+	if r.cachingEnabled() {
+		addrs, err = r.cachedLookup(hostname)
+	} else {
+		addrs, err = r.lookup(hostname)
+	}
+	addrs = r.unmapV4(addrs)
+	if err != nil || !r.SortRFC6724 || len(addrs) < 2 {
+		return addrs, err
 	}
 
-	return r.backend.Resolve(hostname)
+	sourceAddr := r.SourceAddr
+	if sourceAddr == nil {
+		sourceAddr = defaultSourceAddr
+	}
+	addrs = append([]net.IPAddr(nil), addrs...) // don't reorder the backend's slice
+	srcs := make([]net.IP, len(addrs))
+	for i, addr := range addrs {
+		srcs[i] = sourceAddr(addr.IP)
+	}
+	sortIPAddrs(addrs, srcs)
+	return addrs, nil
+}
+
+// unmapV4 applies UnmapV4 to addrs, copying the slice rather than
+// modifying the backend's or the cache's.
+func (r *Resolver) unmapV4(addrs []net.IPAddr) []net.IPAddr {
+	if !r.UnmapV4 {
+		return addrs
+	}
+	var out []net.IPAddr
+	for i, addr := range addrs {
+		v4 := addr.IP.To4()
+		if v4 == nil || len(addr.IP) == net.IPv4len {
+			if out != nil {
+				out = append(out, addr)
+			}
+			continue
+		}
+		if out == nil {
+			out = append(make([]net.IPAddr, 0, len(addrs)), addrs[:i]...)
+		}
+		out = append(out, net.IPAddr{IP: v4})
+	}
+	if out == nil {
+		return addrs
+	}
+	return out
+}
+
+// lookup queries the backend for hostname, applying search domains.
+func (r *Resolver) lookup(hostname string) ([]net.IPAddr, error) {
+	if strings.HasSuffix(hostname, ".") {
+		return r.query(strings.TrimSuffix(hostname, "."))
+	}
+
+	if strings.Count(hostname, ".") < r.NDots {
+		for _, domain := range r.SearchDomains {
+			domain = strings.Trim(domain, ".")
+			if domain == "" {
+				continue
+			}
+			if addrs, err := r.query(hostname + "." + domain); err == nil && len(addrs) > 0 {
+				return addrs, nil
+			}
+		}
+	}
+
+	return r.query(hostname)
+}
+
+// query asks the backend for name, through ResolveIPAddr when it is a
+// ZonedBackend.
+func (r *Resolver) query(name string) ([]net.IPAddr, error) {
+	if zb, ok := r.backend.(ZonedBackend); ok {
+		return zb.ResolveIPAddr(name)
+	}
+	ips, err := r.backend.Resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]net.IPAddr, len(ips))
+	for i, ip := range ips {
+		addrs[i].IP = ip
+	}
+	return addrs, nil
 }
 
 // IsIPLiteral reports whether s is an IP address literal.
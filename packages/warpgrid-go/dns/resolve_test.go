@@ -219,3 +219,260 @@ func TestResolve_BracketedIPv6LiteralBypassesBackend(t *testing.T) {
 		t.Fatalf("expected 1 IP, got %d", len(ips))
 	}
 }
+
+func TestResolve_CIDRRejectedWithDistinctError(t *testing.T) {
+	backendCalled := false
+	backend := mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		backendCalled = true
+		return nil, errors.New("not found")
+	})
+
+	r := dns.NewResolver(backend)
+	for _, host := range []string{"10.0.0.0/24", "fd00::/8"} {
+		_, err := r.Resolve(host)
+		if !errors.Is(err, dns.ErrCIDRHost) {
+			t.Fatalf("%s: expected ErrCIDRHost, got %v", host, err)
+		}
+		if want := `dns: CIDR notation not supported as host: "` + host + `"`; err.Error() != want {
+			t.Fatalf("expected error %q, got %q", want, err.Error())
+		}
+	}
+	if backendCalled {
+		t.Fatal("backend was called for CIDR notation")
+	}
+
+	// A bare IP still takes the literal fast path.
+	ips, err := r.Resolve("10.0.0.1")
+	if err != nil || len(ips) != 1 || !ips[0].Equal(net.ParseIP("10.0.0.1")) {
+		t.Fatalf("expected [10.0.0.1], got %v (%v)", ips, err)
+	}
+}
+
+// ── Fallback backend selection ──────────────────────────────────────
+
+func TestShimAvailable_FalseOnNative(t *testing.T) {
+	if dns.ShimAvailable() {
+		t.Fatal("expected ShimAvailable() to be false on a native build")
+	}
+}
+
+func TestSelectBackend_UsesShimWhenAvailable(t *testing.T) {
+	shimCalled := false
+	shim := mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		shimCalled = true
+		return []net.IP{net.ParseIP("10.0.0.1")}, nil
+	})
+
+	r := dns.NewResolver(dns.SelectBackend(true, shim, nil))
+	if _, err := r.Resolve("db.warp.local"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !shimCalled {
+		t.Fatal("expected shim backend to be selected when available")
+	}
+}
+
+func TestSelectBackend_UnavailableReturnsShimUnavailable(t *testing.T) {
+	shim := mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		t.Fatal("shim backend must not be used when unavailable")
+		return nil, nil
+	})
+
+	r := dns.NewResolver(dns.SelectBackend(false, shim, nil))
+	_, err := r.Resolve("db.warp.local")
+	if !errors.Is(err, dns.ErrShimUnavailable) {
+		t.Fatalf("expected ErrShimUnavailable, got %v", err)
+	}
+	if err.Error() != "dns: shim unavailable" {
+		t.Fatalf("unexpected error text: %q", err.Error())
+	}
+}
+
+func TestSelectBackend_UsesConfiguredFallback(t *testing.T) {
+	fallback := mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP("10.0.0.9")}, nil
+	})
+
+	r := dns.NewResolver(dns.SelectBackend(false, nil, fallback))
+	ips, err := r.Resolve("static.warp.local")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ips) != 1 || !ips[0].Equal(net.ParseIP("10.0.0.9")) {
+		t.Fatalf("expected [10.0.0.9] from fallback, got %v", ips)
+	}
+}
+
+func TestDefaultResolver_FallbackStillResolvesIPLiterals(t *testing.T) {
+	r := dns.DefaultResolver()
+
+	if _, err := r.Resolve("db.warp.local"); !errors.Is(err, dns.ErrShimUnavailable) {
+		t.Fatalf("expected ErrShimUnavailable for hostname, got %v", err)
+	}
+
+	ips, err := r.Resolve("127.0.0.1")
+	if err != nil {
+		t.Fatalf("unexpected error for IP literal: %v", err)
+	}
+	if len(ips) != 1 || !ips[0].Equal(net.ParseIP("127.0.0.1")) {
+		t.Fatalf("expected [127.0.0.1], got %v", ips)
+	}
+}
+
+// ── Search domain tests ─────────────────────────────────────────────
+
+func searchBackend(known map[string]string, queried *[]string) mockResolverFunc {
+	return mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		*queried = append(*queried, hostname)
+		if ip, ok := known[hostname]; ok {
+			return []net.IP{net.ParseIP(ip)}, nil
+		}
+		return nil, errors.New("HostNotFound: " + hostname)
+	})
+}
+
+func TestResolve_SearchDomainsExpandShortName(t *testing.T) {
+	var queried []string
+	r := dns.NewResolver(searchBackend(map[string]string{
+		"postgres.default.svc.warp.local": "10.0.0.5",
+	}, &queried))
+	r.SearchDomains = []string{"svc.warp.local", "default.svc.warp.local"}
+	r.NDots = 1
+
+	ips, err := r.Resolve("postgres")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ips) != 1 || !ips[0].Equal(net.ParseIP("10.0.0.5")) {
+		t.Fatalf("expected [10.0.0.5], got %v", ips)
+	}
+	expected := []string{"postgres.svc.warp.local", "postgres.default.svc.warp.local"}
+	if len(queried) != len(expected) || queried[0] != expected[0] || queried[1] != expected[1] {
+		t.Fatalf("expected queries %v, got %v", expected, queried)
+	}
+}
+
+func TestResolve_SearchDomainsFallBackToBareName(t *testing.T) {
+	var queried []string
+	r := dns.NewResolver(searchBackend(map[string]string{"postgres": "10.0.0.6"}, &queried))
+	r.SearchDomains = []string{"svc.warp.local"}
+	r.NDots = 1
+
+	ips, err := r.Resolve("postgres")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ips) != 1 || !ips[0].Equal(net.ParseIP("10.0.0.6")) {
+		t.Fatalf("expected [10.0.0.6], got %v", ips)
+	}
+	if len(queried) != 2 || queried[1] != "postgres" {
+		t.Fatalf("expected bare name tried last, got %v", queried)
+	}
+}
+
+func TestResolve_FQDNBypassesSearch(t *testing.T) {
+	var queried []string
+	r := dns.NewResolver(searchBackend(map[string]string{"postgres": "10.0.0.7"}, &queried))
+	r.SearchDomains = []string{"svc.warp.local"}
+	r.NDots = 5
+
+	if _, err := r.Resolve("postgres."); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(queried) != 1 || queried[0] != "postgres" {
+		t.Fatalf("expected single query for 'postgres', got %v", queried)
+	}
+}
+
+func TestResolve_NDotsThreshold(t *testing.T) {
+	var queried []string
+	r := dns.NewResolver(searchBackend(map[string]string{
+		"api.prod":                "10.0.0.8",
+		"api.prod.svc.warp.local": "10.0.0.9",
+	}, &queried))
+	r.SearchDomains = []string{"svc.warp.local"}
+
+	// One dot meets NDots=1, so the name is queried as-is.
+	r.NDots = 1
+	ips, err := r.Resolve("api.prod")
+	if err != nil || !ips[0].Equal(net.ParseIP("10.0.0.8")) {
+		t.Fatalf("expected [10.0.0.8], got %v (err %v)", ips, err)
+	}
+	if len(queried) != 1 {
+		t.Fatalf("expected no search expansion, got %v", queried)
+	}
+
+	// One dot is below NDots=2, so the search domain is tried first.
+	queried = nil
+	r.NDots = 2
+	ips, err = r.Resolve("api.prod")
+	if err != nil || !ips[0].Equal(net.ParseIP("10.0.0.9")) {
+		t.Fatalf("expected [10.0.0.9], got %v (err %v)", ips, err)
+	}
+}
+
+func TestResolve_IPLiteralBypassesSearch(t *testing.T) {
+	var queried []string
+	r := dns.NewResolver(searchBackend(nil, &queried))
+	r.SearchDomains = []string{"svc.warp.local"}
+	r.NDots = 5
+
+	if _, err := r.Resolve("10.1.2.3"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(queried) != 0 {
+		t.Fatalf("expected backend not queried, got %v", queried)
+	}
+}
+
+// ── IPv4-mapped IPv6 tests ──────────────────────────────────────────
+
+func mappedBackend() mockResolverFunc {
+	return mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		return []net.IP{
+			net.ParseIP("::ffff:192.0.2.1"),
+			net.ParseIP("2001:db8::1"),
+		}, nil
+	})
+}
+
+func TestResolve_UnmapV4ConvertsMappedAddress(t *testing.T) {
+	r := dns.NewResolver(mappedBackend())
+	r.UnmapV4 = true
+
+	ips, err := r.Resolve("db.warp.local")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ips[0]) != net.IPv4len || !ips[0].Equal(net.ParseIP("192.0.2.1")) {
+		t.Fatalf("expected 4-byte 192.0.2.1, got %d bytes %v", len(ips[0]), ips[0])
+	}
+	if len(ips[1]) != net.IPv6len || !ips[1].Equal(net.ParseIP("2001:db8::1")) {
+		t.Fatalf("expected native IPv6 untouched, got %v", ips[1])
+	}
+}
+
+func TestResolve_UnmapV4DisabledPreservesMappedForm(t *testing.T) {
+	r := dns.NewResolver(mappedBackend())
+
+	ips, err := r.Resolve("db.warp.local")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ips[0]) != net.IPv6len {
+		t.Fatalf("expected mapped address kept in 16-byte form, got %d bytes", len(ips[0]))
+	}
+}
+
+func TestResolve_UnmapV4AppliesToLiterals(t *testing.T) {
+	r := dns.NewResolver(mappedBackend())
+	r.UnmapV4 = true
+
+	ips, err := r.Resolve("::ffff:10.0.0.7")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ips) != 1 || len(ips[0]) != net.IPv4len || ips[0].String() != "10.0.0.7" {
+		t.Fatalf("expected 4-byte 10.0.0.7, got %v", ips)
+	}
+}
@@ -0,0 +1,25 @@
+// Resolver wiring for builds that do not link the WarpGrid DNS shim.
+//
+// This file is compiled for native (non-WASI) targets and for WASI
+// builds using the warpgrid_noshim tag. It provides the same
+// DefaultResolver entry point as shim_wasi.go but never references the
+// warpgrid_shim.dns_resolve import, so the resulting module instantiates
+// on hosts that lack it. See fallback.go for the selection rules.
+
+//go:build (!wasip1 && !wasip2) || warpgrid_noshim
+
+package dns
+
+// shimAvailable is false: the host import is not linked in this build.
+const shimAvailable = false
+
+// shimResolve is nil: WasiBackend without a Raw override reports
+// ErrShimUnavailable.
+var shimResolve RawResolveFunc
+
+// DefaultResolver returns a Resolver backed by FallbackBackend.
+// Hostname lookups fail with ErrShimUnavailable unless FallbackBackend
+// has been replaced; IP literals resolve normally.
+func DefaultResolver() *Resolver {
+	return NewResolver(SelectBackend(shimAvailable, nil, FallbackBackend))
+}
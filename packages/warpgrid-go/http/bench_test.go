@@ -0,0 +1,36 @@
+package wghttp_test
+
+import (
+	"testing"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/http"
+)
+
+// ── Hot path benchmarks ─────────────────────────────────────────────
+//
+// Run with:
+//
+//	go test ./http -run '^$' -bench . -benchmem
+//
+// Allocation baseline for benchWitRequest: ConvertRequest 19 allocs/op.
+
+var benchWitRequest = wghttp.WitRequest{
+	Method: "POST",
+	URI:    "/api/v1/orders?expand=items",
+	Headers: []wghttp.WitHeader{
+		{Name: "Host", Value: "orders.warp.local"},
+		{Name: "Content-Type", Value: "application/json"},
+		{Name: "Accept", Value: "application/json"},
+		{Name: "X-Request-Id", Value: "6f1c2d3e-4b5a-4c6d-8e7f-9a0b1c2d3e4f"},
+	},
+	Body: []byte(`{"sku":"WG-1001","quantity":2}`),
+}
+
+func BenchmarkConvertRequest(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := wghttp.ConvertRequest(benchWitRequest); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package wghttp
+
+import (
+	"context"
+	"io"
+)
+
+// readChunkSize is the size of each read ReadAllContext issues.
+const readChunkSize = 32 * 1024
+
+// ReadAllContext is like io.ReadAll but gives up when ctx is done, so a
+// handler can bound a body read that stalls because the host stops
+// delivering data:
+//
+//	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+//	defer cancel()
+//	body, err := wghttp.ReadAllContext(ctx, r.Body)
+//
+// It returns the data read before EOF with a nil error, or, when ctx
+// ends first, the data read so far together with ctx.Err(). A read
+// error other than EOF is returned with the partial data, as io.ReadAll
+// does.
+//
+// A Read that is blocked when ctx ends cannot be interrupted; it is
+// left to finish in the background and its result is discarded. Close
+// the reader (for example r.Body) to release it sooner.
+func ReadAllContext(ctx context.Context, r io.Reader) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	type chunk struct {
+		data []byte
+		err  error
+	}
+	// Buffered so the reader goroutine never blocks after we give up.
+	chunks := make(chan chunk, 1)
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			buf := make([]byte, readChunkSize)
+			n, err := r.Read(buf)
+			select {
+			case chunks <- chunk{data: buf[:n], err: err}:
+			case <-stop:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	var out []byte
+	for {
+		select {
+		case c := <-chunks:
+			out = append(out, c.data...)
+			if c.err == io.EOF {
+				if out == nil {
+					out = []byte{}
+				}
+				return out, nil
+			}
+			if c.err != nil {
+				return out, c.err
+			}
+		case <-ctx.Done():
+			return out, ctx.Err()
+		}
+	}
+}
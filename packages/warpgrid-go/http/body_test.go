@@ -0,0 +1,67 @@
+package wghttp_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/http"
+)
+
+// ── ReadAllContext tests ────────────────────────────────────────────
+
+func TestReadAllContext_FullRead(t *testing.T) {
+	payload := strings.Repeat("warpgrid", 10000) // spans several reads
+	data, err := wghttp.ReadAllContext(context.Background(), strings.NewReader(payload))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != payload {
+		t.Fatalf("expected %d bytes, got %d", len(payload), len(data))
+	}
+}
+
+func TestReadAllContext_EmptyBody(t *testing.T) {
+	data, err := wghttp.ReadAllContext(context.Background(), bytes.NewReader(nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data == nil || len(data) != 0 {
+		t.Fatalf("expected empty non-nil slice, got %v", data)
+	}
+}
+
+func TestReadAllContext_CancelledMidStreamReturnsPartial(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	go pw.Write([]byte("partial"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	data, err := wghttp.ReadAllContext(ctx, pr)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if string(data) != "partial" {
+		t.Fatalf("expected partial data 'partial', got '%s'", data)
+	}
+}
+
+func TestReadAllContext_ReadErrorReturnsPartial(t *testing.T) {
+	boom := errors.New("boom")
+	r := io.MultiReader(strings.NewReader("head"), iotest.ErrReader(boom))
+
+	data, err := wghttp.ReadAllContext(context.Background(), r)
+	if !errors.Is(err, boom) {
+		t.Fatalf("expected read error, got %v", err)
+	}
+	if string(data) != "head" {
+		t.Fatalf("expected partial data 'head', got '%s'", data)
+	}
+}
@@ -1,6 +1,8 @@
 package wghttp
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 )
@@ -58,13 +60,60 @@ func ResetDefaultServeMux() {
 	defaultServeMux = http.NewServeMux()
 }
 
+// ProductionMode controls how much internal detail the bridge's error
+// responses expose, like the overlay's setting of the same name. When
+// false (the default), the 500 body for a recovered panic includes the
+// panic value and a rejected request's body includes the interceptor's
+// error. When true, both bodies are generic so internal state never
+// leaks to clients.
+var ProductionMode bool
+
 // HandleWitRequest processes a WIT request through the registered handler
 // and returns a WIT response.
 //
 // If no handler is registered, returns a 500 response. If the request
-// conversion fails, returns a 400 response. Panics in the handler are
-// recovered and converted to 500 responses.
-func HandleWitRequest(req WitRequest) (resp WitResponse) {
+// conversion fails, returns a 400 response (413 for a request body that
+// decompresses past MaxDecompressedBodyBytes, 415 for one with an
+// unsupported Content-Encoding). Panics in the handler are
+// recovered and converted to 500 responses, unless the handler had
+// already flushed: the status is then committed, so the response is
+// returned as written so far with Aborted set, telling the host to
+// reset the stream. A handler calling ResetConn gets the same treatment
+// (see ResponseCapture.Finish), as does a body that outgrows
+// MaxResponseBodySize. The returned status is always a valid HTTP status
+// code (see ResponseCapture.Finish).
+//
+// A deadline from DeadlineHeader is enforced cooperatively: a handler
+// cannot be preempted inside the Wasm instance, so it is expected to
+// stop once r.Context() is done. If it returns after the deadline
+// without having written anything, the response is 504 Gateway
+// Timeout; output it did write is sent as is.
+//
+// When the request asked for the connection to be closed (req.Close,
+// see ConvertRequest), the response carries "Connection: close" so the
+// host ends the stream after it; handlers can request the same with
+// CloseConnection.
+//
+// Any request body the handler leaves unread is closed after it
+// returns. A streamed body is drained first, up to MaxBodyDrainBytes;
+// one with more left than that is abandoned and the response carries
+// "Connection: close" so the host discards the rest of the stream.
+//
+// Interceptors registered with RegisterRequestInterceptor and
+// RegisterResponseInterceptor run around all of this, whichever
+// handler serves the request.
+func HandleWitRequest(req WitRequest) WitResponse {
+	resp, ok := interceptRequest(&req)
+	if ok {
+		resp = handleWitRequest(req)
+	}
+	interceptResponse(&resp)
+	return resp
+}
+
+// handleWitRequest implements HandleWitRequest without the
+// interceptors.
+func handleWitRequest(req WitRequest) (resp WitResponse) {
 	handler := registeredHandler
 	if handler == nil {
 		return WitResponse{
@@ -76,26 +125,60 @@ func HandleWitRequest(req WitRequest) (resp WitResponse) {
 
 	httpReq, err := ConvertRequest(req)
 	if err != nil {
+		status := 400
+		if errors.Is(err, ErrDecompressedBodyTooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		} else if errors.Is(err, ErrUnsupportedContentEncoding) {
+			status = http.StatusUnsupportedMediaType
+		}
 		return WitResponse{
-			Status:  400,
+			Status:  uint16(status),
 			Headers: []WitHeader{{Name: "Content-Type", Value: "text/plain"}},
 			Body:    []byte("invalid request: " + err.Error()),
 		}
 	}
 
+	defer ReleaseRequest(httpReq)
+
 	rc := NewResponseCapture()
 
 	// Recover from handler panics to avoid crashing the Wasm module
 	defer func() {
 		if r := recover(); r != nil {
+			if rc.flushed {
+				resp = rc.Finish()
+				resp.Aborted = true
+				return
+			}
+			msg := "internal server error"
+			if !ProductionMode {
+				msg = fmt.Sprintf("internal server error: %v", r)
+			}
 			resp = WitResponse{
 				Status:  500,
 				Headers: []WitHeader{{Name: "Content-Type", Value: "text/plain"}},
-				Body:    []byte(fmt.Sprintf("internal server error: %v", r)),
+				Body:    []byte(msg),
 			}
 		}
 	}()
 
+	// ConvertRequest's body reads from the buffered WIT request; only a
+	// stream the handler swapped in is worth draining.
+	buffered := httpReq.Body
 	handler.ServeHTTP(rc, httpReq)
+	drained := drainBody(httpReq.Body, httpReq.Body == buffered)
+	if rc.reset {
+		return rc.Finish()
+	}
+	if !rc.headersSent && httpReq.Context().Err() == context.DeadlineExceeded {
+		return WitResponse{
+			Status:  http.StatusGatewayTimeout,
+			Headers: []WitHeader{{Name: "Content-Type", Value: "text/plain"}},
+			Body:    []byte("request deadline exceeded"),
+		}
+	}
+	if httpReq.Close || !drained {
+		CloseConnection(rc)
+	}
 	return rc.Finish()
 }
@@ -0,0 +1,42 @@
+package wghttp
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DeadlineHeader is the request header through which the host
+// advertises the time remaining for a request, in whole milliseconds.
+// It lets the host set per-request timeouts without an ABI change.
+const DeadlineHeader = "X-WG-Deadline-Ms"
+
+// deadlineCancelKey is the context key under which ConvertRequest
+// stores the cancel function of a request's deadline context.
+type deadlineCancelKey struct{}
+
+// withDeadlineHeader returns ctx with a deadline DeadlineHeader
+// milliseconds from now when h carries a positive integer value.
+// Malformed, zero, negative, or overflowing values are ignored.
+func withDeadlineHeader(ctx context.Context, h http.Header) context.Context {
+	ms, err := strconv.ParseInt(strings.TrimSpace(h.Get(DeadlineHeader)), 10, 64)
+	if err != nil || ms <= 0 || ms > math.MaxInt64/int64(time.Millisecond) {
+		return ctx
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(ms)*time.Millisecond)
+	return context.WithValue(ctx, deadlineCancelKey{}, cancel)
+}
+
+// ReleaseRequest stops the timer behind the deadline ConvertRequest
+// set on r from DeadlineHeader, if any, cancelling r's context. A
+// caller of ConvertRequest must call it once it is done with r, or the
+// timer lives until the deadline passes; HandleWitRequest does so for
+// the requests it converts. It is a no-op for a request without one.
+func ReleaseRequest(r *http.Request) {
+	if cancel, ok := r.Context().Value(deadlineCancelKey{}).(context.CancelFunc); ok {
+		cancel()
+	}
+}
@@ -0,0 +1,45 @@
+package wghttp
+
+import (
+	"io"
+	"net/http"
+)
+
+// DefaultMaxBodyDrainBytes is the default for MaxBodyDrainBytes
+// (256 KiB, the same bound net/http uses).
+const DefaultMaxBodyDrainBytes = 256 << 10
+
+// MaxBodyDrainBytes bounds how much of a streamed request body
+// HandleWitRequest reads on the handler's behalf. A handler that
+// returns without consuming r.Body has the rest read and discarded,
+// then the body is closed, so the host's request stream is left fully
+// consumed for the next request. If more than MaxBodyDrainBytes remain,
+// draining stops there instead of slurping a large unwanted upload: the
+// body is closed and the response gets "Connection: close", telling the
+// host to discard the stream. A body still backed by the buffered WIT
+// request is already in memory, so it is only closed, and the
+// connection is kept. Zero or negative means DefaultMaxBodyDrainBytes.
+var MaxBodyDrainBytes = DefaultMaxBodyDrainBytes
+
+func maxBodyDrainBytes() int64 {
+	if MaxBodyDrainBytes <= 0 {
+		return DefaultMaxBodyDrainBytes
+	}
+	return int64(MaxBodyDrainBytes)
+}
+
+// drainBody discards what is left of body, up to MaxBodyDrainBytes, and
+// closes it. It reports whether the body was read to its end. A
+// buffered body is closed without being read, and counts as read.
+func drainBody(body io.ReadCloser, buffered bool) bool {
+	if body == nil || body == http.NoBody {
+		return true
+	}
+	defer body.Close()
+	if buffered {
+		return true
+	}
+	limit := maxBodyDrainBytes()
+	n, err := io.CopyN(io.Discard, body, limit+1)
+	return err == io.EOF && n <= limit
+}
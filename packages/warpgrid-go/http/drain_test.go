@@ -0,0 +1,116 @@
+package wghttp_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"testing"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/http"
+)
+
+// ── Request body drain tests ────────────────────────────────────────
+
+// streamBody stands in for a host request stream: it records how much
+// was read from it and whether it was closed.
+type streamBody struct {
+	r      io.Reader
+	read   int
+	closed bool
+}
+
+func (b *streamBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	b.read += n
+	return n, err
+}
+
+func (b *streamBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+// ignoreBodyHandler swaps in body as the request stream and returns
+// without reading it.
+func ignoreBodyHandler(body *streamBody) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = body
+		w.Write([]byte("ok"))
+	})
+}
+
+func TestHandleWitRequest_UnreadBodyDrainedAndClosed(t *testing.T) {
+	defer wghttp.ResetHandler()
+	body := &streamBody{r: bytes.NewReader(make([]byte, 4096))}
+	wghttp.SetHandler(ignoreBodyHandler(body))
+
+	resp := wghttp.HandleWitRequest(wghttp.WitRequest{Method: "POST", URI: "/upload"})
+
+	if body.read != 4096 || !body.closed {
+		t.Fatalf("expected all 4096 bytes drained and the body closed, got %d read, closed=%v", body.read, body.closed)
+	}
+	if got := connectionHeader(resp); got != "" {
+		t.Fatalf("expected the stream kept open after a clean drain, got Connection '%s'", got)
+	}
+
+	// The next request on the stream is served normally.
+	wghttp.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		w.Write(data)
+	}))
+	resp = wghttp.HandleWitRequest(wghttp.WitRequest{Method: "POST", URI: "/echo", Body: []byte("next")})
+	if resp.Status != 200 || string(resp.Body) != "next" {
+		t.Fatalf("expected next request echoed, got %d '%s'", resp.Status, resp.Body)
+	}
+}
+
+func TestHandleWitRequest_OversizedUnreadBodyDiscarded(t *testing.T) {
+	defer func(old int) { wghttp.MaxBodyDrainBytes = old }(wghttp.MaxBodyDrainBytes)
+	wghttp.MaxBodyDrainBytes = 1024
+
+	defer wghttp.ResetHandler()
+	body := &streamBody{r: bytes.NewReader(make([]byte, 1<<20))}
+	wghttp.SetHandler(ignoreBodyHandler(body))
+
+	resp := wghttp.HandleWitRequest(wghttp.WitRequest{Method: "POST", URI: "/upload"})
+
+	if body.read > 1025 || !body.closed {
+		t.Fatalf("expected draining bounded at the limit and the body closed, got %d read, closed=%v", body.read, body.closed)
+	}
+	if got := connectionHeader(resp); got != "close" {
+		t.Fatalf("expected Connection 'close' telling the host to discard the stream, got '%s'", got)
+	}
+	if string(resp.Body) != "ok" {
+		t.Fatalf("expected the handler's response kept, got '%s'", resp.Body)
+	}
+}
+
+func TestHandleWitRequest_ConsumedBodyKeepsStreamOpen(t *testing.T) {
+	defer wghttp.ResetHandler()
+	wghttp.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		r.Body.Close()
+	}))
+
+	resp := wghttp.HandleWitRequest(wghttp.WitRequest{Method: "POST", URI: "/", Body: []byte("payload")})
+
+	if got := connectionHeader(resp); got != "" {
+		t.Fatalf("expected no Connection header, got '%s'", got)
+	}
+}
+
+func TestHandleWitRequest_UnreadBufferedBodyKeepsStreamOpen(t *testing.T) {
+	defer func(old int) { wghttp.MaxBodyDrainBytes = old }(wghttp.MaxBodyDrainBytes)
+	wghttp.MaxBodyDrainBytes = 1024
+
+	defer wghttp.ResetHandler()
+	wghttp.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	resp := wghttp.HandleWitRequest(wghttp.WitRequest{Method: "POST", URI: "/upload", Body: make([]byte, 1<<20)})
+
+	if got := connectionHeader(resp); got != "" {
+		t.Fatalf("expected a buffered body not to close the connection, got Connection '%s'", got)
+	}
+}
@@ -0,0 +1,138 @@
+package wghttp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// DefaultMaxDecompressedBodyBytes is the default for
+// MaxDecompressedBodyBytes (10 MB).
+const DefaultMaxDecompressedBodyBytes = 10 << 20
+
+// MaxDecompressedBodyBytes caps the size, in bytes, that an encoded
+// request body may expand to. A small compressed body
+// can decode to gigabytes (a decompression bomb), so decoding stops at
+// the limit and the request fails with ErrDecompressedBodyTooLarge,
+// which HandleWitRequest answers with 413. Zero or negative means
+// DefaultMaxDecompressedBodyBytes.
+var MaxDecompressedBodyBytes = DefaultMaxDecompressedBodyBytes
+
+// ErrDecompressedBodyTooLarge is returned by ConvertRequest when an
+// encoded request body decodes to more than MaxDecompressedBodyBytes.
+var ErrDecompressedBodyTooLarge = errors.New("wghttp: decompressed request body too large")
+
+func maxDecompressedBodyBytes() int {
+	if MaxDecompressedBodyBytes <= 0 {
+		return DefaultMaxDecompressedBodyBytes
+	}
+	return MaxDecompressedBodyBytes
+}
+
+// ErrUnsupportedContentEncoding is returned by ConvertRequest when a
+// request body uses a Content-Encoding with no registered decoder.
+// HandleWitRequest answers it with 415 Unsupported Media Type.
+var ErrUnsupportedContentEncoding = errors.New("wghttp: unsupported request Content-Encoding")
+
+// A ContentDecoder wraps a reader of an encoded request body with one
+// that yields the decoded bytes. If the returned reader is also an
+// io.Closer it is closed once the body has been read.
+type ContentDecoder func(io.Reader) (io.Reader, error)
+
+// decoders holds the ContentDecoder for each Content-Encoding value,
+// keyed in lower case.
+var decoders = struct {
+	sync.RWMutex
+	m map[string]ContentDecoder
+}{m: map[string]ContentDecoder{
+	"gzip":   gunzip,
+	"x-gzip": gunzip,
+	// HTTP "deflate" is the zlib format (RFC 9110 section 8.4.1.2).
+	"deflate": inflate,
+}}
+
+func gunzip(r io.Reader) (io.Reader, error)  { return gzip.NewReader(r) }
+func inflate(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) }
+
+// RegisterDecoder makes ConvertRequest decode request bodies sent with
+// "Content-Encoding: encoding" using fn, replacing any decoder already
+// registered for it, including the built-in gzip and deflate ones.
+// Encoding names are case-insensitive. A nil fn removes the decoder, so
+// such bodies are rejected again with ErrUnsupportedContentEncoding.
+func RegisterDecoder(encoding string, fn ContentDecoder) {
+	encoding = strings.ToLower(strings.TrimSpace(encoding))
+	decoders.Lock()
+	defer decoders.Unlock()
+	if fn == nil {
+		delete(decoders.m, encoding)
+		return
+	}
+	decoders.m[encoding] = fn
+}
+
+func lookupDecoder(encoding string) ContentDecoder {
+	decoders.RLock()
+	defer decoders.RUnlock()
+	return decoders.m[encoding]
+}
+
+// decodeContentEncoding decompresses body according to the request's
+// Content-Encoding, undoing a list of several encodings in reverse
+// order with the registered decoders. ok is false when the body is not
+// encoded (or only "identity"-encoded), in which case it is passed to
+// the handler untouched; an encoding without a registered decoder fails
+// with ErrUnsupportedContentEncoding. The body is already fully
+// buffered, so it is decoded up front: handlers see a plain body with an
+// accurate length, and a corrupt or oversized body is rejected before
+// the handler runs.
+func decodeContentEncoding(h http.Header, body []byte) (decoded []byte, ok bool, err error) {
+	var chain []ContentDecoder
+	var names []string
+	for _, v := range h.Values("Content-Encoding") {
+		for _, encoding := range strings.Split(v, ",") {
+			encoding = strings.ToLower(strings.TrimSpace(encoding))
+			if encoding == "" || encoding == "identity" {
+				continue
+			}
+			fn := lookupDecoder(encoding)
+			if fn == nil {
+				return nil, false, fmt.Errorf("%w: %q", ErrUnsupportedContentEncoding, encoding)
+			}
+			chain = append(chain, fn)
+			names = append(names, encoding)
+		}
+	}
+	if len(chain) == 0 {
+		return nil, false, nil
+	}
+	if len(body) == 0 {
+		return []byte{}, true, nil
+	}
+
+	var r io.Reader = bytes.NewReader(body)
+	for i := len(chain) - 1; i >= 0; i-- {
+		r, err = chain[i](r)
+		if err != nil {
+			return nil, false, fmt.Errorf("wghttp: invalid %s request body: %w", names[i], err)
+		}
+		if c, ok := r.(io.Closer); ok {
+			defer c.Close()
+		}
+	}
+
+	limit := maxDecompressedBodyBytes()
+	decoded, err = io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, false, fmt.Errorf("wghttp: invalid %s request body: %w", strings.Join(names, ", "), err)
+	}
+	if len(decoded) > limit {
+		return nil, false, ErrDecompressedBodyTooLarge
+	}
+	return decoded, true, nil
+}
@@ -0,0 +1,264 @@
+package wghttp_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/http"
+)
+
+// ── Request Content-Encoding tests ──────────────────────────────────
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(data)
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestHandleWitRequest_GzipBodyDecodedTransparently(t *testing.T) {
+	var got struct{ Name string }
+	var encoding, length string
+	defer wghttp.ResetHandler()
+	wghttp.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("decode failed: %v", err)
+		}
+		encoding = r.Header.Get("Content-Encoding")
+		length = r.Header.Get("Content-Length")
+	}))
+
+	plain := []byte(`{"Name":"warpgrid"}`)
+	resp := wghttp.HandleWitRequest(wghttp.WitRequest{
+		Method: "POST",
+		URI:    "/items",
+		Headers: []wghttp.WitHeader{
+			{Name: "Content-Type", Value: "application/json"},
+			{Name: "Content-Encoding", Value: "gzip"},
+		},
+		Body: gzipBytes(t, plain),
+	})
+
+	if resp.Status != 200 {
+		t.Fatalf("expected status 200, got %d: %s", resp.Status, resp.Body)
+	}
+	if got.Name != "warpgrid" {
+		t.Fatalf("expected Name 'warpgrid', got '%s'", got.Name)
+	}
+	if encoding != "" {
+		t.Fatalf("expected Content-Encoding to be removed, got '%s'", encoding)
+	}
+	if length != "19" {
+		t.Fatalf("expected Content-Length 19 for the decoded body, got '%s'", length)
+	}
+}
+
+func TestConvertRequest_DeflateBodyDecoded(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write([]byte("hello deflate"))
+	zw.Close()
+	compressed := buf.Bytes()
+
+	req, err := wghttp.ConvertRequest(wghttp.WitRequest{
+		Method:  "POST",
+		URI:     "/",
+		Headers: []wghttp.WitHeader{{Name: "Content-Encoding", Value: "deflate"}},
+		Body:    compressed,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body := new(bytes.Buffer)
+	body.ReadFrom(req.Body)
+	if body.String() != "hello deflate" || req.ContentLength != 13 {
+		t.Fatalf("expected decoded body of 13 bytes, got %q (%d)", body.String(), req.ContentLength)
+	}
+	if raw, _ := wghttp.RawBody(req); !bytes.Equal(raw, compressed) {
+		t.Fatal("expected RawBody to keep the bytes as sent")
+	}
+}
+
+func TestHandleWitRequest_DecompressionBombRejected(t *testing.T) {
+	defer func(old int) { wghttp.MaxDecompressedBodyBytes = old }(wghttp.MaxDecompressedBodyBytes)
+	wghttp.MaxDecompressedBodyBytes = 1024
+
+	defer wghttp.ResetHandler()
+	wghttp.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler must not run for an oversized body")
+	}))
+
+	bomb := gzipBytes(t, []byte(strings.Repeat("0", 1<<20)))
+	resp := wghttp.HandleWitRequest(wghttp.WitRequest{
+		Method:  "POST",
+		URI:     "/",
+		Headers: []wghttp.WitHeader{{Name: "Content-Encoding", Value: "gzip"}},
+		Body:    bomb,
+	})
+
+	if resp.Status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected status 413, got %d", resp.Status)
+	}
+	_, err := wghttp.ConvertRequest(wghttp.WitRequest{
+		Method:  "POST",
+		URI:     "/",
+		Headers: []wghttp.WitHeader{{Name: "Content-Encoding", Value: "gzip"}},
+		Body:    bomb,
+	})
+	if !errors.Is(err, wghttp.ErrDecompressedBodyTooLarge) {
+		t.Fatalf("expected ErrDecompressedBodyTooLarge, got %v", err)
+	}
+}
+
+func TestConvertRequest_CorruptGzipReturnsError(t *testing.T) {
+	_, err := wghttp.ConvertRequest(wghttp.WitRequest{
+		Method:  "POST",
+		URI:     "/",
+		Headers: []wghttp.WitHeader{{Name: "Content-Encoding", Value: "gzip"}},
+		Body:    []byte("not gzip"),
+	})
+	if err == nil {
+		t.Fatal("expected error for a corrupt gzip body")
+	}
+}
+
+func TestHandleWitRequest_UnregisteredEncodingReturns415(t *testing.T) {
+	defer wghttp.ResetHandler()
+	wghttp.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler must not run for an undecodable body")
+	}))
+
+	resp := wghttp.HandleWitRequest(wghttp.WitRequest{
+		Method:  "POST",
+		URI:     "/",
+		Headers: []wghttp.WitHeader{{Name: "Content-Encoding", Value: "br"}},
+		Body:    []byte("opaque"),
+	})
+
+	if resp.Status != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected status 415, got %d", resp.Status)
+	}
+	_, err := wghttp.ConvertRequest(wghttp.WitRequest{
+		Method:  "POST",
+		URI:     "/",
+		Headers: []wghttp.WitHeader{{Name: "Content-Encoding", Value: "br"}},
+		Body:    []byte("opaque"),
+	})
+	if !errors.Is(err, wghttp.ErrUnsupportedContentEncoding) {
+		t.Fatalf("expected ErrUnsupportedContentEncoding, got %v", err)
+	}
+}
+
+func TestConvertRequest_IdentityEncodingPassedThrough(t *testing.T) {
+	req, err := wghttp.ConvertRequest(wghttp.WitRequest{
+		Method:  "POST",
+		URI:     "/",
+		Headers: []wghttp.WitHeader{{Name: "Content-Encoding", Value: "identity"}},
+		Body:    []byte("plain"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body := new(bytes.Buffer)
+	body.ReadFrom(req.Body)
+	if body.String() != "plain" {
+		t.Fatalf("expected body 'plain', got %q", body.String())
+	}
+}
+
+// ── Decoder registry tests ──────────────────────────────────────────
+
+// upperDecoder is a fake Content-Encoding whose "decoding" upper-cases
+// the body, recording that it ran.
+func upperDecoder(calls *int) func(io.Reader) (io.Reader, error) {
+	return func(r io.Reader) (io.Reader, error) {
+		*calls++
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(bytes.ToUpper(data)), nil
+	}
+}
+
+func TestRegisterDecoder_InvokedForItsEncoding(t *testing.T) {
+	calls := 0
+	wghttp.RegisterDecoder("X-Upper", upperDecoder(&calls))
+	defer wghttp.RegisterDecoder("x-upper", nil)
+
+	req, err := wghttp.ConvertRequest(wghttp.WitRequest{
+		Method:  "POST",
+		URI:     "/",
+		Headers: []wghttp.WitHeader{{Name: "Content-Encoding", Value: "x-upper"}},
+		Body:    []byte("hello"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body := new(bytes.Buffer)
+	body.ReadFrom(req.Body)
+	if calls != 1 || body.String() != "HELLO" {
+		t.Fatalf("expected decoder invoked once yielding HELLO, got %d calls and %q", calls, body.String())
+	}
+	if req.Header.Get("Content-Encoding") != "" || req.ContentLength != 5 {
+		t.Fatalf("expected Content-Encoding removed and length 5, got '%s' (%d)",
+			req.Header.Get("Content-Encoding"), req.ContentLength)
+	}
+
+	// Other encodings must not reach it.
+	if _, err := wghttp.ConvertRequest(wghttp.WitRequest{
+		Method:  "POST",
+		URI:     "/",
+		Headers: []wghttp.WitHeader{{Name: "Content-Encoding", Value: "gzip"}},
+		Body:    gzipBytes(t, []byte("hello")),
+	}); err != nil || calls != 1 {
+		t.Fatalf("expected gzip body decoded without the fake decoder, got err=%v calls=%d", err, calls)
+	}
+}
+
+func TestRegisterDecoder_StackedEncodingsDecodedInReverse(t *testing.T) {
+	calls := 0
+	wghttp.RegisterDecoder("x-upper", upperDecoder(&calls))
+	defer wghttp.RegisterDecoder("x-upper", nil)
+
+	// Upper-cased first, then gzipped: decoding must gunzip first.
+	req, err := wghttp.ConvertRequest(wghttp.WitRequest{
+		Method:  "POST",
+		URI:     "/",
+		Headers: []wghttp.WitHeader{{Name: "Content-Encoding", Value: "x-upper, gzip"}},
+		Body:    gzipBytes(t, []byte("stacked")),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body := new(bytes.Buffer)
+	body.ReadFrom(req.Body)
+	if body.String() != "STACKED" {
+		t.Fatalf("expected 'STACKED', got %q", body.String())
+	}
+}
+
+func TestRegisterDecoder_NilRemovesDecoder(t *testing.T) {
+	calls := 0
+	wghttp.RegisterDecoder("x-upper", upperDecoder(&calls))
+	wghttp.RegisterDecoder("x-upper", nil)
+
+	_, err := wghttp.ConvertRequest(wghttp.WitRequest{
+		Method:  "POST",
+		URI:     "/",
+		Headers: []wghttp.WitHeader{{Name: "Content-Encoding", Value: "x-upper"}},
+		Body:    []byte("hello"),
+	})
+	if !errors.Is(err, wghttp.ErrUnsupportedContentEncoding) || calls != 0 {
+		t.Fatalf("expected ErrUnsupportedContentEncoding without calling the removed decoder, got %v (%d calls)", err, calls)
+	}
+}
@@ -38,6 +38,12 @@ import "unsafe"
 // Response layout at retPtr (canonical ABI flattened http-response):
 //
 //	[0:2]   u16 status code
+//	[2]     flags: bit 0 set when the response is streaming (no
+//	        Content-Length; the host uses chunked encoding and the body
+//	        ends the stream when this call returns); bit 1 set when
+//	        the handler panicked mid-stream or called ResetConn and the
+//	        host must reset the stream after sending the body; with bit
+//	        0 clear, nothing is sent before the reset
 //	[4:8]   ptr to headers data
 //	[8:12]  headers data length
 //	[12:16] ptr to body data
@@ -139,6 +145,12 @@ func serializeResponse(resp WitResponse, retPtr *byte) {
 	ret[0] = byte(resp.Status)
 	ret[1] = byte(resp.Status >> 8)
 	ret[2] = 0
+	if resp.Streaming {
+		ret[2] |= 1
+	}
+	if resp.Aborted {
+		ret[2] |= 2
+	}
 	ret[3] = 0
 
 	// Headers pointer and length (offsets 4-11)
@@ -0,0 +1,136 @@
+package wghttp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultMinCompressSize is the default value of MinCompressSize.
+const DefaultMinCompressSize = 1024
+
+// MinCompressSize is the smallest response body, in bytes, that Gzip
+// compresses. Smaller bodies gain little or even grow once the gzip
+// header and trailer are added, so they are sent as-is. Zero or a
+// negative value means DefaultMinCompressSize.
+var MinCompressSize = DefaultMinCompressSize
+
+func minCompressSize() int {
+	if MinCompressSize <= 0 {
+		return DefaultMinCompressSize
+	}
+	return MinCompressSize
+}
+
+// Gzip wraps next so that responses are gzip-compressed for clients
+// that send "Accept-Encoding: gzip".
+//
+// WarpGrid responses are buffered in full before they are handed to the
+// host (see ResponseCapture.Finish), so Gzip buffers too and makes the
+// decision once next returns, when the final size is known. The body is
+// left uncompressed when it is smaller than MinCompressSize, when the
+// handler already set a Content-Encoding, or when the status forbids a
+// body. A handler that flushes opts out of buffering, and the response
+// is streamed uncompressed.
+func Gzip(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r.Header) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		gw := &gzipResponseWriter{w: w, status: http.StatusOK}
+		next.ServeHTTP(gw, r)
+		gw.finish()
+	})
+}
+
+// gzipResponseWriter buffers a response so that Gzip can decide whether
+// to compress it once the handler has returned.
+type gzipResponseWriter struct {
+	w           http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	headersSent bool
+	streaming   bool
+}
+
+func (g *gzipResponseWriter) Header() http.Header {
+	return g.w.Header()
+}
+
+func (g *gzipResponseWriter) WriteHeader(statusCode int) {
+	if g.headersSent {
+		return
+	}
+	g.status = statusCode
+	g.headersSent = true
+}
+
+func (g *gzipResponseWriter) Write(data []byte) (int, error) {
+	g.headersSent = true
+	if g.streaming {
+		return g.w.Write(data)
+	}
+	return g.body.Write(data)
+}
+
+// Flush implements http.Flusher by abandoning compression and sending
+// what has been buffered so far.
+func (g *gzipResponseWriter) Flush() {
+	if !g.streaming {
+		g.streaming = true
+		g.w.WriteHeader(g.status)
+		g.w.Write(g.body.Bytes())
+		g.body.Reset()
+	}
+	if f, ok := g.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// finish writes the buffered response, compressed if worthwhile.
+func (g *gzipResponseWriter) finish() {
+	if g.streaming {
+		return
+	}
+	h := g.w.Header()
+	if g.body.Len() < minCompressSize() || h.Get("Content-Encoding") != "" || !bodyAllowed(g.status) {
+		g.w.WriteHeader(g.status)
+		g.w.Write(g.body.Bytes())
+		return
+	}
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write(g.body.Bytes())
+	zw.Close()
+
+	h.Set("Content-Encoding", "gzip")
+	h.Set("Content-Length", strconv.Itoa(compressed.Len()))
+	g.w.WriteHeader(g.status)
+	g.w.Write(compressed.Bytes())
+}
+
+// acceptsGzip reports whether the Accept-Encoding header lists gzip
+// with a non-zero quality.
+func acceptsGzip(h http.Header) bool {
+	for _, v := range h.Values("Accept-Encoding") {
+		for _, part := range strings.Split(v, ",") {
+			coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+			if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+				continue
+			}
+			q, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+			if !ok {
+				return true
+			}
+			if f, err := strconv.ParseFloat(q, 64); err == nil && f > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
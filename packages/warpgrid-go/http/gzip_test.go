@@ -0,0 +1,124 @@
+package wghttp_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/http"
+)
+
+// ── Gzip test helpers ───────────────────────────────────────────────
+
+// serveGzip runs a request through Gzip(handler) via HandleWitRequest.
+func serveGzip(t *testing.T, acceptEncoding string, handler http.HandlerFunc) wghttp.WitResponse {
+	t.Helper()
+	wghttp.SetHandler(wghttp.Gzip(handler))
+	t.Cleanup(wghttp.ResetHandler)
+
+	req := wghttp.WitRequest{Method: "GET", URI: "/data"}
+	if acceptEncoding != "" {
+		req.Headers = []wghttp.WitHeader{{Name: "Accept-Encoding", Value: acceptEncoding}}
+	}
+	return wghttp.HandleWitRequest(req)
+}
+
+func witHeader(resp wghttp.WitResponse, name string) string {
+	for _, h := range resp.Headers {
+		if h.Name == name {
+			return h.Value
+		}
+	}
+	return ""
+}
+
+// ── Gzip tests ──────────────────────────────────────────────────────
+
+func TestGzip_BelowThresholdUncompressed(t *testing.T) {
+	resp := serveGzip(t, "gzip", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "tiny")
+	})
+
+	if enc := witHeader(resp, "Content-Encoding"); enc != "" {
+		t.Fatalf("expected no Content-Encoding, got '%s'", enc)
+	}
+	if string(resp.Body) != "tiny" {
+		t.Fatalf("expected body 'tiny', got '%s'", resp.Body)
+	}
+	if cl := witHeader(resp, "Content-Length"); cl != "4" {
+		t.Fatalf("expected Content-Length 4, got '%s'", cl)
+	}
+}
+
+func TestGzip_AboveThresholdCompressed(t *testing.T) {
+	payload := strings.Repeat("warpgrid ", 500)
+	resp := serveGzip(t, "deflate, gzip;q=0.8", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+		io.WriteString(w, payload)
+	})
+
+	if enc := witHeader(resp, "Content-Encoding"); enc != "gzip" {
+		t.Fatalf("expected Content-Encoding 'gzip', got '%s'", enc)
+	}
+	if len(resp.Body) >= len(payload) {
+		t.Fatalf("expected compressed body smaller than %d, got %d", len(payload), len(resp.Body))
+	}
+	if cl := witHeader(resp, "Content-Length"); cl != strconv.Itoa(len(resp.Body)) {
+		t.Fatalf("expected Content-Length %d, got '%s'", len(resp.Body), cl)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(resp.Body))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	plain, _ := io.ReadAll(zr)
+	if string(plain) != payload {
+		t.Fatalf("expected decompressed body to match the payload")
+	}
+	if vary := witHeader(resp, "Vary"); vary != "Accept-Encoding" {
+		t.Fatalf("expected Vary 'Accept-Encoding', got '%s'", vary)
+	}
+}
+
+func TestGzip_AlreadyEncodedUntouched(t *testing.T) {
+	payload := strings.Repeat("x", 4096)
+	resp := serveGzip(t, "gzip", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "br")
+		io.WriteString(w, payload)
+	})
+
+	if enc := witHeader(resp, "Content-Encoding"); enc != "br" {
+		t.Fatalf("expected Content-Encoding 'br', got '%s'", enc)
+	}
+	if string(resp.Body) != payload {
+		t.Fatalf("expected body to be passed through unchanged")
+	}
+}
+
+func TestGzip_ClientWithoutGzipUncompressed(t *testing.T) {
+	payload := strings.Repeat("x", 4096)
+	for _, ae := range []string{"", "gzip;q=0", "identity"} {
+		resp := serveGzip(t, ae, func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, payload)
+		})
+		if enc := witHeader(resp, "Content-Encoding"); enc != "" {
+			t.Fatalf("Accept-Encoding %q: expected no Content-Encoding, got '%s'", ae, enc)
+		}
+	}
+}
+
+func TestGzip_MinCompressSizeConfigurable(t *testing.T) {
+	defer func(old int) { wghttp.MinCompressSize = old }(wghttp.MinCompressSize)
+	wghttp.MinCompressSize = 8
+
+	resp := serveGzip(t, "gzip", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "aaaaaaaaaaaaaaaa")
+	})
+
+	if enc := witHeader(resp, "Content-Encoding"); enc != "gzip" {
+		t.Fatalf("expected Content-Encoding 'gzip', got '%s'", enc)
+	}
+}
@@ -12,12 +12,15 @@ package wghttp_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/http"
 )
@@ -720,6 +723,21 @@ func TestHandleWitRequest_HandlerPanic(t *testing.T) {
 	}
 }
 
+func TestHandleWitRequest_HandlerPanicProductionMode(t *testing.T) {
+	defer func(old bool) { wghttp.ProductionMode = old }(wghttp.ProductionMode)
+	wghttp.ProductionMode = true
+	wghttp.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("db password rejected")
+	}))
+	defer wghttp.ResetHandler()
+
+	resp := wghttp.HandleWitRequest(wghttp.WitRequest{Method: "GET", URI: "/panic"})
+
+	if resp.Status != 500 || string(resp.Body) != "internal server error" {
+		t.Fatalf("expected a generic 500, got %d '%s'", resp.Status, resp.Body)
+	}
+}
+
 func TestConvertRequest_EmptyHeaders(t *testing.T) {
 	wit := wghttp.WitRequest{
 		Method:  "GET",
@@ -757,3 +775,965 @@ func TestConvertRequest_ZeroLengthBody(t *testing.T) {
 		t.Fatalf("expected empty body, got %d bytes", len(got))
 	}
 }
+
+// ── RawBody tests ───────────────────────────────────────────────────
+
+func TestRawBody_MatchesInputAfterPartialRead(t *testing.T) {
+	payload := []byte(`{"event":"payment.succeeded","amount":4200}`)
+
+	var raw []byte
+	var ok bool
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Partially consume the body before asking for the raw bytes
+		buf := make([]byte, 10)
+		io.ReadFull(r.Body, buf)
+		raw, ok = wghttp.RawBody(r)
+	})
+
+	wghttp.SetHandler(handler)
+	defer wghttp.ResetHandler()
+
+	wghttp.HandleWitRequest(wghttp.WitRequest{
+		Method: "POST",
+		URI:    "/webhooks/stripe",
+		Body:   payload,
+	})
+
+	if !ok {
+		t.Fatal("RawBody reported no raw body for a converted request")
+	}
+	if !bytes.Equal(raw, payload) {
+		t.Fatalf("raw body: expected %q, got %q", payload, raw)
+	}
+}
+
+func TestRawBody_UnavailableForForeignRequest(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/webhooks", strings.NewReader("x"))
+	if _, ok := wghttp.RawBody(req); ok {
+		t.Fatal("RawBody should report false for a request not built by ConvertRequest")
+	}
+}
+
+// ── Streaming response tests ────────────────────────────────────────
+
+func headerValues(resp wghttp.WitResponse, name string) []string {
+	var values []string
+	for _, h := range resp.Headers {
+		if h.Name == name {
+			values = append(values, h.Value)
+		}
+	}
+	return values
+}
+
+func TestResponseCapture_ImplementsFlusher(t *testing.T) {
+	var w http.ResponseWriter = wghttp.NewResponseCapture()
+	if _, ok := w.(http.Flusher); !ok {
+		t.Fatal("expected ResponseCapture to implement http.Flusher")
+	}
+}
+
+func TestResponseCapture_FlushedResponseIsStreaming(t *testing.T) {
+	rc := wghttp.NewResponseCapture()
+	rc.Header().Set("Content-Length", "999")
+	rc.Write([]byte("chunk1"))
+	rc.Flush()
+	rc.Write([]byte("chunk2"))
+
+	resp := rc.Finish()
+	if !resp.Streaming {
+		t.Fatal("expected flushed response to be streaming")
+	}
+	if got := headerValues(resp, "Content-Length"); len(got) != 0 {
+		t.Fatalf("expected no Content-Length on streaming response, got %v", got)
+	}
+	if string(resp.Body) != "chunk1chunk2" {
+		t.Fatalf("body: expected 'chunk1chunk2', got '%s'", resp.Body)
+	}
+}
+
+func TestResponseCapture_UnflushedResponseHasContentLength(t *testing.T) {
+	rc := wghttp.NewResponseCapture()
+	rc.Write([]byte("hello"))
+
+	resp := rc.Finish()
+	if resp.Streaming {
+		t.Fatal("expected non-flushed response not to be streaming")
+	}
+	if got := headerValues(resp, "Content-Length"); len(got) != 1 || got[0] != "5" {
+		t.Fatalf("expected Content-Length [5], got %v", got)
+	}
+}
+
+func TestResponseCapture_NoContentLengthFor204(t *testing.T) {
+	rc := wghttp.NewResponseCapture()
+	rc.WriteHeader(http.StatusNoContent)
+
+	resp := rc.Finish()
+	if got := headerValues(resp, "Content-Length"); len(got) != 0 {
+		t.Fatalf("expected no Content-Length for 204, got %v", got)
+	}
+}
+
+func TestHandleWitRequest_FlushingHandlerStreams(t *testing.T) {
+	defer wghttp.ResetHandler()
+	wghttp.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("event: 1\n"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("event: 2\n"))
+	}))
+
+	resp := wghttp.HandleWitRequest(wghttp.WitRequest{Method: "GET", URI: "/events"})
+	if !resp.Streaming {
+		t.Fatal("expected streaming response from flushing handler")
+	}
+	if got := headerValues(resp, "Content-Length"); len(got) != 0 {
+		t.Fatalf("expected no Content-Length, got %v", got)
+	}
+}
+
+// ── Content-Length reconciliation tests ─────────────────────────────
+
+func TestConvertRequest_MatchingContentLength(t *testing.T) {
+	req, err := wghttp.ConvertRequest(wghttp.WitRequest{
+		Method:  "POST",
+		URI:     "/upload",
+		Headers: []wghttp.WitHeader{{Name: "Content-Length", Value: "5"}},
+		Body:    []byte("hello"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.ContentLength != 5 || req.Header.Get("Content-Length") != "5" {
+		t.Fatalf("expected ContentLength 5 and header '5', got %d and '%s'",
+			req.ContentLength, req.Header.Get("Content-Length"))
+	}
+}
+
+func TestConvertRequest_MissingContentLengthIsFilledIn(t *testing.T) {
+	req, err := wghttp.ConvertRequest(wghttp.WitRequest{
+		Method: "POST",
+		URI:    "/upload",
+		Body:   []byte("hello world"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.ContentLength != 11 || req.Header.Get("Content-Length") != "11" {
+		t.Fatalf("expected ContentLength 11 and header '11', got %d and '%s'",
+			req.ContentLength, req.Header.Get("Content-Length"))
+	}
+
+	get, err := wghttp.ConvertRequest(wghttp.WitRequest{Method: "GET", URI: "/"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if get.Header.Get("Content-Length") != "" {
+		t.Fatalf("expected no Content-Length for empty body, got '%s'", get.Header.Get("Content-Length"))
+	}
+}
+
+func TestConvertRequest_ConflictingContentLength(t *testing.T) {
+	_, err := wghttp.ConvertRequest(wghttp.WitRequest{
+		Method:  "POST",
+		URI:     "/upload",
+		Headers: []wghttp.WitHeader{{Name: "Content-Length", Value: "100"}},
+		Body:    []byte("hello"),
+	})
+	if !errors.Is(err, wghttp.ErrContentLengthMismatch) {
+		t.Fatalf("expected ErrContentLengthMismatch, got %v", err)
+	}
+}
+
+func TestHandleWitRequest_ConflictingContentLengthReturns400(t *testing.T) {
+	defer wghttp.ResetHandler()
+	wghttp.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler must not be invoked for a conflicting Content-Length")
+	}))
+
+	resp := wghttp.HandleWitRequest(wghttp.WitRequest{
+		Method:  "POST",
+		URI:     "/upload",
+		Headers: []wghttp.WitHeader{{Name: "Content-Length", Value: "3"}},
+		Body:    []byte("hello"),
+	})
+	if resp.Status != 400 {
+		t.Fatalf("expected status 400, got %d", resp.Status)
+	}
+}
+
+// ── Protocol version tests ──────────────────────────────────────────
+
+func TestConvertRequest_DefaultsToHTTP11(t *testing.T) {
+	req, err := wghttp.ConvertRequest(wghttp.WitRequest{Method: "GET", URI: "/"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Proto != "HTTP/1.1" || req.ProtoMajor != 1 || req.ProtoMinor != 1 {
+		t.Fatalf("expected HTTP/1.1, got %s (%d.%d)", req.Proto, req.ProtoMajor, req.ProtoMinor)
+	}
+	if req.Close {
+		t.Fatal("expected HTTP/1.1 to keep the connection alive by default")
+	}
+}
+
+func TestConvertRequest_HTTP10ClosesByDefault(t *testing.T) {
+	req, err := wghttp.ConvertRequest(wghttp.WitRequest{Method: "GET", URI: "/", Proto: "HTTP/1.0"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Proto != "HTTP/1.0" || req.ProtoMajor != 1 || req.ProtoMinor != 0 {
+		t.Fatalf("expected HTTP/1.0, got %s (%d.%d)", req.Proto, req.ProtoMajor, req.ProtoMinor)
+	}
+	if !req.Close {
+		t.Fatal("expected HTTP/1.0 request to default to Close")
+	}
+	if req.ProtoAtLeast(1, 1) {
+		t.Fatal("expected ProtoAtLeast(1, 1) to be false for HTTP/1.0")
+	}
+}
+
+func TestConvertRequest_HTTP10KeepAlive(t *testing.T) {
+	req, err := wghttp.ConvertRequest(wghttp.WitRequest{
+		Method:  "GET",
+		URI:     "/",
+		Proto:   "HTTP/1.0",
+		Headers: []wghttp.WitHeader{{Name: "Connection", Value: "Keep-Alive"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Close {
+		t.Fatal("expected HTTP/1.0 with keep-alive not to Close")
+	}
+}
+
+func TestConvertRequest_HTTP11ConnectionClose(t *testing.T) {
+	req, err := wghttp.ConvertRequest(wghttp.WitRequest{
+		Method:  "GET",
+		URI:     "/",
+		Proto:   "HTTP/1.1",
+		Headers: []wghttp.WitHeader{{Name: "Connection", Value: "upgrade, close"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !req.Close {
+		t.Fatal("expected Connection: close to set Close")
+	}
+}
+
+func TestConvertRequest_MalformedProtoReturnsError(t *testing.T) {
+	if _, err := wghttp.ConvertRequest(wghttp.WitRequest{Method: "GET", URI: "/", Proto: "SPDY/3"}); err == nil {
+		t.Fatal("expected error for malformed protocol version")
+	}
+}
+
+// ── Status normalization tests ──────────────────────────────────────
+
+func TestHandleWitRequest_NoOpHandlerReturns200(t *testing.T) {
+	defer wghttp.ResetHandler()
+	wghttp.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	resp := wghttp.HandleWitRequest(wghttp.WitRequest{Method: "GET", URI: "/"})
+	if resp.Status != 200 {
+		t.Fatalf("expected status 200 from no-op handler, got %d", resp.Status)
+	}
+}
+
+func TestHandleWitRequest_ZeroStatusCoercedTo200(t *testing.T) {
+	defer wghttp.ResetHandler()
+	wghttp.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(0)
+	}))
+
+	resp := wghttp.HandleWitRequest(wghttp.WitRequest{Method: "GET", URI: "/"})
+	if resp.Status != 200 {
+		t.Fatalf("expected status 0 coerced to 200, got %d", resp.Status)
+	}
+}
+
+func TestResponseCapture_OutOfRangeStatusBecomes500(t *testing.T) {
+	for _, code := range []int{42, 1000, 70000} {
+		rc := wghttp.NewResponseCapture()
+		rc.WriteHeader(code)
+		if resp := rc.Finish(); resp.Status != 500 {
+			t.Fatalf("status %d: expected 500, got %d", code, resp.Status)
+		}
+	}
+}
+
+// ── Connection: close tests ─────────────────────────────────────────
+
+func connectionHeader(resp wghttp.WitResponse) string {
+	for _, h := range resp.Headers {
+		if h.Name == "Connection" {
+			return h.Value
+		}
+	}
+	return ""
+}
+
+func TestHandleWitRequest_ConnectionCloseEchoed(t *testing.T) {
+	defer wghttp.ResetHandler()
+	wghttp.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("bye"))
+	}))
+
+	resp := wghttp.HandleWitRequest(wghttp.WitRequest{
+		Method:  "GET",
+		URI:     "/",
+		Headers: []wghttp.WitHeader{{Name: "Connection", Value: "close"}},
+	})
+
+	if got := connectionHeader(resp); got != "close" {
+		t.Fatalf("expected Connection 'close', got '%s'", got)
+	}
+	if string(resp.Body) != "bye" {
+		t.Fatalf("expected body 'bye', got '%s'", resp.Body)
+	}
+}
+
+func TestHandleWitRequest_KeepAliveHasNoConnectionHeader(t *testing.T) {
+	defer wghttp.ResetHandler()
+	wghttp.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	resp := wghttp.HandleWitRequest(wghttp.WitRequest{Method: "GET", URI: "/"})
+
+	if got := connectionHeader(resp); got != "" {
+		t.Fatalf("expected no Connection header, got '%s'", got)
+	}
+}
+
+func TestCloseConnection_HandlerRequestsClose(t *testing.T) {
+	defer wghttp.ResetHandler()
+	wghttp.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wghttp.CloseConnection(w)
+	}))
+
+	resp := wghttp.HandleWitRequest(wghttp.WitRequest{Method: "GET", URI: "/"})
+
+	if got := connectionHeader(resp); got != "close" {
+		t.Fatalf("expected Connection 'close', got '%s'", got)
+	}
+}
+
+// ── ReadFrom tests ──────────────────────────────────────────────────
+
+func TestResponseCapture_ReadFrom(t *testing.T) {
+	data := bytes.Repeat([]byte("warpgrid"), 4096)
+	rc := wghttp.NewResponseCapture()
+
+	var w http.ResponseWriter = rc
+	rf, ok := w.(io.ReaderFrom)
+	if !ok {
+		t.Fatal("expected ResponseCapture to implement io.ReaderFrom")
+	}
+	n, err := rf.ReadFrom(bytes.NewReader(data))
+	if err != nil || n != int64(len(data)) {
+		t.Fatalf("expected %d bytes copied, got n=%d err=%v", len(data), n, err)
+	}
+	n, err = rf.ReadFrom(strings.NewReader("!"))
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 byte copied, got n=%d err=%v", n, err)
+	}
+
+	resp := rc.Finish()
+	if resp.Status != 200 {
+		t.Fatalf("expected implicit status 200, got %d", resp.Status)
+	}
+	if !bytes.Equal(resp.Body, append(data, '!')) {
+		t.Fatal("expected body to match the copied readers")
+	}
+}
+
+// ── WitRequestFromContext tests ─────────────────────────────────────
+
+func TestWitRequestFromContext_ReturnsOriginalRequest(t *testing.T) {
+	payload := []byte("tenant payload")
+	wit := wghttp.WitRequest{
+		Method:  "PUT",
+		URI:     "/tenants/acme?dry_run=1",
+		Headers: []wghttp.WitHeader{{Name: "X-Trigger-Source", Value: "cron"}},
+		Body:    payload,
+		Proto:   "HTTP/1.0",
+	}
+
+	var got wghttp.WitRequest
+	var ok bool
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok = wghttp.WitRequestFromContext(r.Context())
+		got.Body[0] = 'X' // must not alias the request body
+		got.Headers[0].Value = "mutated"
+	})
+	wghttp.SetHandler(handler)
+	defer wghttp.ResetHandler()
+
+	wghttp.HandleWitRequest(wit)
+
+	if !ok {
+		t.Fatal("expected the WIT request in the handler's context")
+	}
+	if got.Method != "PUT" || got.URI != "/tenants/acme?dry_run=1" || got.Proto != "HTTP/1.0" {
+		t.Fatalf("expected original method/URI/proto, got %q %q %q", got.Method, got.URI, got.Proto)
+	}
+	if len(got.Headers) != 1 || got.Headers[0].Name != "X-Trigger-Source" {
+		t.Fatalf("expected original header list, got %v", got.Headers)
+	}
+	if string(payload) != "tenant payload" || wit.Headers[0].Value != "cron" {
+		t.Fatal("expected WitRequestFromContext to return a copy")
+	}
+}
+
+func TestWitRequestFromContext_CopiesOnEveryCall(t *testing.T) {
+	req, err := wghttp.ConvertRequest(wghttp.WitRequest{Method: "POST", URI: "/", Body: []byte("abc")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	first, _ := wghttp.WitRequestFromContext(req.Context())
+	first.Body[0] = 'z'
+	second, _ := wghttp.WitRequestFromContext(req.Context())
+
+	if string(second.Body) != "abc" {
+		t.Fatalf("expected an unaliased body on each call, got %q", second.Body)
+	}
+	if raw, _ := wghttp.RawBody(req); string(raw) != "abc" {
+		t.Fatalf("expected RawBody unaffected, got %q", raw)
+	}
+}
+
+func TestWitRequestFromContext_UnavailableForForeignRequest(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/", nil)
+	if _, ok := wghttp.WitRequestFromContext(req.Context()); ok {
+		t.Fatal("expected false for a request not built by ConvertRequest")
+	}
+}
+
+// ── Streaming panic tests ───────────────────────────────────────────
+
+func TestHandleWitRequest_PanicBeforeFlushIsClean500(t *testing.T) {
+	wghttp.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("partial"))
+		panic("boom")
+	}))
+	defer wghttp.ResetHandler()
+
+	resp := wghttp.HandleWitRequest(wghttp.WitRequest{Method: "GET", URI: "/stream"})
+
+	if resp.Status != 500 {
+		t.Fatalf("expected status 500, got %d", resp.Status)
+	}
+	if resp.Aborted || resp.Streaming {
+		t.Fatalf("expected a complete non-streaming 500, got aborted=%v streaming=%v", resp.Aborted, resp.Streaming)
+	}
+	if strings.Contains(string(resp.Body), "partial") {
+		t.Fatalf("expected unflushed output discarded, got '%s'", resp.Body)
+	}
+}
+
+func TestHandleWitRequest_PanicAfterFlushAbortsStream(t *testing.T) {
+	wghttp.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: 1\n\n"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("data: 2\n\n"))
+		panic("boom")
+	}))
+	defer wghttp.ResetHandler()
+
+	resp := wghttp.HandleWitRequest(wghttp.WitRequest{Method: "GET", URI: "/stream"})
+
+	if !resp.Aborted {
+		t.Fatal("expected panic after flush to abort the stream")
+	}
+	if resp.Status != 200 || !resp.Streaming {
+		t.Fatalf("expected the committed 200 streaming response, got %d streaming=%v", resp.Status, resp.Streaming)
+	}
+	if got := headerValues(resp, "Content-Type"); len(got) != 1 || got[0] != "text/event-stream" {
+		t.Fatalf("expected committed headers kept, got %v", got)
+	}
+	if string(resp.Body) != "data: 1\n\ndata: 2\n\n" {
+		t.Fatalf("expected bytes written before the panic, got '%s'", resp.Body)
+	}
+}
+
+func TestHandleWitRequest_NormalStreamNotAborted(t *testing.T) {
+	wghttp.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("chunk"))
+		w.(http.Flusher).Flush()
+	}))
+	defer wghttp.ResetHandler()
+
+	resp := wghttp.HandleWitRequest(wghttp.WitRequest{Method: "GET", URI: "/stream"})
+
+	if resp.Aborted {
+		t.Fatal("expected a completed stream not to be aborted")
+	}
+}
+
+// ── Method canonicalization tests ───────────────────────────────────
+
+func TestConvertRequest_LowercaseMethodNormalized(t *testing.T) {
+	req, err := wghttp.ConvertRequest(wghttp.WitRequest{Method: "get", URI: "/"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Method != http.MethodGet {
+		t.Fatalf("expected method GET, got %q", req.Method)
+	}
+
+	req, _ = wghttp.ConvertRequest(wghttp.WitRequest{Method: "Delete", URI: "/"})
+	if req.Method != http.MethodDelete {
+		t.Fatalf("expected method DELETE, got %q", req.Method)
+	}
+}
+
+func TestConvertRequest_InvalidMethodRejected(t *testing.T) {
+	for _, method := range []string{"GET /admin", "PO\tST", "GET\r\n"} {
+		if _, err := wghttp.ConvertRequest(wghttp.WitRequest{Method: method, URI: "/"}); !errors.Is(err, wghttp.ErrInvalidMethod) {
+			t.Fatalf("expected ErrInvalidMethod for %q, got %v", method, err)
+		}
+	}
+}
+
+func TestHandleWitRequest_InvalidMethodIs400(t *testing.T) {
+	called := false
+	wghttp.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer wghttp.ResetHandler()
+
+	resp := wghttp.HandleWitRequest(wghttp.WitRequest{Method: "BAD METHOD", URI: "/"})
+
+	if resp.Status != 400 || called {
+		t.Fatalf("expected 400 without calling the handler, got %d called=%v", resp.Status, called)
+	}
+}
+
+func TestConvertRequest_CustomMethodPreserved(t *testing.T) {
+	req, err := wghttp.ConvertRequest(wghttp.WitRequest{Method: "PURGE", URI: "/cache/item"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Method != "PURGE" {
+		t.Fatalf("expected method PURGE, got %q", req.Method)
+	}
+}
+
+// ── Deadline header tests ───────────────────────────────────────────
+
+func TestConvertRequest_DeadlineHeaderSetsContextDeadline(t *testing.T) {
+	before := time.Now()
+	req, err := wghttp.ConvertRequest(wghttp.WitRequest{
+		Method:  "GET",
+		URI:     "/",
+		Headers: []wghttp.WitHeader{{Name: wghttp.DeadlineHeader, Value: "1500"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	deadline, ok := req.Context().Deadline()
+	if !ok {
+		t.Fatal("expected a context deadline from the header")
+	}
+	if d := deadline.Sub(before); d < 1500*time.Millisecond || d > 2500*time.Millisecond {
+		t.Fatalf("expected deadline about 1.5s ahead, got %v", d)
+	}
+}
+
+func TestReleaseRequest_StopsDeadline(t *testing.T) {
+	req, err := wghttp.ConvertRequest(wghttp.WitRequest{
+		Method:  "GET",
+		URI:     "/",
+		Headers: []wghttp.WitHeader{{Name: wghttp.DeadlineHeader, Value: "60000"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wghttp.ReleaseRequest(req)
+	if err := req.Context().Err(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the deadline context to be cancelled, got %v", err)
+	}
+
+	plain, err := wghttp.ConvertRequest(wghttp.WitRequest{Method: "GET", URI: "/"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wghttp.ReleaseRequest(plain)
+	if err := plain.Context().Err(); err != nil {
+		t.Fatalf("expected a request without a deadline to be unaffected, got %v", err)
+	}
+}
+
+func TestConvertRequest_InvalidDeadlineHeaderIgnored(t *testing.T) {
+	for _, value := range []string{"soon", "-5", "0", "1.5", "99999999999999999999"} {
+		req, err := wghttp.ConvertRequest(wghttp.WitRequest{
+			Method:  "GET",
+			URI:     "/",
+			Headers: []wghttp.WitHeader{{Name: wghttp.DeadlineHeader, Value: value}},
+		})
+		if err != nil {
+			t.Fatalf("expected malformed %q to be ignored, got error %v", value, err)
+		}
+		if _, ok := req.Context().Deadline(); ok {
+			t.Fatalf("expected no deadline for %q", value)
+		}
+	}
+}
+
+func TestHandleWitRequest_ExpiredDeadlineIs504(t *testing.T) {
+	wghttp.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done() // a handler that honours cancellation
+	}))
+	defer wghttp.ResetHandler()
+
+	resp := wghttp.HandleWitRequest(wghttp.WitRequest{
+		Method:  "GET",
+		URI:     "/slow",
+		Headers: []wghttp.WitHeader{{Name: wghttp.DeadlineHeader, Value: "5"}},
+	})
+
+	if resp.Status != http.StatusGatewayTimeout {
+		t.Fatalf("expected status 504, got %d", resp.Status)
+	}
+}
+
+func TestHandleWitRequest_DeadlineNotHitKeepsResponse(t *testing.T) {
+	wghttp.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("fast"))
+	}))
+	defer wghttp.ResetHandler()
+
+	resp := wghttp.HandleWitRequest(wghttp.WitRequest{
+		Method:  "GET",
+		URI:     "/",
+		Headers: []wghttp.WitHeader{{Name: wghttp.DeadlineHeader, Value: "60000"}},
+	})
+
+	if resp.Status != 200 || string(resp.Body) != "fast" {
+		t.Fatalf("expected 200 'fast', got %d '%s'", resp.Status, resp.Body)
+	}
+}
+
+// ── Body-forbidding status tests ────────────────────────────────────
+
+func TestResponseCapture_WriteAfter204IsDropped(t *testing.T) {
+	rc := wghttp.NewResponseCapture()
+	rc.WriteHeader(http.StatusNoContent)
+
+	n, err := rc.Write([]byte("oops"))
+
+	if n != 0 || !errors.Is(err, http.ErrBodyNotAllowed) {
+		t.Fatalf("expected (0, ErrBodyNotAllowed), got (%d, %v)", n, err)
+	}
+	resp := rc.Finish()
+	if resp.Status != 204 || len(resp.Body) != 0 {
+		t.Fatalf("expected 204 with empty body, got %d '%s'", resp.Status, resp.Body)
+	}
+}
+
+func TestResponseCapture_WriteAfter1xxIsDropped(t *testing.T) {
+	rc := wghttp.NewResponseCapture()
+	rc.WriteHeader(http.StatusSwitchingProtocols)
+
+	if _, err := rc.Write([]byte("oops")); !errors.Is(err, http.ErrBodyNotAllowed) {
+		t.Fatalf("expected ErrBodyNotAllowed, got %v", err)
+	}
+}
+
+func TestResponseCapture_WriteAfter200Normal(t *testing.T) {
+	rc := wghttp.NewResponseCapture()
+	rc.WriteHeader(http.StatusOK)
+
+	if n, err := rc.Write([]byte("fine")); n != 4 || err != nil {
+		t.Fatalf("expected normal write, got (%d, %v)", n, err)
+	}
+	if resp := rc.Finish(); string(resp.Body) != "fine" {
+		t.Fatalf("expected body 'fine', got '%s'", resp.Body)
+	}
+}
+
+// ── Deterministic header tests ──────────────────────────────────────
+
+func TestFinish_DeterministicHeadersSorted(t *testing.T) {
+	defer func(old bool) { wghttp.DeterministicHeaders = old }(wghttp.DeterministicHeaders)
+	wghttp.DeterministicHeaders = true
+
+	for i := 0; i < 20; i++ {
+		rc := wghttp.NewResponseCapture()
+		rc.Header().Set("X-Zeta", "z")
+		rc.Header().Add("Vary", "Origin")
+		rc.Header().Add("Vary", "Accept")
+		rc.Header().Set("Content-Type", "text/plain")
+		rc.Write([]byte("hi"))
+
+		var got []string
+		for _, h := range rc.Finish().Headers {
+			got = append(got, h.Name+": "+h.Value)
+		}
+		want := []string{
+			"Content-Length: 2",
+			"Content-Type: text/plain",
+			"Vary: Origin",
+			"Vary: Accept",
+			"X-Zeta: z",
+		}
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Fatalf("expected sorted headers\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+		}
+	}
+}
+
+// ── Request trailer tests ───────────────────────────────────────────
+
+func TestHandleWitRequest_TrailersAvailableAfterBody(t *testing.T) {
+	var before, after http.Header
+	var body string
+	defer wghttp.ResetHandler()
+	wghttp.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		before = r.Trailer.Clone()
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		after = r.Trailer.Clone()
+	}))
+
+	resp := wghttp.HandleWitRequest(wghttp.WitRequest{
+		Method:   "POST",
+		URI:      "/upload",
+		Body:     []byte("payload"),
+		Trailers: []wghttp.WitHeader{{Name: "x-checksum", Value: "abc123"}},
+	})
+
+	if resp.Status != 200 {
+		t.Fatalf("expected status 200, got %d", resp.Status)
+	}
+	if body != "payload" {
+		t.Fatalf("expected body 'payload', got %q", body)
+	}
+	if v, ok := before["X-Checksum"]; !ok || v != nil {
+		t.Fatalf("expected X-Checksum declared with no value before reading, got %v (%v)", v, ok)
+	}
+	if after.Get("X-Checksum") != "abc123" {
+		t.Fatalf("expected X-Checksum 'abc123' after reading, got '%s'", after.Get("X-Checksum"))
+	}
+}
+
+func TestConvertRequest_NoTrailersLeavesTrailerNil(t *testing.T) {
+	req, err := wghttp.ConvertRequest(wghttp.WitRequest{Method: "GET", URI: "/"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Trailer != nil {
+		t.Fatalf("expected nil Trailer, got %v", req.Trailer)
+	}
+}
+
+// ── Connection reset tests ──────────────────────────────────────────
+
+func TestHandleWitRequest_ResetBeforeWriteIsCleanError(t *testing.T) {
+	var writeErr error
+	wghttp.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Partial", "yes")
+		wghttp.ResetConn(w)
+		_, writeErr = w.Write([]byte("too late"))
+	}))
+	defer wghttp.ResetHandler()
+
+	resp := wghttp.HandleWitRequest(wghttp.WitRequest{Method: "GET", URI: "/"})
+
+	if !errors.Is(writeErr, wghttp.ErrConnReset) {
+		t.Fatalf("expected ErrConnReset from a write after reset, got %v", writeErr)
+	}
+	if !resp.Aborted || resp.Streaming {
+		t.Fatalf("expected an aborted non-streaming response, got aborted=%v streaming=%v", resp.Aborted, resp.Streaming)
+	}
+	if resp.Status != 500 || len(resp.Headers) != 0 || len(resp.Body) != 0 {
+		t.Fatalf("expected a bare 500, got %d with headers %v and body '%s'", resp.Status, resp.Headers, resp.Body)
+	}
+}
+
+func TestHandleWitRequest_ResetAfterFlushAbortsStream(t *testing.T) {
+	wghttp.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data: 1\n\n"))
+		w.(http.Flusher).Flush()
+		wghttp.ResetConn(w)
+		w.Write([]byte("data: 2\n\n"))
+	}))
+	defer wghttp.ResetHandler()
+
+	resp := wghttp.HandleWitRequest(wghttp.WitRequest{Method: "GET", URI: "/stream"})
+
+	if !resp.Aborted || !resp.Streaming || resp.Status != 200 {
+		t.Fatalf("expected an aborted 200 stream, got %d aborted=%v streaming=%v", resp.Status, resp.Aborted, resp.Streaming)
+	}
+	if string(resp.Body) != "data: 1\n\n" {
+		t.Fatalf("expected only bytes written before the reset, got '%s'", resp.Body)
+	}
+}
+
+// unwrappingWriter wraps a ResponseWriter the way middleware does,
+// exposing it through Unwrap.
+type unwrappingWriter struct{ http.ResponseWriter }
+
+func (w unwrappingWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func TestResetConn_ReachesCaptureThroughUnwrap(t *testing.T) {
+	rc := wghttp.NewResponseCapture()
+	wghttp.ResetConn(unwrappingWriter{rc})
+	if resp := rc.Finish(); !resp.Aborted {
+		t.Fatal("expected ResetConn through a wrapper to abort the response")
+	}
+}
+
+// ── Response size limit tests ───────────────────────────────────────
+
+// serveSized runs a handler writing n bytes, in two writes, with
+// MaxResponseBodySize set to limit.
+func serveSized(t *testing.T, limit int64, n int) (wghttp.WitResponse, error) {
+	t.Helper()
+	defer func(old int64) { wghttp.MaxResponseBodySize = old }(wghttp.MaxResponseBodySize)
+	wghttp.MaxResponseBodySize = limit
+
+	var writeErr error
+	wghttp.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := bytes.Repeat([]byte("x"), n)
+		if _, err := w.Write(body[:n/2]); err != nil {
+			writeErr = err
+			return
+		}
+		_, writeErr = w.Write(body[n/2:])
+	}))
+	defer wghttp.ResetHandler()
+	return wghttp.HandleWitRequest(wghttp.WitRequest{Method: "GET", URI: "/"}), writeErr
+}
+
+func TestHandleWitRequest_ResponseUnderAndAtLimitSent(t *testing.T) {
+	for _, n := range []int{99, 100} {
+		resp, err := serveSized(t, 100, n)
+		if err != nil {
+			t.Fatalf("%d bytes: unexpected write error: %v", n, err)
+		}
+		if resp.Status != 200 || len(resp.Body) != n || resp.Aborted {
+			t.Fatalf("%d bytes: expected a complete 200, got %d with %d bytes (aborted=%v)", n, resp.Status, len(resp.Body), resp.Aborted)
+		}
+	}
+}
+
+func TestHandleWitRequest_ResponseOverLimitReplacedWith500(t *testing.T) {
+	resp, err := serveSized(t, 100, 101)
+	if !errors.Is(err, wghttp.ErrResponseTooLarge) {
+		t.Fatalf("expected ErrResponseTooLarge from the handler's write, got %v", err)
+	}
+	if resp.Status != 500 || resp.Aborted {
+		t.Fatalf("expected a clean 500, got %d (aborted=%v)", resp.Status, resp.Aborted)
+	}
+	if strings.Contains(string(resp.Body), "xxx") {
+		t.Fatalf("expected the oversized body discarded, got '%s'", resp.Body)
+	}
+}
+
+func TestHandleWitRequest_ResponseOverLimitTruncated(t *testing.T) {
+	defer func(old bool) { wghttp.TruncateOversizedResponses = old }(wghttp.TruncateOversizedResponses)
+	wghttp.TruncateOversizedResponses = true
+
+	resp, err := serveSized(t, 100, 150)
+	if !errors.Is(err, wghttp.ErrResponseTooLarge) {
+		t.Fatalf("expected ErrResponseTooLarge, got %v", err)
+	}
+	if resp.Status != 200 || len(resp.Body) != 100 || !resp.Aborted {
+		t.Fatalf("expected 100 bytes of an aborted 200, got %d with %d bytes (aborted=%v)", resp.Status, len(resp.Body), resp.Aborted)
+	}
+}
+
+func TestResponseCapture_ReadFromBoundedByLimit(t *testing.T) {
+	defer func(old int64) { wghttp.MaxResponseBodySize = old }(wghttp.MaxResponseBodySize)
+	wghttp.MaxResponseBodySize = 10
+
+	rc := wghttp.NewResponseCapture()
+	n, err := rc.ReadFrom(strings.NewReader("0123456789"))
+	if err != nil || n != 10 {
+		t.Fatalf("expected 10 bytes read within the limit, got %d (%v)", n, err)
+	}
+	rc = wghttp.NewResponseCapture()
+	n, err = rc.ReadFrom(strings.NewReader("0123456789A"))
+	if !errors.Is(err, wghttp.ErrResponseTooLarge) || n != 10 {
+		t.Fatalf("expected 10 bytes and ErrResponseTooLarge, got %d (%v)", n, err)
+	}
+	if resp := rc.Finish(); resp.Status != 500 {
+		t.Fatalf("expected 500 for the oversized body, got %d", resp.Status)
+	}
+}
+
+// ── Content-Type sniffing tests ─────────────────────────────────────
+
+func TestResponseCapture_FinishSniffsContentType(t *testing.T) {
+	rc := wghttp.NewResponseCapture()
+	rc.Write([]byte("<!DOCTYPE html><html></html>"))
+	if got := headerValues(rc.Finish(), "Content-Type"); len(got) != 1 || got[0] != "text/html; charset=utf-8" {
+		t.Fatalf("expected sniffed HTML, got %v", got)
+	}
+
+	rc = wghttp.NewResponseCapture()
+	rc.Write([]byte("\x89PNG\r\n\x1a\n\x00\x00"))
+	if got := headerValues(rc.Finish(), "Content-Type"); len(got) != 1 || got[0] != "image/png" {
+		t.Fatalf("expected sniffed PNG, got %v", got)
+	}
+}
+
+func TestResponseCapture_ExplicitContentTypeKept(t *testing.T) {
+	rc := wghttp.NewResponseCapture()
+	rc.Header().Set("Content-Type", "application/vnd.api+json")
+	rc.Write([]byte("<html></html>"))
+	if got := headerValues(rc.Finish(), "Content-Type"); len(got) != 1 || got[0] != "application/vnd.api+json" {
+		t.Fatalf("expected the handler's Content-Type, got %v", got)
+	}
+
+	defer func(old bool) { wghttp.DisableContentSniffing = old }(wghttp.DisableContentSniffing)
+	wghttp.DisableContentSniffing = true
+	rc = wghttp.NewResponseCapture()
+	rc.Write([]byte("<html></html>"))
+	if got := headerValues(rc.Finish(), "Content-Type"); len(got) != 0 {
+		t.Fatalf("expected no Content-Type with sniffing disabled, got %v", got)
+	}
+}
+
+// ── Status validation tests ─────────────────────────────────────────
+
+func TestResponseCapture_ValidStatusKept(t *testing.T) {
+	for _, code := range []int{100, 204, 418, 599} {
+		rc := wghttp.NewResponseCapture()
+		rc.WriteHeader(code)
+		if resp := rc.Finish(); int(resp.Status) != code {
+			t.Fatalf("expected status %d, got %d", code, resp.Status)
+		}
+	}
+}
+
+func TestResponseCapture_TooSmallStatusBecomes500(t *testing.T) {
+	for _, code := range []int{-1, 1, 99} {
+		rc := wghttp.NewResponseCapture()
+		rc.WriteHeader(code)
+		if resp := rc.Finish(); resp.Status != 500 {
+			t.Fatalf("status %d: expected 500, got %d", code, resp.Status)
+		}
+	}
+}
+
+func TestResponseCapture_TooLargeStatusBecomes500(t *testing.T) {
+	for _, code := range []int{600, 999, 9999, 65536 + 200} {
+		rc := wghttp.NewResponseCapture()
+		rc.Header().Set("X-Handler", "kept")
+		rc.WriteHeader(code)
+		resp := rc.Finish()
+		if resp.Status != 500 {
+			t.Fatalf("status %d: expected 500, got %d", code, resp.Status)
+		}
+		if v := headerValues(resp, "X-Handler"); len(v) != 1 || v[0] != "kept" {
+			t.Fatalf("status %d: expected handler headers kept, got %v", code, v)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package wghttp_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/http"
+)
+
+// ── Interceptor tests ───────────────────────────────────────────────
+
+// interceptedMux registers a mux serving only /hello, which echoes the
+// request body, and clears the handler and interceptors afterwards.
+func interceptedMux(t *testing.T) {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	})
+	wghttp.SetHandler(mux)
+	t.Cleanup(wghttp.ResetHandler)
+	t.Cleanup(wghttp.ResetInterceptors)
+}
+
+func TestInterceptors_RunForMatchedAndUnmatchedRoutes(t *testing.T) {
+	interceptedMux(t)
+	var seen []string
+	wghttp.RegisterRequestInterceptor(func(req *wghttp.WitRequest) error {
+		seen = append(seen, req.URI)
+		return nil
+	})
+	wghttp.RegisterResponseInterceptor(func(resp *wghttp.WitResponse) {
+		resp.Headers = append(resp.Headers, wghttp.WitHeader{Name: "X-Signed", Value: "yes"})
+	})
+
+	hello := wghttp.HandleWitRequest(wghttp.WitRequest{Method: "GET", URI: "/hello"})
+	missing := wghttp.HandleWitRequest(wghttp.WitRequest{Method: "GET", URI: "/missing"})
+
+	if len(seen) != 2 || seen[0] != "/hello" || seen[1] != "/missing" {
+		t.Fatalf("expected request interceptor to see both requests, got %v", seen)
+	}
+	if hello.Status != 200 || missing.Status != 404 {
+		t.Fatalf("expected statuses 200 and 404, got %d and %d", hello.Status, missing.Status)
+	}
+	for _, resp := range []wghttp.WitResponse{hello, missing} {
+		if v := headerValues(resp, "X-Signed"); len(v) != 1 {
+			t.Fatalf("expected X-Signed on a %d response, got %v", resp.Status, v)
+		}
+	}
+}
+
+func TestInterceptors_MutateRequestAndResponse(t *testing.T) {
+	interceptedMux(t)
+	wghttp.RegisterRequestInterceptor(func(req *wghttp.WitRequest) error {
+		// Stand-in for decryption: the host sent the body reversed.
+		for i, j := 0, len(req.Body)-1; i < j; i, j = i+1, j-1 {
+			req.Body[i], req.Body[j] = req.Body[j], req.Body[i]
+		}
+		return nil
+	})
+	wghttp.RegisterResponseInterceptor(func(resp *wghttp.WitResponse) {
+		resp.Body = append([]byte("sealed:"), resp.Body...)
+	})
+
+	resp := wghttp.HandleWitRequest(wghttp.WitRequest{Method: "POST", URI: "/hello", Body: []byte("olleh")})
+
+	if string(resp.Body) != "sealed:hello" {
+		t.Fatalf("expected body 'sealed:hello', got '%s'", resp.Body)
+	}
+}
+
+func TestInterceptors_RejectionShortCircuits(t *testing.T) {
+	interceptedMux(t)
+	defer func(old int) { wghttp.InterceptorRejectStatus = old }(wghttp.InterceptorRejectStatus)
+	wghttp.InterceptorRejectStatus = http.StatusUnauthorized
+
+	var later, responded bool
+	wghttp.RegisterRequestInterceptor(func(req *wghttp.WitRequest) error {
+		return errors.New("bad host signature")
+	})
+	wghttp.RegisterRequestInterceptor(func(req *wghttp.WitRequest) error {
+		later = true
+		return nil
+	})
+	wghttp.RegisterResponseInterceptor(func(resp *wghttp.WitResponse) {
+		responded = true
+	})
+
+	resp := wghttp.HandleWitRequest(wghttp.WitRequest{Method: "POST", URI: "/hello", Body: []byte("x")})
+
+	if resp.Status != http.StatusUnauthorized {
+		t.Fatalf("expected status 401, got %d", resp.Status)
+	}
+	if string(resp.Body) != "request rejected: bad host signature" {
+		t.Fatalf("expected rejection body, got '%s'", resp.Body)
+	}
+	if later {
+		t.Fatal("expected later request interceptors to be skipped")
+	}
+	if !responded {
+		t.Fatal("expected response interceptors to run on the rejection")
+	}
+}
+
+func TestInterceptors_DefaultRejectStatus(t *testing.T) {
+	interceptedMux(t)
+	wghttp.RegisterRequestInterceptor(func(req *wghttp.WitRequest) error {
+		return errors.New("nope")
+	})
+
+	if resp := wghttp.HandleWitRequest(wghttp.WitRequest{Method: "GET", URI: "/hello"}); resp.Status != 400 {
+		t.Fatalf("expected default status 400, got %d", resp.Status)
+	}
+}
+
+func TestInterceptors_ProductionModeHidesRejectionError(t *testing.T) {
+	interceptedMux(t)
+	defer func(old bool) { wghttp.ProductionMode = old }(wghttp.ProductionMode)
+	wghttp.ProductionMode = true
+
+	var hooked error
+	var hookedURI string
+	wghttp.InterceptorErrorHook = func(req *wghttp.WitRequest, err error) {
+		hooked, hookedURI = err, req.URI
+	}
+	wghttp.RegisterRequestInterceptor(func(req *wghttp.WitRequest) error {
+		return errors.New("key 7f3a expired at vault.internal")
+	})
+
+	resp := wghttp.HandleWitRequest(wghttp.WitRequest{Method: "GET", URI: "/hello"})
+
+	if resp.Status != 400 || string(resp.Body) != "Bad Request" {
+		t.Fatalf("expected a 400 with only the status text, got %d '%s'", resp.Status, resp.Body)
+	}
+	if hooked == nil || hooked.Error() != "key 7f3a expired at vault.internal" || hookedURI != "/hello" {
+		t.Fatalf("expected the hook to receive the error and request, got %v for '%s'", hooked, hookedURI)
+	}
+}
@@ -0,0 +1,70 @@
+package wghttp
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// JSONStreamFlushBytes is how many bytes JSONStreamArray writes between
+// flushes. Larger values mean fewer, bigger chunks for the host to
+// send; zero or negative flushes after every element.
+var JSONStreamFlushBytes = 32 << 10
+
+// JSONStreamArray writes the values items yields to w as a JSON array,
+// one element at a time, so a handler can serve a large list (rows from
+// a database cursor, say) without building the whole slice or its
+// encoding in memory. The output is byte-for-byte what json.Marshal
+// produces for a slice of the same values.
+//
+// Content-Type is set to application/json unless the handler set one.
+// When w implements http.Flusher, as ResponseCapture does, it is
+// flushed each time JSONStreamFlushBytes have been written, which turns
+// the response into a streaming one; see Finish.
+//
+// JSONStreamArray stops asking items for values and returns the error
+// when an element fails to encode or a write fails. The array is then
+// left unterminated; since the status has already been sent, the
+// handler should call ResetConn so the client sees a failed transfer
+// rather than a truncated document.
+func JSONStreamArray(w http.ResponseWriter, items func(yield func(any) bool)) error {
+	if _, ok := w.Header()["Content-Type"]; !ok {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	flusher, _ := w.(http.Flusher)
+
+	var err error
+	pending := 0
+	write := func(b []byte) bool {
+		var n int
+		n, err = w.Write(b)
+		pending += n
+		if err == nil && flusher != nil && pending >= JSONStreamFlushBytes {
+			flusher.Flush()
+			pending = 0
+		}
+		return err == nil
+	}
+
+	sep := byte('[')
+	items(func(v any) bool {
+		elem, merr := json.Marshal(v)
+		if merr != nil {
+			err = merr
+			return false
+		}
+		// Prefix the separator so each element goes out in one Write.
+		b := make([]byte, 0, len(elem)+1)
+		b = append(append(b, sep), elem...)
+		sep = ','
+		return write(b)
+	})
+	if err != nil {
+		return err
+	}
+	if sep == '[' {
+		write([]byte("[]"))
+	} else {
+		write([]byte("]"))
+	}
+	return err
+}
@@ -0,0 +1,153 @@
+package wghttp_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/http"
+)
+
+// ── JSONStreamArray tests ───────────────────────────────────────────
+
+// yieldAll returns an items function yielding values in order.
+func yieldAll(values []any) func(yield func(any) bool) {
+	return func(yield func(any) bool) {
+		for _, v := range values {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// chunkRecorder wraps a ResponseCapture and records the body written
+// since the previous flush each time Flush is called, as the host
+// would send it.
+type chunkRecorder struct {
+	*wghttp.ResponseCapture
+	buf    bytes.Buffer
+	chunks []string
+}
+
+func (c *chunkRecorder) Write(p []byte) (int, error) {
+	c.buf.Write(p)
+	return c.ResponseCapture.Write(p)
+}
+
+func (c *chunkRecorder) Flush() {
+	c.chunks = append(c.chunks, c.buf.String())
+	c.buf.Reset()
+	c.ResponseCapture.Flush()
+}
+
+func TestJSONStreamArray_MatchesMarshal(t *testing.T) {
+	values := []any{
+		map[string]any{"id": 1, "name": "ada"},
+		"<script>",
+		3.5,
+		nil,
+		[]int{1, 2},
+		struct {
+			OK bool `json:"ok"`
+		}{true},
+	}
+	want, _ := json.Marshal(values)
+
+	rc := wghttp.NewResponseCapture()
+	if err := wghttp.JSONStreamArray(rc, yieldAll(values)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	resp := rc.Finish()
+	if string(resp.Body) != string(want) {
+		t.Fatalf("expected %s, got %s", want, resp.Body)
+	}
+	if ct := rc.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("expected Content-Type application/json, got %q", ct)
+	}
+}
+
+func TestJSONStreamArray_Empty(t *testing.T) {
+	rc := wghttp.NewResponseCapture()
+	if err := wghttp.JSONStreamArray(rc, yieldAll(nil)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if body := string(rc.Finish().Body); body != "[]" {
+		t.Fatalf("expected [], got %s", body)
+	}
+}
+
+func TestJSONStreamArray_KeepsContentType(t *testing.T) {
+	rc := wghttp.NewResponseCapture()
+	rc.Header().Set("Content-Type", "application/vnd.warp+json")
+	wghttp.JSONStreamArray(rc, yieldAll([]any{1}))
+	if ct := rc.Header().Get("Content-Type"); ct != "application/vnd.warp+json" {
+		t.Fatalf("expected handler's Content-Type, got %q", ct)
+	}
+}
+
+func TestJSONStreamArray_FlushesInChunks(t *testing.T) {
+	defer func(old int) { wghttp.JSONStreamFlushBytes = old }(wghttp.JSONStreamFlushBytes)
+	wghttp.JSONStreamFlushBytes = 16
+
+	values := make([]any, 20)
+	for i := range values {
+		values[i] = map[string]int{"row": i}
+	}
+	want, _ := json.Marshal(values)
+
+	rec := &chunkRecorder{ResponseCapture: wghttp.NewResponseCapture()}
+	if err := wghttp.JSONStreamArray(rec, yieldAll(values)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	resp := rec.Finish()
+
+	if len(rec.chunks) < 2 {
+		t.Fatalf("expected multiple flushed chunks, got %d", len(rec.chunks))
+	}
+	joined := ""
+	for _, c := range rec.chunks {
+		joined += c
+	}
+	joined += rec.buf.String()
+	if joined != string(want) || string(resp.Body) != string(want) {
+		t.Fatalf("expected chunks to join to %s, got %s", want, joined)
+	}
+	if !resp.Streaming {
+		t.Fatal("expected a streaming response after flushes")
+	}
+}
+
+func TestJSONStreamArray_NoFlushWhenSmall(t *testing.T) {
+	rc := wghttp.NewResponseCapture()
+	wghttp.JSONStreamArray(rc, yieldAll([]any{1, 2, 3}))
+	if rc.Finish().Streaming {
+		t.Fatal("expected a small array to stay buffered")
+	}
+}
+
+func TestJSONStreamArray_EncodeErrorStops(t *testing.T) {
+	yielded := 0
+	items := func(yield func(any) bool) {
+		for _, v := range []any{1, make(chan int), 3} {
+			yielded++
+			if !yield(v) {
+				return
+			}
+		}
+	}
+
+	rc := wghttp.NewResponseCapture()
+	err := wghttp.JSONStreamArray(rc, items)
+	var typeErr *json.UnsupportedTypeError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("expected *json.UnsupportedTypeError, got %v", err)
+	}
+	if yielded != 2 {
+		t.Fatalf("expected items to stop after the failing element, yielded %d", yielded)
+	}
+	if body := string(rc.Finish().Body); body != "[1" {
+		t.Fatalf("expected unterminated array %q, got %q", "[1", body)
+	}
+}
@@ -0,0 +1,146 @@
+package wghttp
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	wgnet "github.com/anthropics/warpgrid/packages/warpgrid-go/net"
+)
+
+// ReverseProxy is an http.Handler that forwards each request to an
+// upstream and copies the upstream's response back to the client. It
+// is a simplified port of net/http/httputil.ReverseProxy for gateway
+// modules that front an internal service.
+//
+// Hop-by-hop headers (Connection, Keep-Alive, Transfer-Encoding, and
+// the others listed in RFC 9110 section 7.6.1, plus any named in the
+// Connection header) are removed in both directions, and the client's
+// address is appended to X-Forwarded-For.
+type ReverseProxy struct {
+	// Target is the upstream base URL. The request path is appended to
+	// Target.Path and the query strings are combined.
+	Target *url.URL
+
+	// Transport performs the upstream request. NewReverseProxy sets it
+	// to NewTransport over the given Dialer.
+	Transport http.RoundTripper
+
+	// ErrorHandler, if set, handles upstream errors. The default
+	// replies 502 Bad Gateway.
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// NewReverseProxy returns a ReverseProxy that forwards to target using
+// connections from d, so the upstream hostname is resolved through the
+// WarpGrid DNS shim.
+func NewReverseProxy(target *url.URL, d *wgnet.Dialer) *ReverseProxy {
+	return &ReverseProxy{Target: target, Transport: NewTransport(d)}
+}
+
+// hopHeaders are the hop-by-hop headers that apply to a single
+// connection and must not be forwarded by proxies.
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// ServeHTTP implements http.Handler.
+func (p *ReverseProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	out := r.Clone(r.Context())
+	if r.ContentLength == 0 {
+		out.Body = nil // the transport must not wait on an empty body
+	}
+	out.RequestURI = ""
+	out.Close = false
+	rewriteURL(out.URL, p.Target)
+	out.Host = p.Target.Host
+
+	removeHopHeaders(out.Header)
+	if clientIP, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		if prior := out.Header.Values("X-Forwarded-For"); len(prior) > 0 {
+			clientIP = strings.Join(prior, ", ") + ", " + clientIP
+		}
+		out.Header.Set("X-Forwarded-For", clientIP)
+	}
+	if out.Header.Get("X-Forwarded-Host") == "" && r.Host != "" {
+		out.Header.Set("X-Forwarded-Host", r.Host)
+	}
+
+	transport := p.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(out)
+	if err != nil {
+		p.handleError(w, r, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	removeHopHeaders(resp.Header)
+	for name, values := range resp.Header {
+		w.Header()[name] = append([]string(nil), values...)
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+func (p *ReverseProxy) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	if p.ErrorHandler != nil {
+		p.ErrorHandler(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusBadGateway)
+}
+
+// rewriteURL points u at target, joining paths and merging queries.
+func rewriteURL(u, target *url.URL) {
+	u.Scheme = target.Scheme
+	u.Host = target.Host
+	u.Path = singleJoiningSlash(target.Path, u.Path)
+	u.RawPath = ""
+	switch {
+	case target.RawQuery == "":
+	case u.RawQuery == "":
+		u.RawQuery = target.RawQuery
+	default:
+		u.RawQuery = target.RawQuery + "&" + u.RawQuery
+	}
+}
+
+func singleJoiningSlash(a, b string) string {
+	aslash := strings.HasSuffix(a, "/")
+	bslash := strings.HasPrefix(b, "/")
+	switch {
+	case aslash && bslash:
+		return a + b[1:]
+	case !aslash && !bslash:
+		return a + "/" + b
+	}
+	return a + b
+}
+
+// removeHopHeaders deletes hop-by-hop headers from h, including any
+// headers named in its Connection header.
+func removeHopHeaders(h http.Header) {
+	for _, v := range h.Values("Connection") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				h.Del(name)
+			}
+		}
+	}
+	for _, name := range hopHeaders {
+		h.Del(name)
+	}
+}
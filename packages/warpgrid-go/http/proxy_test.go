@@ -0,0 +1,137 @@
+package wghttp_test
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	wgdns "github.com/anthropics/warpgrid/packages/warpgrid-go/dns"
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/http"
+	wgnet "github.com/anthropics/warpgrid/packages/warpgrid-go/net"
+)
+
+// ── ReverseProxy test helpers ───────────────────────────────────────
+
+// newTestProxy returns a ReverseProxy forwarding to srv under the
+// hostname "upstream.warp.local" with the given base path.
+func newTestProxy(t *testing.T, srv *httptest.Server, basePath string) *wghttp.ReverseProxy {
+	t.Helper()
+	u, _ := url.Parse(srv.URL)
+	resolver := wgdns.NewResolver(mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		if hostname == "upstream.warp.local" {
+			return []net.IP{net.ParseIP(u.Hostname())}, nil
+		}
+		return nil, errors.New("HostNotFound: " + hostname)
+	}))
+	target := &url.URL{Scheme: "http", Host: "upstream.warp.local:" + u.Port(), Path: basePath}
+	return wghttp.NewReverseProxy(target, wgnet.NewDialer(resolver))
+}
+
+// ── ReverseProxy tests ──────────────────────────────────────────────
+
+func TestReverseProxy_ForwardsRequestAndBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Path", r.URL.Path)
+		w.Header().Set("X-Query", r.URL.RawQuery)
+		w.WriteHeader(http.StatusCreated)
+		w.Write(append([]byte("echo:"), body...))
+	}))
+	defer srv.Close()
+	proxy := newTestProxy(t, srv, "/api")
+
+	req := httptest.NewRequest("POST", "http://gateway.local/users?id=7", strings.NewReader("payload"))
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", rec.Code)
+	}
+	if rec.Body.String() != "echo:payload" {
+		t.Fatalf("expected body 'echo:payload', got '%s'", rec.Body.String())
+	}
+	if got := rec.Header().Get("X-Path"); got != "/api/users" {
+		t.Fatalf("expected upstream path '/api/users', got '%s'", got)
+	}
+	if got := rec.Header().Get("X-Query"); got != "id=7" {
+		t.Fatalf("expected upstream query 'id=7', got '%s'", got)
+	}
+}
+
+func TestReverseProxy_StripsHopByHopHeaders(t *testing.T) {
+	var upstream http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstream = r.Header.Clone()
+		w.Header().Set("Keep-Alive", "timeout=5")
+		w.Header().Set("X-Backend-End", "1")
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+	proxy := newTestProxy(t, srv, "")
+
+	req := httptest.NewRequest("GET", "http://gateway.local/", nil)
+	req.Header.Set("Connection", "X-Client-Hop")
+	req.Header.Set("X-Client-Hop", "1")
+	req.Header.Set("Keep-Alive", "timeout=5")
+	req.Header.Set("Proxy-Authorization", "Basic Zm9vOmJhcg==")
+	req.Header.Set("X-End-To-End", "1")
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, req)
+
+	for _, name := range []string{"X-Client-Hop", "Keep-Alive", "Proxy-Authorization"} {
+		if upstream.Get(name) != "" {
+			t.Fatalf("expected %s to be stripped before the upstream, got '%s'", name, upstream.Get(name))
+		}
+	}
+	if upstream.Get("X-End-To-End") != "1" {
+		t.Fatalf("expected end-to-end header to be forwarded, got %v", upstream)
+	}
+	for _, name := range []string{"Connection", "Keep-Alive"} {
+		if rec.Header().Get(name) != "" {
+			t.Fatalf("expected %s to be stripped from the response, got '%s'", name, rec.Header().Get(name))
+		}
+	}
+	if rec.Header().Get("X-Backend-End") != "1" {
+		t.Fatalf("expected end-to-end response header, got %v", rec.Header())
+	}
+}
+
+func TestReverseProxy_AppendsXForwardedFor(t *testing.T) {
+	var xff, xfh string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xff = r.Header.Get("X-Forwarded-For")
+		xfh = r.Header.Get("X-Forwarded-Host")
+	}))
+	defer srv.Close()
+	proxy := newTestProxy(t, srv, "")
+
+	req := httptest.NewRequest("GET", "http://gateway.local/", nil)
+	req.RemoteAddr = "192.0.2.10:4321"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	proxy.ServeHTTP(httptest.NewRecorder(), req)
+
+	if xff != "198.51.100.1, 192.0.2.10" {
+		t.Fatalf("expected X-Forwarded-For '198.51.100.1, 192.0.2.10', got '%s'", xff)
+	}
+	if xfh != "gateway.local" {
+		t.Fatalf("expected X-Forwarded-Host 'gateway.local', got '%s'", xfh)
+	}
+}
+
+func TestReverseProxy_UpstreamErrorReturns502(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	proxy := newTestProxy(t, srv, "")
+	srv.Close()
+
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, httptest.NewRequest("GET", "http://gateway.local/", nil))
+
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("expected status 502, got %d", rec.Code)
+	}
+}
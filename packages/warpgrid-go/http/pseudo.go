@@ -0,0 +1,79 @@
+package wghttp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidPseudoHeader is returned by ConvertRequest when a request
+// carries an HTTP/2 pseudo-header it cannot translate: an unknown or
+// repeated one, or one that contradicts the WIT request's own fields.
+var ErrInvalidPseudoHeader = errors.New("wghttp: invalid HTTP/2 pseudo-header")
+
+// pseudoHeaders holds the request pseudo-headers of RFC 9113 §8.3.1 a
+// host that terminates HTTP/2 may leave in the header list.
+type pseudoHeaders struct {
+	method, path, authority, scheme string
+}
+
+// splitPseudoHeaders separates the pseudo-headers (names starting with
+// ':') from the regular headers of wit, which is returned with them
+// removed and with Method and URI filled in from :method and :path when
+// empty. A pseudo-header that disagrees with a non-empty Method or URI
+// is rejected, since the two would describe different requests.
+func splitPseudoHeaders(wit WitRequest) (WitRequest, pseudoHeaders, error) {
+	var p pseudoHeaders
+	if !hasPseudoHeader(wit.Headers) {
+		return wit, p, nil
+	}
+
+	headers := make([]WitHeader, 0, len(wit.Headers))
+	for _, h := range wit.Headers {
+		if !strings.HasPrefix(h.Name, ":") {
+			headers = append(headers, h)
+			continue
+		}
+		var field *string
+		switch strings.ToLower(h.Name) {
+		case ":method":
+			field = &p.method
+		case ":path":
+			field = &p.path
+		case ":authority":
+			field = &p.authority
+		case ":scheme":
+			field = &p.scheme
+		default:
+			return wit, p, fmt.Errorf("%w: unknown %q", ErrInvalidPseudoHeader, h.Name)
+		}
+		if *field != "" {
+			return wit, p, fmt.Errorf("%w: repeated %q", ErrInvalidPseudoHeader, h.Name)
+		}
+		*field = h.Value
+	}
+	wit.Headers = headers
+
+	if p.method != "" {
+		if wit.Method != "" && !strings.EqualFold(wit.Method, p.method) {
+			return wit, p, fmt.Errorf("%w: :method %q contradicts method %q", ErrInvalidPseudoHeader, p.method, wit.Method)
+		}
+		wit.Method = p.method
+	}
+	if p.path != "" {
+		if wit.URI != "" && wit.URI != p.path {
+			return wit, p, fmt.Errorf("%w: :path %q contradicts URI %q", ErrInvalidPseudoHeader, p.path, wit.URI)
+		}
+		wit.URI = p.path
+	}
+	return wit, p, nil
+}
+
+func hasPseudoHeader(headers []WitHeader) bool {
+	for _, h := range headers {
+		if strings.HasPrefix(h.Name, ":") {
+			return true
+		}
+	}
+	return false
+}
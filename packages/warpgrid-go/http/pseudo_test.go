@@ -0,0 +1,95 @@
+package wghttp_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/http"
+)
+
+// ── HTTP/2 pseudo-header tests ──────────────────────────────────────
+
+func TestConvertRequest_PseudoHeadersTranslated(t *testing.T) {
+	req, err := wghttp.ConvertRequest(wghttp.WitRequest{
+		Headers: []wghttp.WitHeader{
+			{Name: ":method", Value: "POST"},
+			{Name: ":scheme", Value: "https"},
+			{Name: ":authority", Value: "api.warp.local"},
+			{Name: ":path", Value: "/items?id=7"},
+			{Name: "Content-Type", Value: "application/json"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Method != "POST" || req.RequestURI != "/items?id=7" || req.URL.Query().Get("id") != "7" {
+		t.Fatalf("expected POST /items?id=7, got %s %s", req.Method, req.RequestURI)
+	}
+	if req.Host != "api.warp.local" || req.URL.Scheme != "https" {
+		t.Fatalf("expected host api.warp.local over https, got '%s' '%s'", req.Host, req.URL.Scheme)
+	}
+	for name := range req.Header {
+		if name[0] == ':' {
+			t.Fatalf("expected pseudo-headers removed from Header, found %q", name)
+		}
+	}
+	if req.Header.Get("Content-Type") != "application/json" {
+		t.Fatal("expected regular headers kept")
+	}
+}
+
+func TestConvertRequest_PseudoHeadersAgreeingWithFields(t *testing.T) {
+	req, err := wghttp.ConvertRequest(wghttp.WitRequest{
+		Method: "GET",
+		URI:    "/",
+		Headers: []wghttp.WitHeader{
+			{Name: ":method", Value: "GET"},
+			{Name: ":path", Value: "/"},
+			{Name: "Host", Value: "old.warp.local"},
+			{Name: ":authority", Value: "new.warp.local"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Host != "new.warp.local" {
+		t.Fatalf("expected :authority to win over Host, got '%s'", req.Host)
+	}
+}
+
+func TestConvertRequest_InvalidPseudoHeadersRejected(t *testing.T) {
+	cases := map[string]wghttp.WitRequest{
+		"unknown": {Method: "GET", URI: "/", Headers: []wghttp.WitHeader{{Name: ":status", Value: "200"}}},
+		"repeated": {Method: "GET", URI: "/", Headers: []wghttp.WitHeader{
+			{Name: ":authority", Value: "a"}, {Name: ":authority", Value: "b"},
+		}},
+		"contradicting": {Method: "GET", URI: "/", Headers: []wghttp.WitHeader{{Name: ":path", Value: "/admin"}}},
+	}
+	for name, wit := range cases {
+		if _, err := wghttp.ConvertRequest(wit); !errors.Is(err, wghttp.ErrInvalidPseudoHeader) {
+			t.Fatalf("%s: expected ErrInvalidPseudoHeader, got %v", name, err)
+		}
+	}
+
+	defer wghttp.ResetHandler()
+	wghttp.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler must not run for an invalid pseudo-header")
+	}))
+	if resp := wghttp.HandleWitRequest(cases["unknown"]); resp.Status != 400 {
+		t.Fatalf("expected status 400, got %d", resp.Status)
+	}
+}
+
+func TestWitRequestFromContext_KeepsPseudoHeadersAsSent(t *testing.T) {
+	req, err := wghttp.ConvertRequest(wghttp.WitRequest{
+		Headers: []wghttp.WitHeader{{Name: ":method", Value: "GET"}, {Name: ":path", Value: "/"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wit, _ := wghttp.WitRequestFromContext(req.Context())
+	if len(wit.Headers) != 2 || wit.Method != "" {
+		t.Fatalf("expected the WIT request as sent, got %+v", wit)
+	}
+}
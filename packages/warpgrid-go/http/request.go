@@ -14,11 +14,24 @@ package wghttp
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 )
 
+// ErrInvalidMethod is returned by ConvertRequest when the request
+// method is not a valid HTTP token.
+var ErrInvalidMethod = errors.New("wghttp: invalid request method")
+
+// ErrContentLengthMismatch is returned by ConvertRequest when the
+// request's Content-Length header disagrees with the actual body size.
+var ErrContentLengthMismatch = errors.New("wghttp: Content-Length does not match body length")
+
 // WitHeader mirrors the WIT record warpgrid:shim/http-types.http-header.
 type WitHeader struct {
 	Name  string
@@ -26,33 +39,120 @@ type WitHeader struct {
 }
 
 // WitRequest mirrors the WIT record warpgrid:shim/http-types.http-request.
+//
+// Proto is the protocol version the client spoke, such as "HTTP/1.0".
+// When empty, "HTTP/1.1" is assumed.
+//
+// Trailers holds the trailer fields the client sent after a chunked
+// body. ConvertRequest exposes them as the request's Trailer.
+//
+// TLS describes the connection when the client used TLS, including the
+// client certificate for mTLS; nil means plain HTTP.
 type WitRequest struct {
-	Method  string
-	URI     string
-	Headers []WitHeader
-	Body    []byte
+	Method   string
+	URI      string
+	Headers  []WitHeader
+	Body     []byte
+	Proto    string
+	Trailers []WitHeader
+	TLS      *WitTLS
 }
 
 // WitResponse mirrors the WIT record warpgrid:shim/http-types.http-response.
+//
+// Streaming reports that the handler flushed before completing, so the
+// final size was not known up front: the response carries no
+// Content-Length and the host should use chunked transfer encoding,
+// treating the end of Body as end-of-stream.
+//
+// Aborted reports that a streaming handler panicked after flushing. The
+// status line and part of Body may already have reached the client, so
+// the response cannot be replaced with a 500: the host must send what
+// Body holds and then reset the connection (or send its protocol's
+// error frame) instead of ending the stream cleanly, so the client sees
+// a failed transfer rather than a truncated success. A handler can ask
+// for the same with ResetConn. Aborted on a response that is not
+// streaming means nothing has been sent: the host resets the connection
+// without sending the response at all.
 type WitResponse struct {
-	Status  uint16
-	Headers []WitHeader
-	Body    []byte
+	Status    uint16
+	Headers   []WitHeader
+	Body      []byte
+	Streaming bool
+	Aborted   bool
 }
 
 // ConvertRequest converts a WIT http-request to a Go *http.Request.
 //
 // The returned request has:
-//   - Method, URL, and RequestURI set from the WIT fields
-//   - Headers populated from the WIT header list
+//   - Method, URL, and RequestURI set from the WIT fields, with the
+//     method canonicalized by canonicalMethod; a method that is not a
+//     valid token fails with ErrInvalidMethod, answered with 400
+//   - Headers populated from the WIT header list, except HTTP/2
+//     pseudo-headers left in it by a host terminating HTTP/2: :method
+//     and :path fill in an empty Method or URI, :authority sets Host,
+//     and :scheme sets URL.Scheme for a path-only URI, and none of them
+//     reach Header. An unknown or repeated pseudo-header, or one that
+//     contradicts Method or URI, fails with ErrInvalidPseudoHeader,
+//     answered with 400
 //   - Body backed by a bytes.Reader (supports io.Reader streaming)
 //   - Host set from the "Host" header or the URI authority
-//   - Proto set to "HTTP/1.1" (the WIT layer is protocol-agnostic)
+//   - Proto, ProtoMajor, and ProtoMinor set from the WIT Proto field
+//     ("HTTP/1.1" when empty)
+//   - Close set when the client asked not to keep the connection alive:
+//     "Connection: close", or HTTP/1.0 without "Connection: keep-alive"
+//   - The original body bytes retained for RawBody, and wit itself for
+//     WitRequestFromContext, which hands out copies
+//   - A context deadline when the host sent DeadlineHeader with a
+//     positive integer number of milliseconds; other values are ignored.
+//     Call ReleaseRequest when done with the request to stop its timer
+//   - ContentLength and the Content-Length header both equal to the
+//     body size: a missing header is filled in for non-empty bodies, and
+//     a header that disagrees with the body (or repeats with different
+//     values) fails with ErrContentLengthMismatch, which HandleWitRequest
+//     answers with 400
+//   - A body sent with a Content-Encoding decoded by the decoder
+//     registered for it (gzip and deflate are built in, see
+//     RegisterDecoder), with Content-Encoding removed and ContentLength
+//     and Content-Length describing the decoded body; RawBody still
+//     returns the bytes as sent. An encoding with no registered decoder
+//     fails with ErrUnsupportedContentEncoding, which HandleWitRequest
+//     answers with 415
+//   - Trailer holding the name of each of wit.Trailers with a nil value,
+//     as net/http declares trailers; the values are filled in once Body
+//     has been read to EOF, so handlers must read the body before
+//     consulting them
+//   - TLS summarizing wit.TLS when the host sent it, with the client
+//     certificate's subject and SANs for mTLS authorization; see
+//     WitTLS for what it can and cannot hold
 func ConvertRequest(wit WitRequest) (*http.Request, error) {
+	orig := wit
+	wit, pseudo, err := splitPseudoHeaders(wit)
+	if err != nil {
+		return nil, err
+	}
+
+	method, err := canonicalMethod(wit.Method)
+	if err != nil {
+		return nil, err
+	}
+
 	parsedURL, err := url.ParseRequestURI(wit.URI)
 	if err != nil {
 		return nil, err
 	}
+	if pseudo.scheme != "" && parsedURL.Scheme == "" {
+		parsedURL.Scheme = strings.ToLower(pseudo.scheme)
+	}
+
+	proto := wit.Proto
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+	major, minor, ok := http.ParseHTTPVersion(proto)
+	if !ok {
+		return nil, fmt.Errorf("wghttp: malformed HTTP version %q", proto)
+	}
 
 	body := wit.Body
 	if body == nil {
@@ -60,12 +160,12 @@ func ConvertRequest(wit WitRequest) (*http.Request, error) {
 	}
 
 	req := &http.Request{
-		Method:        wit.Method,
+		Method:        method,
 		URL:           parsedURL,
 		RequestURI:    wit.URI,
-		Proto:         "HTTP/1.1",
-		ProtoMajor:    1,
-		ProtoMinor:    1,
+		Proto:         proto,
+		ProtoMajor:    major,
+		ProtoMinor:    minor,
 		Header:        make(http.Header),
 		Body:          io.NopCloser(bytes.NewReader(body)),
 		ContentLength: int64(len(body)),
@@ -76,10 +176,201 @@ func ConvertRequest(wit WitRequest) (*http.Request, error) {
 		req.Header.Add(h.Name, h.Value)
 	}
 
-	// Host header overrides the URI authority
+	// Host header overrides the URI authority, and :authority both
 	if host := req.Header.Get("Host"); host != "" {
 		req.Host = host
 	}
+	if pseudo.authority != "" {
+		req.Host = pseudo.authority
+	}
+
+	if err := reconcileContentLength(req.Header, len(body)); err != nil {
+		return nil, err
+	}
+
+	if decoded, ok, err := decodeContentEncoding(req.Header, body); err != nil {
+		return nil, err
+	} else if ok {
+		req.Body = io.NopCloser(bytes.NewReader(decoded))
+		req.ContentLength = int64(len(decoded))
+		req.Header.Del("Content-Encoding")
+		req.Header.Set("Content-Length", strconv.Itoa(len(decoded)))
+	}
+
+	if len(wit.Trailers) > 0 {
+		req.Trailer = make(http.Header, len(wit.Trailers))
+		for _, t := range wit.Trailers {
+			req.Trailer[http.CanonicalHeaderKey(t.Name)] = nil
+		}
+		req.Body = &trailerBody{ReadCloser: req.Body, trailer: req.Trailer, trailers: wit.Trailers}
+	}
+
+	if wit.TLS != nil {
+		req.TLS = connectionState(wit.TLS)
+	}
+
+	req.Close = shouldClose(req.ProtoMajor, req.ProtoMinor, req.Header)
+
+	ctx := context.WithValue(req.Context(), rawBodyKey{}, body)
+	// Stored as is; WitRequestFromContext copies it on the way out, so
+	// requests that never ask for it pay no copy.
+	ctx = context.WithValue(ctx, witRequestKey{}, orig)
+	ctx = withDeadlineHeader(ctx, req.Header)
+	return req.WithContext(ctx), nil
+}
+
+// trailerBody is a request body that fills in the request's trailer
+// values once it has been read to EOF, the point at which net/http
+// makes a chunked body's trailers available.
+type trailerBody struct {
+	io.ReadCloser
+	trailer  http.Header
+	trailers []WitHeader
+	done     bool
+}
+
+func (b *trailerBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF && !b.done {
+		b.done = true
+		for _, t := range b.trailers {
+			b.trailer.Add(t.Name, t.Value)
+		}
+	}
+	return n, err
+}
+
+// witRequestKey is the context key under which ConvertRequest stores
+// the WIT request.
+type witRequestKey struct{}
+
+// WitRequestFromContext returns the WIT request a handler's request was
+// converted from, as stored by ConvertRequest. It gives access to
+// host-specific fields that have no *http.Request counterpart. The
+// result is a fresh copy: its header list and body may be modified
+// without affecting the request or later calls.
+func WitRequestFromContext(ctx context.Context) (WitRequest, bool) {
+	wit, ok := ctx.Value(witRequestKey{}).(WitRequest)
+	if !ok {
+		return WitRequest{}, false
+	}
+	return cloneWitRequest(wit), true
+}
+
+// cloneWitRequest returns a copy of wit that shares no slices with it.
+func cloneWitRequest(wit WitRequest) WitRequest {
+	wit.Headers = append([]WitHeader(nil), wit.Headers...)
+	if wit.Trailers != nil {
+		wit.Trailers = append([]WitHeader(nil), wit.Trailers...)
+	}
+	wit.TLS = cloneWitTLS(wit.TLS)
+	if wit.Body != nil {
+		wit.Body = append([]byte{}, wit.Body...)
+	}
+	return wit
+}
+
+// standardMethods are the methods canonicalMethod upper-cases.
+var standardMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodConnect,
+	http.MethodOptions, http.MethodTrace,
+}
+
+// canonicalMethod validates method as an HTTP token (RFC 9110 §9.1) and
+// upper-cases the standard methods, so "get" reaches handlers as "GET".
+// Extension methods such as "PURGE" are kept as sent, since methods are
+// case-sensitive. An empty method means GET, as in net/http.
+func canonicalMethod(method string) (string, error) {
+	if method == "" {
+		return http.MethodGet, nil
+	}
+	for i := 0; i < len(method); i++ {
+		if !isTokenChar(method[i]) {
+			return "", fmt.Errorf("%w %q", ErrInvalidMethod, method)
+		}
+	}
+	for _, m := range standardMethods {
+		if strings.EqualFold(method, m) {
+			return m, nil
+		}
+	}
+	return method, nil
+}
 
-	return req, nil
+// isTokenChar reports whether c may appear in an HTTP token.
+func isTokenChar(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0
+}
+
+// shouldClose reports whether the client expects the connection to be
+// closed after this request, following the HTTP/1.x keep-alive
+// defaults: HTTP/1.1 persists unless "Connection: close" is sent, and
+// HTTP/1.0 closes unless "Connection: keep-alive" is sent.
+func shouldClose(major, minor int, h http.Header) bool {
+	if major < 1 {
+		return true
+	}
+	if hasConnectionToken(h, "close") {
+		return true
+	}
+	if major == 1 && minor == 0 {
+		return !hasConnectionToken(h, "keep-alive")
+	}
+	return false
+}
+
+// hasConnectionToken reports whether the Connection header lists token.
+func hasConnectionToken(h http.Header, token string) bool {
+	for _, v := range h.Values("Connection") {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// reconcileContentLength checks any Content-Length header against the
+// body size n, and sets the header when it is absent and n > 0.
+func reconcileContentLength(h http.Header, n int) error {
+	values := h.Values("Content-Length")
+	if len(values) == 0 {
+		if n > 0 {
+			h.Set("Content-Length", strconv.Itoa(n))
+		}
+		return nil
+	}
+	for _, v := range values {
+		declared, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil || declared != int64(n) {
+			return fmt.Errorf("%w: header %q, body %d bytes", ErrContentLengthMismatch, v, n)
+		}
+	}
+	h.Set("Content-Length", strconv.Itoa(n))
+	return nil
+}
+
+// rawBodyKey is the context key under which ConvertRequest stores the
+// original WIT request body.
+type rawBodyKey struct{}
+
+// RawBody returns the request body exactly as it arrived in the WIT
+// request, regardless of how much of r.Body has been read or whether
+// middleware replaced r.Body. It is intended for webhook signature
+// verification (HMAC over the raw payload).
+//
+// The returned slice is shared with the request and must not be
+// modified. The second result is false when r was not produced by
+// ConvertRequest. This only works in buffered mode, where the WIT
+// request carries the complete body; a streamed body has no retained
+// copy.
+func RawBody(r *http.Request) ([]byte, bool) {
+	body, ok := r.Context().Value(rawBodyKey{}).([]byte)
+	return body, ok
 }
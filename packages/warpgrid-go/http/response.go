@@ -2,9 +2,56 @@ package wghttp
 
 import (
 	"bytes"
+	"errors"
+	"io"
 	"net/http"
+	"sort"
+	"strconv"
 )
 
+// ErrConnReset is returned by writes to a response after ResetConn.
+var ErrConnReset = errors.New("wghttp: connection reset by handler")
+
+// ErrResponseTooLarge is returned by writes to a ResponseCapture that
+// would take the body past MaxResponseBodySize.
+var ErrResponseTooLarge = errors.New("wghttp: response body too large")
+
+// MaxResponseBodySize caps the size, in bytes, of a captured response
+// body, so a runaway handler cannot buffer enough to exhaust the
+// instance's memory. Once a write would exceed it, only what fits is
+// kept and the write fails with ErrResponseTooLarge; Finish then
+// replaces the response with a 500, or, with TruncateOversizedResponses,
+// sends the truncated body. Zero or negative means no limit. It is read
+// when a ResponseCapture is created.
+//
+// It is the bridge's counterpart of MaxResponseBytes in the net/http
+// overlay, which limits the overlay's buffered response writer the same
+// way; the two packages serve requests independently, so an application
+// using both sets each.
+var MaxResponseBodySize int64
+
+// TruncateOversizedResponses makes Finish send the first
+// MaxResponseBodySize bytes of an oversized response instead of a 500.
+// The response is marked Aborted, so the host resets the connection
+// after the body and the client sees a failed transfer rather than a
+// complete one. Flushed responses are always handled this way, since
+// their status is already committed.
+var TruncateOversizedResponses bool
+
+// DisableContentSniffing stops Finish from filling in a missing
+// Content-Type. By default, as net/http's server does, a response with
+// a body but no Content-Type header gets one from
+// http.DetectContentType, unless it has a Content-Encoding. A handler
+// can opt a single response out by setting the header to nil:
+// w.Header()["Content-Type"] = nil.
+var DisableContentSniffing bool
+
+// DeterministicHeaders makes Finish emit headers sorted by name,
+// keeping the order of each header's values, instead of in Go map
+// order, so tests and caches see stable output. It is off by default to
+// save the sort on every response.
+var DeterministicHeaders bool
+
 // ResponseCapture implements http.ResponseWriter by capturing all writes
 // into an in-memory buffer. After the handler returns, call Finish() to
 // extract a WitResponse.
@@ -13,19 +60,25 @@ import (
 //   - Default status is 200 (sent implicitly on first Write)
 //   - WriteHeader can only be called once; subsequent calls are ignored
 //   - Write triggers an implicit WriteHeader(200) if not already called
+//   - Flush (http.Flusher) switches the response to streaming mode
 type ResponseCapture struct {
 	status      int
 	headers     http.Header
 	body        bytes.Buffer
 	headersSent bool
+	flushed     bool
+	reset       bool
+	maxBytes    int64
+	overflow    bool
 }
 
 // NewResponseCapture creates a ResponseCapture with default 200 status
 // and empty headers.
 func NewResponseCapture() *ResponseCapture {
 	return &ResponseCapture{
-		status:  200,
-		headers: make(http.Header),
+		status:   200,
+		headers:  make(http.Header),
+		maxBytes: MaxResponseBodySize,
 	}
 }
 
@@ -37,16 +90,70 @@ func (rc *ResponseCapture) Header() http.Header {
 
 // Write writes the data to the response body buffer. If WriteHeader has
 // not been called, an implicit WriteHeader(200) is triggered.
+//
+// As in net/http, writes after a status that forbids a body (1xx, 204,
+// 304) are dropped with http.ErrBodyNotAllowed.
 func (rc *ResponseCapture) Write(data []byte) (int, error) {
+	if rc.reset {
+		return 0, ErrConnReset
+	}
 	if !rc.headersSent {
 		rc.headersSent = true
 	}
+	if !bodyAllowed(rc.status) {
+		return 0, http.ErrBodyNotAllowed
+	}
+	if rc.maxBytes > 0 && int64(rc.body.Len())+int64(len(data)) > rc.maxBytes {
+		n, _ := rc.body.Write(data[:rc.maxBytes-int64(rc.body.Len())])
+		rc.overflow = true
+		return n, ErrResponseTooLarge
+	}
 	return rc.body.Write(data)
 }
 
+// ReadFrom implements io.ReaderFrom, reading src directly into the body
+// buffer as net/http's response writer does. When src reports its
+// remaining length (as *bytes.Reader and *strings.Reader do), the
+// buffer grows once to fit. Like Write, it triggers an implicit
+// WriteHeader(200) and is bounded by MaxResponseBodySize.
+func (rc *ResponseCapture) ReadFrom(src io.Reader) (int64, error) {
+	if rc.reset {
+		return 0, ErrConnReset
+	}
+	if !rc.headersSent {
+		rc.headersSent = true
+	}
+	if !bodyAllowed(rc.status) {
+		return 0, http.ErrBodyNotAllowed
+	}
+	remaining := rc.maxBytes - int64(rc.body.Len())
+	if l, ok := src.(interface{ Len() int }); ok {
+		size := int64(l.Len())
+		if rc.maxBytes > 0 && size > remaining {
+			size = remaining
+		}
+		// bytes.Buffer.ReadFrom keeps MinRead bytes free before each
+		// Read, so reserve that too to avoid a second growth at EOF.
+		rc.body.Grow(int(size) + bytes.MinRead)
+	}
+	if rc.maxBytes <= 0 {
+		return rc.body.ReadFrom(src)
+	}
+	// Read one byte past the limit to tell a body that fits exactly
+	// from one that overflows.
+	n, err := rc.body.ReadFrom(io.LimitReader(src, remaining+1))
+	if n > remaining {
+		rc.body.Truncate(int(rc.maxBytes))
+		rc.overflow = true
+		return remaining, ErrResponseTooLarge
+	}
+	return n, err
+}
+
 // WriteHeader sends an HTTP response header with the provided status code.
 // Only the first call takes effect; subsequent calls are no-ops matching
-// net/http behavior.
+// net/http behavior. An invalid code is recorded as given and replaced
+// by Finish; see there.
 func (rc *ResponseCapture) WriteHeader(statusCode int) {
 	if rc.headersSent {
 		return
@@ -55,19 +162,156 @@ func (rc *ResponseCapture) WriteHeader(statusCode int) {
 	rc.headersSent = true
 }
 
+// Flush implements http.Flusher. It commits the headers (an implicit
+// WriteHeader(200) if needed) and marks the response as streaming: a
+// handler that flushes does not know its final size, so Finish omits
+// Content-Length and sets WitResponse.Streaming.
+func (rc *ResponseCapture) Flush() {
+	if !rc.headersSent {
+		rc.headersSent = true
+	}
+	rc.flushed = true
+}
+
 // Finish extracts the captured response as a WitResponse. This should be
 // called after the handler has returned.
+//
+// The status is always a valid HTTP status code: zero (for example
+// from WriteHeader(0)) becomes 200, and any other code outside 100-599,
+// the classes HTTP defines, becomes 500. Unlike net/http, which panics
+// in WriteHeader on codes outside 100-999, an invalid code is coerced
+// rather than rejected: it is a handler bug, and a 500 reports it to
+// the client without losing the headers the handler set.
+//
+// A response that was never flushed gets a Content-Length matching the
+// captured body unless the handler set one or the status forbids a body
+// (1xx, 204, 304). A flushed response is returned with Streaming set
+// and any Content-Length removed.
+//
+// Headers come out in Go map order unless DeterministicHeaders is set.
+// A body without a Content-Type gets a sniffed one; see
+// DisableContentSniffing.
+//
+// After ResetConn the response is returned with Aborted set. If it was
+// flushed, it keeps what was written, as for a mid-stream panic;
+// otherwise nothing has reached the client, so the headers and body are
+// dropped and the status is 500, which a host unable to reset the
+// connection sends instead.
+//
+// A body that outgrew MaxResponseBodySize is replaced by a 500 unless
+// it was flushed or TruncateOversizedResponses is set, in which case
+// the truncated body is returned with Aborted set.
 func (rc *ResponseCapture) Finish() WitResponse {
+	if rc.reset && !rc.flushed {
+		return WitResponse{Status: http.StatusInternalServerError, Aborted: true}
+	}
+	if rc.overflow && !rc.flushed && !TruncateOversizedResponses {
+		return WitResponse{
+			Status:  http.StatusInternalServerError,
+			Headers: []WitHeader{{Name: "Content-Type", Value: "text/plain"}},
+			Body:    []byte("response too large"),
+		}
+	}
+
+	status := normalizeStatus(rc.status)
+	rc.sniffContentType(status)
+
 	var witHeaders []WitHeader
 	for name, values := range rc.headers {
+		if rc.flushed && name == "Content-Length" {
+			continue
+		}
 		for _, v := range values {
 			witHeaders = append(witHeaders, WitHeader{Name: name, Value: v})
 		}
 	}
 
+	if !rc.flushed && rc.headers.Get("Content-Length") == "" && bodyAllowed(status) {
+		witHeaders = append(witHeaders, WitHeader{
+			Name:  "Content-Length",
+			Value: strconv.Itoa(rc.body.Len()),
+		})
+	}
+	if DeterministicHeaders {
+		sort.SliceStable(witHeaders, func(i, j int) bool { return witHeaders[i].Name < witHeaders[j].Name })
+	}
+
 	return WitResponse{
-		Status:  uint16(rc.status),
-		Headers: witHeaders,
-		Body:    rc.body.Bytes(),
+		Status:    uint16(status),
+		Headers:   witHeaders,
+		Body:      rc.body.Bytes(),
+		Streaming: rc.flushed,
+		Aborted:   rc.reset || rc.overflow,
+	}
+}
+
+// sniffContentType sets Content-Type from the captured body when the
+// handler wrote one but set neither Content-Type nor Content-Encoding,
+// unless DisableContentSniffing is set.
+func (rc *ResponseCapture) sniffContentType(status int) {
+	if DisableContentSniffing || rc.body.Len() == 0 || !bodyAllowed(status) {
+		return
+	}
+	if _, ok := rc.headers["Content-Type"]; ok || rc.headers.Get("Content-Encoding") != "" {
+		return
+	}
+	rc.headers.Set("Content-Type", http.DetectContentType(rc.body.Bytes()))
+}
+
+// ResetConn tells the host to abort the connection (a TCP reset, or its
+// protocol's stream error) instead of completing the response, for a
+// handler that hits an unrecoverable error and must not let a partial
+// response pass for a successful one. Later writes fail with
+// ErrConnReset. See Finish for what the host receives.
+//
+// w must be the ResponseCapture passed to the handler, or wrap it and
+// return it from an Unwrap() http.ResponseWriter method, as with
+// http.ResponseController; otherwise ResetConn does nothing.
+func ResetConn(w http.ResponseWriter) {
+	for {
+		switch t := w.(type) {
+		case *ResponseCapture:
+			t.reset = true
+			return
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = t.Unwrap()
+		default:
+			return
+		}
+	}
+}
+
+// CloseConnection marks the response as the last on its connection by
+// setting "Connection: close", telling the host not to keep the stream
+// open after it. Like any header, it must be set before the response
+// is written to take effect on a real connection; with ResponseCapture
+// it may be set at any point before Finish.
+func CloseConnection(w http.ResponseWriter) {
+	if !hasConnectionToken(w.Header(), "close") {
+		w.Header().Set("Connection", "close")
+	}
+}
+
+// normalizeStatus maps a handler's status code to one the host
+// accepts: zero becomes 200 and codes outside 100-599 become 500.
+func normalizeStatus(code int) int {
+	switch {
+	case code == 0:
+		return http.StatusOK
+	case code < 100 || code > 599:
+		return http.StatusInternalServerError
+	}
+	return code
+}
+
+// bodyAllowed reports whether a response with the given status may
+// carry a body (and therefore a Content-Length).
+func bodyAllowed(status int) bool {
+	switch {
+	case status >= 100 && status <= 199:
+		return false
+	case status == http.StatusNoContent, status == http.StatusNotModified:
+		return false
 	}
+	return true
 }
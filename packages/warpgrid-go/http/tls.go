@@ -0,0 +1,97 @@
+package wghttp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/url"
+)
+
+// WitTLS carries what the host knows about the TLS connection a request
+// arrived on. TLS is terminated by the host, so the guest never sees
+// the handshake: it gets this summary instead.
+//
+// ConvertRequest turns it into r.TLS, which is a summary too, not real
+// handshake state: each peer certificate carries only the subject
+// common name and the SANs the host reported, with no raw DER, public
+// key, or signature, so it cannot be re-verified or used with x509
+// functions that need them, and the full chain may not be available.
+// Handlers should authorize by r.TLS.PeerCertificates[0].Subject and
+// SANs, trusting them only when r.TLS.VerifiedChains is non-empty.
+type WitTLS struct {
+	// Version is the negotiated TLS version, such as tls.VersionTLS13.
+	Version uint16
+
+	// ServerName is the SNI name the client asked for.
+	ServerName string
+
+	// PeerCertificates describes the client's certificate chain, leaf
+	// first, when the client presented one (mTLS). Hosts may send only
+	// the leaf.
+	PeerCertificates []WitCertificate
+
+	// Verified reports that the host verified the client's chain
+	// against its trusted client CAs.
+	Verified bool
+}
+
+// WitCertificate is the identity-bearing part of a peer certificate as
+// reported by the host.
+type WitCertificate struct {
+	SubjectCN      string
+	DNSNames       []string
+	IPAddresses    []string
+	URIs           []string
+	EmailAddresses []string
+}
+
+// connectionState builds the *tls.ConnectionState ConvertRequest sets as
+// r.TLS. A verified chain becomes the single entry of VerifiedChains.
+// Malformed IP and URI SANs are skipped.
+func connectionState(t *WitTLS) *tls.ConnectionState {
+	state := &tls.ConnectionState{
+		Version:           t.Version,
+		HandshakeComplete: true,
+		ServerName:        t.ServerName,
+	}
+	for _, c := range t.PeerCertificates {
+		cert := &x509.Certificate{
+			Subject:        pkix.Name{CommonName: c.SubjectCN},
+			DNSNames:       append([]string(nil), c.DNSNames...),
+			EmailAddresses: append([]string(nil), c.EmailAddresses...),
+		}
+		for _, s := range c.IPAddresses {
+			if ip := net.ParseIP(s); ip != nil {
+				cert.IPAddresses = append(cert.IPAddresses, ip)
+			}
+		}
+		for _, s := range c.URIs {
+			if u, err := url.Parse(s); err == nil {
+				cert.URIs = append(cert.URIs, u)
+			}
+		}
+		state.PeerCertificates = append(state.PeerCertificates, cert)
+	}
+	if t.Verified && len(state.PeerCertificates) > 0 {
+		state.VerifiedChains = [][]*x509.Certificate{state.PeerCertificates}
+	}
+	return state
+}
+
+// cloneWitTLS returns a copy of t that shares no slices with it.
+func cloneWitTLS(t *WitTLS) *WitTLS {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	c.PeerCertificates = make([]WitCertificate, len(t.PeerCertificates))
+	for i, cert := range t.PeerCertificates {
+		cert.DNSNames = append([]string(nil), cert.DNSNames...)
+		cert.IPAddresses = append([]string(nil), cert.IPAddresses...)
+		cert.URIs = append([]string(nil), cert.URIs...)
+		cert.EmailAddresses = append([]string(nil), cert.EmailAddresses...)
+		c.PeerCertificates[i] = cert
+	}
+	return &c
+}
@@ -0,0 +1,85 @@
+package wghttp_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"testing"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/http"
+)
+
+// ── Peer TLS tests ──────────────────────────────────────────────────
+
+func mtlsRequest(verified bool) wghttp.WitRequest {
+	return wghttp.WitRequest{
+		Method: "GET",
+		URI:    "/secure",
+		TLS: &wghttp.WitTLS{
+			Version:    tls.VersionTLS13,
+			ServerName: "api.warp.local",
+			PeerCertificates: []wghttp.WitCertificate{{
+				SubjectCN:   "billing-service",
+				DNSNames:    []string{"billing.warp.local"},
+				IPAddresses: []string{"10.0.0.7", "not-an-ip"},
+				URIs:        []string{"spiffe://warp.local/billing"},
+			}},
+			Verified: verified,
+		},
+	}
+}
+
+func TestHandleWitRequest_HandlerReadsClientCN(t *testing.T) {
+	var cn string
+	var verified bool
+	defer wghttp.ResetHandler()
+	wghttp.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			t.Fatal("expected r.TLS with a peer certificate")
+		}
+		cn = r.TLS.PeerCertificates[0].Subject.CommonName
+		verified = len(r.TLS.VerifiedChains) > 0
+	}))
+
+	resp := wghttp.HandleWitRequest(mtlsRequest(true))
+
+	if resp.Status != 200 {
+		t.Fatalf("expected status 200, got %d", resp.Status)
+	}
+	if cn != "billing-service" || !verified {
+		t.Fatalf("expected verified CN 'billing-service', got '%s' (verified=%v)", cn, verified)
+	}
+}
+
+func TestConvertRequest_TLSSummary(t *testing.T) {
+	req, err := wghttp.ConvertRequest(mtlsRequest(false))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	state := req.TLS
+	if state.Version != tls.VersionTLS13 || state.ServerName != "api.warp.local" || !state.HandshakeComplete {
+		t.Fatalf("expected TLS 1.3 to api.warp.local, got %x '%s'", state.Version, state.ServerName)
+	}
+	if len(state.VerifiedChains) != 0 {
+		t.Fatal("expected no verified chains for an unverified certificate")
+	}
+	cert := state.PeerCertificates[0]
+	if len(cert.DNSNames) != 1 || cert.DNSNames[0] != "billing.warp.local" {
+		t.Fatalf("expected DNS SAN billing.warp.local, got %v", cert.DNSNames)
+	}
+	if len(cert.IPAddresses) != 1 || cert.IPAddresses[0].String() != "10.0.0.7" {
+		t.Fatalf("expected only the valid IP SAN, got %v", cert.IPAddresses)
+	}
+	if len(cert.URIs) != 1 || cert.URIs[0].String() != "spiffe://warp.local/billing" {
+		t.Fatalf("expected the SPIFFE URI SAN, got %v", cert.URIs)
+	}
+}
+
+func TestConvertRequest_PlainHTTPHasNoTLS(t *testing.T) {
+	req, err := wghttp.ConvertRequest(wghttp.WitRequest{Method: "GET", URI: "/"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.TLS != nil {
+		t.Fatalf("expected nil TLS for plain HTTP, got %+v", req.TLS)
+	}
+}
@@ -0,0 +1,117 @@
+package wghttp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+
+	wgnet "github.com/anthropics/warpgrid/packages/warpgrid-go/net"
+)
+
+// NewTransport returns an http.RoundTripper that sends outbound requests
+// over connections from d, so that
+//
+//	client := &http.Client{Transport: wghttp.NewTransport(d)}
+//
+// resolves hostnames through the WarpGrid DNS shim and benefits from
+// the Dialer's failover, balancing, and stats.
+//
+// This is the interim transport until outbound calls are marshaled to
+// the wasi:http outgoing-handler ABI: each request dials a fresh
+// connection and speaks HTTP/1.1 over it with "Connection: close", and
+// only the "http" scheme is supported. As with http.Transport, redirects
+// are left to http.Client.
+func NewTransport(d *wgnet.Dialer) http.RoundTripper {
+	return &transport{dialer: d}
+}
+
+// transport implements http.RoundTripper on top of a wgnet.Dialer.
+type transport struct {
+	dialer *wgnet.Dialer
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL == nil {
+		closeRequestBody(req)
+		return nil, errors.New("wghttp: nil Request.URL")
+	}
+	if req.URL.Scheme != "http" {
+		closeRequestBody(req)
+		return nil, fmt.Errorf("wghttp: unsupported protocol scheme %q", req.URL.Scheme)
+	}
+	if req.URL.Host == "" {
+		closeRequestBody(req)
+		return nil, errors.New("wghttp: no Host in request URL")
+	}
+
+	ctx := req.Context()
+	addr := req.URL.Host
+	if req.URL.Port() == "" {
+		addr = net.JoinHostPort(req.URL.Hostname(), "80")
+	}
+	conn, err := t.dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		closeRequestBody(req)
+		return nil, err
+	}
+
+	// Abort the exchange if the request context ends before the body
+	// has been consumed; stop() detaches this once the body is closed.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+
+	// RoundTrip must not modify req, so send a shallow copy that asks
+	// the server to close the connection after this response.
+	out := *req
+	out.Close = true
+	if err := out.Write(conn); err != nil {
+		stop()
+		conn.Close()
+		return nil, contextErr(ctx, err)
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		stop()
+		conn.Close()
+		return nil, contextErr(ctx, err)
+	}
+	resp.Body = &connBody{ReadCloser: resp.Body, conn: conn, stop: stop}
+	return resp, nil
+}
+
+// connBody closes the underlying connection when the response body is
+// closed, since connections are not reused.
+type connBody struct {
+	io.ReadCloser
+	conn net.Conn
+	stop func() bool
+}
+
+// Close closes the body and its connection.
+func (b *connBody) Close() error {
+	b.stop()
+	err := b.ReadCloser.Close()
+	b.conn.Close()
+	return err
+}
+
+// contextErr prefers the context's error when the context ended, since
+// the I/O error is then only a symptom of the connection being closed.
+func contextErr(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// closeRequestBody closes req.Body, as RoundTrip must on every path.
+func closeRequestBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
@@ -0,0 +1,162 @@
+package wghttp_test
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	wgdns "github.com/anthropics/warpgrid/packages/warpgrid-go/dns"
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/http"
+	wgnet "github.com/anthropics/warpgrid/packages/warpgrid-go/net"
+)
+
+// ── Transport test helpers ──────────────────────────────────────────
+
+type mockResolverFunc func(hostname string) ([]net.IP, error)
+
+func (f mockResolverFunc) Resolve(hostname string) ([]net.IP, error) {
+	return f(hostname)
+}
+
+// newTransportClient returns a client whose transport resolves
+// "api.warp.local" to the test server and the server's base URL using
+// that hostname.
+func newTransportClient(t *testing.T, srv *httptest.Server) (*http.Client, string) {
+	t.Helper()
+	u, _ := url.Parse(srv.URL)
+	resolver := wgdns.NewResolver(mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		if hostname == "api.warp.local" {
+			return []net.IP{net.ParseIP(u.Hostname())}, nil
+		}
+		return nil, errors.New("HostNotFound: " + hostname)
+	}))
+	client := &http.Client{Transport: wghttp.NewTransport(wgnet.NewDialer(resolver))}
+	return client, "http://api.warp.local:" + u.Port()
+}
+
+// ── Transport tests ─────────────────────────────────────────────────
+
+func TestTransport_GET(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Host", r.Host)
+		io.WriteString(w, "hello "+r.URL.Query().Get("name"))
+	}))
+	defer srv.Close()
+	client, base := newTransportClient(t, srv)
+
+	resp, err := client.Get(base + "/greet?name=warp")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	if string(body) != "hello warp" {
+		t.Fatalf("expected body 'hello warp', got '%s'", body)
+	}
+	if !strings.HasPrefix(resp.Header.Get("X-Host"), "api.warp.local") {
+		t.Fatalf("expected Host header to carry the hostname, got '%s'", resp.Header.Get("X-Host"))
+	}
+}
+
+func TestTransport_POSTWithHeaders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Echo-Auth", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+	}))
+	defer srv.Close()
+	client, base := newTransportClient(t, srv)
+
+	req, _ := http.NewRequest("POST", base+"/items", strings.NewReader(`{"id":1}`))
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", resp.StatusCode)
+	}
+	if string(body) != `{"id":1}` {
+		t.Fatalf("expected echoed body, got '%s'", body)
+	}
+	if resp.Header.Get("X-Echo-Auth") != "Bearer token" {
+		t.Fatalf("expected Authorization to propagate, got '%s'", resp.Header.Get("X-Echo-Auth"))
+	}
+	if resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("expected Content-Type to propagate, got '%s'", resp.Header.Get("Content-Type"))
+	}
+}
+
+func TestTransport_ErrorStatusIsNotAnError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	client, base := newTransportClient(t, srv)
+
+	resp, err := client.Get(base + "/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d", resp.StatusCode)
+	}
+}
+
+func TestTransport_ClientFollowsRedirects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusFound)
+			return
+		}
+		io.WriteString(w, "moved here")
+	}))
+	defer srv.Close()
+	client, base := newTransportClient(t, srv)
+
+	resp, err := client.Get(base + "/old")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 || string(body) != "moved here" {
+		t.Fatalf("expected redirect to be followed, got %d '%s'", resp.StatusCode, body)
+	}
+}
+
+func TestTransport_DNSFailureIsReturned(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	client, _ := newTransportClient(t, srv)
+
+	_, err := client.Get("http://unknown.warp.local/")
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		t.Fatalf("expected *net.OpError, got %v", err)
+	}
+}
+
+func TestTransport_RejectsHTTPS(t *testing.T) {
+	rt := wghttp.NewTransport(wgnet.NewDialer(wgdns.NewResolver(nil)))
+	req, _ := http.NewRequest("GET", "https://api.warp.local/", nil)
+	if _, err := rt.RoundTrip(req); err == nil || !strings.Contains(err.Error(), "unsupported protocol scheme") {
+		t.Fatalf("expected unsupported scheme error, got %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+// Package randsrc is the random source shared by the WarpGrid Go
+// overlay: backoff jitter and random balancing in net, and request IDs
+// in net/http. Keeping one injectable source lets tests make all of
+// them deterministic at once.
+//
+// The default source reads crypto/rand, which TinyGo maps to the WASI
+// random_get call. Where that fails, as on hosts that do not implement
+// random_get, it falls back to mixing the current time with a
+// process-wide counter, which is unique within the instance but
+// predictable.
+package randsrc
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"sync/atomic"
+	"time"
+)
+
+// source holds the function set by Set, if any.
+var source atomic.Pointer[func() uint64]
+
+// Set replaces the random source with fn; nil restores the default.
+func Set(fn func() uint64) {
+	if fn == nil {
+		source.Store(nil)
+		return
+	}
+	source.Store(&fn)
+}
+
+// Uint64 returns a random value from the current source.
+func Uint64() uint64 {
+	if fn := source.Load(); fn != nil {
+		return (*fn)()
+	}
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fallback()
+	}
+	return binary.LittleEndian.Uint64(b[:])
+}
+
+// Intn returns a random value in [0, n). It panics if n <= 0.
+func Intn(n int) int {
+	if n <= 0 {
+		panic("randsrc: invalid argument to Intn")
+	}
+	return int(Uint64() % uint64(n))
+}
+
+// counter distinguishes fallback values generated in the same
+// nanosecond.
+var counter atomic.Uint64
+
+// fallback derives a value from the time and counter with the
+// splitmix64 finalizer, so consecutive values do not look related.
+func fallback() uint64 {
+	z := uint64(time.Now().UnixNano()) + counter.Add(1)*0x9e3779b97f4a7c15
+	z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+	z = (z ^ z>>27) * 0x94d049bb133111eb
+	return z ^ z>>31
+}
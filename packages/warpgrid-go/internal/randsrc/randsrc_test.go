@@ -0,0 +1,32 @@
+package randsrc_test
+
+import (
+	"testing"
+
+	"github.com/anthropics/warpgrid/packages/warpgrid-go/internal/randsrc"
+)
+
+// ── Source tests ────────────────────────────────────────────────────
+
+func TestSet_OverridesAndRestoresDefault(t *testing.T) {
+	randsrc.Set(func() uint64 { return 42 })
+	if got := randsrc.Uint64(); got != 42 {
+		t.Fatalf("expected 42 from the injected source, got %d", got)
+	}
+	if got := randsrc.Intn(10); got != 2 {
+		t.Fatalf("expected Intn(10) = 2, got %d", got)
+	}
+
+	randsrc.Set(nil)
+	if randsrc.Uint64() == randsrc.Uint64() {
+		t.Fatal("expected the default source to vary between calls")
+	}
+}
+
+func TestIntn_InRange(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		if n := randsrc.Intn(7); n < 0 || n >= 7 {
+			t.Fatalf("expected a value in [0, 7), got %d", n)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package net
+
+import (
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/anthropics/warpgrid/packages/warpgrid-go/internal/randsrc"
+)
+
+// maxBackoff caps Backoff's wait before jitter, so a large attempt
+// count cannot overflow it.
+const maxBackoff = time.Hour
+
+// Backoff returns how long to wait before retry attempt n, counting
+// from zero: base doubled n times, at most an hour, plus up to a
+// quarter of that again chosen at random, so that instances retrying
+// together spread out instead of hitting the backend in lockstep. The
+// jitter comes from the random source the net/http overlay's
+// SetRandSource replaces.
+func Backoff(base time.Duration, n int) time.Duration {
+	if n < 0 {
+		n = 0
+	}
+	wait := maxBackoff
+	if n < 63 && base <= maxBackoff>>n {
+		wait = base << n
+	}
+	if spread := int(wait / 4); spread > 0 {
+		wait += time.Duration(randsrc.Intn(spread))
+	}
+	return wait
+}
+
+// Balancer chooses which resolved address a Dialer tries first.
+//
+// Pick returns an index into addrs. Dial starts with that address and,
+// on failure, walks the remaining addresses in order (wrapping around),
+// so failover still covers every record. An out-of-range index is
+// treated as 0. Implementations must be safe for concurrent use.
+type Balancer interface {
+	Pick(addrs []net.IP) int
+}
+
+// RoundRobin rotates the first choice across successive dials.
+// The zero value is ready to use.
+type RoundRobin struct {
+	next atomic.Uint64
+}
+
+// Pick returns the next index in rotation.
+func (rr *RoundRobin) Pick(addrs []net.IP) int {
+	if len(addrs) == 0 {
+		return 0
+	}
+	n := rr.next.Add(1) - 1
+	return int(n % uint64(len(addrs)))
+}
+
+// Random picks the first address uniformly at random.
+type Random struct{}
+
+// Pick returns a uniformly random index.
+func (Random) Pick(addrs []net.IP) int {
+	if len(addrs) == 0 {
+		return 0
+	}
+	return randsrc.Intn(len(addrs))
+}
+
+// WeightedRandom picks the first address at random in proportion to a
+// parallel weight list: Weights[i] is the relative weight of addrs[i].
+// Addresses without a corresponding entry get weight 1; non-positive
+// weights exclude an address from the first pick (it is still reached
+// by failover). If every weight is non-positive, index 0 is returned.
+type WeightedRandom struct {
+	Weights []int
+}
+
+// Pick returns an index chosen with probability proportional to its weight.
+func (wr WeightedRandom) Pick(addrs []net.IP) int {
+	total := 0
+	for i := range addrs {
+		total += wr.weight(i)
+	}
+	if total <= 0 {
+		return 0
+	}
+
+	n := randsrc.Intn(total)
+	for i := range addrs {
+		w := wr.weight(i)
+		if n < w {
+			return i
+		}
+		n -= w
+	}
+	return 0
+}
+
+func (wr WeightedRandom) weight(i int) int {
+	if i >= len(wr.Weights) {
+		return 1
+	}
+	if wr.Weights[i] < 0 {
+		return 0
+	}
+	return wr.Weights[i]
+}
+
+// rotate returns addrs reordered to start at index start, preserving
+// the relative order of the remaining addresses.
+func rotate(addrs []net.IPAddr, start int) []net.IPAddr {
+	if start <= 0 || start >= len(addrs) {
+		return addrs
+	}
+	out := make([]net.IPAddr, 0, len(addrs))
+	out = append(out, addrs[start:]...)
+	return append(out, addrs[:start]...)
+}
@@ -0,0 +1,222 @@
+package net_test
+
+import (
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	wgdns "github.com/anthropics/warpgrid/packages/warpgrid-go/dns"
+	"github.com/anthropics/warpgrid/packages/warpgrid-go/internal/randsrc"
+	wgnet "github.com/anthropics/warpgrid/packages/warpgrid-go/net"
+)
+
+// ── Test helpers ────────────────────────────────────────────────────
+
+// fixedBalancer always picks the same index.
+type fixedBalancer int
+
+func (b fixedBalancer) Pick(addrs []net.IP) int { return int(b) }
+
+func testAddrs(n int) []net.IP {
+	addrs := make([]net.IP, n)
+	for i := range addrs {
+		addrs[i] = net.IPv4(10, 0, 0, byte(i+1))
+	}
+	return addrs
+}
+
+// pickCounts runs b.Pick iterations times and tallies each index.
+func pickCounts(b wgnet.Balancer, addrs []net.IP, iterations int) []int {
+	counts := make([]int, len(addrs))
+	for i := 0; i < iterations; i++ {
+		counts[b.Pick(addrs)]++
+	}
+	return counts
+}
+
+// assertShare fails if count/total deviates from want by more than tol.
+func assertShare(t *testing.T, idx, count, total int, want, tol float64) {
+	t.Helper()
+	got := float64(count) / float64(total)
+	if math.Abs(got-want) > tol {
+		t.Fatalf("index %d: share %.3f, want %.3f ± %.3f", idx, got, want, tol)
+	}
+}
+
+// ── RoundRobin tests ────────────────────────────────────────────────
+
+func TestRoundRobin_CyclesThroughAddresses(t *testing.T) {
+	rr := &wgnet.RoundRobin{}
+	addrs := testAddrs(3)
+
+	want := []int{0, 1, 2, 0, 1, 2}
+	for i, w := range want {
+		if got := rr.Pick(addrs); got != w {
+			t.Fatalf("pick %d: expected %d, got %d", i, w, got)
+		}
+	}
+}
+
+// ── Random tests ────────────────────────────────────────────────────
+
+func TestRandom_UniformDistribution(t *testing.T) {
+	const iterations = 30000
+	addrs := testAddrs(3)
+
+	counts := pickCounts(wgnet.Random{}, addrs, iterations)
+	for i, c := range counts {
+		assertShare(t, i, c, iterations, 1.0/3, 0.03)
+	}
+}
+
+// ── WeightedRandom tests ────────────────────────────────────────────
+
+func TestWeightedRandom_ProportionalDistribution(t *testing.T) {
+	const iterations = 40000
+	addrs := testAddrs(3)
+	b := wgnet.WeightedRandom{Weights: []int{6, 3, 1}}
+
+	counts := pickCounts(b, addrs, iterations)
+	assertShare(t, 0, counts[0], iterations, 0.6, 0.03)
+	assertShare(t, 1, counts[1], iterations, 0.3, 0.03)
+	assertShare(t, 2, counts[2], iterations, 0.1, 0.03)
+}
+
+func TestWeightedRandom_MissingWeightsDefaultToOne(t *testing.T) {
+	const iterations = 30000
+	addrs := testAddrs(2)
+	b := wgnet.WeightedRandom{Weights: []int{3}} // addrs[1] defaults to 1
+
+	counts := pickCounts(b, addrs, iterations)
+	assertShare(t, 0, counts[0], iterations, 0.75, 0.03)
+}
+
+func TestWeightedRandom_ZeroWeightNeverPickedFirst(t *testing.T) {
+	addrs := testAddrs(2)
+	b := wgnet.WeightedRandom{Weights: []int{0, 5}}
+
+	counts := pickCounts(b, addrs, 1000)
+	if counts[0] != 0 {
+		t.Fatalf("zero-weight address picked %d times", counts[0])
+	}
+}
+
+// ── Dialer integration ──────────────────────────────────────────────
+
+func TestDial_BalancerChoosesFirstAddressAndFailoverWraps(t *testing.T) {
+	addr, cleanup := startEchoServer(t)
+	defer cleanup()
+	_, port, _ := net.SplitHostPort(addr)
+
+	// The echo server only listens on 127.0.0.1; 127.0.0.2 refuses.
+	backend := mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("127.0.0.2")}, nil
+	})
+	dialer := wgnet.NewDialer(wgdns.NewResolver(backend))
+	dialer.Balancer = fixedBalancer(1)
+
+	conn, err := dialer.Dial("tcp", "balanced:"+port)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	if got := conn.RemoteAddr().String(); got != "127.0.0.1:"+port {
+		t.Fatalf("expected failover to wrap to 127.0.0.1, got %s", got)
+	}
+}
+
+func TestDial_NilBalancerKeepsResolverOrder(t *testing.T) {
+	addr, cleanup := startEchoServer(t)
+	defer cleanup()
+	_, port, _ := net.SplitHostPort(addr)
+
+	backend := mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("127.0.0.2")}, nil
+	})
+	dialer := wgnet.NewDialer(wgdns.NewResolver(backend))
+
+	conn, err := dialer.Dial("tcp", "ordered:"+port)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	if got := conn.RemoteAddr().String(); got != "127.0.0.1:"+port {
+		t.Fatalf("expected first resolved address, got %s", got)
+	}
+}
+
+// ── Random source tests ─────────────────────────────────────────────
+
+// counterSource returns a deterministic source yielding seed, seed+1, ...
+func counterSource(seed uint64) func() uint64 {
+	return func() uint64 {
+		seed++
+		return seed - 1
+	}
+}
+
+func TestRandSource_BackoffReproducible(t *testing.T) {
+	defer randsrc.Set(nil)
+
+	run := func() []time.Duration {
+		randsrc.Set(counterSource(7))
+		var waits []time.Duration
+		for n := 0; n < 4; n++ {
+			waits = append(waits, wgnet.Backoff(100*time.Millisecond, n))
+		}
+		return waits
+	}
+	first, second := run(), run()
+	for n := range first {
+		if first[n] != second[n] {
+			t.Fatalf("attempt %d: expected the same wait from the same source, got %v and %v", n, first[n], second[n])
+		}
+	}
+	// 100ms doubled n times, plus 7+n nanoseconds of jitter.
+	want := []time.Duration{100*time.Millisecond + 7, 200*time.Millisecond + 8, 400*time.Millisecond + 9, 800*time.Millisecond + 10}
+	for n, w := range want {
+		if first[n] != w {
+			t.Fatalf("attempt %d: expected %v, got %v", n, w, first[n])
+		}
+	}
+}
+
+func TestBackoff_JitterBounded(t *testing.T) {
+	for n := 0; n < 5; n++ {
+		base := 40 * time.Millisecond << n
+		for i := 0; i < 100; i++ {
+			if got := wgnet.Backoff(40*time.Millisecond, n); got < base || got >= base+base/4 {
+				t.Fatalf("attempt %d: expected a wait in [%v, %v), got %v", n, base, base+base/4, got)
+			}
+		}
+	}
+}
+
+func TestBackoff_CappedForLargeAttempts(t *testing.T) {
+	for _, n := range []int{20, 62, 63, 64, 1000} {
+		got := wgnet.Backoff(100*time.Millisecond, n)
+		if got < time.Hour || got >= time.Hour+time.Hour/4 {
+			t.Fatalf("attempt %d: expected a wait in [1h, 1h15m), got %v", n, got)
+		}
+	}
+}
+
+func TestRandSource_BalancerPicksReproducible(t *testing.T) {
+	defer randsrc.Set(nil)
+	addrs := testAddrs(5)
+
+	randsrc.Set(counterSource(3))
+	got := []int{wgnet.Random{}.Pick(addrs), wgnet.Random{}.Pick(addrs), wgnet.Random{}.Pick(addrs)}
+	if got[0] != 3 || got[1] != 4 || got[2] != 0 {
+		t.Fatalf("expected picks [3 4 0], got %v", got)
+	}
+
+	randsrc.Set(counterSource(0))
+	wr := wgnet.WeightedRandom{Weights: []int{0, 0, 2, 0, 0}}
+	if idx := wr.Pick(addrs); idx != 2 {
+		t.Fatalf("expected the only weighted address, got %d", idx)
+	}
+}
@@ -0,0 +1,38 @@
+package net
+
+import (
+	"net"
+	"sync/atomic"
+)
+
+// countingConn is a net.Conn that counts the bytes passing through it.
+// Embedding forwards Close, the address accessors, and the deadline
+// setters unchanged.
+type countingConn struct {
+	net.Conn
+	read    atomic.Int64
+	written atomic.Int64
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.read.Add(int64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.written.Add(int64(n))
+	return n, err
+}
+
+// ConnStats returns the number of bytes read from and written to conn
+// so far. ok is false when conn was not returned by a Dialer with
+// CountBytes set. It is safe to call while conn is in use.
+func ConnStats(conn net.Conn) (read, written int64, ok bool) {
+	c, ok := conn.(*countingConn)
+	if !ok {
+		return 0, 0, false
+	}
+	return c.read.Load(), c.written.Load(), true
+}
@@ -0,0 +1,81 @@
+package net_test
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	wgdns "github.com/anthropics/warpgrid/packages/warpgrid-go/dns"
+	wgnet "github.com/anthropics/warpgrid/packages/warpgrid-go/net"
+)
+
+// ── Byte counting tests ─────────────────────────────────────────────
+
+func TestDial_CountBytesTracksReadAndWritten(t *testing.T) {
+	addr, cleanup := startEchoServer(t)
+	defer cleanup()
+
+	dialer := wgnet.NewDialer(wgdns.NewResolver(mockResolverFunc(nil)))
+	dialer.CountBytes = true
+
+	conn, err := dialer.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	message := []byte("count these twenty-nine bytes")
+	if _, err := conn.Write(message); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, len(message))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+
+	read, written, ok := wgnet.ConnStats(conn)
+	if !ok {
+		t.Fatal("expected ConnStats to recognize the connection")
+	}
+	if written != int64(len(message)) || read != int64(len(message)) {
+		t.Fatalf("expected %d bytes each way, got read=%d written=%d", len(message), read, written)
+	}
+}
+
+func TestDial_CountBytesForwardsDeadlines(t *testing.T) {
+	addr, cleanup := startEchoServer(t)
+	defer cleanup()
+
+	dialer := wgnet.NewDialer(wgdns.NewResolver(mockResolverFunc(nil)))
+	dialer.CountBytes = true
+
+	conn, err := dialer.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+	_, err = conn.Read(make([]byte, 1))
+	if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+		t.Fatalf("expected a timeout error from the forwarded deadline, got %v", err)
+	}
+}
+
+func TestConnStats_UncountedConn(t *testing.T) {
+	addr, cleanup := startEchoServer(t)
+	defer cleanup()
+
+	dialer := wgnet.NewDialer(wgdns.NewResolver(mockResolverFunc(nil)))
+	conn, err := dialer.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	if _, _, ok := wgnet.ConnStats(conn); ok {
+		t.Fatal("expected ok=false for a connection dialed without CountBytes")
+	}
+}
@@ -3,15 +3,20 @@
 // The Dialer resolves hostnames through the WarpGrid DNS shim before
 // attempting TCP/UDP connections. IP literals bypass DNS entirely.
 // When multiple addresses are returned, each is tried in order until
-// one succeeds (basic failover). DNS failures are wrapped as *net.OpError
+// one succeeds (basic failover), or raced Happy Eyeballs style when
+// Dialer.FallbackDelay is set. DNS failures are wrapped as *net.OpError
 // for compatibility with standard Go error handling patterns.
 //
 // This package is part of the WarpGrid Go overlay (Domain 3, US-304).
 package net
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/anthropics/warpgrid/packages/warpgrid-go/dns"
@@ -25,6 +30,83 @@ type Dialer struct {
 	// ConnectTimeout is the per-address connection timeout.
 	// When zero, net.Dialer uses its default (no timeout).
 	ConnectTimeout time.Duration
+
+	// Balancer chooses which resolved address is tried first. Failover
+	// then walks the remaining addresses in order. When nil, addresses
+	// are tried in the order the resolver returned them.
+	Balancer Balancer
+
+	// FallbackDelay enables Happy Eyeballs (RFC 8305) racing across the
+	// resolved addresses. Addresses are interleaved by family (IPv6,
+	// IPv4, ...) starting with the family of the first address, and a
+	// new attempt starts every FallbackDelay, or as soon as the previous
+	// attempt fails. The first connection to succeed wins; every other
+	// attempt is cancelled and any connection that completes afterwards
+	// is closed. When zero or negative, addresses are tried one at a
+	// time in order.
+	FallbackDelay time.Duration
+
+	// CountBytes wraps each returned connection so the bytes read from
+	// and written to it are counted; read them with ConnStats. The
+	// wrapper forwards every net.Conn method, but hides methods beyond
+	// net.Conn (such as *net.TCPConn's CloseWrite), so leave it off
+	// when those are needed.
+	CountBytes bool
+
+	// MaxConnsPerHost caps how many connections returned by this Dialer
+	// may be open to the same host at once, to protect a fragile
+	// backend. Hosts are keyed as in Stats. When the limit is reached,
+	// a dial waits until one of the host's connections is closed, its
+	// context is done, or MaxConnsPerHostTimeout elapses. Set it before
+	// the first dial; zero or negative means no limit.
+	MaxConnsPerHost int
+
+	// MaxConnsPerHostTimeout bounds the wait for a free slot under
+	// MaxConnsPerHost; a dial that times out fails with an error
+	// wrapping ErrMaxConnsPerHost. Zero or negative waits as long as the
+	// context allows.
+	MaxConnsPerHostTimeout time.Duration
+
+	// RetryOnEmptyDNS retries a lookup that succeeds with no addresses,
+	// as happens while a service's record briefly has zero healthy
+	// endpoints during a rollout or cold start. Up to four more lookups
+	// are made after waits of about 25ms, 50ms, 100ms and 200ms (see
+	// Backoff for the jitter) before the dial fails as not found. A
+	// context done while waiting ends the dial with its error. Lookup
+	// errors and connection failures are not retried.
+	RetryOnEmptyDNS bool
+
+	// MaxFailoverAttempts caps how many resolved addresses one dial
+	// tries, bounding its worst-case latency to about
+	// MaxFailoverAttempts × ConnectTimeout however many records the
+	// name has. The first addresses in dial order (after Balancer and
+	// Happy Eyeballs interleaving) are tried, and once they all fail the
+	// last error is returned. Zero or negative means every address.
+	MaxFailoverAttempts int
+
+	// StickyTTL gives dials to a hostname affinity for the address the
+	// last successful dial to it connected to: for StickyTTL after each
+	// success, that address is tried first, ahead of Balancer's pick, as
+	// long as the resolver still returns it. This keeps stateful
+	// workloads on one replica and cuts reconnection churn. If the
+	// address fails, the dial falls back to the rest of the list and
+	// whichever address then connects becomes the sticky one. Zero
+	// disables affinity.
+	StickyTTL time.Duration
+
+	// Proxy, when set, routes TCP dials through an HTTP CONNECT proxy:
+	// Dial connects to the proxy, asks it to tunnel to the address, and
+	// returns the tunnel once the proxy answers 2xx. The target is sent
+	// to the proxy unresolved, so DNS for it happens at the proxy;
+	// Balancer, StickyTTL, failover, and DialTrace's hooks apply to the
+	// proxy's own address instead. A 407 fails with an error wrapping
+	// ErrProxyAuthRequired. UDP and Unix networks are dialed directly.
+	Proxy *ProxyConfig
+
+	stats    dialerStats
+	limits   hostLimiter
+	sticky   stickyTable
+	draining atomic.Bool
 }
 
 // NewDialer creates a Dialer that resolves hostnames via the given resolver.
@@ -34,14 +116,87 @@ func NewDialer(resolver *dns.Resolver) *Dialer {
 
 // Dial connects to the address on the named network.
 //
+// Dial is equivalent to DialContext with context.Background().
+func (d *Dialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+// DialContext connects to the address on the named network using the
+// provided context. The context bounds the whole operation, including
+// every failover or Happy Eyeballs attempt; cancelling it aborts
+// in-flight connection attempts.
+//
 // If the host component is an IP literal, it is used directly without
 // DNS resolution. Otherwise, the hostname is resolved via the WarpGrid
-// DNS shim and each returned address is tried in order. The first
+// DNS shim and each returned address is tried in order, starting from
+// the address chosen by Balancer when one is set. The first
 // successful connection is returned. If all addresses fail, the last
 // error is returned wrapped as *net.OpError.
 //
-// Supported networks: "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6".
-func (d *Dialer) Dial(network, address string) (net.Conn, error) {
+// Supported networks: "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6",
+// and the Unix domain socket networks "unix", "unixgram", "unixpacket".
+// For Unix networks the address is a socket path (optionally written as
+// "unix:///path"); DNS and host:port splitting are skipped and the path
+// is dialed directly. Under WASI, Unix sockets are only reachable when
+// the host's socket shim exposes the path to the guest.
+//
+// While the Dialer is draining (see StartDraining), DialContext fails
+// with ErrDraining.
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if d.draining.Load() {
+		return nil, &net.OpError{
+			Op:   "dial",
+			Net:  network,
+			Addr: hostAddr{network, address},
+			Err:  ErrDraining,
+		}
+	}
+	d.stats.dials.Add(1)
+	conn, err := d.dialLimited(ctx, network, address)
+	d.stats.recordResult(network, address, err == nil)
+	if err == nil && d.CountBytes {
+		conn = &countingConn{Conn: conn}
+	}
+	return conn, err
+}
+
+// dialLimited applies MaxConnsPerHost around dial.
+func (d *Dialer) dialLimited(ctx context.Context, network, address string) (net.Conn, error) {
+	if d.MaxConnsPerHost <= 0 {
+		return d.dial(ctx, network, address)
+	}
+	release, err := d.limits.acquire(ctx, hostKey(network, address), d.MaxConnsPerHost, d.MaxConnsPerHostTimeout)
+	if err != nil {
+		return nil, &net.OpError{
+			Op:   "dial",
+			Net:  network,
+			Addr: hostAddr{network, address},
+			Err:  err,
+		}
+	}
+	conn, err := d.dial(ctx, network, address)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return &limitedConn{Conn: conn, release: release}, nil
+}
+
+// dial implements DialContext; it is wrapped for stats accounting.
+func (d *Dialer) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	if d.Proxy != nil && isTCPNetwork(network) {
+		return d.dialProxy(ctx, network, address)
+	}
+	return d.dialTarget(ctx, network, address)
+}
+
+// dialTarget dials address itself, resolving it and failing over
+// across its addresses.
+func (d *Dialer) dialTarget(ctx context.Context, network, address string) (net.Conn, error) {
+	if isUnixNetwork(network) {
+		return d.dialDirect(ctx, network, strings.TrimPrefix(address, "unix://"))
+	}
+
 	host, port, err := net.SplitHostPort(address)
 	if err != nil {
 		return nil, &net.OpError{
@@ -53,27 +208,45 @@ func (d *Dialer) Dial(network, address string) (net.Conn, error) {
 
 	// IP literal: dial directly, no DNS needed
 	if dns.IsIPLiteral(host) {
-		return d.dialDirect(network, address)
+		return d.dialDirect(ctx, network, address)
 	}
 
 	// Resolve hostname via WarpGrid DNS shim
-	ips, err := d.resolver.Resolve(host)
+	trace := ContextDialTrace(ctx)
+	if trace != nil && trace.DNSStart != nil {
+		trace.DNSStart(DNSStartInfo{Host: host})
+	}
+	d.stats.dnsLookups.Add(1)
+	addrs, err := d.resolver.ResolveIPAddr(host)
+	if err == nil && len(addrs) == 0 && d.RetryOnEmptyDNS {
+		addrs, err = d.retryEmptyDNS(ctx, host)
+	}
+	if trace != nil && trace.DNSDone != nil {
+		trace.DNSDone(DNSDoneInfo{Addrs: addrs, Err: err})
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		// Cut short while retrying; report it as such, not as a
+		// missing record.
+		return nil, &net.OpError{Op: "dial", Net: network, Addr: hostAddr{network, address}, Err: err}
+	}
 	if err != nil {
 		return nil, &net.OpError{
-			Op:  "dial",
-			Net: network,
+			Op:   "dial",
+			Net:  network,
+			Addr: hostAddr{network, address},
 			Err: &DNSError{
 				Err:        err.Error(),
 				Name:       host,
-				IsNotFound: true,
+				IsNotFound: !errors.Is(err, dns.ErrCIDRHost),
 			},
 		}
 	}
 
-	if len(ips) == 0 {
+	if len(addrs) == 0 {
 		return nil, &net.OpError{
-			Op:  "dial",
-			Net: network,
+			Op:   "dial",
+			Net:  network,
+			Addr: hostAddr{network, address},
 			Err: &DNSError{
 				Err:        "no addresses found",
 				Name:       host,
@@ -82,29 +255,244 @@ func (d *Dialer) Dial(network, address string) (net.Conn, error) {
 		}
 	}
 
-	// Try each resolved address in order (failover)
+	if d.Balancer != nil {
+		addrs = rotate(addrs, d.Balancer.Pick(ipsOf(addrs)))
+	}
+	if d.StickyTTL > 0 {
+		addrs = d.sticky.prefer(host, addrs)
+	}
+
+	parallel := d.FallbackDelay > 0 && len(addrs) > 1
+	if parallel {
+		addrs = interleaveFamilies(addrs)
+	}
+	if n := d.MaxFailoverAttempts; n > 0 && len(addrs) > n {
+		addrs = addrs[:n]
+	}
+
+	var conn net.Conn
+	if parallel {
+		conn, err = d.dialParallel(ctx, network, port, addrs)
+	} else {
+		conn, err = d.dialSerial(ctx, network, port, addrs)
+	}
+	if d.StickyTTL > 0 {
+		if err == nil {
+			d.sticky.remember(host, conn, d.StickyTTL)
+		} else {
+			d.sticky.forget(host)
+		}
+	}
+	if err == nil {
+		return conn, nil
+	}
+
+	opErr := &net.OpError{
+		Op:   "dial",
+		Net:  network,
+		Addr: hostAddr{network, address},
+		Err:  fmt.Errorf("all %d addresses failed for %s: %w", len(addrs), host, err),
+	}
+	// Report the last address actually tried, as the stdlib does, so the
+	// message reads "dial tcp 10.0.0.1:5432: ...".
+	var last *net.OpError
+	if errors.As(err, &last) && last.Addr != nil {
+		opErr.Addr = last.Addr
+		opErr.Source = last.Source
+	}
+	return nil, opErr
+}
+
+// emptyDNSRetries and emptyDNSBackoff shape RetryOnEmptyDNS: up to
+// emptyDNSRetries further lookups, waiting Backoff(emptyDNSBackoff, i)
+// before the i-th.
+const (
+	emptyDNSRetries = 4
+	emptyDNSBackoff = 25 * time.Millisecond
+)
+
+// retryEmptyDNS looks host up again, with backoff, after it resolved
+// to no addresses. It returns the first non-empty answer or lookup
+// error, no addresses once the retries run out, or ctx's error if it
+// is done first.
+func (d *Dialer) retryEmptyDNS(ctx context.Context, host string) ([]net.IPAddr, error) {
+	for i := 0; i < emptyDNSRetries; i++ {
+		timer := time.NewTimer(Backoff(emptyDNSBackoff, i))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		d.stats.dnsLookups.Add(1)
+		addrs, err := d.resolver.ResolveIPAddr(host)
+		if err != nil || len(addrs) > 0 {
+			return addrs, err
+		}
+	}
+	return nil, nil
+}
+
+// hostAddr is a net.Addr for a host:port that has not been resolved,
+// used as OpError.Addr when no resolved address is available.
+type hostAddr struct {
+	network, address string
+}
+
+func (a hostAddr) Network() string { return a.network }
+func (a hostAddr) String() string  { return a.address }
+
+// dialSerial tries each address in order (failover) and returns the
+// first connection, or the last error if every attempt fails.
+func (d *Dialer) dialSerial(ctx context.Context, network, port string, addrs []net.IPAddr) (net.Conn, error) {
 	var lastErr error
-	for _, ip := range ips {
-		addr := net.JoinHostPort(ip.String(), port)
-		conn, err := d.dialDirect(network, addr)
+	for i, addr := range addrs {
+		conn, err := d.dialDirect(ctx, network, joinHostPort(addr, port))
 		if err == nil {
 			return conn, nil
 		}
 		lastErr = err
+		if ctx.Err() != nil {
+			break
+		}
+		if i < len(addrs)-1 {
+			d.stats.failovers.Add(1)
+		}
+	}
+	return nil, lastErr
+}
+
+// dialResult is the outcome of one Happy Eyeballs attempt.
+type dialResult struct {
+	conn net.Conn
+	err  error
+}
+
+// dialParallel races connection attempts to addrs, staggered by
+// FallbackDelay. Each attempt runs under a child of ctx that is
+// cancelled the instant a winner is chosen, so losers abort promptly
+// instead of running to their timeout; a loser that connects anyway is
+// closed rather than leaked as a half-open socket on the backend.
+func (d *Dialer) dialParallel(ctx context.Context, network, port string, addrs []net.IPAddr) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Buffered so attempts finishing after the winner never block.
+	results := make(chan dialResult, len(addrs))
+	next, pending := 0, 0
+	start := func() {
+		addr := joinHostPort(addrs[next], port)
+		next++
+		pending++
+		go func() {
+			conn, err := d.dialDirect(ctx, network, addr)
+			results <- dialResult{conn: conn, err: err}
+		}()
+	}
+
+	start()
+	fallback := time.After(d.FallbackDelay)
+	var lastErr error
+	for pending > 0 {
+		select {
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				cancel()
+				go closeLosers(results, pending)
+				return res.conn, nil
+			}
+			lastErr = res.err
+			if next < len(addrs) && ctx.Err() == nil {
+				d.stats.failovers.Add(1)
+				start()
+				fallback = time.After(d.FallbackDelay)
+			}
+		case <-fallback:
+			if next < len(addrs) {
+				start()
+				fallback = time.After(d.FallbackDelay)
+			}
+		}
+	}
+	return nil, lastErr
+}
+
+// closeLosers waits for the n attempts still in flight after a winner
+// was chosen and closes any that managed to connect.
+func closeLosers(results <-chan dialResult, n int) {
+	for i := 0; i < n; i++ {
+		if res := <-results; res.conn != nil {
+			res.conn.Close()
+		}
+	}
+}
+
+// interleaveFamilies reorders addrs so address families alternate,
+// starting with the family of the first address and otherwise
+// preserving the resolver's order, as RFC 8305 section 4 recommends.
+func interleaveFamilies(addrs []net.IPAddr) []net.IPAddr {
+	var primary, secondary []net.IPAddr
+	firstIs4 := addrs[0].IP.To4() != nil
+	for _, addr := range addrs {
+		if (addr.IP.To4() != nil) == firstIs4 {
+			primary = append(primary, addr)
+		} else {
+			secondary = append(secondary, addr)
+		}
 	}
 
-	return nil, &net.OpError{
-		Op:  "dial",
-		Net: network,
-		Err: fmt.Errorf("all %d addresses failed for %s: %w", len(ips), host, lastErr),
+	out := make([]net.IPAddr, 0, len(addrs))
+	for i := 0; i < len(primary) || i < len(secondary); i++ {
+		if i < len(primary) {
+			out = append(out, primary[i])
+		}
+		if i < len(secondary) {
+			out = append(out, secondary[i])
+		}
 	}
+	return out
+}
+
+// joinHostPort combines a resolved address and port into a dialable
+// "host:port", keeping an IPv6 zone as in "[fe80::1%eth0]:5432".
+func joinHostPort(addr net.IPAddr, port string) string {
+	return net.JoinHostPort(addr.String(), port)
+}
+
+// ipsOf returns the IPs of addrs, without their zones.
+func ipsOf(addrs []net.IPAddr) []net.IP {
+	ips := make([]net.IP, len(addrs))
+	for i, addr := range addrs {
+		ips[i] = addr.IP
+	}
+	return ips
+}
+
+// isUnixNetwork reports whether network names a Unix domain socket
+// network, whose addresses are filesystem paths rather than host:port.
+func isUnixNetwork(network string) bool {
+	switch network {
+	case "unix", "unixgram", "unixpacket":
+		return true
+	}
+	return false
 }
 
 // dialDirect connects to an address without DNS resolution.
-func (d *Dialer) dialDirect(network, address string) (net.Conn, error) {
+func (d *Dialer) dialDirect(ctx context.Context, network, address string) (net.Conn, error) {
 	dialer := &net.Dialer{}
 	if d.ConnectTimeout > 0 {
 		dialer.Timeout = d.ConnectTimeout
 	}
-	return dialer.Dial(network, address)
+	trace := ContextDialTrace(ctx)
+	if trace != nil && trace.ConnectStart != nil {
+		trace.ConnectStart(network, address)
+	}
+	conn, err := dialer.DialContext(ctx, network, address)
+	if trace != nil && trace.ConnectDone != nil {
+		trace.ConnectDone(network, address, err)
+	}
+	return conn, err
 }
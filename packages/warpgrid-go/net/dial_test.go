@@ -1,11 +1,14 @@
 package net_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -520,3 +523,406 @@ func TestDial_DNSEmptyResultWrapsInnerDNSError(t *testing.T) {
 		t.Fatalf("DNSError.Err = %q, want substring %q", dnsErr.Err, "no addresses found")
 	}
 }
+
+// ── Unix domain socket tests ────────────────────────────────────────
+
+// startUnixEchoServer starts a Unix socket server that echoes back
+// received data. Returns the socket path and a cleanup function.
+func startUnixEchoServer(t *testing.T) (string, func()) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "echo.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("Unix sockets not available: %v", err)
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return // listener closed
+			}
+			go func(c net.Conn) {
+				defer c.Close()
+				io.Copy(c, c)
+			}(conn)
+		}
+	}()
+
+	return path, func() { ln.Close() }
+}
+
+func TestDial_UnixSocketSkipsDNS(t *testing.T) {
+	path, cleanup := startUnixEchoServer(t)
+	defer cleanup()
+
+	dnsResolveCalled := false
+	backend := mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		dnsResolveCalled = true
+		return nil, errors.New("should not be called")
+	})
+	dialer := wgnet.NewDialer(wgdns.NewResolver(backend))
+
+	conn, err := dialer.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("Dial unix failed: %v", err)
+	}
+	defer conn.Close()
+
+	message := "Hello over a Unix socket!"
+	if _, err := conn.Write([]byte(message)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	buf := make([]byte, len(message))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if string(buf) != message {
+		t.Fatalf("expected %q, got %q", message, string(buf))
+	}
+
+	if dnsResolveCalled {
+		t.Fatal("DNS resolver was called for a Unix socket path")
+	}
+}
+
+func TestDial_UnixSocketURLForm(t *testing.T) {
+	path, cleanup := startUnixEchoServer(t)
+	defer cleanup()
+
+	backend := mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		return nil, errors.New("should not be called")
+	})
+	dialer := wgnet.NewDialer(wgdns.NewResolver(backend))
+
+	conn, err := dialer.Dial("unix", "unix://"+path)
+	if err != nil {
+		t.Fatalf("Dial unix:// failed: %v", err)
+	}
+	conn.Close()
+}
+
+func TestDial_UnixSocketMissingPathReturnsError(t *testing.T) {
+	backend := mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		return nil, errors.New("should not be called")
+	})
+	dialer := wgnet.NewDialer(wgdns.NewResolver(backend))
+
+	_, err := dialer.Dial("unix", filepath.Join(t.TempDir(), "missing.sock"))
+	if err == nil {
+		t.Fatal("expected error dialing a nonexistent socket path")
+	}
+}
+
+// ── OpError address reporting ───────────────────────────────────────
+
+func TestDial_DNSFailureErrorIncludesHost(t *testing.T) {
+	backend := mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		return nil, errors.New("HostNotFound: " + hostname)
+	})
+	dialer := wgnet.NewDialer(wgdns.NewResolver(backend))
+
+	_, err := dialer.Dial("tcp", "db.warp.local:5432")
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		t.Fatalf("expected *net.OpError, got %T: %v", err, err)
+	}
+	if opErr.Addr == nil || opErr.Addr.String() != "db.warp.local:5432" {
+		t.Fatalf("expected Addr 'db.warp.local:5432', got %v", opErr.Addr)
+	}
+	if !strings.HasPrefix(err.Error(), "dial tcp db.warp.local:5432: lookup db.warp.local") {
+		t.Fatalf("expected stdlib-style message, got %q", err.Error())
+	}
+}
+
+func TestDial_AllAddressesFailErrorIncludesAddress(t *testing.T) {
+	// Grab a free port and close it so connections are refused quickly.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	ln.Close()
+
+	backend := mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP("127.0.0.1")}, nil
+	})
+	dialer := wgnet.NewDialer(wgdns.NewResolver(backend))
+
+	_, err = dialer.Dial("tcp", "refused.warp.local:"+port)
+	if err == nil {
+		t.Fatal("expected error when all addresses fail")
+	}
+
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		t.Fatalf("expected *net.OpError, got %T: %v", err, err)
+	}
+	want := "127.0.0.1:" + port
+	if opErr.Addr == nil || opErr.Addr.String() != want {
+		t.Fatalf("expected Addr %q, got %v", want, opErr.Addr)
+	}
+	if !strings.HasPrefix(err.Error(), "dial tcp "+want+": ") {
+		t.Fatalf("expected message to start with 'dial tcp %s: ', got %q", want, err.Error())
+	}
+}
+
+// ── Zoned address tests ─────────────────────────────────────────────
+
+func TestDial_ZonedLinkLocalKeepsZone(t *testing.T) {
+	backend := wgdns.WasiBackend{
+		Zones: true,
+		Raw: func(hostname string, family uint32, buf []byte) uint32 {
+			buf[0] = 6
+			copy(buf[1:17], net.ParseIP("fe80::1"))
+			copy(buf[17:33], "wg0")
+			return 1
+		},
+	}
+	dialer := wgnet.NewDialer(wgdns.NewResolver(backend))
+	dialer.ConnectTimeout = 100 * time.Millisecond
+
+	// No such interface here, so the dial fails, but the address it
+	// tried must carry the zone.
+	_, err := dialer.Dial("tcp", "router.warp.local:5432")
+	if err == nil {
+		t.Skip("link-local dial unexpectedly succeeded")
+	}
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		t.Fatalf("expected *net.OpError, got %T: %v", err, err)
+	}
+	want := "[fe80::1%wg0]:5432"
+	if opErr.Addr == nil || opErr.Addr.String() != want {
+		t.Fatalf("expected dialed address %q, got %v (%v)", want, opErr.Addr, err)
+	}
+}
+
+// ── RetryOnEmptyDNS tests ───────────────────────────────────────────
+
+func TestDial_RetryOnEmptyDNSSucceedsOnceRecordsAppear(t *testing.T) {
+	addr, cleanup := startEchoServer(t)
+	defer cleanup()
+	_, port, _ := net.SplitHostPort(addr)
+
+	lookups := 0
+	backend := mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		lookups++
+		if lookups < 3 {
+			return []net.IP{}, nil // no healthy endpoints yet
+		}
+		return []net.IP{net.ParseIP("127.0.0.1")}, nil
+	})
+	dialer := wgnet.NewDialer(wgdns.NewResolver(backend))
+	dialer.RetryOnEmptyDNS = true
+
+	conn, err := dialer.Dial("tcp", "rolling.warp.local:"+port)
+	if err != nil {
+		t.Fatalf("expected the dial to succeed after retrying, got %v", err)
+	}
+	conn.Close()
+	if lookups != 3 {
+		t.Fatalf("expected 3 lookups, got %d", lookups)
+	}
+	if got := dialer.Stats().DNSLookups; got != 3 {
+		t.Fatalf("expected 3 DNS lookups in stats, got %d", got)
+	}
+}
+
+func TestDial_RetryOnEmptyDNSGivesUpAfterBound(t *testing.T) {
+	lookups := 0
+	backend := mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		lookups++
+		return []net.IP{}, nil
+	})
+	dialer := wgnet.NewDialer(wgdns.NewResolver(backend))
+	dialer.RetryOnEmptyDNS = true
+
+	start := time.Now()
+	_, err := dialer.Dial("tcp", "empty.warp.local:5432")
+	if err == nil {
+		t.Fatal("expected error when the record stays empty")
+	}
+	var dnsErr *wgnet.DNSError
+	if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+		t.Fatalf("expected a not-found DNSError, got %v", err)
+	}
+	if lookups != 5 {
+		t.Fatalf("expected 1 lookup plus 4 retries, got %d", lookups)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("expected retries bounded to well under 2s, took %v", elapsed)
+	}
+}
+
+func TestDial_RetryOnEmptyDNSBoundedByContext(t *testing.T) {
+	backend := mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		return []net.IP{}, nil
+	})
+	dialer := wgnet.NewDialer(wgdns.NewResolver(backend))
+	dialer.RetryOnEmptyDNS = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := dialer.DialContext(ctx, "tcp", "empty.warp.local:5432"); err == nil {
+		t.Fatal("expected error when the record stays empty")
+	}
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Fatalf("expected the context to cut retries short, took %v", elapsed)
+	}
+}
+
+func TestDial_EmptyDNSNotRetriedByDefault(t *testing.T) {
+	lookups := 0
+	backend := mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		lookups++
+		return []net.IP{}, nil
+	})
+	dialer := wgnet.NewDialer(wgdns.NewResolver(backend))
+
+	if _, err := dialer.Dial("tcp", "empty.warp.local:5432"); err == nil {
+		t.Fatal("expected error for empty DNS result")
+	}
+	if lookups != 1 {
+		t.Fatalf("expected a single lookup without RetryOnEmptyDNS, got %d", lookups)
+	}
+}
+
+// ── MaxFailoverAttempts tests ───────────────────────────────────────
+
+// refusingBackend resolves every name to n loopback addresses with
+// nothing listening on the port used, so each attempt is refused.
+func refusingBackend(n int) mockResolverFunc {
+	return func(hostname string) ([]net.IP, error) {
+		ips := make([]net.IP, n)
+		for i := range ips {
+			ips[i] = net.IPv4(127, 0, 0, byte(i+1))
+		}
+		return ips, nil
+	}
+}
+
+// closedPort returns a local port with no listener.
+func closedPort(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	ln.Close()
+	return port
+}
+
+func TestDial_MaxFailoverAttemptsCapsTries(t *testing.T) {
+	port := closedPort(t)
+	dialer := wgnet.NewDialer(wgdns.NewResolver(refusingBackend(5)))
+	dialer.MaxFailoverAttempts = 2
+
+	var attempts []string
+	ctx := wgnet.WithDialTrace(context.Background(), &wgnet.DialTrace{
+		ConnectStart: func(network, addr string) { attempts = append(attempts, addr) },
+	})
+	_, err := dialer.DialContext(ctx, "tcp", "dead.warp.local:"+port)
+	if err == nil {
+		t.Fatal("expected error when every address is refused")
+	}
+
+	want := []string{"127.0.0.1:" + port, "127.0.0.2:" + port}
+	if strings.Join(attempts, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected attempts %v, got %v", want, attempts)
+	}
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) || opErr.Addr == nil || opErr.Addr.String() != want[1] {
+		t.Fatalf("expected the last error from %s, got %v", want[1], err)
+	}
+	if !strings.Contains(err.Error(), "all 2 addresses failed") {
+		t.Fatalf("expected the message to count the addresses tried, got %q", err.Error())
+	}
+}
+
+func TestDial_MaxFailoverAttemptsAppliesToHappyEyeballs(t *testing.T) {
+	port := closedPort(t)
+	dialer := wgnet.NewDialer(wgdns.NewResolver(refusingBackend(5)))
+	dialer.MaxFailoverAttempts = 3
+	dialer.FallbackDelay = time.Millisecond
+
+	var mu sync.Mutex
+	attempts := 0
+	ctx := wgnet.WithDialTrace(context.Background(), &wgnet.DialTrace{
+		ConnectStart: func(network, addr string) {
+			mu.Lock()
+			attempts++
+			mu.Unlock()
+		},
+	})
+	if _, err := dialer.DialContext(ctx, "tcp", "dead.warp.local:"+port); err == nil {
+		t.Fatal("expected error when every address is refused")
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestDial_MaxFailoverAttemptsZeroTriesAll(t *testing.T) {
+	port := closedPort(t)
+	dialer := wgnet.NewDialer(wgdns.NewResolver(refusingBackend(5)))
+
+	attempts := 0
+	ctx := wgnet.WithDialTrace(context.Background(), &wgnet.DialTrace{
+		ConnectStart: func(network, addr string) { attempts++ },
+	})
+	dialer.DialContext(ctx, "tcp", "dead.warp.local:"+port)
+	if attempts != 5 {
+		t.Fatalf("expected all 5 addresses tried, got %d", attempts)
+	}
+}
+
+// ── CIDR host tests ─────────────────────────────────────────────────
+
+func TestDial_CIDRHostIsNotReportedAsNotFound(t *testing.T) {
+	backend := mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		t.Fatalf("backend must not be called for %q", hostname)
+		return nil, nil
+	})
+	dialer := wgnet.NewDialer(wgdns.NewResolver(backend))
+
+	_, err := dialer.Dial("tcp", "10.0.0.0/24:5432")
+	var dnsErr *wgnet.DNSError
+	if !errors.As(err, &dnsErr) {
+		t.Fatalf("expected a DNSError, got %v", err)
+	}
+	if dnsErr.IsNotFound || !strings.Contains(dnsErr.Err, "CIDR notation") {
+		t.Fatalf("expected a CIDR error that is not a not-found, got %+v", dnsErr)
+	}
+}
+
+func TestDial_RetryOnEmptyDNSReportsCancellation(t *testing.T) {
+	backend := mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		return []net.IP{}, nil
+	})
+	dialer := wgnet.NewDialer(wgdns.NewResolver(backend))
+	dialer.RetryOnEmptyDNS = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, err := dialer.DialContext(ctx, "tcp", "empty.warp.local:5432")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	var dnsErr *wgnet.DNSError
+	if errors.As(err, &dnsErr) {
+		t.Fatalf("expected no DNS error for a cancelled dial, got %v", dnsErr)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := dialer.DialContext(ctx, "tcp", "empty.warp.local:5432"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
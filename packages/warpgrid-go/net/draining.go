@@ -0,0 +1,27 @@
+package net
+
+import "errors"
+
+// ErrDraining is returned, wrapped in a *net.OpError, by dials made
+// while the Dialer is draining.
+var ErrDraining = errors.New("net: dialer is draining")
+
+// StartDraining makes later Dial and DialContext calls fail with
+// ErrDraining without resolving or connecting, so a node being drained
+// opens no fresh sockets while it shuts down gracefully. Connections
+// already open, and dials already in progress, are not affected.
+// Rejected dials are not counted in Stats.
+func (d *Dialer) StartDraining() {
+	d.draining.Store(true)
+}
+
+// StopDraining lets the Dialer make new connections again after
+// StartDraining.
+func (d *Dialer) StopDraining() {
+	d.draining.Store(false)
+}
+
+// Draining reports whether the Dialer is rejecting new dials.
+func (d *Dialer) Draining() bool {
+	return d.draining.Load()
+}
@@ -0,0 +1,68 @@
+package net_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	wgnet "github.com/anthropics/warpgrid/packages/warpgrid-go/net"
+)
+
+// ── Draining tests ──────────────────────────────────────────────────
+
+func TestDial_DrainingRejectsNewDials(t *testing.T) {
+	addr, cleanup := startEchoServer(t)
+	defer cleanup()
+	_, port, _ := net.SplitHostPort(addr)
+
+	dialer := loopbackDialer()
+	existing, err := dialer.Dial("tcp", "db.warp.local:"+port)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer existing.Close()
+
+	dialer.StartDraining()
+	if !dialer.Draining() {
+		t.Fatal("expected Draining to report true after StartDraining")
+	}
+	_, err = dialer.Dial("tcp", "db.warp.local:"+port)
+	if !errors.Is(err, wgnet.ErrDraining) {
+		t.Fatalf("expected ErrDraining, got %v", err)
+	}
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) || opErr.Op != "dial" {
+		t.Fatalf("expected a dial *net.OpError, got %T", err)
+	}
+	if got := dialer.Stats().Dials; got != 1 {
+		t.Fatalf("expected the rejected dial not to be counted, got %d dials", got)
+	}
+
+	// The connection opened before draining keeps working.
+	if _, err := existing.Write([]byte("ping")); err != nil {
+		t.Fatalf("expected the existing connection to stay usable, got %v", err)
+	}
+	buf := make([]byte, 4)
+	if _, err := existing.Read(buf); err != nil || string(buf) != "ping" {
+		t.Fatalf("expected echo 'ping', got '%s' (%v)", buf, err)
+	}
+}
+
+func TestDial_StopDrainingResumesDials(t *testing.T) {
+	addr, cleanup := startEchoServer(t)
+	defer cleanup()
+	_, port, _ := net.SplitHostPort(addr)
+
+	dialer := loopbackDialer()
+	dialer.StartDraining()
+	if _, err := dialer.Dial("tcp", "db.warp.local:"+port); !errors.Is(err, wgnet.ErrDraining) {
+		t.Fatalf("expected ErrDraining, got %v", err)
+	}
+
+	dialer.StopDraining()
+	conn, err := dialer.Dial("tcp", "db.warp.local:"+port)
+	if err != nil {
+		t.Fatalf("expected the dial to succeed after StopDraining, got %v", err)
+	}
+	conn.Close()
+}
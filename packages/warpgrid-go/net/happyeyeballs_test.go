@@ -0,0 +1,173 @@
+package net_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	wgdns "github.com/anthropics/warpgrid/packages/warpgrid-go/dns"
+	wgnet "github.com/anthropics/warpgrid/packages/warpgrid-go/net"
+)
+
+// ── Happy Eyeballs test helpers ─────────────────────────────────────
+
+// trackingServer is an echo server that counts accepted connections
+// and how many of them the client has since closed.
+type trackingServer struct {
+	ln net.Listener
+
+	mu       sync.Mutex
+	accepted int
+	closed   int
+}
+
+func startTrackingServer(t *testing.T, addr string) *trackingServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("cannot listen on %s: %v", addr, err)
+	}
+	s := &trackingServer{ln: ln}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.accepted++
+			s.mu.Unlock()
+			go func(c net.Conn) {
+				defer c.Close()
+				io.Copy(c, c) // returns once the client closes
+				s.mu.Lock()
+				s.closed++
+				s.mu.Unlock()
+			}(conn)
+		}
+	}()
+	return s
+}
+
+// acceptedCount returns the number of connections accepted so far.
+func (s *trackingServer) acceptedCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.accepted
+}
+
+// waitFor polls cond until it holds or two seconds pass, reporting
+// whether it held. The servers count connections on their own
+// goroutines, so counts lag behind the client's view.
+func waitFor(cond func() bool) bool {
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return true
+}
+
+// open returns the number of accepted connections still held open.
+func (s *trackingServer) open() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.accepted - s.closed
+}
+
+// ── Happy Eyeballs tests ────────────────────────────────────────────
+
+func TestDialContext_HappyEyeballsClosesLoser(t *testing.T) {
+	first := startTrackingServer(t, "127.0.0.1:0")
+	_, port, _ := net.SplitHostPort(first.ln.Addr().String())
+	second := startTrackingServer(t, net.JoinHostPort("127.0.0.2", port))
+
+	resolver := wgdns.NewResolver(mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("127.0.0.2")}, nil
+	}))
+	d := wgnet.NewDialer(resolver)
+	// A tiny delay starts both attempts almost at once so both servers
+	// often see a connection and the loser must be torn down. The
+	// primary can still win before the fallback connects, so the test
+	// only requires that whatever the loser accepted gets closed.
+	d.FallbackDelay = time.Nanosecond
+
+	conn, err := d.DialContext(context.Background(), "tcp", net.JoinHostPort("db.warp.local", port))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	winner, loser := first, second
+	if conn.RemoteAddr().(*net.TCPAddr).IP.Equal(net.ParseIP("127.0.0.2")) {
+		winner, loser = second, first
+	}
+
+	if !waitFor(func() bool { return winner.acceptedCount() >= 1 }) {
+		t.Fatal("expected the winner to accept the connection")
+	}
+	if !waitFor(func() bool { return loser.open() == 0 }) {
+		t.Fatalf("expected losing connection to be closed, %d still open", loser.open())
+	}
+	if n := winner.open(); n != 1 {
+		t.Fatalf("expected exactly 1 open connection on the winner, got %d", n)
+	}
+
+	msg := []byte("still alive")
+	conn.Write(msg)
+	buf := make([]byte, len(msg))
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != string(msg) {
+		t.Fatalf("winning connection not usable: %q, %v", buf, err)
+	}
+}
+
+func TestDialContext_HappyEyeballsStartsNextAttemptOnFailure(t *testing.T) {
+	addr, cleanup := startEchoServer(t)
+	defer cleanup()
+	_, port, _ := net.SplitHostPort(addr)
+
+	// 127.0.0.2 has no listener on this port and refuses immediately.
+	resolver := wgdns.NewResolver(mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP("127.0.0.2"), net.ParseIP("127.0.0.1")}, nil
+	}))
+	d := wgnet.NewDialer(resolver)
+	d.FallbackDelay = time.Hour
+
+	start := time.Now()
+	conn, err := d.DialContext(context.Background(), "tcp", net.JoinHostPort("db.warp.local", port))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	conn.Close()
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("expected failover without waiting for FallbackDelay, took %v", elapsed)
+	}
+}
+
+func TestDialContext_CancelledContextAborts(t *testing.T) {
+	resolver := wgdns.NewResolver(mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("127.0.0.2")}, nil
+	}))
+	d := wgnet.NewDialer(resolver)
+	d.FallbackDelay = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := d.DialContext(ctx, "tcp", "db.warp.local:5432")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		t.Fatalf("expected *net.OpError, got %T", err)
+	}
+}
@@ -0,0 +1,97 @@
+package net
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrMaxConnsPerHost is returned, wrapped in a *net.OpError, when a dial
+// waited MaxConnsPerHostTimeout for a connection slot to the host
+// without one becoming free.
+var ErrMaxConnsPerHost = errors.New("net: timed out waiting for a free connection slot to host")
+
+// hostLimiter holds a counting semaphore per destination host for
+// Dialer.MaxConnsPerHost. A host's entry is dropped once no dial is
+// waiting for or holding one of its slots, so the map only holds hosts
+// with connections in flight.
+type hostLimiter struct {
+	mu    sync.Mutex
+	slots map[string]*hostSlots
+}
+
+// hostSlots is the semaphore for one host and the number of dials
+// waiting for or holding a slot in it.
+type hostSlots struct {
+	sem   chan struct{}
+	users int
+}
+
+// acquire takes a connection slot for host, waiting while limit slots
+// are taken until one is released, ctx is done, or timeout (if
+// positive) elapses. The semaphore for a host is sized by the limit in
+// effect when its entry is created, on a dial to a host with no other
+// dial in flight.
+func (l *hostLimiter) acquire(ctx context.Context, host string, limit int, timeout time.Duration) (release func(), err error) {
+	l.mu.Lock()
+	if l.slots == nil {
+		l.slots = make(map[string]*hostSlots)
+	}
+	hs, ok := l.slots[host]
+	if !ok {
+		hs = &hostSlots{sem: make(chan struct{}, limit)}
+		l.slots[host] = hs
+	}
+	hs.users++
+	l.mu.Unlock()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case hs.sem <- struct{}{}:
+	case <-ctx.Done():
+		l.leave(host, hs)
+		return nil, ctx.Err()
+	case <-expired:
+		l.leave(host, hs)
+		return nil, ErrMaxConnsPerHost
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-hs.sem
+			l.leave(host, hs)
+		})
+	}, nil
+}
+
+// leave records that a dial no longer waits for or holds a slot in hs,
+// dropping the host's entry when it was the last.
+func (l *hostLimiter) leave(host string, hs *hostSlots) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	hs.users--
+	if hs.users == 0 && l.slots[host] == hs {
+		delete(l.slots, host)
+	}
+}
+
+// limitedConn holds a MaxConnsPerHost slot for as long as the
+// connection is open, releasing it on the first Close.
+type limitedConn struct {
+	net.Conn
+	release func()
+}
+
+func (c *limitedConn) Close() error {
+	err := c.Conn.Close()
+	c.release()
+	return err
+}
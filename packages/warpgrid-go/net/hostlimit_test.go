@@ -0,0 +1,180 @@
+package net_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	wgdns "github.com/anthropics/warpgrid/packages/warpgrid-go/dns"
+	wgnet "github.com/anthropics/warpgrid/packages/warpgrid-go/net"
+)
+
+// ── MaxConnsPerHost tests ───────────────────────────────────────────
+
+// loopbackDialer returns a Dialer whose resolver maps every hostname
+// to 127.0.0.1.
+func loopbackDialer() *wgnet.Dialer {
+	backend := mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP("127.0.0.1")}, nil
+	})
+	return wgnet.NewDialer(wgdns.NewResolver(backend))
+}
+
+func TestDial_MaxConnsPerHostBlocksUntilClose(t *testing.T) {
+	addr, cleanup := startEchoServer(t)
+	defer cleanup()
+	_, port, _ := net.SplitHostPort(addr)
+
+	dialer := loopbackDialer()
+	dialer.MaxConnsPerHost = 2
+
+	first, err := dialer.Dial("tcp", "db.warp.local:"+port)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	second, err := dialer.Dial("tcp", "db.warp.local:"+port)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer second.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		conn, err := dialer.Dial("tcp", "db.warp.local:"+port)
+		if err == nil {
+			conn.Close()
+		}
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("expected the third dial to block at the limit, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	first.Close()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("expected the third dial to succeed once a slot freed, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("third dial still blocked after a connection closed")
+	}
+}
+
+func TestDial_MaxConnsPerHostTimeout(t *testing.T) {
+	addr, cleanup := startEchoServer(t)
+	defer cleanup()
+	_, port, _ := net.SplitHostPort(addr)
+
+	dialer := loopbackDialer()
+	dialer.MaxConnsPerHost = 1
+	dialer.MaxConnsPerHostTimeout = 20 * time.Millisecond
+
+	conn, err := dialer.Dial("tcp", "db.warp.local:"+port)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	_, err = dialer.Dial("tcp", "db.warp.local:"+port)
+	if !errors.Is(err, wgnet.ErrMaxConnsPerHost) {
+		t.Fatalf("expected ErrMaxConnsPerHost, got %v", err)
+	}
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		t.Fatalf("expected *net.OpError, got %T", err)
+	}
+}
+
+func TestDial_MaxConnsPerHostRespectsContext(t *testing.T) {
+	addr, cleanup := startEchoServer(t)
+	defer cleanup()
+	_, port, _ := net.SplitHostPort(addr)
+
+	dialer := loopbackDialer()
+	dialer.MaxConnsPerHost = 1
+
+	conn, err := dialer.Dial("tcp", "db.warp.local:"+port)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = dialer.DialContext(ctx, "tcp", "db.warp.local:"+port)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestDial_MaxConnsPerHostIsPerHost(t *testing.T) {
+	addr, cleanup := startEchoServer(t)
+	defer cleanup()
+	_, port, _ := net.SplitHostPort(addr)
+
+	dialer := loopbackDialer()
+	dialer.MaxConnsPerHost = 1
+	dialer.MaxConnsPerHostTimeout = 20 * time.Millisecond
+	dialer.CountBytes = true
+
+	a, err := dialer.Dial("tcp", "a.warp.local:"+port)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer a.Close()
+	b, err := dialer.Dial("tcp", "b.warp.local:"+port)
+	if err != nil {
+		t.Fatalf("expected a separate limit for another host, got %v", err)
+	}
+	if _, _, ok := wgnet.ConnStats(b); !ok {
+		t.Fatal("expected CountBytes to still apply to limited connections")
+	}
+
+	// Closing twice must release the slot only once.
+	b.Close()
+	b.Close()
+	c, err := dialer.Dial("tcp", "b.warp.local:"+port)
+	if err != nil {
+		t.Fatalf("expected the slot freed by Close, got %v", err)
+	}
+	defer c.Close()
+	if _, err := dialer.Dial("tcp", "b.warp.local:"+port); !errors.Is(err, wgnet.ErrMaxConnsPerHost) {
+		t.Fatalf("expected the limit still enforced after a double Close, got %v", err)
+	}
+}
+
+func TestDial_MaxConnsPerHostForgetsIdleHosts(t *testing.T) {
+	addr, cleanup := startEchoServer(t)
+	defer cleanup()
+	_, port, _ := net.SplitHostPort(addr)
+
+	dialer := loopbackDialer()
+	dialer.MaxConnsPerHost = 1
+	dialer.MaxConnsPerHostTimeout = 20 * time.Millisecond
+
+	conn, err := dialer.Dial("tcp", "db.warp.local:"+port)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	conn.Close()
+
+	// With no connection left open the host's semaphore is dropped, so
+	// the next dial creates a fresh one sized by the current limit.
+	dialer.MaxConnsPerHost = 2
+	first, err := dialer.Dial("tcp", "db.warp.local:"+port)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer first.Close()
+	second, err := dialer.Dial("tcp", "db.warp.local:"+port)
+	if err != nil {
+		t.Fatalf("expected a second slot once the idle host was forgotten, got %v", err)
+	}
+	second.Close()
+}
@@ -0,0 +1,53 @@
+package http
+
+import "strings"
+
+// AbsoluteURL returns path as an absolute URL on the host the client
+// addressed, for links and redirects that must be fully qualified, such
+// as a pagination "next" link. path may carry a query string.
+//
+// The scheme is taken from X-Forwarded-Proto when it is "http" or
+// "https", else from r.Scheme, defaulting to "http". The host is taken
+// from X-Forwarded-Host, else r.Host, else r.URL.Host. Only the first
+// value of a comma-separated forwarded header is used, matching the
+// proxy nearest the client. A port that is the default for the scheme
+// is dropped. Forwarded headers are trusted as sent; deploy behind a
+// proxy that sets them.
+func AbsoluteURL(r *Request, path string) string {
+	scheme := strings.ToLower(firstForwarded(r.Header, "X-Forwarded-Proto"))
+	if scheme != "http" && scheme != "https" {
+		scheme = strings.ToLower(r.Scheme)
+	}
+	if scheme == "" {
+		scheme = "http"
+	}
+
+	host := firstForwarded(r.Header, "X-Forwarded-Host")
+	if host == "" {
+		host = r.Host
+	}
+	if host == "" && r.URL != nil {
+		host = r.URL.Host
+	}
+	switch scheme {
+	case "http":
+		host = strings.TrimSuffix(host, ":80")
+	case "https":
+		host = strings.TrimSuffix(host, ":443")
+	}
+
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return scheme + "://" + host + path
+}
+
+// firstForwarded returns the first comma-separated element of header
+// name, trimmed of spaces.
+func firstForwarded(h Header, name string) string {
+	v := h.Get(name)
+	if i := strings.IndexByte(v, ','); i >= 0 {
+		v = v[:i]
+	}
+	return strings.TrimSpace(v)
+}
@@ -0,0 +1,84 @@
+package http_test
+
+import (
+	"testing"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/net/http"
+)
+
+// ── AbsoluteURL tests ───────────────────────────────────────────────
+
+// requestFor runs a WIT request through a handler and returns the
+// overlay request it saw.
+func requestFor(t *testing.T, uri string, headers ...wghttp.WitHttpHeader) *wghttp.Request {
+	t.Helper()
+	var got *wghttp.Request
+	handler := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		got = r
+	})
+	wghttp.HandleRequestWith(handler, wghttp.MarshalRequest(wghttp.WitHttpRequest{
+		Method:  "GET",
+		URI:     uri,
+		Headers: headers,
+	}))
+	if got == nil {
+		t.Fatal("handler was not called")
+	}
+	return got
+}
+
+func TestAbsoluteURL_HTTP(t *testing.T) {
+	r := requestFor(t, "/items", wghttp.WitHttpHeader{Name: "Host", Value: "api.example.com"})
+
+	if got := wghttp.AbsoluteURL(r, "/items/2"); got != "http://api.example.com/items/2" {
+		t.Fatalf("expected http://api.example.com/items/2, got %s", got)
+	}
+}
+
+func TestAbsoluteURL_HTTPSFromRequestURI(t *testing.T) {
+	r := requestFor(t, "https://api.example.com:443/items")
+
+	if r.Scheme != "https" || r.Host != "api.example.com:443" {
+		t.Fatalf("expected scheme https and host api.example.com:443, got %q %q", r.Scheme, r.Host)
+	}
+	if got := wghttp.AbsoluteURL(r, "/items/2"); got != "https://api.example.com/items/2" {
+		t.Fatalf("expected default port dropped, got %s", got)
+	}
+}
+
+func TestAbsoluteURL_KeepsNonDefaultPort(t *testing.T) {
+	r := requestFor(t, "/", wghttp.WitHttpHeader{Name: "Host", Value: "localhost:8080"})
+
+	if got := wghttp.AbsoluteURL(r, "/"); got != "http://localhost:8080/" {
+		t.Fatalf("expected http://localhost:8080/, got %s", got)
+	}
+}
+
+func TestAbsoluteURL_ForwardedHost(t *testing.T) {
+	r := requestFor(t, "/items",
+		wghttp.WitHttpHeader{Name: "Host", Value: "10.0.0.5:8080"},
+		wghttp.WitHttpHeader{Name: "X-Forwarded-Host", Value: "shop.example.com, edge.internal"},
+		wghttp.WitHttpHeader{Name: "X-Forwarded-Proto", Value: "https"},
+	)
+
+	if got := wghttp.AbsoluteURL(r, "/cart"); got != "https://shop.example.com/cart" {
+		t.Fatalf("expected https://shop.example.com/cart, got %s", got)
+	}
+}
+
+func TestAbsoluteURL_PathWithQuery(t *testing.T) {
+	r := requestFor(t, "/items", wghttp.WitHttpHeader{Name: "Host", Value: "api.example.com"})
+
+	if got := wghttp.AbsoluteURL(r, "items?page=3&size=20"); got != "http://api.example.com/items?page=3&size=20" {
+		t.Fatalf("expected query preserved, got %s", got)
+	}
+}
+
+func TestAbsoluteURL_IgnoresBogusForwardedProto(t *testing.T) {
+	r := requestFor(t, "/", wghttp.WitHttpHeader{Name: "Host", Value: "api.example.com"},
+		wghttp.WitHttpHeader{Name: "X-Forwarded-Proto", Value: "javascript"})
+
+	if got := wghttp.AbsoluteURL(r, "/"); got != "http://api.example.com/" {
+		t.Fatalf("expected unknown forwarded scheme ignored, got %s", got)
+	}
+}
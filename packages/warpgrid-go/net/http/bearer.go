@@ -0,0 +1,72 @@
+package http
+
+import "strings"
+
+// BearerTokenOptions enables the places BearerTokenFrom looks for a
+// token besides the Authorization header. Both are off by default:
+// tokens in URLs end up in access logs, proxies, and browser history,
+// and cookies are sent on cross-site requests, so each should be
+// accepted only by the routes that need it, such as an EventSource
+// endpoint whose client cannot set headers.
+type BearerTokenOptions struct {
+	// QueryParam accepts the token from the access_token query
+	// parameter (RFC 6750 section 2.3).
+	QueryParam bool
+
+	// Cookie names a cookie to accept the token from. Empty accepts
+	// none.
+	Cookie string
+}
+
+// BearerToken returns the token from an "Authorization: Bearer <token>"
+// header (RFC 6750 section 2.1). The scheme is matched
+// case-insensitively. ok is false when there is no such header or the
+// token is empty.
+func BearerToken(r *Request) (token string, ok bool) {
+	scheme, token, found := strings.Cut(strings.TrimSpace(r.Header.Get("Authorization")), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// BearerTokenFrom returns the first token found, trying in order the
+// Authorization header, then, if enabled in opts, the access_token
+// query parameter and the named cookie. A source that is present but
+// empty is skipped.
+func BearerTokenFrom(r *Request, opts BearerTokenOptions) (token string, ok bool) {
+	if token, ok := BearerToken(r); ok {
+		return token, true
+	}
+	if opts.QueryParam && r.URL != nil {
+		if token := r.URL.Query().Get("access_token"); token != "" {
+			return token, true
+		}
+	}
+	if opts.Cookie != "" {
+		if token := cookieValue(r.Header, opts.Cookie); token != "" {
+			return token, true
+		}
+	}
+	return "", false
+}
+
+// cookieValue returns the value of the first cookie called name in the
+// Cookie headers of h, with surrounding double quotes removed, or ""
+// if there is none.
+func cookieValue(h Header, name string) string {
+	for _, line := range h.Values("Cookie") {
+		for _, pair := range strings.Split(line, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok || key != name {
+				continue
+			}
+			if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+				value = value[1 : len(value)-1]
+			}
+			return value
+		}
+	}
+	return ""
+}
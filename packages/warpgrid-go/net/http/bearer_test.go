@@ -0,0 +1,94 @@
+package http_test
+
+import (
+	"testing"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/net/http"
+)
+
+// ── BearerToken tests ───────────────────────────────────────────────
+
+// bearerRequest builds a GET for target with the given Authorization
+// and Cookie headers, each omitted when empty.
+func bearerRequest(target, authorization, cookie string) *wghttp.Request {
+	req := wghttp.NewRequest(wghttp.MethodGet, target, nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	if cookie != "" {
+		req.Header.Set("Cookie", cookie)
+	}
+	return req
+}
+
+func TestBearerToken_Header(t *testing.T) {
+	for _, auth := range []string{"Bearer abc.def", "bearer abc.def", "BEARER   abc.def "} {
+		token, ok := wghttp.BearerToken(bearerRequest("/", auth, ""))
+		if !ok || token != "abc.def" {
+			t.Fatalf("%q: expected token 'abc.def', got '%s' (ok=%v)", auth, token, ok)
+		}
+	}
+}
+
+func TestBearerToken_RejectsOtherSchemesAndEmpty(t *testing.T) {
+	for _, auth := range []string{"", "Basic dXNlcjpwYXNz", "Bearer", "Bearer   ", "Bearerabc"} {
+		if token, ok := wghttp.BearerToken(bearerRequest("/", auth, "")); ok {
+			t.Fatalf("%q: expected no token, got '%s'", auth, token)
+		}
+	}
+}
+
+func TestBearerTokenFrom_FallbacksAreOptIn(t *testing.T) {
+	req := bearerRequest("/events?access_token=from-query", "", "session=from-cookie")
+
+	if token, ok := wghttp.BearerTokenFrom(req, wghttp.BearerTokenOptions{}); ok {
+		t.Fatalf("expected no token without fallbacks, got '%s'", token)
+	}
+	if token, ok := wghttp.BearerToken(req); ok {
+		t.Fatalf("expected BearerToken to ignore query and cookie, got '%s'", token)
+	}
+}
+
+func TestBearerTokenFrom_QueryParam(t *testing.T) {
+	req := bearerRequest("/events?access_token=from%2Bquery", "", "")
+
+	token, ok := wghttp.BearerTokenFrom(req, wghttp.BearerTokenOptions{QueryParam: true})
+	if !ok || token != "from+query" {
+		t.Fatalf("expected token 'from+query', got '%s' (ok=%v)", token, ok)
+	}
+}
+
+func TestBearerTokenFrom_Cookie(t *testing.T) {
+	req := bearerRequest("/events", "", `theme=dark; session="from-cookie"; other=1`)
+
+	token, ok := wghttp.BearerTokenFrom(req, wghttp.BearerTokenOptions{Cookie: "session"})
+	if !ok || token != "from-cookie" {
+		t.Fatalf("expected token 'from-cookie', got '%s' (ok=%v)", token, ok)
+	}
+	if token, ok := wghttp.BearerTokenFrom(req, wghttp.BearerTokenOptions{Cookie: "missing"}); ok {
+		t.Fatalf("expected no token for an absent cookie, got '%s'", token)
+	}
+}
+
+func TestBearerTokenFrom_Precedence(t *testing.T) {
+	opts := wghttp.BearerTokenOptions{QueryParam: true, Cookie: "session"}
+	tests := []struct {
+		name   string
+		target string
+		auth   string
+		cookie string
+		want   string
+	}{
+		{"header wins", "/?access_token=q", "Bearer h", "session=c", "h"},
+		{"query before cookie", "/?access_token=q", "", "session=c", "q"},
+		{"cookie last", "/", "", "session=c", "c"},
+		{"non-bearer header falls through", "/?access_token=q", "Basic dXNlcjpwYXNz", "", "q"},
+		{"empty query falls through", "/?access_token=", "", "session=c", "c"},
+	}
+	for _, tt := range tests {
+		token, ok := wghttp.BearerTokenFrom(bearerRequest(tt.target, tt.auth, tt.cookie), opts)
+		if !ok || token != tt.want {
+			t.Fatalf("%s: expected token '%s', got '%s' (ok=%v)", tt.name, tt.want, token, ok)
+		}
+	}
+}
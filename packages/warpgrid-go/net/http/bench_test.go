@@ -0,0 +1,108 @@
+package http_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/net/http"
+)
+
+// ── Hot path benchmarks ─────────────────────────────────────────────
+//
+// These cover the request path a host call takes: decode the wire
+// frame, convert it, serve it, capture the response, and encode it.
+// Run with:
+//
+//	go test ./net/http -run '^$' -bench . -benchmem
+//
+// Allocation baseline for benchRequest (allocs/op are stable across
+// machines, unlike ns/op): MarshalRequest 1, UnmarshalRequest 3 (16
+// before the small-frame fast path), HandleRequestWith 24 (37 before).
+
+// benchRequest is a typical small API request: a handful of headers
+// and a short JSON body, well under 4KB on the wire.
+var benchRequest = wghttp.WitHttpRequest{
+	Method: "POST",
+	URI:    "/api/v1/orders?expand=items",
+	Headers: []wghttp.WitHttpHeader{
+		{Name: "Host", Value: "orders.warp.local"},
+		{Name: "Content-Type", Value: "application/json"},
+		{Name: "Accept", Value: "application/json"},
+		{Name: "User-Agent", Value: "warpgrid-bench/1.0"},
+		{Name: "X-Request-Id", Value: "6f1c2d3e-4b5a-4c6d-8e7f-9a0b1c2d3e4f"},
+		{Name: "Authorization", Value: "Bearer eyJhbGciOiJIUzI1NiJ9.e30.sig"},
+	},
+	Body: []byte(`{"sku":"WG-1001","quantity":2}`),
+}
+
+func BenchmarkMarshalRequest(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		wghttp.MarshalRequest(benchRequest)
+	}
+}
+
+func BenchmarkUnmarshalRequest(b *testing.B) {
+	data := wghttp.MarshalRequest(benchRequest)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		wghttp.UnmarshalRequest(data)
+	}
+}
+
+func BenchmarkHandleRequestWith(b *testing.B) {
+	data := wghttp.MarshalRequest(benchRequest)
+	handler := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		wghttp.HandleRequestWith(handler, data)
+	}
+}
+
+// ── Fast path correctness ───────────────────────────────────────────
+
+// TestUnmarshalRequest_FastPathMatchesGeneralPath checks frames on both
+// sides of the 4KB fast-path threshold against the streaming decoder,
+// which shares no code with either path, and against the original
+// bytes.
+func TestUnmarshalRequest_FastPathMatchesGeneralPath(t *testing.T) {
+	for _, bodySize := range []int{0, 1, 3900, 4096, 8192} {
+		req := benchRequest
+		req.Body = bytes.Repeat([]byte("b"), bodySize)
+		data := wghttp.MarshalRequest(req)
+
+		fast := wghttp.UnmarshalRequest(data)
+		general, err := wghttp.DecodeRequest(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("body %d: unexpected decode error: %v", bodySize, err)
+		}
+
+		if fast.Method != general.Method || fast.URI != general.URI || !bytes.Equal(fast.Body, general.Body) {
+			t.Fatalf("body %d: expected fields to match the general decoder", bodySize)
+		}
+		if !reflect.DeepEqual(fast.Headers, general.Headers) {
+			t.Fatalf("body %d: expected headers %v, got %v", bodySize, general.Headers, fast.Headers)
+		}
+		if !bytes.Equal(wghttp.MarshalRequest(fast), data) {
+			t.Fatalf("body %d: expected re-encoding to reproduce the frame byte-for-byte", bodySize)
+		}
+	}
+}
+
+func TestUnmarshalRequest_FastPathDoesNotAliasFrame(t *testing.T) {
+	data := wghttp.MarshalRequest(benchRequest)
+	req := wghttp.UnmarshalRequest(data)
+
+	for i := range data {
+		data[i] = 0
+	}
+
+	if req.Method != "POST" || req.Headers[0].Value != "orders.warp.local" || string(req.Body) != `{"sku":"WG-1001","quantity":2}` {
+		t.Fatal("expected decoded request to be independent of the frame buffer")
+	}
+}
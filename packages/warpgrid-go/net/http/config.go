@@ -0,0 +1,120 @@
+package http
+
+import "time"
+
+// Config gathers the overlay's server settings so they can be set, and
+// saved and restored, in one place. Each field mirrors the package
+// variable of the same name, which remains the live setting: Configure
+// assigns them all and CurrentConfig reads them back, so code that sets
+// the variables directly keeps working.
+type Config struct {
+	// MaxHeaderBytes caps inbound request headers; see MaxHeaderBytes.
+	MaxHeaderBytes int
+
+	// MaxResponseBytes caps response bodies; see MaxResponseBytes.
+	MaxResponseBytes int
+
+	// MaxConcurrentRequests limits requests in flight; see
+	// MaxConcurrentRequests.
+	MaxConcurrentRequests int
+
+	// ProductionMode hides internal detail in error responses; see
+	// ProductionMode.
+	ProductionMode bool
+
+	// DebugRouting marks mux-generated 404s; see DebugRouting.
+	DebugRouting bool
+
+	// DeterministicHeaders sorts response headers by name; see
+	// DeterministicHeaders.
+	DeterministicHeaders bool
+
+	// DisableContentSniffing leaves a missing Content-Type unset; see
+	// DisableContentSniffing.
+	DisableContentSniffing bool
+
+	// ErrorRenderer writes mux-generated errors; nil means
+	// TextErrorRenderer. See ErrorRenderer.
+	ErrorRenderer func(w ResponseWriter, r *Request, message string, code int)
+
+	// PanicHandler answers requests whose handler panicked; nil means
+	// DefaultPanicHandler. See PanicHandler.
+	PanicHandler func(w ResponseWriter, r *Request, recovered any)
+
+	// ErrorPageRenderer renders HTML error pages for clients that
+	// prefer them; nil turns them off. See SetErrorPageRenderer, which
+	// sets the same setting.
+	ErrorPageRenderer func(status int, message string) (contentType string, body []byte)
+
+	// InterceptorRejectStatus answers requests a request interceptor
+	// rejects; zero means 400. See InterceptorRejectStatus.
+	InterceptorRejectStatus int
+
+	// Clock supplies the time for ServerTiming, dated headers, and idle
+	// tracking; nil means time.Now. See Clock.
+	Clock func() time.Time
+}
+
+// DefaultConfig returns the settings the package starts with.
+func DefaultConfig() Config {
+	return Config{
+		MaxHeaderBytes:          DefaultMaxHeaderBytes,
+		ErrorRenderer:           TextErrorRenderer,
+		PanicHandler:            DefaultPanicHandler,
+		InterceptorRejectStatus: StatusBadRequest,
+	}
+}
+
+// Configure applies c to the package settings, replacing all of them;
+// fields left at their zero value get their defaults. Configure with
+// DefaultConfig restores the initial state, and
+//
+//	defer http.Configure(http.CurrentConfig())
+//
+// lets a test change settings without leaking them into other tests.
+// Like the variables it sets, it should be called before serving
+// begins.
+func Configure(c Config) {
+	if c.MaxHeaderBytes <= 0 {
+		c.MaxHeaderBytes = DefaultMaxHeaderBytes
+	}
+	if c.ErrorRenderer == nil {
+		c.ErrorRenderer = TextErrorRenderer
+	}
+	if c.PanicHandler == nil {
+		c.PanicHandler = DefaultPanicHandler
+	}
+	if c.InterceptorRejectStatus == 0 {
+		c.InterceptorRejectStatus = StatusBadRequest
+	}
+	MaxHeaderBytes = c.MaxHeaderBytes
+	MaxResponseBytes = c.MaxResponseBytes
+	MaxConcurrentRequests = c.MaxConcurrentRequests
+	ProductionMode = c.ProductionMode
+	DebugRouting = c.DebugRouting
+	DeterministicHeaders = c.DeterministicHeaders
+	DisableContentSniffing = c.DisableContentSniffing
+	ErrorRenderer = c.ErrorRenderer
+	PanicHandler = c.PanicHandler
+	errorPageRenderer = c.ErrorPageRenderer
+	InterceptorRejectStatus = c.InterceptorRejectStatus
+	Clock = c.Clock
+}
+
+// CurrentConfig returns the settings currently in effect.
+func CurrentConfig() Config {
+	return Config{
+		MaxHeaderBytes:          MaxHeaderBytes,
+		MaxResponseBytes:        MaxResponseBytes,
+		MaxConcurrentRequests:   MaxConcurrentRequests,
+		ProductionMode:          ProductionMode,
+		DebugRouting:            DebugRouting,
+		DeterministicHeaders:    DeterministicHeaders,
+		DisableContentSniffing:  DisableContentSniffing,
+		ErrorRenderer:           ErrorRenderer,
+		PanicHandler:            PanicHandler,
+		ErrorPageRenderer:       errorPageRenderer,
+		InterceptorRejectStatus: InterceptorRejectStatus,
+		Clock:                   Clock,
+	}
+}
@@ -0,0 +1,87 @@
+package http_test
+
+import (
+	"bytes"
+	"testing"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/net/http"
+)
+
+// ── Config tests ────────────────────────────────────────────────────
+
+func TestConfigure_SettingsTakeEffect(t *testing.T) {
+	defer wghttp.Configure(wghttp.CurrentConfig())
+
+	wghttp.Configure(wghttp.Config{
+		MaxResponseBytes: 4,
+		ProductionMode:   true,
+		ErrorRenderer:    wghttp.JSONErrorRenderer,
+	})
+
+	if wghttp.MaxResponseBytes != 4 || !wghttp.ProductionMode {
+		t.Fatalf("expected package settings updated, got MaxResponseBytes=%d ProductionMode=%v",
+			wghttp.MaxResponseBytes, wghttp.ProductionMode)
+	}
+
+	reqBytes := wghttp.MarshalRequest(wghttp.WitHttpRequest{Method: "GET", URI: "/"})
+
+	big := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		w.Write([]byte("hello"))
+	})
+	resp := wghttp.UnmarshalResponse(wghttp.HandleRequestWith(big, reqBytes))
+	if resp.Status != wghttp.StatusInternalServerError {
+		t.Fatalf("expected MaxResponseBytes to apply, got %d", resp.Status)
+	}
+	if !bytes.HasPrefix(resp.Body, []byte(`{"error":`)) {
+		t.Fatalf("expected ErrorRenderer to apply, got '%s'", resp.Body)
+	}
+
+	boom := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		panic("secret detail")
+	})
+	resp = wghttp.UnmarshalResponse(wghttp.HandleRequestWith(boom, reqBytes))
+	if bytes.Contains(resp.Body, []byte("secret detail")) {
+		t.Fatalf("expected ProductionMode to hide the panic value, got '%s'", resp.Body)
+	}
+}
+
+func TestConfigure_FreshConfigResetsState(t *testing.T) {
+	defer wghttp.Configure(wghttp.CurrentConfig())
+
+	wghttp.MaxHeaderBytes = 10
+	wghttp.MaxConcurrentRequests = 3
+	wghttp.DebugRouting = true
+	wghttp.PanicHandler = nil
+
+	wghttp.Configure(wghttp.DefaultConfig())
+
+	got := wghttp.CurrentConfig()
+	if got.MaxHeaderBytes != wghttp.DefaultMaxHeaderBytes || got.MaxConcurrentRequests != 0 || got.DebugRouting {
+		t.Fatalf("expected defaults restored, got %+v", got)
+	}
+	if got.PanicHandler == nil || got.ErrorRenderer == nil {
+		t.Fatal("expected default PanicHandler and ErrorRenderer restored")
+	}
+}
+
+func TestConfigure_ZeroConfigUsesDefaults(t *testing.T) {
+	defer wghttp.Configure(wghttp.CurrentConfig())
+
+	wghttp.ProductionMode = true
+	wghttp.Configure(wghttp.Config{})
+
+	got := wghttp.CurrentConfig()
+	if got.ProductionMode || got.MaxHeaderBytes != wghttp.DefaultMaxHeaderBytes {
+		t.Fatalf("expected zero Config to mean defaults, got %+v", got)
+	}
+}
+
+func TestCurrentConfig_ReflectsPackageVariables(t *testing.T) {
+	defer wghttp.Configure(wghttp.CurrentConfig())
+
+	wghttp.MaxResponseBytes = 123
+
+	if got := wghttp.CurrentConfig().MaxResponseBytes; got != 123 {
+		t.Fatalf("expected CurrentConfig to read the package variable, got %d", got)
+	}
+}
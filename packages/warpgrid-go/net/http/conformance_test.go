@@ -0,0 +1,334 @@
+// Under TinyGo's -overlay, "net/http" resolves to this package itself,
+// so the comparison only builds with the standard toolchain.
+
+//go:build !tinygo
+
+package http_test
+
+import (
+	"bytes"
+	"io"
+	"log"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/net/http"
+	"github.com/anthropics/warpgrid/packages/warpgrid-go/net/http/wghttptest"
+)
+
+// ── net/http conformance tests ──────────────────────────────────────
+//
+// Each case runs the same handler logic behind a net/http server (via
+// httptest) and behind the overlay (via the WIT round-trip) and
+// requires the same status, body, and headers. Headers the host or the
+// stdlib transport owns are not compared: Date, the framing headers
+// Content-Length and Transfer-Encoding (the host frames the body), and
+// Connection.
+//
+// Known, intended divergences are left out of the matrix:
+//   - Content-Type sniffing: the overlay's DetectContentType recognizes
+//     JSON, which net/http reports as text/plain.
+//   - Header changes after WriteHeader or Write still reach the client,
+//     since the overlay sends the response after the handler returns.
+//   - HEAD requests only reach GET handlers when ServeMux.AutoHead is
+//     set, where net/http always routes them; the overlay muxes here
+//     set it.
+
+// conformanceWriter is the subset of a ResponseWriter the cases use,
+// over either implementation.
+type conformanceWriter struct {
+	set         func(name, value string)
+	add         func(name, value string)
+	writeHeader func(code int)
+	write       func(p []byte) (int, error)
+}
+
+func (w conformanceWriter) Write(p []byte) (int, error) { return w.write(p) }
+
+// conformanceRequest is the subset of a Request the cases read.
+type conformanceRequest struct {
+	Method string
+	Body   io.Reader
+}
+
+type conformanceCase struct {
+	name   string
+	method string
+	target string
+	body   string
+
+	// patterns, if set, are registered on a ServeMux of each kind with
+	// a handler that writes its own pattern; otherwise serve handles
+	// every request.
+	patterns []string
+	serve    func(w conformanceWriter, r conformanceRequest)
+}
+
+// observed is the comparable part of a response.
+type observed struct {
+	status int
+	header map[string][]string
+	body   string
+}
+
+// ignoredHeaders are owned by the transport or host, not the handler.
+var ignoredHeaders = map[string]bool{
+	"Date":              true,
+	"Content-Length":    true,
+	"Transfer-Encoding": true,
+	"Connection":        true,
+}
+
+func comparableHeader(h map[string][]string) map[string][]string {
+	out := make(map[string][]string)
+	for name, values := range h {
+		if !ignoredHeaders[name] {
+			out[name] = values
+		}
+	}
+	return out
+}
+
+func stdHandler(c conformanceCase) stdhttp.Handler {
+	if len(c.patterns) > 0 {
+		mux := stdhttp.NewServeMux()
+		for _, p := range c.patterns {
+			mux.HandleFunc(p, func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+				io.WriteString(w, p)
+			})
+		}
+		return mux
+	}
+	return stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		c.serve(conformanceWriter{
+			set:         w.Header().Set,
+			add:         w.Header().Add,
+			writeHeader: w.WriteHeader,
+			write:       w.Write,
+		}, conformanceRequest{Method: r.Method, Body: r.Body})
+	})
+}
+
+func overlayHandler(c conformanceCase) wghttp.Handler {
+	if len(c.patterns) > 0 {
+		mux := wghttp.NewServeMux()
+		mux.AutoHead = true
+		for _, p := range c.patterns {
+			mux.HandleFunc(p, func(w wghttp.ResponseWriter, r *wghttp.Request) {
+				io.WriteString(w, p)
+			})
+		}
+		return mux
+	}
+	return wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		c.serve(conformanceWriter{
+			set:         w.Header().Set,
+			add:         w.Header().Add,
+			writeHeader: w.WriteHeader,
+			write:       w.Write,
+		}, conformanceRequest{Method: r.Method, Body: r.Body})
+	})
+}
+
+func observeStd(t *testing.T, c conformanceCase) observed {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(stdHandler(c))
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.Start()
+	defer srv.Close()
+
+	req, err := stdhttp.NewRequest(c.method, srv.URL+c.target, strings.NewReader(c.body))
+	if err != nil {
+		t.Fatalf("building request: %v", err)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("net/http request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return observed{status: resp.StatusCode, header: comparableHeader(resp.Header), body: string(body)}
+}
+
+func observeOverlay(c conformanceCase) observed {
+	resp := wghttptest.Do(overlayHandler(c), c.method, c.target, nil, []byte(c.body))
+	header := wghttptest.ResponseHeader(resp)
+	return observed{status: int(resp.Status), header: comparableHeader(header), body: string(resp.Body)}
+}
+
+var conformanceCases = []conformanceCase{
+	{
+		name:   "no writes defaults to 200",
+		method: "GET", target: "/",
+		serve: func(w conformanceWriter, r conformanceRequest) {},
+	},
+	{
+		name:   "write without WriteHeader sends 200",
+		method: "GET", target: "/",
+		serve: func(w conformanceWriter, r conformanceRequest) {
+			w.set("Content-Type", "text/plain")
+			io.WriteString(w, "hello")
+		},
+	},
+	{
+		name:   "only the first WriteHeader counts",
+		method: "POST", target: "/",
+		serve: func(w conformanceWriter, r conformanceRequest) {
+			w.writeHeader(201)
+			w.writeHeader(500)
+		},
+	},
+	{
+		name:   "WriteHeader after Write is ignored",
+		method: "GET", target: "/",
+		serve: func(w conformanceWriter, r conformanceRequest) {
+			w.set("Content-Type", "text/plain")
+			io.WriteString(w, "body")
+			w.writeHeader(404)
+		},
+	},
+	{
+		name:   "header names are canonicalized",
+		method: "GET", target: "/",
+		serve: func(w conformanceWriter, r conformanceRequest) {
+			w.set("x-request-id", "abc")
+			w.add("CACHE-CONTROL", "no-store")
+			w.writeHeader(204)
+		},
+	},
+	{
+		name:   "multi-value headers keep their order",
+		method: "GET", target: "/",
+		serve: func(w conformanceWriter, r conformanceRequest) {
+			w.add("Vary", "Accept")
+			w.add("Vary", "Accept-Encoding")
+			w.add("Set-Cookie", "a=1")
+			w.add("Set-Cookie", "b=2")
+		},
+	},
+	{
+		name:   "empty body with explicit status",
+		method: "DELETE", target: "/items/1",
+		serve: func(w conformanceWriter, r conformanceRequest) {
+			w.writeHeader(202)
+			w.write(nil)
+		},
+	},
+	{
+		name:   "large body is sent whole",
+		method: "GET", target: "/",
+		serve: func(w conformanceWriter, r conformanceRequest) {
+			w.set("Content-Type", "application/octet-stream")
+			chunk := bytes.Repeat([]byte("0123456789abcdef"), 4096)
+			for i := 0; i < 16; i++ {
+				w.write(chunk)
+			}
+		},
+	},
+	{
+		name:   "missing Content-Type is sniffed",
+		method: "GET", target: "/",
+		serve: func(w conformanceWriter, r conformanceRequest) {
+			io.WriteString(w, "<!DOCTYPE html><title>x</title>")
+		},
+	},
+	{
+		name:   "request body reaches the handler",
+		method: "PUT", target: "/echo",
+		body: strings.Repeat("payload-", 1000),
+		serve: func(w conformanceWriter, r conformanceRequest) {
+			// net/http may stop delivering the request body once the
+			// response starts, so read it all first.
+			body, _ := io.ReadAll(r.Body)
+			w.set("Content-Type", "text/plain")
+			w.write(body)
+		},
+	},
+	{
+		name:   "method dispatch picks the method pattern",
+		method: "POST", target: "/items",
+		patterns: []string{"GET /items", "POST /items", "/other"},
+	},
+	{
+		name:   "method dispatch falls back to a method-less pattern",
+		method: "PATCH", target: "/other",
+		patterns: []string{"GET /items", "POST /items", "/other"},
+	},
+	{
+		name:   "unknown path is 404",
+		method: "GET", target: "/missing",
+		patterns: []string{"GET /items"},
+	},
+	{
+		name:   "unregistered method is 405",
+		method: "DELETE", target: "/items",
+		patterns: []string{"GET /items", "POST /items"},
+	},
+	{
+		name:   "HEAD is served by the GET pattern",
+		method: "HEAD", target: "/items",
+		patterns: []string{"GET /items"},
+	},
+	{
+		name:   "prefix pattern matches deeper paths",
+		method: "GET", target: "/static/css/app.css",
+		patterns: []string{"/static/", "/"},
+	},
+}
+
+func TestConformance_MatchesNetHTTP(t *testing.T) {
+	for _, c := range conformanceCases {
+		t.Run(c.name, func(t *testing.T) {
+			want := observeStd(t, c)
+			got := observeOverlay(c)
+
+			if got.status != want.status {
+				t.Fatalf("expected status %d, got %d", want.status, got.status)
+			}
+			if got.body != want.body {
+				t.Fatalf("expected body %q, got %q", abbreviate(want.body), abbreviate(got.body))
+			}
+			if !sameHeader(got.header, want.header) {
+				t.Fatalf("expected headers %v, got %v", want.header, got.header)
+			}
+		})
+	}
+}
+
+func sameHeader(a, b map[string][]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, values := range a {
+		other, ok := b[name]
+		if !ok || len(other) != len(values) {
+			return false
+		}
+		if name == "Allow" {
+			// The order of methods in Allow is unspecified.
+			values, other = sortedCopy(values), sortedCopy(other)
+		}
+		for i := range values {
+			if values[i] != other[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func sortedCopy(values []string) []string {
+	out := append([]string(nil), values...)
+	sort.Strings(out)
+	return out
+}
+
+func abbreviate(s string) string {
+	if len(s) > 64 {
+		return s[:64] + "..."
+	}
+	return s
+}
@@ -0,0 +1,203 @@
+package http
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOptions configures the CORS middleware.
+type CORSOptions struct {
+	// AllowedOrigins lists the origins allowed to make cross-origin
+	// requests, such as "https://app.example.com". "*" allows any
+	// origin, and a single "*" in the host, as in
+	// "https://*.example.com", allows any subdomain. Empty allows none.
+	AllowedOrigins []string
+
+	// AllowedMethods lists the methods a preflight may ask for. Empty
+	// means GET, HEAD, and POST.
+	AllowedMethods []string
+
+	// AllowedHeaders lists the request headers a preflight may ask for,
+	// compared case-insensitively. "*" allows whatever is asked.
+	AllowedHeaders []string
+
+	// ExposedHeaders lists the response headers scripts may read beyond
+	// the CORS-safelisted ones.
+	ExposedHeaders []string
+
+	// AllowCredentials lets requests carry cookies and HTTP
+	// authentication. It cannot be combined with the "*" origin.
+	AllowCredentials bool
+
+	// MaxAge is how long browsers may cache a preflight result. Zero
+	// leaves it to the browser.
+	MaxAge time.Duration
+}
+
+// defaultCORSMethods is used when CORSOptions.AllowedMethods is empty.
+var defaultCORSMethods = []string{MethodGet, MethodHead, MethodPost}
+
+// CORS returns middleware that applies opts to cross-origin requests.
+//
+// Requests without an Origin header pass through untouched. For an
+// allowed origin, the response carries Access-Control-Allow-Origin
+// ("*" when any origin is allowed without credentials, otherwise the
+// request's origin reflected) and the configured exposed headers and
+// credentials flag. A request from any other origin still reaches the
+// handler but gets no CORS headers, so the browser withholds the
+// response from the calling script.
+//
+// Preflight requests (OPTIONS with Access-Control-Request-Method) are
+// answered directly with 204, listing the allowed methods and headers,
+// when the origin, method, and every requested header are allowed,
+// and with 403 otherwise; the handler is not called.
+//
+// CORS panics if AllowCredentials is combined with the "*" origin,
+// which would let any site make credentialed requests on the user's
+// behalf; browsers refuse that combination as well.
+func CORS(opts CORSOptions) Middleware {
+	c := &cors{opts: opts, methods: opts.AllowedMethods}
+	if len(c.methods) == 0 {
+		c.methods = defaultCORSMethods
+	}
+	for _, o := range opts.AllowedOrigins {
+		if o == "*" {
+			c.anyOrigin = true
+		}
+	}
+	for _, h := range opts.AllowedHeaders {
+		if h == "*" {
+			c.anyHeader = true
+		}
+	}
+	if c.anyOrigin && opts.AllowCredentials {
+		panic("http: CORS: AllowCredentials cannot be used with the \"*\" origin")
+	}
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w ResponseWriter, r *Request) {
+			c.serve(w, r, next)
+		})
+	}
+}
+
+type cors struct {
+	opts      CORSOptions
+	methods   []string
+	anyOrigin bool
+	anyHeader bool
+}
+
+func (c *cors) serve(w ResponseWriter, r *Request, next Handler) {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	h := w.Header()
+	if !c.anyOrigin || c.opts.AllowCredentials {
+		h.Add("Vary", "Origin")
+	}
+	allowed := c.originAllowed(origin)
+
+	if r.Method == MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+		h.Add("Vary", "Access-Control-Request-Method")
+		h.Add("Vary", "Access-Control-Request-Headers")
+		method := r.Header.Get("Access-Control-Request-Method")
+		headers := requestedHeaders(r.Header)
+		if !allowed || !containsFold(c.methods, method) || !c.headersAllowed(headers) {
+			w.WriteHeader(StatusForbidden)
+			return
+		}
+		c.setOrigin(h, origin)
+		h.Set("Access-Control-Allow-Methods", strings.Join(c.methods, ", "))
+		if len(headers) > 0 {
+			h.Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+		}
+		if c.opts.MaxAge > 0 {
+			h.Set("Access-Control-Max-Age", strconv.Itoa(int(c.opts.MaxAge/time.Second)))
+		}
+		w.WriteHeader(StatusNoContent)
+		return
+	}
+
+	if allowed {
+		c.setOrigin(h, origin)
+		if len(c.opts.ExposedHeaders) > 0 {
+			h.Set("Access-Control-Expose-Headers", strings.Join(c.opts.ExposedHeaders, ", "))
+		}
+	}
+	next.ServeHTTP(w, r)
+}
+
+// setOrigin sets Access-Control-Allow-Origin and, when configured,
+// Access-Control-Allow-Credentials.
+func (c *cors) setOrigin(h Header, origin string) {
+	if c.anyOrigin && !c.opts.AllowCredentials {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+	if c.opts.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// originAllowed reports whether origin matches AllowedOrigins.
+func (c *cors) originAllowed(origin string) bool {
+	if c.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	for _, o := range c.opts.AllowedOrigins {
+		o = strings.ToLower(o)
+		if o == origin {
+			return true
+		}
+		if prefix, suffix, ok := strings.Cut(o, "*"); ok &&
+			len(origin) > len(prefix)+len(suffix) &&
+			strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// headersAllowed reports whether every requested header is allowed.
+func (c *cors) headersAllowed(headers []string) bool {
+	if c.anyHeader {
+		return true
+	}
+	for _, name := range headers {
+		if !containsFold(c.opts.AllowedHeaders, name) {
+			return false
+		}
+	}
+	return true
+}
+
+// requestedHeaders splits a preflight's Access-Control-Request-Headers
+// into canonical header names.
+func requestedHeaders(h Header) []string {
+	var names []string
+	for _, v := range h.Values("Access-Control-Request-Headers") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names
+}
+
+// containsFold reports whether list contains s, ignoring case.
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package http_test
+
+import (
+	"testing"
+	"time"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/net/http"
+)
+
+// ── CORS tests ──────────────────────────────────────────────────────
+
+// serveCORS runs req through CORS(opts) around a handler that writes
+// "ok", reporting whether the handler ran.
+func serveCORS(opts wghttp.CORSOptions, req *wghttp.Request) (capturedResponse, bool) {
+	called := false
+	handler := wghttp.CORS(opts)(wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		called = true
+		w.Write([]byte("ok"))
+	}))
+	w := wghttp.NewTestResponseWriter()
+	handler.ServeHTTP(w, req)
+	return w, called
+}
+
+func TestCORS_SimpleGetAllowedOrigin(t *testing.T) {
+	req := wghttp.NewRequest(wghttp.MethodGet, "/api", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+
+	w, called := serveCORS(wghttp.CORSOptions{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		ExposedHeaders:   []string{"X-Total-Count"},
+		AllowCredentials: true,
+	}, req)
+
+	if !called || string(w.Body()) != "ok" {
+		t.Fatal("expected handler to run for a simple request")
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Fatalf("expected origin reflected, got '%s'", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Fatalf("expected credentials allowed, got '%s'", got)
+	}
+	if got := w.Header().Get("Access-Control-Expose-Headers"); got != "X-Total-Count" {
+		t.Fatalf("expected exposed headers, got '%s'", got)
+	}
+	if got := w.Header().Get("Vary"); got != "Origin" {
+		t.Fatalf("expected Vary: Origin, got '%s'", got)
+	}
+}
+
+func TestCORS_WildcardOrigin(t *testing.T) {
+	req := wghttp.NewRequest(wghttp.MethodGet, "/api", nil)
+	req.Header.Set("Origin", "https://anywhere.test")
+
+	w, _ := serveCORS(wghttp.CORSOptions{AllowedOrigins: []string{"*"}}, req)
+
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Fatalf("expected '*', got '%s'", got)
+	}
+}
+
+func TestCORS_SubdomainWildcardReflectsOrigin(t *testing.T) {
+	opts := wghttp.CORSOptions{AllowedOrigins: []string{"https://*.example.com"}}
+
+	req := wghttp.NewRequest(wghttp.MethodGet, "/api", nil)
+	req.Header.Set("Origin", "https://shop.example.com")
+	w, _ := serveCORS(opts, req)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://shop.example.com" {
+		t.Fatalf("expected subdomain origin reflected, got '%s'", got)
+	}
+
+	req = wghttp.NewRequest(wghttp.MethodGet, "/api", nil)
+	req.Header.Set("Origin", "https://example.com.evil.test")
+	w, _ = serveCORS(opts, req)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("expected lookalike origin rejected, got '%s'", got)
+	}
+}
+
+func TestCORS_Preflight(t *testing.T) {
+	req := wghttp.NewRequest(wghttp.MethodOptions, "/api", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "PUT")
+	req.Header.Set("Access-Control-Request-Headers", "content-type, x-api-key")
+
+	w, called := serveCORS(wghttp.CORSOptions{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []string{"GET", "PUT"},
+		AllowedHeaders: []string{"Content-Type", "X-Api-Key"},
+		MaxAge:         10 * time.Minute,
+	}, req)
+
+	if called {
+		t.Fatal("expected preflight to be answered without calling the handler")
+	}
+	if w.StatusCode() != wghttp.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", w.StatusCode())
+	}
+	checks := map[string]string{
+		"Access-Control-Allow-Origin":  "https://app.example.com",
+		"Access-Control-Allow-Methods": "GET, PUT",
+		"Access-Control-Allow-Headers": "Content-Type, X-Api-Key",
+		"Access-Control-Max-Age":       "600",
+	}
+	for name, want := range checks {
+		if got := w.Header().Get(name); got != want {
+			t.Fatalf("expected %s '%s', got '%s'", name, want, got)
+		}
+	}
+}
+
+func TestCORS_PreflightDisallowedMethod(t *testing.T) {
+	req := wghttp.NewRequest(wghttp.MethodOptions, "/api", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "DELETE")
+
+	w, called := serveCORS(wghttp.CORSOptions{AllowedOrigins: []string{"https://app.example.com"}}, req)
+
+	if called || w.StatusCode() != wghttp.StatusForbidden {
+		t.Fatalf("expected 403 without calling the handler, got %d called=%v", w.StatusCode(), called)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("expected no CORS headers, got '%s'", got)
+	}
+}
+
+func TestCORS_DisallowedOrigin(t *testing.T) {
+	req := wghttp.NewRequest(wghttp.MethodGet, "/api", nil)
+	req.Header.Set("Origin", "https://evil.test")
+
+	w, called := serveCORS(wghttp.CORSOptions{AllowedOrigins: []string{"https://app.example.com"}}, req)
+
+	if !called {
+		t.Fatal("expected handler to still run for a disallowed simple request")
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("expected no Access-Control-Allow-Origin, got '%s'", got)
+	}
+
+	preflight := wghttp.NewRequest(wghttp.MethodOptions, "/api", nil)
+	preflight.Header.Set("Origin", "https://evil.test")
+	preflight.Header.Set("Access-Control-Request-Method", "GET")
+	w, called = serveCORS(wghttp.CORSOptions{AllowedOrigins: []string{"https://app.example.com"}}, preflight)
+	if called || w.StatusCode() != wghttp.StatusForbidden {
+		t.Fatalf("expected disallowed preflight to get 403, got %d called=%v", w.StatusCode(), called)
+	}
+}
+
+func TestCORS_NoOriginPassesThrough(t *testing.T) {
+	w, called := serveCORS(wghttp.CORSOptions{AllowedOrigins: []string{"*"}}, wghttp.NewRequest(wghttp.MethodGet, "/api", nil))
+
+	if !called || len(w.Header()) != 0 {
+		t.Fatalf("expected same-origin request untouched, got headers %v", w.Header())
+	}
+}
+
+func TestCORS_CredentialsWithWildcardPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected CORS to reject AllowCredentials with the '*' origin")
+		}
+	}()
+	wghttp.CORS(wghttp.CORSOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+}
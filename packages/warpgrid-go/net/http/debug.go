@@ -0,0 +1,58 @@
+package http
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// routeInfo describes one registered ServeMux pattern.
+type routeInfo struct {
+	Pattern string   `json:"pattern"`
+	Methods []string `json:"methods"`
+}
+
+// routes returns the mux's registered paths sorted by pattern, each
+// with its sorted methods. A route with no methods listed matches any
+// method.
+func (mux *ServeMux) routes() []routeInfo {
+	mux.mu.RLock()
+	defer mux.mu.RUnlock()
+
+	out := make([]routeInfo, 0, len(mux.handlers))
+	for pattern, byMethod := range mux.handlers {
+		info := routeInfo{Pattern: pattern, Methods: []string{}}
+		if _, any := byMethod[""]; !any {
+			info.Methods = mux.allowed(byMethod)
+		}
+		out = append(out, info)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Pattern < out[j].Pattern })
+	return out
+}
+
+// DebugRoutesHandler returns a Handler that lists the routes registered
+// on mux as JSON, sorted by pattern:
+//
+//	{"routes":[{"pattern":"/health","methods":[]},{"pattern":"/users/","methods":["GET","POST"]}]}
+//
+// An empty methods list means the route accepts any method. If mux is
+// nil, DefaultServeMux is used. The listing is read at request time, so
+// routes registered later are included.
+//
+// The handler exposes the application's surface area; mount it (for
+// example at "/_debug/routes") only behind the operator's own auth.
+func DebugRoutesHandler(mux *ServeMux) Handler {
+	return HandlerFunc(func(w ResponseWriter, r *Request) {
+		m := mux
+		if m == nil {
+			m = DefaultServeMux
+		}
+		body, _ := json.Marshal(struct {
+			Routes []routeInfo `json:"routes"`
+		}{m.routes()})
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(StatusOK)
+		w.Write(body)
+	})
+}
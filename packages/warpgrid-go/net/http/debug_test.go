@@ -0,0 +1,97 @@
+package http_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/net/http"
+)
+
+// ── DebugRoutesHandler tests ────────────────────────────────────────
+
+type routesBody struct {
+	Routes []struct {
+		Pattern string   `json:"pattern"`
+		Methods []string `json:"methods"`
+	} `json:"routes"`
+}
+
+func TestDebugRoutesHandler_ListsRoutesSorted(t *testing.T) {
+	noop := func(w wghttp.ResponseWriter, r *wghttp.Request) {}
+	mux := wghttp.NewServeMux()
+	mux.HandleFunc("/users/", noop)
+	mux.HandleFunc("/health", noop)
+	mux.HandleFunc("/api/orders", noop)
+	mux.Handle("/_debug/routes", wghttp.DebugRoutesHandler(mux))
+
+	w := wghttp.NewTestResponseWriter()
+	mux.ServeHTTP(w, wghttp.NewRequest(wghttp.MethodGet, "/_debug/routes", nil))
+
+	if w.StatusCode() != wghttp.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.StatusCode())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("expected application/json content type, got '%s'", ct)
+	}
+	var body routesBody
+	if err := json.Unmarshal(w.Body(), &body); err != nil {
+		t.Fatalf("invalid JSON body %q: %v", w.Body(), err)
+	}
+
+	want := []string{"/_debug/routes", "/api/orders", "/health", "/users/"}
+	if len(body.Routes) != len(want) {
+		t.Fatalf("expected %d routes, got %d: %s", len(want), len(body.Routes), w.Body())
+	}
+	for i, p := range want {
+		if body.Routes[i].Pattern != p {
+			t.Fatalf("route %d: expected pattern '%s', got '%s'", i, p, body.Routes[i].Pattern)
+		}
+		if body.Routes[i].Methods == nil || len(body.Routes[i].Methods) != 0 {
+			t.Fatalf("route %d: expected empty methods (any), got %v", i, body.Routes[i].Methods)
+		}
+	}
+}
+
+func TestDebugRoutesHandler_ListsMethods(t *testing.T) {
+	noop := func(w wghttp.ResponseWriter, r *wghttp.Request) {}
+	mux := wghttp.NewServeMux()
+	mux.HandleFunc("POST /users", noop)
+	mux.HandleFunc("GET /users", noop)
+	mux.HandleFunc("DELETE /users/", noop)
+
+	w := wghttp.NewTestResponseWriter()
+	wghttp.DebugRoutesHandler(mux).ServeHTTP(w, wghttp.NewRequest(wghttp.MethodGet, "/", nil))
+
+	var body routesBody
+	if err := json.Unmarshal(w.Body(), &body); err != nil {
+		t.Fatalf("invalid JSON body %q: %v", w.Body(), err)
+	}
+	if len(body.Routes) != 2 {
+		t.Fatalf("expected 2 routes, got %s", w.Body())
+	}
+	if r := body.Routes[0]; r.Pattern != "/users" || len(r.Methods) != 2 || r.Methods[0] != "GET" || r.Methods[1] != "POST" {
+		t.Fatalf("expected /users with [GET POST], got %+v", r)
+	}
+	if r := body.Routes[1]; r.Pattern != "/users/" || len(r.Methods) != 1 || r.Methods[0] != "DELETE" {
+		t.Fatalf("expected /users/ with [DELETE], got %+v", r)
+	}
+}
+
+func TestDebugRoutesHandler_Deterministic(t *testing.T) {
+	noop := func(w wghttp.ResponseWriter, r *wghttp.Request) {}
+	mux := wghttp.NewServeMux()
+	for _, p := range []string{"/e", "/d", "/c", "/b", "/a"} {
+		mux.HandleFunc(p, noop)
+	}
+	h := wghttp.DebugRoutesHandler(mux)
+
+	first := wghttp.NewTestResponseWriter()
+	h.ServeHTTP(first, wghttp.NewRequest(wghttp.MethodGet, "/", nil))
+	for i := 0; i < 10; i++ {
+		w := wghttp.NewTestResponseWriter()
+		h.ServeHTTP(w, wghttp.NewRequest(wghttp.MethodGet, "/", nil))
+		if string(w.Body()) != string(first.Body()) {
+			t.Fatalf("expected identical output, got %s and %s", first.Body(), w.Body())
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package http
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SetDeprecation marks the response as coming from a deprecated
+// resource. It sets Deprecation (RFC 9745) to the current time, as a
+// structured-field date such as "@1767225600", meaning the resource is
+// deprecated as of this response, and, unless sunset is zero, Sunset
+// (RFC 8594) to the HTTP-date after which the resource may stop
+// responding. The current time comes from Clock.
+func SetDeprecation(w ResponseWriter, sunset time.Time) {
+	w.Header().Set("Deprecation", "@"+strconv.FormatInt(now().Unix(), 10))
+	if !sunset.IsZero() {
+		w.Header().Set("Sunset", sunset.UTC().Format(TimeFormat))
+	}
+}
+
+// AddWarning adds a Warning header (RFC 7234 section 5.5) to the
+// response, such as
+//
+//	Warning: 299 api.warp.local "Deprecated API" "Thu, 01 Jan 2026 00:00:00 GMT"
+//
+// code is the three-digit warn-code (299 for a miscellaneous persistent
+// warning), agent names the server adding it ("-" when empty), and text
+// is quoted with '"' and '\' escaped. The date is the current time from
+// Clock. Warnings accumulate: each call adds another header value.
+func AddWarning(w ResponseWriter, code int, agent, text string) {
+	if agent == "" {
+		agent = "-"
+	}
+	value := strconv.Itoa(code) + " " + agent + " " + quoteString(text) +
+		` "` + now().UTC().Format(TimeFormat) + `"`
+	w.Header().Add("Warning", value)
+}
+
+// quoteString returns s as an HTTP quoted-string.
+func quoteString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	b.WriteByte('"')
+	return b.String()
+}
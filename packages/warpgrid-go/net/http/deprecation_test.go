@@ -0,0 +1,66 @@
+package http_test
+
+import (
+	"testing"
+	"time"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/net/http"
+)
+
+// ── Deprecation and Warning tests ───────────────────────────────────
+
+// fixedClock pins Clock to 2026-01-01 00:00:00 UTC.
+func fixedClock() func() time.Time {
+	return func() time.Time { return time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC) }
+}
+
+func TestSetDeprecation_FormatsDeprecationAndSunset(t *testing.T) {
+	defer wghttp.Configure(wghttp.CurrentConfig())
+	wghttp.Clock = fixedClock()
+
+	w := wghttp.NewTestResponseWriter()
+	sunset := time.Date(2026, 7, 1, 12, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	wghttp.SetDeprecation(w, sunset)
+
+	if got := w.Header().Get("Deprecation"); got != "@1767225600" {
+		t.Fatalf("expected Deprecation '@1767225600', got '%s'", got)
+	}
+	if got := w.Header().Get("Sunset"); got != "Wed, 01 Jul 2026 10:30:00 GMT" {
+		t.Fatalf("expected Sunset as an HTTP-date in GMT, got '%s'", got)
+	}
+}
+
+func TestSetDeprecation_ZeroSunsetOmitted(t *testing.T) {
+	w := wghttp.NewTestResponseWriter()
+	wghttp.SetDeprecation(w, time.Time{})
+
+	if w.Header().Get("Deprecation") == "" {
+		t.Fatal("expected a Deprecation header")
+	}
+	if got := w.Header().Values("Sunset"); len(got) != 0 {
+		t.Fatalf("expected no Sunset header, got %v", got)
+	}
+}
+
+func TestAddWarning_FormatsAndAppends(t *testing.T) {
+	defer wghttp.Configure(wghttp.CurrentConfig())
+	wghttp.Clock = fixedClock()
+
+	w := wghttp.NewTestResponseWriter()
+	wghttp.AddWarning(w, 299, "api.warp.local", "Deprecated API")
+	wghttp.AddWarning(w, 199, "", `use "v2" \ soon`)
+
+	got := w.Header().Values("Warning")
+	want := []string{
+		`299 api.warp.local "Deprecated API" "Thu, 01 Jan 2026 00:00:00 GMT"`,
+		`199 - "use \"v2\" \\ soon" "Thu, 01 Jan 2026 00:00:00 GMT"`,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d warnings, got %v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("warning %d: expected %s, got %s", i, want[i], got[i])
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package http
+
+// interimWriter is implemented by ResponseWriters that can send
+// informational (1xx) responses ahead of the final response.
+type interimWriter interface {
+	writeInterim(statusCode int, header Header)
+}
+
+// WriteEarlyHints sends a 103 Early Hints response carrying header
+// (typically Link preload hints) ahead of the final response, so the
+// client can start fetching subresources while the handler is still
+// working:
+//
+//	h := make(http.Header)
+//	h.Add("Link", "</app.css>; rel=preload; as=style")
+//	http.WriteEarlyHints(w, h)
+//
+// The hints are kept apart from the final response, whose status,
+// headers, and body are unaffected. HandleRequestWith does not yet send
+// them: the host reads a single response frame, so they are dropped
+// until it can receive interim frames. WriteEarlyHints may be called
+// more than once, but only before the final response has started (the
+// first WriteHeader or Write); later calls are ignored, as are calls on
+// ResponseWriters that cannot send interim responses.
+func WriteEarlyHints(w ResponseWriter, header Header) {
+	if iw, ok := w.(interimWriter); ok {
+		iw.writeInterim(StatusEarlyHints, header)
+	}
+}
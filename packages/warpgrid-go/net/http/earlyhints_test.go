@@ -0,0 +1,69 @@
+package http_test
+
+import (
+	"testing"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/net/http"
+)
+
+// ── WriteEarlyHints tests ───────────────────────────────────────────
+
+func earlyHintsHandler() wghttp.Handler {
+	return wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		h := make(wghttp.Header)
+		h.Add("Link", "</app.css>; rel=preload; as=style")
+		wghttp.WriteEarlyHints(w, h)
+
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(wghttp.StatusCreated)
+		w.Write([]byte("<html></html>"))
+	})
+}
+
+func TestWriteEarlyHints_RecordedApartFromFinalResponse(t *testing.T) {
+	w := wghttp.NewTestResponseWriter()
+	earlyHintsHandler().ServeHTTP(w, wghttp.NewRequest(wghttp.MethodGet, "/", nil))
+
+	interim := w.InterimResponses()
+	if len(interim) != 1 {
+		t.Fatalf("expected 1 interim response, got %d", len(interim))
+	}
+	if interim[0].Status != wghttp.StatusEarlyHints {
+		t.Fatalf("expected interim status 103, got %d", interim[0].Status)
+	}
+	if len(interim[0].Headers) != 1 || interim[0].Headers[0].Name != "Link" {
+		t.Fatalf("expected a single Link header on the interim response, got %v", interim[0].Headers)
+	}
+	if w.StatusCode() != wghttp.StatusCreated {
+		t.Fatalf("expected final status 201, got %d", w.StatusCode())
+	}
+	if w.Header().Get("Link") != "" {
+		t.Fatalf("expected Link to stay off the final response, got %v", w.Header())
+	}
+}
+
+func TestWriteEarlyHints_DroppedFromBufferedResponse(t *testing.T) {
+	req := wghttp.MarshalRequest(wghttp.WitHttpRequest{Method: "GET", URI: "/"})
+	respBytes := wghttp.HandleRequestWith(earlyHintsHandler(), req)
+
+	resp := wghttp.UnmarshalResponse(respBytes)
+	if resp.Status != wghttp.StatusCreated {
+		t.Fatalf("expected status 201, got %d", resp.Status)
+	}
+	if string(resp.Body) != "<html></html>" {
+		t.Fatalf("expected body '<html></html>', got '%s'", resp.Body)
+	}
+	if want := wghttp.MarshalResponse(resp); string(want) != string(respBytes) {
+		t.Fatalf("expected response to be a single frame")
+	}
+}
+
+func TestWriteEarlyHints_IgnoredAfterFinalResponseStarts(t *testing.T) {
+	w := wghttp.NewTestResponseWriter()
+	w.Write([]byte("body"))
+	wghttp.WriteEarlyHints(w, wghttp.Header{"Link": {"</late.js>; rel=preload"}})
+
+	if n := len(w.InterimResponses()); n != 0 {
+		t.Fatalf("expected no interim responses after Write, got %d", n)
+	}
+}
@@ -0,0 +1,81 @@
+package http
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// errorPageRenderer renders HTML error pages; nil, the default, leaves
+// every error response as plain text (or as ErrorRenderer writes it).
+var errorPageRenderer func(status int, message string) (contentType string, body []byte)
+
+// SetErrorPageRenderer sets the renderer used for the error responses
+// the server generates itself (the mux's 404 and 405, the 500 for an
+// oversized response, and DefaultPanicHandler's 500) when the client's
+// Accept header prefers HTML, as a browser's does. The renderer returns
+// the body and its Content-Type. Clients that prefer anything else, or
+// send no Accept header, get the existing plain text or ErrorRenderer
+// output. Error itself takes no request and so always writes plain
+// text.
+//
+// HTMLErrorPage is a ready-made renderer; nil, the default, turns HTML
+// error pages off. Like the other settings it should be called before
+// serving begins.
+func SetErrorPageRenderer(fn func(status int, message string) (contentType string, body []byte)) {
+	errorPageRenderer = fn
+}
+
+// HTMLErrorPage renders a minimal HTML error page showing the status
+// line and message, for use with SetErrorPageRenderer.
+func HTMLErrorPage(status int, message string) (contentType string, body []byte) {
+	title := html.EscapeString(strings.TrimSpace(strconv.Itoa(status) + " " + StatusText(status)))
+	page := fmt.Sprintf("<!DOCTYPE html>\n<html>\n<head><meta charset=\"utf-8\"><title>%s</title></head>\n"+
+		"<body>\n<h1>%s</h1>\n<p>%s</p>\n</body>\n</html>\n", title, title, html.EscapeString(message))
+	return "text/html; charset=utf-8", []byte(page)
+}
+
+// writeErrorPage writes the error through the error page renderer if
+// one is set and r prefers HTML, reporting whether it did.
+func writeErrorPage(w ResponseWriter, r *Request, message string, code int) bool {
+	render := errorPageRenderer
+	if render == nil || r == nil || !prefersHTML(r.Header) {
+		return false
+	}
+	contentType, body := render(code, message)
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(code)
+	w.Write(body)
+	return true
+}
+
+// prefersHTML reports whether the Accept header ranks an HTML media
+// type above every other range it lists. Ties go to the range listed
+// first, so "*/*" alone or "application/json, text/html" do not count.
+func prefersHTML(h Header) bool {
+	bestQ, bestHTML := -1.0, false
+	for _, v := range h.Values("Accept") {
+		for _, part := range strings.Split(v, ",") {
+			mediaType, params, _ := strings.Cut(part, ";")
+			mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+			if mediaType == "" {
+				continue
+			}
+			q := 1.0
+			for _, p := range strings.Split(params, ";") {
+				name, value, _ := strings.Cut(p, "=")
+				if strings.TrimSpace(strings.ToLower(name)) == "q" {
+					if f, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+						q = f
+					}
+				}
+			}
+			if q > bestQ {
+				bestQ = q
+				bestHTML = mediaType == "text/html" || mediaType == "application/xhtml+xml"
+			}
+		}
+	}
+	return bestHTML && bestQ > 0
+}
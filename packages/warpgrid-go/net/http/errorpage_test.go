@@ -0,0 +1,106 @@
+package http_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/net/http"
+)
+
+// ── Error page tests ────────────────────────────────────────────────
+
+const browserAccept = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+
+// serveUnmatched sends a request with the given Accept header to an
+// empty mux, which answers 404.
+func serveUnmatched(accept string) capturedResponse {
+	req := wghttp.NewRequest(wghttp.MethodGet, "/missing", nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	w := wghttp.NewTestResponseWriter()
+	wghttp.NewServeMux().ServeHTTP(w, req)
+	return w
+}
+
+func TestErrorPage_HTMLClientGetsPage(t *testing.T) {
+	defer wghttp.Configure(wghttp.CurrentConfig())
+	wghttp.SetErrorPageRenderer(wghttp.HTMLErrorPage)
+
+	w := serveUnmatched(browserAccept)
+
+	if w.StatusCode() != wghttp.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.StatusCode())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Fatalf("expected HTML content type, got '%s'", ct)
+	}
+	if !bytes.Contains(w.Body(), []byte("<h1>404 Not Found</h1>")) {
+		t.Fatalf("expected HTML error page, got '%s'", w.Body())
+	}
+}
+
+func TestErrorPage_NonHTMLClientsUnchanged(t *testing.T) {
+	defer wghttp.Configure(wghttp.CurrentConfig())
+	wghttp.SetErrorPageRenderer(wghttp.HTMLErrorPage)
+
+	for _, accept := range []string{"", "*/*", "application/json", "text/plain", "application/json, text/html", "text/html;q=0"} {
+		w := serveUnmatched(accept)
+		if ct := w.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+			t.Fatalf("Accept %q: expected plain text, got '%s'", accept, ct)
+		}
+		if string(w.Body()) != "404 page not found\n" {
+			t.Fatalf("Accept %q: expected the plain 404 body, got '%s'", accept, w.Body())
+		}
+	}
+
+	wghttp.ErrorRenderer = wghttp.JSONErrorRenderer
+	w := serveUnmatched("application/json")
+	if !bytes.HasPrefix(w.Body(), []byte(`{"error":`)) {
+		t.Fatalf("expected ErrorRenderer output for a JSON client, got '%s'", w.Body())
+	}
+}
+
+func TestErrorPage_DefaultIsPlainText(t *testing.T) {
+	w := serveUnmatched(browserAccept)
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Fatalf("expected plain text without a renderer set, got '%s'", ct)
+	}
+}
+
+func TestErrorPage_CustomRendererAndPanics(t *testing.T) {
+	defer wghttp.Configure(wghttp.CurrentConfig())
+	wghttp.ProductionMode = true
+	var gotStatus int
+	wghttp.SetErrorPageRenderer(func(status int, message string) (string, []byte) {
+		gotStatus = status
+		return "text/html", []byte("<p>" + message + "</p>")
+	})
+
+	boom := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		panic("secret detail")
+	})
+	reqBytes := wghttp.MarshalRequest(wghttp.WitHttpRequest{
+		Method:  "GET",
+		URI:     "/",
+		Headers: []wghttp.WitHttpHeader{{Name: "Accept", Value: "text/html"}},
+	})
+	resp := wghttp.UnmarshalResponse(wghttp.HandleRequestWith(boom, reqBytes))
+
+	if resp.Status != wghttp.StatusInternalServerError || gotStatus != wghttp.StatusInternalServerError {
+		t.Fatalf("expected 500 rendered as a page, got %d (renderer saw %d)", resp.Status, gotStatus)
+	}
+	if string(resp.Body) != "<p>internal server error</p>" || strings.Contains(string(resp.Body), "secret") {
+		t.Fatalf("expected the custom page without panic detail, got '%s'", resp.Body)
+	}
+}
+
+func TestHTMLErrorPage_EscapesMessage(t *testing.T) {
+	_, body := wghttp.HTMLErrorPage(wghttp.StatusBadRequest, "<script>x</script>")
+
+	if bytes.Contains(body, []byte("<script>")) || !bytes.Contains(body, []byte("&lt;script&gt;")) {
+		t.Fatalf("expected the message HTML-escaped, got '%s'", body)
+	}
+}
@@ -4,7 +4,12 @@
 // The host calls warpgrid_http_handle_request with a pointer to the
 // serialized WIT http-request in linear memory. The guest processes
 // it through the registered handler and returns a pointer and length
-// to the serialized WIT http-response.
+// to the serialized WIT http-response. Modules with several triggers
+// are invoked through warpgrid_http_handle_trigger, which also takes
+// the trigger name. Large responses can instead be pulled in chunks
+// through warpgrid_http_handle_request_pull and
+// warpgrid_http_response_read (see pull.go). The host polls
+// warpgrid_http_check_idle to run OnIdle callbacks (see idle.go).
 
 //go:build wasip2
 
@@ -29,3 +34,69 @@ func warpgridHttpHandleRequest(reqPtr *byte, reqLen uint32) (respPtr *byte, resp
 	}
 	return &lastResponse[0], uint32(len(lastResponse))
 }
+
+// warpgridHttpHandleTrigger is the export for modules serving several
+// triggers: the host passes the trigger name (see RegisterTrigger)
+// alongside the serialized request, and the response is returned the
+// same way as from warpgrid_http_handle_request.
+//
+//go:wasmexport warpgrid_http_handle_trigger
+func warpgridHttpHandleTrigger(namePtr *byte, nameLen uint32, reqPtr *byte, reqLen uint32) (respPtr *byte, respLen uint32) {
+	name := DefaultTrigger
+	if nameLen > 0 {
+		name = string(unsafe.Slice(namePtr, nameLen))
+	}
+	reqBytes := unsafe.Slice(reqPtr, reqLen)
+	lastResponse = HandleTrigger(name, reqBytes)
+	if len(lastResponse) == 0 {
+		return nil, 0
+	}
+	return &lastResponse[0], uint32(len(lastResponse))
+}
+
+// warpgridHttpHandleRequestPull serves a request like
+// warpgrid_http_handle_request but keeps the response in the guest,
+// returning a handle and the response size for the host to drain with
+// warpgrid_http_response_read.
+//
+//go:wasmexport warpgrid_http_handle_request_pull
+func warpgridHttpHandleRequestPull(reqPtr *byte, reqLen uint32) (handle uint32, size uint32) {
+	reqBytes := unsafe.Slice(reqPtr, reqLen)
+	h, n := HandleRequestPull(reqBytes)
+	return h, uint32(n)
+}
+
+// warpgridHttpResponseRead copies up to chunkCap bytes of the response
+// retained under handle into the host-provided buffer at chunkPtr. It
+// returns the number of bytes copied and done = 1 once the whole
+// response has been read, at which point the handle is released.
+//
+//go:wasmexport warpgrid_http_response_read
+func warpgridHttpResponseRead(handle uint32, chunkPtr *byte, chunkCap uint32) (n uint32, done uint32) {
+	var chunk []byte
+	if chunkCap > 0 {
+		chunk = unsafe.Slice(chunkPtr, chunkCap)
+	}
+	copied, finished := ReadResponseChunk(handle, chunk)
+	if finished {
+		done = 1
+	}
+	return uint32(copied), done
+}
+
+// warpgridHttpCheckIdle lets the host, which keeps time while the
+// instance is suspended, run the OnIdle callbacks that are due (see
+// CheckIdle).
+//
+//go:wasmexport warpgrid_http_check_idle
+func warpgridHttpCheckIdle() {
+	CheckIdle()
+}
+
+// warpgridHttpResponseRelease discards a retained response the host
+// will not finish reading.
+//
+//go:wasmexport warpgrid_http_response_release
+func warpgridHttpResponseRelease(handle uint32) {
+	ReleaseResponse(handle)
+}
@@ -0,0 +1,103 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// FileServer returns a handler that serves requests with the contents
+// of fsys, such as an embed.FS of static assets, mirroring
+// net/http.FileServer(http.FS(fsys)).
+//
+// The URL path, cleaned and made relative, names the file in fsys, so
+// ".." elements can never climb out of its root. Files are served
+// through ServeContent, which sets the Content-Type from the extension
+// and handles Range requests. A directory is served by its index.html;
+// a request for a directory without the trailing slash is redirected
+// to add it, so relative links in the index resolve. Directories
+// without an index, like missing files, are answered with 404: unlike
+// net/http, directory contents are never listed.
+func FileServer(fsys fs.FS) Handler {
+	return &fileHandler{fsys: fsys}
+}
+
+type fileHandler struct {
+	fsys fs.FS
+}
+
+func (h *fileHandler) ServeHTTP(w ResponseWriter, r *Request) {
+	urlPath := r.URL.Path
+	if !strings.HasPrefix(urlPath, "/") {
+		urlPath = "/" + urlPath
+	}
+	name := strings.TrimPrefix(path.Clean(urlPath), "/")
+	if name == "" {
+		name = "."
+	}
+	if !fs.ValidPath(name) {
+		Error(w, "404 page not found", StatusNotFound)
+		return
+	}
+
+	f, err := h.fsys.Open(name)
+	if err != nil {
+		serveFSError(w, err)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		serveFSError(w, err)
+		return
+	}
+
+	if info.IsDir() {
+		if !strings.HasSuffix(urlPath, "/") {
+			u := *r.URL
+			u.Path, u.RawPath = urlPath+"/", ""
+			w.Header().Set("Location", u.RequestURI())
+			w.WriteHeader(StatusMovedPermanently)
+			return
+		}
+		index, err := h.fsys.Open(path.Join(name, "index.html"))
+		if err != nil {
+			serveFSError(w, err)
+			return
+		}
+		defer index.Close()
+		if info, err = index.Stat(); err != nil || info.IsDir() {
+			Error(w, "404 page not found", StatusNotFound)
+			return
+		}
+		f = index
+	}
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			serveFSError(w, err)
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+	ServeContent(w, r, info.Name(), info.ModTime(), content)
+}
+
+// serveFSError maps an fs error to a response without exposing the
+// underlying path.
+func serveFSError(w ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		Error(w, "404 page not found", StatusNotFound)
+	case errors.Is(err, fs.ErrPermission):
+		Error(w, "403 Forbidden", StatusForbidden)
+	default:
+		Error(w, "500 Internal Server Error", StatusInternalServerError)
+	}
+}
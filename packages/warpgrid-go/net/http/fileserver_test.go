@@ -0,0 +1,123 @@
+package http_test
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/net/http"
+)
+
+// ── FileServer tests ────────────────────────────────────────────────
+
+var assetModTime = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func assetFS() fstest.MapFS {
+	return fstest.MapFS{
+		"app.css":         {Data: []byte("body{margin:0}"), ModTime: assetModTime},
+		"docs/index.html": {Data: []byte("<h1>docs</h1>"), ModTime: assetModTime},
+		"docs/guide.txt":  {Data: []byte("read me")},
+		"empty/note.txt":  {Data: []byte("no index here")},
+	}
+}
+
+func serveAsset(uri string, headers ...string) capturedResponse {
+	req := wghttp.NewRequest(wghttp.MethodGet, uri, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := wghttp.NewTestResponseWriter()
+	wghttp.FileServer(assetFS()).ServeHTTP(w, req)
+	return w
+}
+
+// capturedResponse is the view of NewTestResponseWriter used here.
+type capturedResponse interface {
+	wghttp.ResponseWriter
+	StatusCode() int
+	Body() []byte
+}
+
+func TestFileServer_ServesKnownFile(t *testing.T) {
+	w := serveAsset("/app.css")
+
+	if w.StatusCode() != wghttp.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.StatusCode())
+	}
+	if string(w.Body()) != "body{margin:0}" {
+		t.Fatalf("expected file contents, got '%s'", w.Body())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/css") {
+		t.Fatalf("expected text/css content type, got '%s'", ct)
+	}
+	if lm := w.Header().Get("Last-Modified"); lm != assetModTime.Format(wghttp.TimeFormat) {
+		t.Fatalf("expected Last-Modified from the file, got '%s'", lm)
+	}
+}
+
+func TestFileServer_RangeRequest(t *testing.T) {
+	w := serveAsset("/app.css", "Range", "bytes=0-3")
+
+	if w.StatusCode() != wghttp.StatusPartialContent {
+		t.Fatalf("expected status 206, got %d", w.StatusCode())
+	}
+	if string(w.Body()) != "body" {
+		t.Fatalf("expected 'body', got '%s'", w.Body())
+	}
+}
+
+func TestFileServer_DirectoryIndex(t *testing.T) {
+	w := serveAsset("/docs/")
+
+	if w.StatusCode() != wghttp.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.StatusCode())
+	}
+	if string(w.Body()) != "<h1>docs</h1>" {
+		t.Fatalf("expected index.html contents, got '%s'", w.Body())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Fatalf("expected text/html content type, got '%s'", ct)
+	}
+}
+
+func TestFileServer_DirectoryRedirectsToSlash(t *testing.T) {
+	w := serveAsset("/docs?lang=en")
+
+	if w.StatusCode() != wghttp.StatusMovedPermanently {
+		t.Fatalf("expected status 301, got %d", w.StatusCode())
+	}
+	if loc := w.Header().Get("Location"); loc != "/docs/?lang=en" {
+		t.Fatalf("expected Location /docs/?lang=en, got '%s'", loc)
+	}
+}
+
+func TestFileServer_DirectoryWithoutIndexIs404(t *testing.T) {
+	w := serveAsset("/empty/")
+
+	if w.StatusCode() != wghttp.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.StatusCode())
+	}
+	if strings.Contains(string(w.Body()), "note.txt") {
+		t.Fatalf("expected directory contents not to be listed, got '%s'", w.Body())
+	}
+}
+
+func TestFileServer_MissingFileIs404(t *testing.T) {
+	w := serveAsset("/missing.js")
+
+	if w.StatusCode() != wghttp.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.StatusCode())
+	}
+}
+
+func TestFileServer_TraversalStaysInRoot(t *testing.T) {
+	req := wghttp.NewRequest(wghttp.MethodGet, "/", nil)
+	req.URL.Path = "/docs/../../app.css"
+	w := wghttp.NewTestResponseWriter()
+	wghttp.FileServer(assetFS()).ServeHTTP(w, req)
+
+	if w.StatusCode() != wghttp.StatusOK || string(w.Body()) != "body{margin:0}" {
+		t.Fatalf("expected traversal clamped to the FS root, got %d '%s'", w.StatusCode(), w.Body())
+	}
+}
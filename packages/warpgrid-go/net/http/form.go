@@ -0,0 +1,104 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/url"
+)
+
+// maxFormBytes caps how much of a request body ParseForm reads,
+// matching the 10 MB limit net/http applies.
+const maxFormBytes = 10 << 20
+
+// ParseForm populates r.Form and r.PostForm, matching net/http.
+//
+// For POST, PUT, and PATCH requests with an
+// application/x-www-form-urlencoded body, the body is read and parsed
+// into r.PostForm. r.Form holds the body values followed by the URL
+// query values. For other requests r.PostForm is empty and r.Form holds
+// only the query values. ParseForm is idempotent: once r.Form is set,
+// later calls do nothing.
+func (r *Request) ParseForm() error {
+	var err error
+	if r.PostForm == nil {
+		if r.Method == MethodPost || r.Method == MethodPut || r.Method == MethodPatch {
+			r.PostForm, err = parsePostForm(r)
+		}
+		if r.PostForm == nil {
+			r.PostForm = make(url.Values)
+		}
+	}
+
+	if r.Form == nil {
+		r.Form = make(url.Values)
+		for k, vs := range r.PostForm {
+			r.Form[k] = append(r.Form[k], vs...)
+		}
+		if r.URL != nil {
+			query, qerr := url.ParseQuery(r.URL.RawQuery)
+			if err == nil {
+				err = qerr
+			}
+			for k, vs := range query {
+				r.Form[k] = append(r.Form[k], vs...)
+			}
+		}
+	}
+	return err
+}
+
+// parsePostForm reads and parses a urlencoded request body.
+func parsePostForm(r *Request) (url.Values, error) {
+	if r.Body == nil {
+		return nil, errors.New("http: missing form body")
+	}
+	ct := r.Header.Get("Content-Type")
+	if ct == "" {
+		return nil, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return nil, err
+	}
+	if mediaType != "application/x-www-form-urlencoded" {
+		return nil, nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxFormBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxFormBytes {
+		return nil, errors.New("http: POST too large")
+	}
+	return url.ParseQuery(string(data))
+}
+
+// FormValue returns the first value for the named component of the
+// query or form body, calling ParseForm if necessary. Errors are
+// ignored; it returns "" when the key is absent.
+func (r *Request) FormValue(key string) string {
+	if r.Form == nil {
+		r.ParseForm()
+	}
+	return r.Form.Get(key)
+}
+
+// PostFormValue is like FormValue but only consults the request body.
+func (r *Request) PostFormValue(key string) string {
+	if r.PostForm == nil {
+		r.ParseForm()
+	}
+	return r.PostForm.Get(key)
+}
+
+// WriteForm replies with v encoded as an
+// application/x-www-form-urlencoded body and the given status. Keys are
+// sorted, repeated keys are written once per value, and empty values
+// are kept as "key=".
+func WriteForm(w ResponseWriter, status int, v url.Values) {
+	w.Header().Set("Content-Type", "application/x-www-form-urlencoded")
+	w.WriteHeader(status)
+	w.Write([]byte(v.Encode()))
+}
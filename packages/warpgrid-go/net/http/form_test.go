@@ -0,0 +1,112 @@
+package http_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/net/http"
+)
+
+// ── WriteForm tests ─────────────────────────────────────────────────
+
+func TestWriteForm_EncodesMultiKeyForm(t *testing.T) {
+	w := wghttp.NewTestResponseWriter()
+	wghttp.WriteForm(w, wghttp.StatusOK, url.Values{
+		"name":  {"Ada Lovelace"},
+		"tag":   {"a&b", "c=d"},
+		"empty": {""},
+	})
+
+	if w.StatusCode() != 200 {
+		t.Fatalf("expected status 200, got %d", w.StatusCode())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-www-form-urlencoded" {
+		t.Fatalf("expected form content type, got '%s'", ct)
+	}
+	expected := "empty=&name=Ada+Lovelace&tag=a%26b&tag=c%3Dd"
+	if string(w.Body()) != expected {
+		t.Fatalf("expected body %q, got %q", expected, w.Body())
+	}
+}
+
+// ── ParseForm tests ─────────────────────────────────────────────────
+
+func newFormRequest(method, uri, body string) *wghttp.Request {
+	req := wghttp.NewRequest(method, uri, []byte(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func TestParseForm_RoundTripsWriteForm(t *testing.T) {
+	values := url.Values{
+		"name":  {"Ada Lovelace"},
+		"tag":   {"a&b", "c=d"},
+		"empty": {""},
+	}
+	w := wghttp.NewTestResponseWriter()
+	wghttp.WriteForm(w, wghttp.StatusOK, values)
+
+	req := newFormRequest(wghttp.MethodPost, "/submit", string(w.Body()))
+	if err := req.ParseForm(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if req.PostForm.Encode() != values.Encode() {
+		t.Fatalf("expected PostForm %v, got %v", values, req.PostForm)
+	}
+	if tags := req.PostForm["tag"]; len(tags) != 2 || tags[0] != "a&b" || tags[1] != "c=d" {
+		t.Fatalf("expected repeated tag values, got %v", tags)
+	}
+	if _, ok := req.PostForm["empty"]; !ok {
+		t.Fatal("expected empty value to be present")
+	}
+}
+
+func TestParseForm_MergesBodyBeforeQuery(t *testing.T) {
+	req := newFormRequest(wghttp.MethodPost, "/submit?id=1&name=query", "name=body")
+	if err := req.ParseForm(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if names := req.Form["name"]; len(names) != 2 || names[0] != "body" || names[1] != "query" {
+		t.Fatalf("expected [body query], got %v", names)
+	}
+	if req.FormValue("id") != "1" {
+		t.Fatalf("expected id '1', got '%s'", req.FormValue("id"))
+	}
+	if req.PostFormValue("id") != "" {
+		t.Fatalf("expected query-only id absent from PostForm, got '%s'", req.PostFormValue("id"))
+	}
+}
+
+func TestParseForm_GETIgnoresBody(t *testing.T) {
+	req := newFormRequest(wghttp.MethodGet, "/search?q=warp", "q=body")
+	if err := req.ParseForm(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(req.PostForm) != 0 {
+		t.Fatalf("expected empty PostForm for GET, got %v", req.PostForm)
+	}
+	if req.FormValue("q") != "warp" {
+		t.Fatalf("expected q 'warp', got '%s'", req.FormValue("q"))
+	}
+}
+
+func TestParseForm_MalformedBodyReturnsError(t *testing.T) {
+	req := newFormRequest(wghttp.MethodPost, "/submit", "bad=%zz")
+	if err := req.ParseForm(); err == nil {
+		t.Fatal("expected error for malformed form body")
+	}
+}
+
+func TestRequestClone_CopiesForm(t *testing.T) {
+	req := newFormRequest(wghttp.MethodPost, "/submit", "a=1")
+	req.ParseForm()
+
+	clone := req.Clone(context.Background())
+	clone.Form.Set("a", "2")
+	if req.Form.Get("a") != "1" {
+		t.Fatalf("expected original Form unaffected, got '%s'", req.Form.Get("a"))
+	}
+}
@@ -0,0 +1,232 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeFormat is the time format to use when generating times in HTTP
+// headers, matching net/http.TimeFormat.
+const TimeFormat = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+// timeFormats lists the HTTP-date forms accepted by ParseTime
+// (RFC 7231 §7.1.1.1).
+var timeFormats = []string{
+	TimeFormat,
+	time.RFC850,
+	time.ANSIC,
+}
+
+// ParseTime parses a time header (such as Date or If-Range) in any of
+// the three formats allowed by HTTP/1.1, matching net/http.ParseTime.
+func ParseTime(text string) (t time.Time, err error) {
+	for _, layout := range timeFormats {
+		t, err = time.Parse(layout, text)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return t, err
+}
+
+// errUnsatisfiableRange is returned by parseRange when no byte of the
+// requested range lies within the content.
+var errUnsatisfiableRange = errors.New("invalid range: failed to overlap")
+
+// ServeContent replies to the request using the content in the provided
+// io.ReadSeeker, matching the core of net/http.ServeContent.
+//
+// If the response has no Content-Type, it is derived from the extension
+// of name, falling back to application/octet-stream. A non-zero modtime
+// is sent as Last-Modified.
+//
+// Single byte-range requests ("Range: bytes=a-b", "a-", "-n") are served
+// as 206 Partial Content. Requests for multiple ranges receive the full
+// entity with 200, which RFC 7233 permits. A conditional If-Range header
+// is honoured: the range is served only when its ETag (strong comparison
+// against the response's ETag header) or HTTP-date (exact match against
+// modtime) still matches; otherwise the full entity is served.
+func ServeContent(w ResponseWriter, req *Request, name string, modtime time.Time, content io.ReadSeeker) {
+	size, err := content.Seek(0, io.SeekEnd)
+	if err != nil {
+		Error(w, "seeker can't seek", StatusInternalServerError)
+		return
+	}
+
+	h := w.Header()
+	if h.Get("Content-Type") == "" {
+		ctype := mime.TypeByExtension(filepath.Ext(name))
+		if ctype == "" {
+			ctype = "application/octet-stream"
+		}
+		h.Set("Content-Type", ctype)
+	}
+	if !isZeroTime(modtime) {
+		h.Set("Last-Modified", modtime.UTC().Format(TimeFormat))
+	}
+	h.Set("Accept-Ranges", "bytes")
+
+	start, length, status := int64(0), size, StatusOK
+	if rangeHeader := req.Header.Get("Range"); rangeHeader != "" && ifRangeMatches(req, h, modtime) {
+		rs, rl, ok, err := parseRange(rangeHeader, size)
+		if err != nil {
+			h.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+			Error(w, err.Error(), StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		if ok {
+			start, length, status = rs, rl, StatusPartialContent
+			h.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, start+length-1, size))
+		}
+	}
+
+	if _, err := content.Seek(start, io.SeekStart); err != nil {
+		Error(w, "seeker can't seek", StatusInternalServerError)
+		return
+	}
+	h.Set("Content-Length", strconv.FormatInt(length, 10))
+	w.WriteHeader(status)
+
+	if req.Method != MethodHead {
+		io.CopyN(w, content, length)
+	}
+}
+
+// ServeContentGzip is like ServeContent but can serve a precompressed
+// gzip variant of the content, avoiding compression at request time.
+//
+// When gzipped is non-nil and the request's Accept-Encoding allows
+// gzip, the gzipped content is served with "Content-Encoding: gzip";
+// otherwise content is served as-is. Either way, when a variant exists
+// the response carries "Vary: Accept-Encoding" so caches keep the two
+// apart. The Content-Type is derived from name (for example "app.js",
+// not "app.js.gz"), and Range requests apply to the representation
+// actually served, so byte offsets refer to the compressed bytes when
+// the gzip variant is chosen. An ETag set by the caller should differ
+// between the two representations.
+func ServeContentGzip(w ResponseWriter, req *Request, name string, modtime time.Time, content, gzipped io.ReadSeeker) {
+	if gzipped == nil {
+		ServeContent(w, req, name, modtime, content)
+		return
+	}
+	h := w.Header()
+	h.Add("Vary", "Accept-Encoding")
+	if !acceptsEncoding(req.Header, "gzip") {
+		ServeContent(w, req, name, modtime, content)
+		return
+	}
+	h.Set("Content-Encoding", "gzip")
+	ServeContent(w, req, name, modtime, gzipped)
+}
+
+// acceptsEncoding reports whether the Accept-Encoding header lists
+// coding with a non-zero quality.
+func acceptsEncoding(h Header, coding string) bool {
+	for _, v := range h.Values("Accept-Encoding") {
+		for _, part := range strings.Split(v, ",") {
+			name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+			if !strings.EqualFold(strings.TrimSpace(name), coding) {
+				continue
+			}
+			q, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+			if !ok {
+				return true
+			}
+			if f, err := strconv.ParseFloat(q, 64); err == nil && f > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ifRangeMatches reports whether a Range header should be honoured given
+// the request's If-Range precondition. Without If-Range it is always true.
+func ifRangeMatches(req *Request, h Header, modtime time.Time) bool {
+	ir := strings.TrimSpace(req.Header.Get("If-Range"))
+	if ir == "" {
+		return true
+	}
+
+	// Entity-tag form. Weak validators never match (strong comparison).
+	if strings.HasPrefix(ir, `"`) || strings.HasPrefix(ir, "W/") {
+		etag := h.Get("ETag")
+		return etag != "" && !strings.HasPrefix(ir, "W/") && !strings.HasPrefix(etag, "W/") && ir == etag
+	}
+
+	// HTTP-date form: must equal the modification time to the second.
+	if isZeroTime(modtime) {
+		return false
+	}
+	t, err := ParseTime(ir)
+	if err != nil {
+		return false
+	}
+	return t.Unix() == modtime.Unix()
+}
+
+// parseRange parses a "bytes=" Range header against a content of the
+// given size. ok is false when the header should be ignored (multiple
+// ranges). err is non-nil when the header is malformed or unsatisfiable.
+func parseRange(s string, size int64) (start, length int64, ok bool, err error) {
+	const prefix = "bytes="
+	if !strings.HasPrefix(s, prefix) {
+		return 0, 0, false, errors.New("invalid range")
+	}
+	spec := strings.TrimSpace(s[len(prefix):])
+	if strings.Contains(spec, ",") {
+		return 0, 0, false, nil
+	}
+
+	first, last, found := strings.Cut(spec, "-")
+	if !found {
+		return 0, 0, false, errors.New("invalid range")
+	}
+	first, last = strings.TrimSpace(first), strings.TrimSpace(last)
+
+	if first == "" {
+		// Suffix range "-n": the final n bytes.
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return 0, 0, false, errors.New("invalid range")
+		}
+		if n == 0 || size == 0 {
+			return 0, 0, false, errUnsatisfiableRange
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, n, true, nil
+	}
+
+	start, err = strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, false, errors.New("invalid range")
+	}
+	if start >= size {
+		return 0, 0, false, errUnsatisfiableRange
+	}
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, false, errors.New("invalid range")
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	return start, end - start + 1, true, nil
+}
+
+// isZeroTime reports whether t is the zero time or the Unix epoch,
+// both of which mean "unknown" for Last-Modified purposes.
+func isZeroTime(t time.Time) bool {
+	return t.IsZero() || t.Equal(time.Unix(0, 0))
+}
@@ -0,0 +1,202 @@
+package http_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/net/http"
+)
+
+// ── ServeContent tests ──────────────────────────────────────────────
+
+const contentBody = "0123456789abcdefghij"
+
+var contentModTime = time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+
+// serveContent runs ServeContent for a request with the given headers
+// against contentBody, with the response ETag preset to `"v1"`.
+func serveContent(t *testing.T, headers map[string]string) *testResponse {
+	t.Helper()
+	req := wghttp.NewRequest(wghttp.MethodGet, "/file.txt", nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := wghttp.NewTestResponseWriter()
+	w.Header().Set("ETag", `"v1"`)
+	wghttp.ServeContent(w, req, "file.txt", contentModTime, strings.NewReader(contentBody))
+	return &testResponse{status: w.StatusCode(), body: string(w.Body()), header: w.Header()}
+}
+
+type testResponse struct {
+	status int
+	body   string
+	header wghttp.Header
+}
+
+func TestServeContent_FullEntity(t *testing.T) {
+	resp := serveContent(t, nil)
+
+	if resp.status != wghttp.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.status)
+	}
+	if resp.body != contentBody {
+		t.Fatalf("expected full body, got '%s'", resp.body)
+	}
+	if got := resp.header.Get("Content-Type"); !strings.HasPrefix(got, "text/plain") {
+		t.Fatalf("expected text/plain content type, got '%s'", got)
+	}
+	if got := resp.header.Get("Last-Modified"); got != "Sun, 15 Mar 2026 12:00:00 GMT" {
+		t.Fatalf("unexpected Last-Modified: '%s'", got)
+	}
+}
+
+func TestServeContent_RangeRequest(t *testing.T) {
+	resp := serveContent(t, map[string]string{"Range": "bytes=2-5"})
+
+	if resp.status != wghttp.StatusPartialContent {
+		t.Fatalf("expected status 206, got %d", resp.status)
+	}
+	if resp.body != "2345" {
+		t.Fatalf("expected body '2345', got '%s'", resp.body)
+	}
+	if got := resp.header.Get("Content-Range"); got != "bytes 2-5/20" {
+		t.Fatalf("unexpected Content-Range: '%s'", got)
+	}
+}
+
+func TestServeContent_SuffixRange(t *testing.T) {
+	resp := serveContent(t, map[string]string{"Range": "bytes=-3"})
+
+	if resp.status != wghttp.StatusPartialContent || resp.body != "hij" {
+		t.Fatalf("expected 206 'hij', got %d '%s'", resp.status, resp.body)
+	}
+}
+
+func TestServeContent_UnsatisfiableRange(t *testing.T) {
+	resp := serveContent(t, map[string]string{"Range": "bytes=50-60"})
+
+	if resp.status != wghttp.StatusRequestedRangeNotSatisfiable {
+		t.Fatalf("expected status 416, got %d", resp.status)
+	}
+	if got := resp.header.Get("Content-Range"); got != "bytes */20" {
+		t.Fatalf("unexpected Content-Range: '%s'", got)
+	}
+}
+
+// ── If-Range tests ──────────────────────────────────────────────────
+
+func TestServeContent_IfRangeETagMatchServesPartial(t *testing.T) {
+	resp := serveContent(t, map[string]string{"Range": "bytes=0-3", "If-Range": `"v1"`})
+
+	if resp.status != wghttp.StatusPartialContent || resp.body != "0123" {
+		t.Fatalf("expected 206 '0123', got %d '%s'", resp.status, resp.body)
+	}
+}
+
+func TestServeContent_IfRangeETagMismatchServesFull(t *testing.T) {
+	resp := serveContent(t, map[string]string{"Range": "bytes=0-3", "If-Range": `"v0"`})
+
+	if resp.status != wghttp.StatusOK || resp.body != contentBody {
+		t.Fatalf("expected 200 full body, got %d '%s'", resp.status, resp.body)
+	}
+	if resp.header.Get("Content-Range") != "" {
+		t.Fatal("full response must not carry Content-Range")
+	}
+}
+
+func TestServeContent_IfRangeWeakETagServesFull(t *testing.T) {
+	resp := serveContent(t, map[string]string{"Range": "bytes=0-3", "If-Range": `W/"v1"`})
+
+	if resp.status != wghttp.StatusOK {
+		t.Fatalf("weak If-Range must not match: expected 200, got %d", resp.status)
+	}
+}
+
+func TestServeContent_IfRangeDateMatchServesPartial(t *testing.T) {
+	resp := serveContent(t, map[string]string{
+		"Range":    "bytes=0-3",
+		"If-Range": contentModTime.Format(wghttp.TimeFormat),
+	})
+
+	if resp.status != wghttp.StatusPartialContent || resp.body != "0123" {
+		t.Fatalf("expected 206 '0123', got %d '%s'", resp.status, resp.body)
+	}
+}
+
+func TestServeContent_IfRangeDateMismatchServesFull(t *testing.T) {
+	resp := serveContent(t, map[string]string{
+		"Range":    "bytes=0-3",
+		"If-Range": contentModTime.Add(-time.Hour).Format(wghttp.TimeFormat),
+	})
+
+	if resp.status != wghttp.StatusOK || resp.body != contentBody {
+		t.Fatalf("expected 200 full body, got %d '%s'", resp.status, resp.body)
+	}
+}
+
+// ── ServeContentGzip tests ──────────────────────────────────────────
+
+const gzipVariant = "\x1f\x8b-pretend-gzip-bytes"
+
+func serveContentGzip(t *testing.T, headers map[string]string) *testResponse {
+	t.Helper()
+	req := wghttp.NewRequest(wghttp.MethodGet, "/app.js", nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := wghttp.NewTestResponseWriter()
+	wghttp.ServeContentGzip(w, req, "app.js", contentModTime,
+		strings.NewReader(contentBody), strings.NewReader(gzipVariant))
+	return &testResponse{status: w.StatusCode(), body: string(w.Body()), header: w.Header()}
+}
+
+func TestServeContentGzip_AcceptingClientGetsVariant(t *testing.T) {
+	resp := serveContentGzip(t, map[string]string{"Accept-Encoding": "br, gzip"})
+
+	if resp.body != gzipVariant {
+		t.Fatalf("expected gzip variant body, got '%s'", resp.body)
+	}
+	if got := resp.header.Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("expected Content-Encoding 'gzip', got '%s'", got)
+	}
+	if got := resp.header.Get("Vary"); got != "Accept-Encoding" {
+		t.Fatalf("expected Vary 'Accept-Encoding', got '%s'", got)
+	}
+	if got := resp.header.Get("Content-Type"); !strings.Contains(got, "javascript") {
+		t.Fatalf("expected JavaScript content type from the plain name, got '%s'", got)
+	}
+	if got := resp.header.Get("Content-Length"); got != "21" {
+		t.Fatalf("expected Content-Length of the variant (21), got '%s'", got)
+	}
+}
+
+func TestServeContentGzip_NonAcceptingClientGetsPlain(t *testing.T) {
+	for _, ae := range []string{"", "identity", "gzip;q=0"} {
+		resp := serveContentGzip(t, map[string]string{"Accept-Encoding": ae})
+
+		if resp.body != contentBody {
+			t.Fatalf("Accept-Encoding %q: expected plain body, got '%s'", ae, resp.body)
+		}
+		if got := resp.header.Get("Content-Encoding"); got != "" {
+			t.Fatalf("Accept-Encoding %q: expected no Content-Encoding, got '%s'", ae, got)
+		}
+		if got := resp.header.Get("Vary"); got != "Accept-Encoding" {
+			t.Fatalf("Accept-Encoding %q: expected Vary 'Accept-Encoding', got '%s'", ae, got)
+		}
+	}
+}
+
+func TestServeContentGzip_RangeAppliesToServedRepresentation(t *testing.T) {
+	resp := serveContentGzip(t, map[string]string{"Accept-Encoding": "gzip", "Range": "bytes=0-1"})
+
+	if resp.status != wghttp.StatusPartialContent {
+		t.Fatalf("expected status 206, got %d", resp.status)
+	}
+	if resp.body != gzipVariant[:2] {
+		t.Fatalf("expected the first two gzip bytes, got %q", resp.body)
+	}
+	if got := resp.header.Get("Content-Range"); got != "bytes 0-1/21" {
+		t.Fatalf("unexpected Content-Range: '%s'", got)
+	}
+}
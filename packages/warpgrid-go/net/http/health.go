@@ -0,0 +1,67 @@
+package http
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// HealthCheck is a named readiness check. Check returns nil when the
+// dependency it probes (database, upstream, cache) is healthy.
+type HealthCheck struct {
+	Name  string
+	Check func() error
+}
+
+// healthFailure is one entry in the failures list of a 503 response.
+type healthFailure struct {
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+// healthStatus is the JSON body written by health handlers.
+type healthStatus struct {
+	Status   string          `json:"status"`
+	Failures []healthFailure `json:"failures,omitempty"`
+}
+
+// HealthHandler returns a Handler for health probes that runs the given
+// checks in order. Checks are reported by position ("check-0", ...);
+// use ReadinessHandler to give them descriptive names.
+//
+// With no checks it acts as a liveness probe and always replies 200
+// {"status":"ok"}. With checks it acts as a readiness probe: 200 when
+// every check passes, otherwise 503 with the failed checks listed.
+func HealthHandler(checks ...func() error) Handler {
+	named := make([]HealthCheck, len(checks))
+	for i, check := range checks {
+		named[i] = HealthCheck{Name: "check-" + strconv.Itoa(i), Check: check}
+	}
+	return ReadinessHandler(named...)
+}
+
+// ReadinessHandler is like HealthHandler but reports failures using each
+// check's Name, for example:
+//
+//	503 {"status":"unavailable","failures":[{"name":"postgres","error":"dial tcp: connection refused"}]}
+func ReadinessHandler(checks ...HealthCheck) Handler {
+	return HandlerFunc(func(w ResponseWriter, r *Request) {
+		status := healthStatus{Status: "ok"}
+		for _, c := range checks {
+			if err := c.Check(); err != nil {
+				status.Failures = append(status.Failures, healthFailure{Name: c.Name, Error: err.Error()})
+			}
+		}
+
+		code := StatusOK
+		if len(status.Failures) > 0 {
+			status.Status = "unavailable"
+			code = StatusServiceUnavailable
+		}
+
+		body, _ := json.Marshal(status)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(code)
+		w.Write(body)
+	})
+}
@@ -0,0 +1,87 @@
+package http_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/net/http"
+)
+
+// ── HealthHandler tests ─────────────────────────────────────────────
+
+type healthBody struct {
+	Status   string `json:"status"`
+	Failures []struct {
+		Name  string `json:"name"`
+		Error string `json:"error"`
+	} `json:"failures"`
+}
+
+func serveHealth(t *testing.T, h wghttp.Handler) (int, healthBody) {
+	t.Helper()
+	w := wghttp.NewTestResponseWriter()
+	h.ServeHTTP(w, wghttp.NewRequest(wghttp.MethodGet, "/health", nil))
+
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("expected application/json content type, got '%s'", ct)
+	}
+	var body healthBody
+	if err := json.Unmarshal(w.Body(), &body); err != nil {
+		t.Fatalf("invalid JSON body %q: %v", w.Body(), err)
+	}
+	return w.StatusCode(), body
+}
+
+func TestHealthHandler_NoChecksIsLiveness(t *testing.T) {
+	status, body := serveHealth(t, wghttp.HealthHandler())
+
+	if status != wghttp.StatusOK {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+	if body.Status != "ok" {
+		t.Fatalf("expected status 'ok', got '%s'", body.Status)
+	}
+}
+
+func TestHealthHandler_AllChecksPass(t *testing.T) {
+	ok := func() error { return nil }
+	status, body := serveHealth(t, wghttp.HealthHandler(ok, ok))
+
+	if status != wghttp.StatusOK || body.Status != "ok" {
+		t.Fatalf("expected 200 ok, got %d %s", status, body.Status)
+	}
+	if len(body.Failures) != 0 {
+		t.Fatalf("expected no failures, got %v", body.Failures)
+	}
+}
+
+func TestHealthHandler_OneFailingCheckReturns503(t *testing.T) {
+	ok := func() error { return nil }
+	bad := func() error { return errors.New("connection refused") }
+	status, body := serveHealth(t, wghttp.HealthHandler(ok, bad))
+
+	if status != wghttp.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d", status)
+	}
+	if body.Status != "unavailable" {
+		t.Fatalf("expected status 'unavailable', got '%s'", body.Status)
+	}
+	if len(body.Failures) != 1 || body.Failures[0].Name != "check-1" || body.Failures[0].Error != "connection refused" {
+		t.Fatalf("unexpected failures: %+v", body.Failures)
+	}
+}
+
+func TestReadinessHandler_ReportsCheckNames(t *testing.T) {
+	status, body := serveHealth(t, wghttp.ReadinessHandler(
+		wghttp.HealthCheck{Name: "postgres", Check: func() error { return errors.New("timeout") }},
+		wghttp.HealthCheck{Name: "cache", Check: func() error { return nil }},
+	))
+
+	if status != wghttp.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d", status)
+	}
+	if len(body.Failures) != 1 || body.Failures[0].Name != "postgres" {
+		t.Fatalf("expected postgres failure, got %+v", body.Failures)
+	}
+}
@@ -14,8 +14,11 @@ package http
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"io"
 	"net/url"
+	"strings"
 )
 
 // HTTP method constants matching net/http.
@@ -33,45 +36,151 @@ const (
 
 // HTTP status code constants matching net/http.
 const (
-	StatusOK                  = 200
-	StatusCreated             = 201
-	StatusNoContent           = 204
-	StatusBadRequest          = 400
-	StatusUnauthorized        = 401
-	StatusForbidden           = 403
-	StatusNotFound            = 404
-	StatusMethodNotAllowed    = 405
-	StatusInternalServerError = 500
-	StatusBadGateway          = 502
-	StatusServiceUnavailable  = 503
+	StatusSwitchingProtocols           = 101
+	StatusEarlyHints                   = 103
+	StatusOK                           = 200
+	StatusCreated                      = 201
+	StatusNoContent                    = 204
+	StatusPartialContent               = 206
+	StatusMovedPermanently             = 301
+	StatusNotModified                  = 304
+	StatusBadRequest                   = 400
+	StatusUnauthorized                 = 401
+	StatusForbidden                    = 403
+	StatusNotFound                     = 404
+	StatusMethodNotAllowed             = 405
+	StatusRequestedRangeNotSatisfiable = 416
+	StatusUpgradeRequired              = 426
+	StatusRequestHeaderFieldsTooLarge  = 431
+	StatusInternalServerError          = 500
+	StatusBadGateway                   = 502
+	StatusServiceUnavailable           = 503
 )
 
+// statusText holds the reason phrases for the status constants above.
+var statusText = map[int]string{
+	StatusSwitchingProtocols:           "Switching Protocols",
+	StatusEarlyHints:                   "Early Hints",
+	StatusOK:                           "OK",
+	StatusCreated:                      "Created",
+	StatusNoContent:                    "No Content",
+	StatusPartialContent:               "Partial Content",
+	StatusMovedPermanently:             "Moved Permanently",
+	StatusNotModified:                  "Not Modified",
+	StatusBadRequest:                   "Bad Request",
+	StatusUnauthorized:                 "Unauthorized",
+	StatusForbidden:                    "Forbidden",
+	StatusNotFound:                     "Not Found",
+	StatusMethodNotAllowed:             "Method Not Allowed",
+	StatusRequestedRangeNotSatisfiable: "Requested Range Not Satisfiable",
+	StatusUpgradeRequired:              "Upgrade Required",
+	StatusRequestHeaderFieldsTooLarge:  "Request Header Fields Too Large",
+	StatusInternalServerError:          "Internal Server Error",
+	StatusBadGateway:                   "Bad Gateway",
+	StatusServiceUnavailable:           "Service Unavailable",
+}
+
+// StatusText returns a text for the HTTP status code, matching
+// net/http.StatusText. It returns the empty string if the code is
+// unknown.
+func StatusText(code int) string {
+	return statusText[code]
+}
+
 // Header represents HTTP headers as a map of header name to values.
 // This matches the net/http.Header interface.
+//
+// As in net/http, the Set, Add, Get, Values, and Del methods canonicalize
+// the key with CanonicalHeaderKey, so h.Add("content-type", v) is stored
+// under "Content-Type". Direct map access bypasses canonicalization.
 type Header map[string][]string
 
 // Set sets the header entry associated with key to the single value.
 func (h Header) Set(key, value string) {
-	h[key] = []string{value}
+	h[CanonicalHeaderKey(key)] = []string{value}
 }
 
 // Get returns the first value associated with the given key.
 // Returns empty string if the key is not present.
 func (h Header) Get(key string) string {
-	if values, ok := h[key]; ok && len(values) > 0 {
+	if values, ok := h[CanonicalHeaderKey(key)]; ok && len(values) > 0 {
 		return values[0]
 	}
 	return ""
 }
 
+// Values returns all values associated with the given key.
+// The returned slice is not a copy.
+func (h Header) Values(key string) []string {
+	return h[CanonicalHeaderKey(key)]
+}
+
 // Add appends a value to the header associated with key.
 func (h Header) Add(key, value string) {
+	key = CanonicalHeaderKey(key)
 	h[key] = append(h[key], value)
 }
 
 // Del removes the header entry associated with key.
 func (h Header) Del(key string) {
-	delete(h, key)
+	delete(h, CanonicalHeaderKey(key))
+}
+
+// CanonicalHeaderKey returns the canonical format of the header key s,
+// matching net/http.CanonicalHeaderKey: the first letter and any letter
+// following a hyphen are upper case, the rest are lower case. For
+// example, "accept-encoding" becomes "Accept-Encoding". If s contains a
+// space or other invalid header field byte, it is returned unchanged.
+func CanonicalHeaderKey(s string) string {
+	needsChange := false
+	upper := true
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !isTokenByte(c) {
+			return s
+		}
+		if upper && 'a' <= c && c <= 'z' || !upper && 'A' <= c && c <= 'Z' {
+			needsChange = true
+		}
+		upper = c == '-'
+	}
+	if !needsChange {
+		return s
+	}
+
+	b := []byte(s)
+	upper = true
+	for i, c := range b {
+		if upper && 'a' <= c && c <= 'z' {
+			b[i] = c - ('a' - 'A')
+		} else if !upper && 'A' <= c && c <= 'Z' {
+			b[i] = c + ('a' - 'A')
+		}
+		upper = c == '-'
+	}
+	return string(b)
+}
+
+// isTokenByte reports whether c may appear in a header field name
+// (RFC 7230 token characters).
+func isTokenByte(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0
+}
+
+// Clone returns a deep copy of h, or nil if h is nil.
+func (h Header) Clone() Header {
+	if h == nil {
+		return nil
+	}
+	h2 := make(Header, len(h))
+	for k, v := range h {
+		h2[k] = append([]string(nil), v...)
+	}
+	return h2
 }
 
 // Handler responds to an HTTP request.
@@ -87,6 +196,10 @@ func (f HandlerFunc) ServeHTTP(w ResponseWriter, r *Request) {
 	f(w, r)
 }
 
+// Middleware wraps a Handler with cross-cutting behaviour, such as
+// CORS, returning the wrapped Handler.
+type Middleware func(Handler) Handler
+
 // ResponseWriter interface for building HTTP responses.
 // Matches the net/http.ResponseWriter interface.
 type ResponseWriter interface {
@@ -101,8 +214,116 @@ type Request struct {
 	URL    *url.URL
 	Header Header
 	Body   io.ReadCloser
+
+	// Host is the host the request was sent to, from the Host header or
+	// else the request URI's authority. It may include a port.
+	Host string
+
+	// Scheme is the scheme the client used, "http" or "https", taken
+	// from an absolute request URI. It is empty when the host did not
+	// say; AbsoluteURL then assumes "http".
+	Scheme string
+
+	// Form holds the parsed URL query and form body values. It is only
+	// available after ParseForm is called.
+	Form url.Values
+
+	// PostForm holds the parsed form body values of a POST, PUT, or
+	// PATCH request. It is only available after ParseForm is called.
+	PostForm url.Values
+
+	// Close reports whether the client sent "Connection: close", asking
+	// for the connection to be closed after this response.
+	Close bool
+
+	// Trailer holds the trailer fields the client declared after the
+	// body. As in net/http, the names are present with nil values until
+	// Body has been read to EOF, when the values are filled in.
+	Trailer Header
+
+	ctx        context.Context
+	pathValues map[string]string
+}
+
+// Context returns the request's context. It is never nil; requests
+// without an explicit context use context.Background.
+func (r *Request) Context() context.Context {
+	if r.ctx != nil {
+		return r.ctx
+	}
+	return context.Background()
 }
 
+// WithContext returns a shallow copy of r with its context changed to
+// ctx. The header map and body are shared with r; use Clone when the
+// copy will be mutated. The provided ctx must be non-nil.
+func (r *Request) WithContext(ctx context.Context) *Request {
+	if ctx == nil {
+		panic("http: nil context")
+	}
+	r2 := *r
+	r2.ctx = ctx
+	return &r2
+}
+
+// Clone returns a deep copy of r with its context changed to ctx, so
+// middleware can modify headers or the URL without affecting the
+// original. The provided ctx must be non-nil.
+//
+// Unlike net/http, Clone also makes the body safe to read twice: the
+// remaining body is read into memory once and both r and the clone are
+// given independent readers over that buffer. This costs one copy of
+// the unread body per call; handlers streaming large uploads should
+// avoid cloning before the body is consumed.
+func (r *Request) Clone(ctx context.Context) *Request {
+	if ctx == nil {
+		panic("http: nil context")
+	}
+	r2 := *r
+	r2.ctx = ctx
+	r2.Header = r.Header.Clone()
+	if r.URL != nil {
+		u := *r.URL
+		if r.URL.User != nil {
+			user := *r.URL.User
+			u.User = &user
+		}
+		r2.URL = &u
+	}
+	r2.Form = cloneValues(r.Form)
+	r2.PostForm = cloneValues(r.PostForm)
+	if r.Body != nil {
+		data, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		r.Body = newReplayBody(data, err)
+		r2.Body = newReplayBody(data, err)
+	}
+	r2.Trailer = r.Trailer.Clone()
+	return &r2
+}
+
+// cloneValues returns a deep copy of v, or nil if v is nil.
+func cloneValues(v url.Values) url.Values {
+	if v == nil {
+		return nil
+	}
+	return url.Values(Header(v).Clone())
+}
+
+// newReplayBody returns a reader over data that reports err (if any)
+// once data is exhausted, preserving a read error seen while buffering.
+func newReplayBody(data []byte, err error) io.ReadCloser {
+	if err == nil {
+		return io.NopCloser(bytes.NewReader(data))
+	}
+	return io.NopCloser(io.MultiReader(bytes.NewReader(data), errReader{err}))
+}
+
+// errReader is an io.Reader that always fails with err.
+type errReader struct{ err error }
+
+func (e errReader) Read([]byte) (int, error) { return 0, e.err }
+
 // NewRequest creates a Request from method, URI, and optional body.
 // Used for testing and internal request construction.
 func NewRequest(method, uri string, body []byte) *Request {
@@ -123,13 +344,74 @@ func NewRequest(method, uri string, body []byte) *Request {
 		URL:    u,
 		Header: make(Header),
 		Body:   bodyReader,
+		Host:   u.Host,
+		Scheme: u.Scheme,
 	}
 }
 
 // Error replies to the request with the specified error message and code.
-// Matches the net/http.Error signature.
+// As net/http.Error does, it removes any Content-Length, marks the body
+// as plain text with "X-Content-Type-Options: nosniff", and writes the
+// message followed by a newline.
 func Error(w ResponseWriter, error string, code int) {
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", "text/plain; charset=utf-8")
+	h.Set("X-Content-Type-Options", "nosniff")
 	w.WriteHeader(code)
-	w.Write([]byte(error))
+	w.Write([]byte(error + "\n"))
+}
+
+// CloseConnection marks the response as the last on its connection by
+// setting "Connection: close", telling the host not to keep the stream
+// open after it. HandleRequestWith does this automatically when the
+// request set Close.
+func CloseConnection(w ResponseWriter) {
+	if !hasToken(w.Header(), "Connection", "close") {
+		w.Header().Set("Connection", "close")
+	}
+}
+
+// hasToken reports whether the comma-separated header name lists token,
+// compared case-insensitively.
+func hasToken(h Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ErrorJSON is like Error but writes the message as a JSON object of
+// the form {"error":"...","code":404} with an application/json content
+// type, for APIs whose clients expect JSON error bodies. The message is
+// written as given; use JSONError to report a Go error safely.
+func ErrorJSON(w ResponseWriter, message string, code int) {
+	body, _ := json.Marshal(struct {
+		Error string `json:"error"`
+		Code  int    `json:"code"`
+	}{message, code})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(body)
+}
+
+// JSONError replies with err in the same JSON shape as ErrorJSON. In
+// ProductionMode the error text, which may carry internal detail from
+// wrapped errors, is replaced by the generic status text for code.
+func JSONError(w ResponseWriter, code int, err error) {
+	message := "unknown error"
+	if err != nil {
+		message = err.Error()
+	}
+	if ProductionMode {
+		message = StatusText(code)
+		if message == "" {
+			message = "error"
+		}
+	}
+	ErrorJSON(w, message, code)
 }
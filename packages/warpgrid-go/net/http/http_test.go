@@ -2,7 +2,12 @@ package http_test
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io"
+	"strconv"
+	"strings"
 	"testing"
 
 	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/net/http"
@@ -154,6 +159,96 @@ func TestServeMux_MultiplePatterns(t *testing.T) {
 	}
 }
 
+// ── ServeMux registration validation tests ──────────────────────────
+
+func TestServeMux_TryHandleValidRegistration(t *testing.T) {
+	mux := wghttp.NewServeMux()
+	err := mux.TryHandle("/users", wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		w.Write([]byte("users"))
+	}))
+	if err != nil {
+		t.Fatalf("TryHandle failed: %v", err)
+	}
+
+	req := wghttp.NewRequest(wghttp.MethodGet, "/users", nil)
+	w := wghttp.NewTestResponseWriter()
+	mux.ServeHTTP(w, req)
+
+	if string(w.Body()) != "users" {
+		t.Fatalf("expected body 'users', got '%s'", string(w.Body()))
+	}
+}
+
+func TestServeMux_TryHandleDuplicateReturnsError(t *testing.T) {
+	mux := wghttp.NewServeMux()
+	noop := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {})
+
+	if err := mux.TryHandle("/users", noop); err != nil {
+		t.Fatalf("first registration failed: %v", err)
+	}
+	err := mux.TryHandle("/users", noop)
+	if !errors.Is(err, wghttp.ErrDuplicatePattern) {
+		t.Fatalf("expected ErrDuplicatePattern, got %v", err)
+	}
+}
+
+func TestServeMux_HandleDuplicatePanics(t *testing.T) {
+	mux := wghttp.NewServeMux()
+	mux.HandleFunc("/users", func(w wghttp.ResponseWriter, r *wghttp.Request) {})
+
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatal("expected panic on duplicate registration")
+		}
+		err, ok := r.(error)
+		if !ok || !errors.Is(err, wghttp.ErrDuplicatePattern) {
+			t.Fatalf("expected ErrDuplicatePattern panic, got %v", r)
+		}
+	}()
+	mux.HandleFunc("/users", func(w wghttp.ResponseWriter, r *wghttp.Request) {})
+}
+
+func TestServeMux_TryHandleRejectsInvalidPatterns(t *testing.T) {
+	noop := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {})
+
+	for _, pattern := range []string{"", "users", "example.com/users"} {
+		t.Run(pattern, func(t *testing.T) {
+			mux := wghttp.NewServeMux()
+			err := mux.TryHandle(pattern, noop)
+			if !errors.Is(err, wghttp.ErrInvalidPattern) {
+				t.Fatalf("expected ErrInvalidPattern for %q, got %v", pattern, err)
+			}
+		})
+	}
+}
+
+func TestServeMux_TryHandleRejectsNilHandler(t *testing.T) {
+	mux := wghttp.NewServeMux()
+	err := mux.TryHandle("/users", nil)
+	if !errors.Is(err, wghttp.ErrNilHandler) {
+		t.Fatalf("expected ErrNilHandler, got %v", err)
+	}
+}
+
+func TestServeMux_ReplaceOverridesRegistration(t *testing.T) {
+	mux := wghttp.NewServeMux()
+	mux.HandleFunc("/users", func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		w.Write([]byte("old"))
+	})
+	mux.Replace("/users", wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		w.Write([]byte("new"))
+	}))
+
+	req := wghttp.NewRequest(wghttp.MethodGet, "/users", nil)
+	w := wghttp.NewTestResponseWriter()
+	mux.ServeHTTP(w, req)
+
+	if string(w.Body()) != "new" {
+		t.Fatalf("expected replaced handler body 'new', got '%s'", string(w.Body()))
+	}
+}
+
 // ── ResponseWriter tests ────────────────────────────────────────────
 
 func TestResponseWriter_DefaultStatus200(t *testing.T) {
@@ -271,6 +366,67 @@ func TestNewRequest_HeadersAreInitialized(t *testing.T) {
 	}
 }
 
+// ── Request.Clone tests ─────────────────────────────────────────────
+
+type ctxKey string
+
+func TestRequestClone_HeaderMutationDoesNotAffectOriginal(t *testing.T) {
+	req := wghttp.NewRequest(wghttp.MethodGet, "/users?page=1", nil)
+	req.Header.Set("X-Trace", "original")
+
+	clone := req.Clone(context.Background())
+	clone.Header.Set("X-Trace", "clone")
+	clone.Header.Add("X-Added", "yes")
+	clone.URL.Path = "/rewritten"
+
+	if got := req.Header.Get("X-Trace"); got != "original" {
+		t.Fatalf("original X-Trace mutated: got '%s'", got)
+	}
+	if req.Header.Get("X-Added") != "" {
+		t.Fatal("header added to clone leaked into original")
+	}
+	if req.URL.Path != "/users" {
+		t.Fatalf("original URL mutated: got '%s'", req.URL.Path)
+	}
+}
+
+func TestRequestClone_BothCanReadFullBody(t *testing.T) {
+	body := []byte(`{"name":"Alice"}`)
+	req := wghttp.NewRequest(wghttp.MethodPost, "/users", body)
+
+	clone := req.Clone(context.Background())
+
+	cloneBody, err := io.ReadAll(clone.Body)
+	if err != nil {
+		t.Fatalf("reading clone body: %v", err)
+	}
+	origBody, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Fatalf("reading original body: %v", err)
+	}
+
+	if !bytes.Equal(cloneBody, body) {
+		t.Fatalf("clone body: expected '%s', got '%s'", body, cloneBody)
+	}
+	if !bytes.Equal(origBody, body) {
+		t.Fatalf("original body: expected '%s', got '%s'", body, origBody)
+	}
+}
+
+func TestRequestClone_CarriesGivenContext(t *testing.T) {
+	req := wghttp.NewRequest(wghttp.MethodGet, "/", nil)
+	ctx := context.WithValue(context.Background(), ctxKey("user"), "alice")
+
+	clone := req.Clone(ctx)
+
+	if got := clone.Context().Value(ctxKey("user")); got != "alice" {
+		t.Fatalf("clone context value: expected 'alice', got %v", got)
+	}
+	if req.Context().Value(ctxKey("user")) != nil {
+		t.Fatal("original request context should be unchanged")
+	}
+}
+
 // ── Error helper tests ──────────────────────────────────────────────
 
 func TestError_WritesStatusAndMessage(t *testing.T) {
@@ -280,8 +436,11 @@ func TestError_WritesStatusAndMessage(t *testing.T) {
 	if w.StatusCode() != wghttp.StatusBadRequest {
 		t.Fatalf("expected status 400, got %d", w.StatusCode())
 	}
-	if string(w.Body()) != "bad request" {
-		t.Fatalf("expected body 'bad request', got '%s'", string(w.Body()))
+	if string(w.Body()) != "bad request\n" {
+		t.Fatalf("expected body 'bad request\\n', got '%s'", string(w.Body()))
+	}
+	if w.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Fatalf("expected X-Content-Type-Options nosniff, got '%s'", w.Header().Get("X-Content-Type-Options"))
 	}
 	if w.Header().Get("Content-Type") != "text/plain; charset=utf-8" {
 		t.Fatalf("expected text/plain content type, got '%s'",
@@ -329,6 +488,70 @@ func TestHeader_Get_MissingKeyReturnsEmpty(t *testing.T) {
 	}
 }
 
+func TestHeader_AddCanonicalizesKey(t *testing.T) {
+	h := make(wghttp.Header)
+	h.Add("content-type", "application/json")
+
+	if got := h.Get("Content-Type"); got != "application/json" {
+		t.Fatalf("Get(Content-Type): expected 'application/json', got '%s'", got)
+	}
+	if _, ok := h["Content-Type"]; !ok {
+		t.Fatalf("expected value stored under canonical key, got keys %v", h)
+	}
+	if _, ok := h["content-type"]; ok {
+		t.Fatal("value should not be stored under the non-canonical key")
+	}
+}
+
+func TestHeader_MixedCaseOperationsShareKey(t *testing.T) {
+	h := make(wghttp.Header)
+	h.Set("X-CUSTOM-id", "1")
+	h.Add("x-custom-ID", "2")
+
+	if got := h.Values("X-Custom-Id"); len(got) != 2 {
+		t.Fatalf("expected 2 values under X-Custom-Id, got %v", got)
+	}
+	h.Del("x-custom-id")
+	if len(h) != 0 {
+		t.Fatalf("Del with different casing should remove the entry, got %v", h)
+	}
+}
+
+func TestCanonicalHeaderKey(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"content-type", "Content-Type"},
+		{"CONTENT-LENGTH", "Content-Length"},
+		{"x-request-id", "X-Request-Id"},
+		{"Accept", "Accept"},
+		{"www-authenticate", "Www-Authenticate"},
+		{"bad header", "bad header"}, // invalid byte: unchanged
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := wghttp.CanonicalHeaderKey(tt.in); got != tt.want {
+			t.Fatalf("CanonicalHeaderKey(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestHandleRequest_RequestHeadersCanonicalized(t *testing.T) {
+	var got string
+	handler := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		got = r.Header.Get("X-Api-Key")
+	})
+
+	reqBytes := wghttp.MarshalRequest(wghttp.WitHttpRequest{
+		Method:  "GET",
+		URI:     "/",
+		Headers: []wghttp.WitHttpHeader{{Name: "x-api-key", Value: "secret"}},
+	})
+	wghttp.HandleRequestWith(handler, reqBytes)
+
+	if got != "secret" {
+		t.Fatalf("expected lowercase wire header to be readable canonically, got '%s'", got)
+	}
+}
+
 // ── HandleRequest integration tests ─────────────────────────────────
 
 func TestHandleRequest_FullRoundTrip(t *testing.T) {
@@ -416,7 +639,7 @@ func TestHandleRequest_MethodDispatch(t *testing.T) {
 	}{
 		{"GET", wghttp.StatusOK, "get"},
 		{"POST", wghttp.StatusCreated, "post"},
-		{"DELETE", wghttp.StatusMethodNotAllowed, "method not allowed"},
+		{"DELETE", wghttp.StatusMethodNotAllowed, "method not allowed\n"},
 	}
 
 	for _, tt := range tests {
@@ -543,3 +766,1306 @@ func TestWireFormat_ResponseNoHeaders(t *testing.T) {
 		t.Fatalf("body: expected empty, got %d bytes", len(decoded.Body))
 	}
 }
+
+// ── Streaming wire codec tests ──────────────────────────────────────
+
+// oneByteReader returns at most one byte per Read call, simulating a
+// host that delivers the frame in the smallest possible pieces.
+type oneByteReader struct{ r io.Reader }
+
+func (o oneByteReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	return o.r.Read(p[:1])
+}
+
+func TestDecodeRequest_ThroughPipe(t *testing.T) {
+	original := wghttp.WitHttpRequest{
+		Method: "POST",
+		URI:    "/upload?name=a.bin",
+		Headers: []wghttp.WitHttpHeader{
+			{Name: "Content-Type", Value: "application/octet-stream"},
+			{Name: "X-Chunk", Value: "1"},
+		},
+		Body: bytes.Repeat([]byte("payload-"), 8192), // 64 KiB
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.Write(wghttp.MarshalRequest(original))
+		pw.Close()
+	}()
+
+	decoded, err := wghttp.DecodeRequest(pr)
+	if err != nil {
+		t.Fatalf("DecodeRequest failed: %v", err)
+	}
+	if decoded.Method != original.Method || decoded.URI != original.URI {
+		t.Fatalf("expected %s %s, got %s %s", original.Method, original.URI, decoded.Method, decoded.URI)
+	}
+	if len(decoded.Headers) != 2 || decoded.Headers[1].Name != "X-Chunk" {
+		t.Fatalf("unexpected headers: %v", decoded.Headers)
+	}
+	if !bytes.Equal(decoded.Body, original.Body) {
+		t.Fatalf("body mismatch: got %d bytes, expected %d", len(decoded.Body), len(original.Body))
+	}
+}
+
+func TestDecodeRequest_OneByteAtATime(t *testing.T) {
+	original := wghttp.WitHttpRequest{
+		Method:  "GET",
+		URI:     "/health",
+		Headers: []wghttp.WitHttpHeader{{Name: "Accept", Value: "*/*"}},
+		Body:    []byte("tiny"),
+	}
+
+	r := oneByteReader{bytes.NewReader(wghttp.MarshalRequest(original))}
+	decoded, err := wghttp.DecodeRequest(r)
+	if err != nil {
+		t.Fatalf("DecodeRequest failed: %v", err)
+	}
+	if decoded.Method != "GET" || decoded.URI != "/health" {
+		t.Fatalf("unexpected request line: %s %s", decoded.Method, decoded.URI)
+	}
+	if len(decoded.Headers) != 1 || decoded.Headers[0].Value != "*/*" {
+		t.Fatalf("unexpected headers: %v", decoded.Headers)
+	}
+	if string(decoded.Body) != "tiny" {
+		t.Fatalf("expected body 'tiny', got '%s'", decoded.Body)
+	}
+}
+
+func TestDecodeRequest_TruncatedFrameReturnsError(t *testing.T) {
+	data := wghttp.MarshalRequest(wghttp.WitHttpRequest{
+		Method: "POST",
+		URI:    "/users",
+		Body:   []byte(`{"name":"Alice"}`),
+	})
+
+	_, err := wghttp.DecodeRequest(bytes.NewReader(data[:len(data)-4]))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
+
+func TestEncodeResponse_MatchesMarshalResponse(t *testing.T) {
+	resp := wghttp.WitHttpResponse{
+		Status: 201,
+		Headers: []wghttp.WitHttpHeader{
+			{Name: "Content-Type", Value: "application/json"},
+		},
+		Body: []byte(`{"id":1}`),
+	}
+
+	var buf bytes.Buffer
+	if err := wghttp.EncodeResponse(&buf, resp); err != nil {
+		t.Fatalf("EncodeResponse failed: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), wghttp.MarshalResponse(resp)) {
+		t.Fatal("EncodeResponse output differs from MarshalResponse")
+	}
+}
+
+func TestEncodeResponse_ThroughPipe(t *testing.T) {
+	resp := wghttp.WitHttpResponse{
+		Status:  200,
+		Headers: []wghttp.WitHttpHeader{{Name: "X-Stream", Value: "yes"}},
+		Body:    bytes.Repeat([]byte("z"), 1<<16),
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(wghttp.EncodeResponse(pw, resp))
+	}()
+
+	data, err := io.ReadAll(oneByteReader{pr})
+	if err != nil {
+		t.Fatalf("reading encoded response: %v", err)
+	}
+	decoded := wghttp.UnmarshalResponse(data)
+	if decoded.Status != 200 {
+		t.Fatalf("status: expected 200, got %d", decoded.Status)
+	}
+	if len(decoded.Headers) != 1 || decoded.Headers[0].Name != "X-Stream" {
+		t.Fatalf("unexpected headers: %v", decoded.Headers)
+	}
+	if !bytes.Equal(decoded.Body, resp.Body) {
+		t.Fatalf("body mismatch: got %d bytes, expected %d", len(decoded.Body), len(resp.Body))
+	}
+}
+
+// ── Panic recovery tests ────────────────────────────────────────────
+
+func TestHandleRequestWith_RecoversHandlerPanic(t *testing.T) {
+	handler := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		w.Header().Set("X-Partial", "yes")
+		w.Write([]byte("partial"))
+		panic("handler exploded")
+	})
+
+	reqBytes := wghttp.MarshalRequest(wghttp.WitHttpRequest{Method: "GET", URI: "/boom"})
+	resp := wghttp.UnmarshalResponse(wghttp.HandleRequestWith(handler, reqBytes))
+
+	if resp.Status != wghttp.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", resp.Status)
+	}
+	if string(resp.Body) != "internal server error: handler exploded\n" {
+		t.Fatalf("unexpected body: '%s'", resp.Body)
+	}
+	for _, h := range resp.Headers {
+		if h.Name == "X-Partial" {
+			t.Fatal("headers written before the panic should be discarded")
+		}
+	}
+}
+
+func TestServeMux_RecoversHandlerPanic(t *testing.T) {
+	mux := wghttp.NewServeMux()
+	mux.HandleFunc("/boom", func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		panic("route exploded")
+	})
+
+	req := wghttp.NewRequest(wghttp.MethodGet, "/boom", nil)
+	w := wghttp.NewTestResponseWriter()
+	mux.ServeHTTP(w, req)
+
+	if w.StatusCode() != wghttp.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", w.StatusCode())
+	}
+}
+
+func TestHandleRequestWith_ProductionModeHidesPanicValue(t *testing.T) {
+	wghttp.ProductionMode = true
+	defer func() { wghttp.ProductionMode = false }()
+
+	handler := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		panic("secret: db password is hunter2")
+	})
+
+	reqBytes := wghttp.MarshalRequest(wghttp.WitHttpRequest{Method: "GET", URI: "/"})
+	resp := wghttp.UnmarshalResponse(wghttp.HandleRequestWith(handler, reqBytes))
+
+	if resp.Status != wghttp.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", resp.Status)
+	}
+	if string(resp.Body) != "internal server error\n" {
+		t.Fatalf("expected generic body in production mode, got '%s'", resp.Body)
+	}
+}
+
+func TestHandleRequestWith_CustomPanicHandler(t *testing.T) {
+	var recovered any
+	wghttp.PanicHandler = func(w wghttp.ResponseWriter, r *wghttp.Request, v any) {
+		recovered = v
+		w.WriteHeader(wghttp.StatusServiceUnavailable)
+		w.Write([]byte("custom"))
+	}
+	defer func() { wghttp.PanicHandler = wghttp.DefaultPanicHandler }()
+
+	handler := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		panic("custom path")
+	})
+
+	reqBytes := wghttp.MarshalRequest(wghttp.WitHttpRequest{Method: "GET", URI: "/"})
+	resp := wghttp.UnmarshalResponse(wghttp.HandleRequestWith(handler, reqBytes))
+
+	if recovered != "custom path" {
+		t.Fatalf("panic handler received %v, expected 'custom path'", recovered)
+	}
+	if resp.Status != wghttp.StatusServiceUnavailable || string(resp.Body) != "custom" {
+		t.Fatalf("expected custom 503 response, got %d '%s'", resp.Status, resp.Body)
+	}
+}
+
+// ── Header size limit tests ─────────────────────────────────────────
+
+func withMaxHeaderBytes(t *testing.T, n int) {
+	t.Helper()
+	prev := wghttp.MaxHeaderBytes
+	wghttp.MaxHeaderBytes = n
+	t.Cleanup(func() { wghttp.MaxHeaderBytes = prev })
+}
+
+func TestHandleRequestWith_HeadersAtLimitAccepted(t *testing.T) {
+	withMaxHeaderBytes(t, 10)
+
+	called := false
+	handler := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		called = true
+	})
+	// "X-A" + "1234" + "X-B" = 10 bytes, exactly at the limit.
+	reqBytes := wghttp.MarshalRequest(wghttp.WitHttpRequest{
+		Method: "GET",
+		URI:    "/",
+		Headers: []wghttp.WitHttpHeader{
+			{Name: "X-A", Value: "1234"},
+			{Name: "X-B", Value: ""},
+		},
+	})
+
+	resp := wghttp.UnmarshalResponse(wghttp.HandleRequestWith(handler, reqBytes))
+	if resp.Status != 200 {
+		t.Fatalf("expected status 200, got %d", resp.Status)
+	}
+	if !called {
+		t.Fatal("expected handler to be invoked")
+	}
+}
+
+func TestHandleRequestWith_HeadersOverLimitReturn431(t *testing.T) {
+	withMaxHeaderBytes(t, 10)
+
+	handler := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		t.Fatal("handler must not be invoked for oversized headers")
+	})
+	reqBytes := wghttp.MarshalRequest(wghttp.WitHttpRequest{
+		Method: "GET",
+		URI:    "/",
+		Headers: []wghttp.WitHttpHeader{
+			{Name: "X-A", Value: "1234"},
+			{Name: "X-B", Value: "5"},
+		},
+	})
+
+	resp := wghttp.UnmarshalResponse(wghttp.HandleRequestWith(handler, reqBytes))
+	if resp.Status != wghttp.StatusRequestHeaderFieldsTooLarge {
+		t.Fatalf("expected status 431, got %d", resp.Status)
+	}
+}
+
+func TestHandleRequestWith_SingleHugeHeaderReturns431(t *testing.T) {
+	handler := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		t.Fatal("handler must not be invoked for oversized headers")
+	})
+	reqBytes := wghttp.MarshalRequest(wghttp.WitHttpRequest{
+		Method: "GET",
+		URI:    "/",
+		Headers: []wghttp.WitHttpHeader{
+			{Name: "Cookie", Value: string(bytes.Repeat([]byte("a"), wghttp.DefaultMaxHeaderBytes))},
+		},
+	})
+
+	resp := wghttp.UnmarshalResponse(wghttp.HandleRequestWith(handler, reqBytes))
+	if resp.Status != 431 {
+		t.Fatalf("expected status 431, got %d", resp.Status)
+	}
+}
+
+func TestDecodeRequest_HeadersOverLimit(t *testing.T) {
+	withMaxHeaderBytes(t, 8)
+
+	frame := wghttp.MarshalRequest(wghttp.WitHttpRequest{
+		Method:  "GET",
+		URI:     "/",
+		Headers: []wghttp.WitHttpHeader{{Name: "X-Big", Value: "value"}},
+	})
+
+	_, err := wghttp.DecodeRequest(bytes.NewReader(frame))
+	if !errors.Is(err, wghttp.ErrHeaderTooLarge) {
+		t.Fatalf("expected ErrHeaderTooLarge, got %v", err)
+	}
+}
+
+// ── JSON error tests ────────────────────────────────────────────────
+
+func TestErrorJSON_WritesJSONBody(t *testing.T) {
+	w := wghttp.NewTestResponseWriter()
+	wghttp.ErrorJSON(w, `missing "name" field`, wghttp.StatusBadRequest)
+
+	if w.StatusCode() != 400 {
+		t.Fatalf("expected status 400, got %d", w.StatusCode())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("expected application/json content type, got '%s'", ct)
+	}
+	expected := `{"error":"missing \"name\" field","code":400}`
+	if string(w.Body()) != expected {
+		t.Fatalf("expected body %s, got %s", expected, w.Body())
+	}
+}
+
+func TestServeMux_UsesRegisteredErrorRenderer(t *testing.T) {
+	prev := wghttp.ErrorRenderer
+	defer func() { wghttp.ErrorRenderer = prev }()
+
+	var gotCode int
+	wghttp.ErrorRenderer = func(w wghttp.ResponseWriter, r *wghttp.Request, message string, code int) {
+		gotCode = code
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(code)
+		w.Write([]byte(`{"title":"` + message + `","path":"` + r.URL.Path + `"}`))
+	}
+
+	mux := wghttp.NewServeMux()
+	w := wghttp.NewTestResponseWriter()
+	mux.ServeHTTP(w, wghttp.NewRequest(wghttp.MethodGet, "/missing", nil))
+
+	if gotCode != wghttp.StatusNotFound || w.StatusCode() != 404 {
+		t.Fatalf("expected renderer called with 404, got %d (status %d)", gotCode, w.StatusCode())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("expected custom content type, got '%s'", ct)
+	}
+	if string(w.Body()) != `{"title":"404 page not found","path":"/missing"}` {
+		t.Fatalf("unexpected body: %s", w.Body())
+	}
+}
+
+func TestServeMux_JSONErrorRendererFallback(t *testing.T) {
+	prev := wghttp.ErrorRenderer
+	defer func() { wghttp.ErrorRenderer = prev }()
+	wghttp.ErrorRenderer = wghttp.JSONErrorRenderer
+
+	w := wghttp.NewTestResponseWriter()
+	wghttp.NewServeMux().ServeHTTP(w, wghttp.NewRequest(wghttp.MethodGet, "/nope", nil))
+
+	if w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("expected application/json, got '%s'", w.Header().Get("Content-Type"))
+	}
+	if string(w.Body()) != `{"error":"404 page not found","code":404}` {
+		t.Fatalf("unexpected body: %s", w.Body())
+	}
+}
+
+// ── Path normalization tests ────────────────────────────────────────
+
+func TestServeMux_EscapedSlashMatchesDecodedPath(t *testing.T) {
+	mux := wghttp.NewServeMux()
+	var gotPath, gotEscaped string
+	mux.HandleFunc("/users/", func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		gotPath = r.URL.Path
+		gotEscaped = r.URL.EscapedPath()
+	})
+
+	reqBytes := wghttp.MarshalRequest(wghttp.WitHttpRequest{Method: "GET", URI: "/users/a%2Fb"})
+	resp := wghttp.UnmarshalResponse(wghttp.HandleRequestWith(mux, reqBytes))
+
+	if resp.Status != 200 {
+		t.Fatalf("expected status 200, got %d", resp.Status)
+	}
+	if gotPath != "/users/a/b" {
+		t.Fatalf("expected decoded path '/users/a/b', got '%s'", gotPath)
+	}
+	if gotEscaped != "/users/a%2Fb" {
+		t.Fatalf("expected escaped path '/users/a%%2Fb', got '%s'", gotEscaped)
+	}
+}
+
+func TestHandleRequestWith_MalformedPercentEncodingReturns400(t *testing.T) {
+	handler := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		t.Fatal("handler must not be invoked for a malformed URI")
+	})
+
+	reqBytes := wghttp.MarshalRequest(wghttp.WitHttpRequest{Method: "GET", URI: "/files/%zz"})
+	resp := wghttp.UnmarshalResponse(wghttp.HandleRequestWith(handler, reqBytes))
+
+	if resp.Status != wghttp.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", resp.Status)
+	}
+}
+
+func TestServeMux_TraversalOutOfPrefixRedirects(t *testing.T) {
+	mux := wghttp.NewServeMux()
+	mux.HandleFunc("/static/", func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		t.Fatalf("static handler must not serve traversal path %s", r.URL.Path)
+	})
+
+	w := wghttp.NewTestResponseWriter()
+	mux.ServeHTTP(w, wghttp.NewRequest(wghttp.MethodGet, "/static/../admin?x=1", nil))
+
+	if w.StatusCode() != wghttp.StatusMovedPermanently {
+		t.Fatalf("expected status 301, got %d", w.StatusCode())
+	}
+	if loc := w.Header().Get("Location"); loc != "/admin?x=1" {
+		t.Fatalf("expected Location '/admin?x=1', got '%s'", loc)
+	}
+}
+
+func TestServeMux_CleanPathPreservesTrailingSlash(t *testing.T) {
+	mux := wghttp.NewServeMux()
+	w := wghttp.NewTestResponseWriter()
+	mux.ServeHTTP(w, wghttp.NewRequest(wghttp.MethodGet, "/a//b/./c/", nil))
+
+	if loc := w.Header().Get("Location"); loc != "/a/b/c/" {
+		t.Fatalf("expected Location '/a/b/c/', got '%s'", loc)
+	}
+}
+
+// ── Trailing-slash redirect tests ───────────────────────────────────
+
+func TestServeMux_TrailingSlashRedirectOn(t *testing.T) {
+	mux := wghttp.NewServeMux()
+	mux.RedirectTrailingSlash = true
+	mux.HandleFunc("/docs/", func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		t.Fatalf("handler must not run for the redirected path %s", r.URL.Path)
+	})
+
+	w := wghttp.NewTestResponseWriter()
+	mux.ServeHTTP(w, wghttp.NewRequest(wghttp.MethodGet, "/docs?page=2", nil))
+
+	if w.StatusCode() != wghttp.StatusMovedPermanently {
+		t.Fatalf("expected status 301, got %d", w.StatusCode())
+	}
+	if loc := w.Header().Get("Location"); loc != "/docs/?page=2" {
+		t.Fatalf("expected Location '/docs/?page=2', got '%s'", loc)
+	}
+}
+
+func TestServeMux_TrailingSlashRedirectPrefersExactMatch(t *testing.T) {
+	mux := wghttp.NewServeMux()
+	mux.RedirectTrailingSlash = true
+	mux.HandleFunc("/docs/", func(w wghttp.ResponseWriter, r *wghttp.Request) {})
+	mux.HandleFunc("/docs", func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		w.WriteHeader(wghttp.StatusNoContent)
+	})
+
+	w := wghttp.NewTestResponseWriter()
+	mux.ServeHTTP(w, wghttp.NewRequest(wghttp.MethodGet, "/docs", nil))
+
+	if w.StatusCode() != wghttp.StatusNoContent {
+		t.Fatalf("expected the exact /docs handler (204), got %d", w.StatusCode())
+	}
+}
+
+func TestServeMux_TrailingSlashRedirectOffByDefault(t *testing.T) {
+	mux := wghttp.NewServeMux()
+	mux.HandleFunc("/docs/", func(w wghttp.ResponseWriter, r *wghttp.Request) {})
+
+	w := wghttp.NewTestResponseWriter()
+	mux.ServeHTTP(w, wghttp.NewRequest(wghttp.MethodGet, "/docs", nil))
+
+	if w.StatusCode() != wghttp.StatusNotFound {
+		t.Fatalf("expected status 404 without redirect, got %d", w.StatusCode())
+	}
+	if loc := w.Header().Get("Location"); loc != "" {
+		t.Fatalf("expected no Location header, got '%s'", loc)
+	}
+}
+
+// ── Method routing tests ────────────────────────────────────────────
+
+func TestServeMux_MethodPatternTakesPrecedence(t *testing.T) {
+	mux := wghttp.NewServeMux()
+	mux.HandleFunc("/users", func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		w.Write([]byte("any"))
+	})
+	mux.HandleFunc("POST /users", func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		w.WriteHeader(wghttp.StatusCreated)
+		w.Write([]byte("create"))
+	})
+
+	post := wghttp.NewTestResponseWriter()
+	mux.ServeHTTP(post, wghttp.NewRequest(wghttp.MethodPost, "/users", nil))
+	if post.StatusCode() != wghttp.StatusCreated || string(post.Body()) != "create" {
+		t.Fatalf("expected POST handler, got %d '%s'", post.StatusCode(), post.Body())
+	}
+
+	get := wghttp.NewTestResponseWriter()
+	mux.ServeHTTP(get, wghttp.NewRequest(wghttp.MethodGet, "/users", nil))
+	if string(get.Body()) != "any" {
+		t.Fatalf("expected method-less handler for GET, got '%s'", get.Body())
+	}
+}
+
+func TestServeMux_WrongMethodReturns405WithAllow(t *testing.T) {
+	mux := wghttp.NewServeMux()
+	noop := func(w wghttp.ResponseWriter, r *wghttp.Request) {}
+	mux.HandleFunc("GET /items/", noop)
+	mux.HandleFunc("DELETE /items/", noop)
+
+	w := wghttp.NewTestResponseWriter()
+	mux.ServeHTTP(w, wghttp.NewRequest(wghttp.MethodPut, "/items/7", nil))
+
+	if w.StatusCode() != wghttp.StatusMethodNotAllowed {
+		t.Fatalf("expected status 405, got %d", w.StatusCode())
+	}
+	if allow := w.Header().Get("Allow"); allow != "DELETE, GET" {
+		t.Fatalf("expected Allow 'DELETE, GET', got '%s'", allow)
+	}
+}
+
+func TestServeMux_MethodPatternDuplicateAndInvalid(t *testing.T) {
+	mux := wghttp.NewServeMux()
+	noop := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {})
+	if err := mux.TryHandle("GET /a", noop); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mux.TryHandle("/a", noop); err != nil {
+		t.Fatalf("expected method-less /a to coexist with GET /a, got %v", err)
+	}
+	if err := mux.TryHandle("GET /a", noop); !errors.Is(err, wghttp.ErrDuplicatePattern) {
+		t.Fatalf("expected ErrDuplicatePattern, got %v", err)
+	}
+	for _, p := range []string{"GET", "GET a", "G(T /a"} {
+		if err := mux.TryHandle(p, noop); !errors.Is(err, wghttp.ErrInvalidPattern) {
+			t.Fatalf("%q: expected ErrInvalidPattern, got %v", p, err)
+		}
+	}
+}
+
+// ── HEAD auto-derivation tests ──────────────────────────────────────
+
+func TestServeMux_AutoHeadRunsGetHandler(t *testing.T) {
+	mux := wghttp.NewServeMux()
+	mux.AutoHead = true
+	mux.HandleFunc("GET /report", func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("a,b,c\n"))
+	})
+
+	reqBytes := wghttp.MarshalRequest(wghttp.WitHttpRequest{Method: "HEAD", URI: "/report"})
+	resp := wghttp.UnmarshalResponse(wghttp.HandleRequestWith(mux, reqBytes))
+
+	if resp.Status != 200 {
+		t.Fatalf("expected status 200, got %d", resp.Status)
+	}
+	if len(resp.Body) != 0 {
+		t.Fatalf("expected empty body, got '%s'", resp.Body)
+	}
+	headers := map[string]string{}
+	for _, h := range resp.Headers {
+		headers[h.Name] = h.Value
+	}
+	if headers["Content-Type"] != "text/csv" || headers["Etag"] != `"v1"` {
+		t.Fatalf("expected GET headers to be kept, got %v", headers)
+	}
+	if headers["Content-Length"] != "6" {
+		t.Fatalf("expected Content-Length 6, got '%s'", headers["Content-Length"])
+	}
+}
+
+func TestServeMux_AutoHeadSizesLargeBodyAcrossWrites(t *testing.T) {
+	mux := wghttp.NewServeMux()
+	mux.AutoHead = true
+	chunk := bytes.Repeat([]byte("x"), 64<<10)
+	mux.HandleFunc("GET /export", func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		w.Write([]byte("<html>"))
+		for i := 0; i < 16; i++ {
+			w.Write(chunk)
+		}
+	})
+
+	w := wghttp.NewTestResponseWriter()
+	mux.ServeHTTP(w, wghttp.NewRequest(wghttp.MethodHead, "/export", nil))
+
+	if got, want := w.Header().Get("Content-Length"), strconv.Itoa(6+16*len(chunk)); got != want {
+		t.Fatalf("expected Content-Length %s, got '%s'", want, got)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Fatalf("expected Content-Type sniffed from the first write, got '%s'", ct)
+	}
+	if len(w.Body()) != 0 {
+		t.Fatalf("expected empty body, got %d bytes", len(w.Body()))
+	}
+}
+
+func TestServeMux_ExplicitHeadTakesPrecedence(t *testing.T) {
+	mux := wghttp.NewServeMux()
+	mux.AutoHead = true
+	mux.HandleFunc("GET /report", func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		t.Fatal("GET handler must not run when a HEAD handler is registered")
+	})
+	mux.HandleFunc("HEAD /report", func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		w.Header().Set("X-Head", "explicit")
+	})
+
+	w := wghttp.NewTestResponseWriter()
+	mux.ServeHTTP(w, wghttp.NewRequest(wghttp.MethodHead, "/report", nil))
+
+	if w.Header().Get("X-Head") != "explicit" {
+		t.Fatalf("expected explicit HEAD handler, got headers %v", w.Header())
+	}
+}
+
+func TestServeMux_AutoHeadOffReturns405(t *testing.T) {
+	mux := wghttp.NewServeMux()
+	mux.HandleFunc("GET /report", func(w wghttp.ResponseWriter, r *wghttp.Request) {})
+
+	w := wghttp.NewTestResponseWriter()
+	mux.ServeHTTP(w, wghttp.NewRequest(wghttp.MethodHead, "/report", nil))
+
+	if w.StatusCode() != wghttp.StatusMethodNotAllowed {
+		t.Fatalf("expected status 405, got %d", w.StatusCode())
+	}
+}
+
+// ── Connection: close tests ─────────────────────────────────────────
+
+func TestHandleRequestWith_ConnectionCloseEchoed(t *testing.T) {
+	var sawClose bool
+	handler := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		sawClose = r.Close
+	})
+
+	reqBytes := wghttp.MarshalRequest(wghttp.WitHttpRequest{
+		Method:  "GET",
+		URI:     "/",
+		Headers: []wghttp.WitHttpHeader{{Name: "Connection", Value: "Close"}},
+	})
+	resp := wghttp.UnmarshalResponse(wghttp.HandleRequestWith(handler, reqBytes))
+
+	if !sawClose {
+		t.Fatal("expected Request.Close to be set")
+	}
+	var got string
+	for _, h := range resp.Headers {
+		if h.Name == "Connection" {
+			got = h.Value
+		}
+	}
+	if got != "close" {
+		t.Fatalf("expected Connection 'close', got '%s'", got)
+	}
+}
+
+func TestHandleRequestWith_NoConnectionCloseByDefault(t *testing.T) {
+	handler := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {})
+
+	reqBytes := wghttp.MarshalRequest(wghttp.WitHttpRequest{Method: "GET", URI: "/"})
+	resp := wghttp.UnmarshalResponse(wghttp.HandleRequestWith(handler, reqBytes))
+
+	for _, h := range resp.Headers {
+		if h.Name == "Connection" {
+			t.Fatalf("expected no Connection header, got '%s'", h.Value)
+		}
+	}
+}
+
+func TestCloseConnection_SetsHeader(t *testing.T) {
+	w := wghttp.NewTestResponseWriter()
+	wghttp.CloseConnection(w)
+	wghttp.CloseConnection(w)
+
+	if got := w.Header().Values("Connection"); len(got) != 1 || got[0] != "close" {
+		t.Fatalf("expected a single Connection 'close', got %v", got)
+	}
+}
+
+// ── Response size limit tests ───────────────────────────────────────
+
+// serveWithResponseLimit runs a handler writing body (in one Write)
+// under MaxResponseBytes = limit and returns the response and the
+// handler's Write result.
+func serveWithResponseLimit(t *testing.T, limit int, body string) (wghttp.WitHttpResponse, int, error) {
+	t.Helper()
+	defer func(old int) { wghttp.MaxResponseBytes = old }(wghttp.MaxResponseBytes)
+	wghttp.MaxResponseBytes = limit
+
+	var n int
+	var werr error
+	handler := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		n, werr = w.Write([]byte(body))
+	})
+	reqBytes := wghttp.MarshalRequest(wghttp.WitHttpRequest{Method: "GET", URI: "/"})
+	return wghttp.UnmarshalResponse(wghttp.HandleRequestWith(handler, reqBytes)), n, werr
+}
+
+func TestMaxResponseBytes_UnderLimit(t *testing.T) {
+	resp, n, err := serveWithResponseLimit(t, 10, "hello")
+
+	if err != nil || n != 5 {
+		t.Fatalf("expected full write, got n=%d err=%v", n, err)
+	}
+	if resp.Status != 200 || string(resp.Body) != "hello" {
+		t.Fatalf("expected 200 'hello', got %d '%s'", resp.Status, resp.Body)
+	}
+}
+
+func TestMaxResponseBytes_AtLimit(t *testing.T) {
+	resp, n, err := serveWithResponseLimit(t, 5, "hello")
+
+	if err != nil || n != 5 {
+		t.Fatalf("expected full write at the limit, got n=%d err=%v", n, err)
+	}
+	if resp.Status != 200 || string(resp.Body) != "hello" {
+		t.Fatalf("expected 200 'hello', got %d '%s'", resp.Status, resp.Body)
+	}
+}
+
+func TestMaxResponseBytes_OverLimitReturns500(t *testing.T) {
+	resp, n, err := serveWithResponseLimit(t, 4, "hello")
+
+	if !errors.Is(err, wghttp.ErrResponseTooLarge) {
+		t.Fatalf("expected ErrResponseTooLarge, got %v", err)
+	}
+	if n != 4 {
+		t.Fatalf("expected short write of 4 bytes, got %d", n)
+	}
+	if resp.Status != wghttp.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", resp.Status)
+	}
+	if bytes.Contains(resp.Body, []byte("hell")) {
+		t.Fatalf("expected the truncated body to be discarded, got '%s'", resp.Body)
+	}
+}
+
+// ── ReadFrom tests ──────────────────────────────────────────────────
+
+// writerOnly hides every method but Write, forcing io.Copy through the
+// generic buffered loop.
+type writerOnly struct{ io.Writer }
+
+// readerOnly hides every method but Read, so the source cannot size
+// or write itself.
+type readerOnly struct{ io.Reader }
+
+func TestResponseWriter_ReadFromBytesReader(t *testing.T) {
+	data := bytes.Repeat([]byte("warpgrid"), 4096)
+	w := wghttp.NewTestResponseWriter()
+
+	n, err := w.ReadFrom(bytes.NewReader(data))
+
+	if err != nil || n != int64(len(data)) {
+		t.Fatalf("expected %d bytes copied, got n=%d err=%v", len(data), n, err)
+	}
+	if !bytes.Equal(w.Body(), data) {
+		t.Fatal("expected body to match the source bytes")
+	}
+	if w.StatusCode() != wghttp.StatusOK {
+		t.Fatalf("expected implicit status 200, got %d", w.StatusCode())
+	}
+}
+
+func TestResponseWriter_ReadFromStringsReader(t *testing.T) {
+	w := wghttp.NewTestResponseWriter()
+	w.Write([]byte("hello, "))
+
+	rf, ok := wghttp.ResponseWriter(w).(io.ReaderFrom)
+	if !ok {
+		t.Fatal("expected ResponseWriter to implement io.ReaderFrom")
+	}
+	n, err := rf.ReadFrom(strings.NewReader("warpgrid"))
+
+	if err != nil || n != 8 {
+		t.Fatalf("expected 8 bytes copied, got n=%d err=%v", n, err)
+	}
+	if string(w.Body()) != "hello, warpgrid" {
+		t.Fatalf("expected body 'hello, warpgrid', got '%s'", w.Body())
+	}
+}
+
+func TestResponseWriter_ReadFromGrowsOnce(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 256<<10)
+
+	readFrom := testing.AllocsPerRun(10, func() {
+		w := wghttp.NewTestResponseWriter()
+		w.ReadFrom(bytes.NewReader(data))
+	})
+	copied := testing.AllocsPerRun(10, func() {
+		w := wghttp.NewTestResponseWriter()
+		io.Copy(writerOnly{w}, readerOnly{bytes.NewReader(data)})
+	})
+
+	if readFrom >= copied {
+		t.Fatalf("expected ReadFrom to allocate less than a plain copy, got %v vs %v", readFrom, copied)
+	}
+	// A single Write of the whole slice grows the body exactly once;
+	// ReadFrom should match it, plus the escaping source reader.
+	written := testing.AllocsPerRun(10, func() {
+		w := wghttp.NewTestResponseWriter()
+		w.Write(data)
+	})
+	if readFrom > written+1 {
+		t.Fatalf("expected ReadFrom to grow the body once, got %v allocations vs %v for one Write", readFrom, written)
+	}
+}
+
+func TestResponseWriter_ReadFromUnsizedReader(t *testing.T) {
+	data := bytes.Repeat([]byte("abc"), 1000)
+	w := wghttp.NewTestResponseWriter()
+
+	n, err := w.ReadFrom(readerOnly{bytes.NewReader(data)})
+
+	if err != nil || n != int64(len(data)) || !bytes.Equal(w.Body(), data) {
+		t.Fatalf("expected %d bytes copied, got n=%d err=%v", len(data), n, err)
+	}
+}
+
+func TestResponseWriter_ReadFromRespectsMaxResponseBytes(t *testing.T) {
+	defer func(old int) { wghttp.MaxResponseBytes = old }(wghttp.MaxResponseBytes)
+	wghttp.MaxResponseBytes = 4
+
+	var n int64
+	var rerr error
+	handler := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		n, rerr = w.(io.ReaderFrom).ReadFrom(strings.NewReader("hello"))
+	})
+	reqBytes := wghttp.MarshalRequest(wghttp.WitHttpRequest{Method: "GET", URI: "/"})
+	resp := wghttp.UnmarshalResponse(wghttp.HandleRequestWith(handler, reqBytes))
+
+	if !errors.Is(rerr, wghttp.ErrResponseTooLarge) || n != 4 {
+		t.Fatalf("expected short copy of 4 bytes with ErrResponseTooLarge, got n=%d err=%v", n, rerr)
+	}
+	if resp.Status != wghttp.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", resp.Status)
+	}
+}
+
+// ── Route debug header tests ────────────────────────────────────────
+
+// routeDebugHeaders serves paths through a mux with one handler that
+// answers 404 itself, and returns the debug header for each response.
+func routeDebugHeaders(t *testing.T, debug, production bool) (unmatched, handler404 string) {
+	t.Helper()
+	defer func(d, p bool) { wghttp.DebugRouting, wghttp.ProductionMode = d, p }(wghttp.DebugRouting, wghttp.ProductionMode)
+	wghttp.DebugRouting, wghttp.ProductionMode = debug, production
+
+	mux := wghttp.NewServeMux()
+	mux.HandleFunc("/users/missing", func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		wghttp.Error(w, "no such user", wghttp.StatusNotFound)
+	})
+
+	w := wghttp.NewTestResponseWriter()
+	mux.ServeHTTP(w, wghttp.NewRequest(wghttp.MethodGet, "/nowhere", nil))
+	if w.StatusCode() != wghttp.StatusNotFound {
+		t.Fatalf("expected 404 for unmatched path, got %d", w.StatusCode())
+	}
+	unmatched = w.Header().Get(wghttp.RouteMatchedHeader)
+
+	w = wghttp.NewTestResponseWriter()
+	mux.ServeHTTP(w, wghttp.NewRequest(wghttp.MethodGet, "/users/missing", nil))
+	if w.StatusCode() != wghttp.StatusNotFound {
+		t.Fatalf("expected handler 404, got %d", w.StatusCode())
+	}
+	return unmatched, w.Header().Get(wghttp.RouteMatchedHeader)
+}
+
+func TestDebugRouting_MarksUnmatchedRoute(t *testing.T) {
+	unmatched, handler404 := routeDebugHeaders(t, true, false)
+
+	if unmatched != "false" {
+		t.Fatalf("expected %s: false on unmatched path, got '%s'", wghttp.RouteMatchedHeader, unmatched)
+	}
+	if handler404 != "" {
+		t.Fatalf("expected no debug header on handler 404, got '%s'", handler404)
+	}
+}
+
+func TestDebugRouting_OffByDefault(t *testing.T) {
+	unmatched, handler404 := routeDebugHeaders(t, false, false)
+
+	if unmatched != "" || handler404 != "" {
+		t.Fatalf("expected no debug header with DebugRouting off, got '%s' and '%s'", unmatched, handler404)
+	}
+}
+
+func TestDebugRouting_NeverInProductionMode(t *testing.T) {
+	unmatched, handler404 := routeDebugHeaders(t, true, true)
+
+	if unmatched != "" || handler404 != "" {
+		t.Fatalf("expected no debug header in ProductionMode, got '%s' and '%s'", unmatched, handler404)
+	}
+}
+
+// ── Body-forbidding status tests ────────────────────────────────────
+
+func TestResponseWriter_WriteAfter204IsDropped(t *testing.T) {
+	w := wghttp.NewTestResponseWriter()
+	w.WriteHeader(wghttp.StatusNoContent)
+
+	n, err := w.Write([]byte("oops"))
+
+	if n != 0 || !errors.Is(err, wghttp.ErrBodyNotAllowed) {
+		t.Fatalf("expected (0, ErrBodyNotAllowed), got (%d, %v)", n, err)
+	}
+	if len(w.Body()) != 0 {
+		t.Fatalf("expected empty body, got '%s'", w.Body())
+	}
+}
+
+func TestResponseWriter_WriteAfter304AndReadFromDropped(t *testing.T) {
+	w := wghttp.NewTestResponseWriter()
+	w.WriteHeader(wghttp.StatusNotModified)
+
+	if _, err := w.ReadFrom(strings.NewReader("cached")); !errors.Is(err, wghttp.ErrBodyNotAllowed) {
+		t.Fatalf("expected ErrBodyNotAllowed from ReadFrom, got %v", err)
+	}
+	if len(w.Body()) != 0 {
+		t.Fatalf("expected empty body, got '%s'", w.Body())
+	}
+}
+
+func TestResponseWriter_WriteAfter200Normal(t *testing.T) {
+	w := wghttp.NewTestResponseWriter()
+	w.WriteHeader(wghttp.StatusOK)
+
+	n, err := w.Write([]byte("fine"))
+
+	if n != 4 || err != nil || string(w.Body()) != "fine" {
+		t.Fatalf("expected normal write, got (%d, %v) body '%s'", n, err, w.Body())
+	}
+}
+
+func TestHandleRequest_204WithBodySerializesEmpty(t *testing.T) {
+	handler := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		w.WriteHeader(wghttp.StatusNoContent)
+		w.Write([]byte("oops"))
+	})
+	reqBytes := wghttp.MarshalRequest(wghttp.WitHttpRequest{Method: "DELETE", URI: "/items/1"})
+	resp := wghttp.UnmarshalResponse(wghttp.HandleRequestWith(handler, reqBytes))
+
+	if resp.Status != wghttp.StatusNoContent || len(resp.Body) != 0 {
+		t.Fatalf("expected 204 with no body, got %d '%s'", resp.Status, resp.Body)
+	}
+}
+
+// ── PeekRequest tests ───────────────────────────────────────────────
+
+func TestPeekRequest_DecodesKnownFrame(t *testing.T) {
+	// GET /items?x=1, one header, body "hi", spelled out byte by byte.
+	frame := []byte{
+		3, 0, 0, 0, 'G', 'E', 'T',
+		10, 0, 0, 0, '/', 'i', 't', 'e', 'm', 's', '?', 'x', '=', '1',
+		1, 0, 0, 0,
+		4, 0, 0, 0, 'H', 'o', 's', 't',
+		3, 0, 0, 0, 'a', 'p', 'p',
+		2, 0, 0, 0, 'h', 'i',
+	}
+
+	req, err := wghttp.PeekRequest(frame)
+	if err != nil {
+		t.Fatalf("PeekRequest failed: %v", err)
+	}
+	if req.Method != "GET" || req.URI != "/items?x=1" {
+		t.Fatalf("expected GET /items?x=1, got %s %s", req.Method, req.URI)
+	}
+	if len(req.Headers) != 1 || req.Headers[0] != (wghttp.WitHttpHeader{Name: "Host", Value: "app"}) {
+		t.Fatalf("expected header Host: app, got %v", req.Headers)
+	}
+	if string(req.Body) != "hi" {
+		t.Fatalf("expected body 'hi', got '%s'", req.Body)
+	}
+}
+
+func TestPeekRequest_KeepsFieldsVerbatim(t *testing.T) {
+	frame := wghttp.MarshalRequest(wghttp.WitHttpRequest{
+		Method:  "POST",
+		URI:     "/",
+		Headers: []wghttp.WitHttpHeader{{Name: "x-odd", Value: " spaced "}},
+	})
+	req, err := wghttp.PeekRequest(frame)
+	if err != nil {
+		t.Fatalf("PeekRequest failed: %v", err)
+	}
+	if req.Headers[0].Name != "x-odd" || req.Headers[0].Value != " spaced " {
+		t.Fatalf("expected the header exactly as sent, got %q", req.Headers[0])
+	}
+}
+
+func TestPeekRequest_MalformedFrame(t *testing.T) {
+	frame := wghttp.MarshalRequest(wghttp.WitHttpRequest{Method: "GET", URI: "/"})
+
+	if _, err := wghttp.PeekRequest(frame[:len(frame)-2]); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected io.ErrUnexpectedEOF for a truncated frame, got %v", err)
+	}
+	if _, err := wghttp.PeekRequest([]byte{0xff, 0xff, 0xff, 0x7f}); err == nil {
+		t.Fatal("expected error for a bogus length prefix")
+	}
+	if _, err := wghttp.PeekRequest(append(frame, 0)); err == nil {
+		t.Fatal("expected error for trailing bytes")
+	}
+}
+
+// ── Deterministic header tests ──────────────────────────────────────
+
+func TestHandleRequest_DeterministicHeadersSorted(t *testing.T) {
+	defer wghttp.Configure(wghttp.CurrentConfig())
+	wghttp.DeterministicHeaders = true
+
+	handler := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		h := w.Header()
+		h.Set("X-Zeta", "z")
+		h.Add("Set-Cookie", "b=2")
+		h.Add("Set-Cookie", "a=1")
+		h.Set("Content-Type", "text/plain")
+		h.Set("Cache-Control", "no-store")
+		h.Set("X-Alpha", "a")
+	})
+	reqBytes := wghttp.MarshalRequest(wghttp.WitHttpRequest{Method: "GET", URI: "/"})
+
+	for i := 0; i < 20; i++ {
+		resp := wghttp.UnmarshalResponse(wghttp.HandleRequestWith(handler, reqBytes))
+		var got []string
+		for _, h := range resp.Headers {
+			got = append(got, h.Name+": "+h.Value)
+		}
+		want := []string{
+			"Cache-Control: no-store",
+			"Content-Type: text/plain",
+			"Set-Cookie: b=2",
+			"Set-Cookie: a=1",
+			"X-Alpha: a",
+			"X-Zeta: z",
+		}
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Fatalf("expected sorted headers\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+		}
+	}
+}
+
+// ── Request trailer tests ───────────────────────────────────────────
+
+func TestMarshalRequest_TrailersRoundTrip(t *testing.T) {
+	want := wghttp.WitHttpRequest{
+		Method:   "POST",
+		URI:      "/upload",
+		Headers:  []wghttp.WitHttpHeader{{Name: "Trailer", Value: "X-Checksum"}},
+		Body:     []byte("payload"),
+		Trailers: []wghttp.WitHttpHeader{{Name: "X-Checksum", Value: "abc123"}},
+	}
+	frame := wghttp.MarshalRequest(want)
+
+	decoders := map[string]func() (wghttp.WitHttpRequest, error){
+		"UnmarshalRequest": func() (wghttp.WitHttpRequest, error) { return wghttp.UnmarshalRequest(frame), nil },
+		"DecodeRequest":    func() (wghttp.WitHttpRequest, error) { return wghttp.DecodeRequest(bytes.NewReader(frame)) },
+		"PeekRequest":      func() (wghttp.WitHttpRequest, error) { return wghttp.PeekRequest(frame) },
+	}
+	for name, decode := range decoders {
+		got, err := decode()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if len(got.Trailers) != 1 || got.Trailers[0] != want.Trailers[0] {
+			t.Fatalf("%s: expected trailers %v, got %v", name, want.Trailers, got.Trailers)
+		}
+		if string(got.Body) != "payload" {
+			t.Fatalf("%s: expected body 'payload', got %q", name, got.Body)
+		}
+	}
+}
+
+func TestMarshalRequest_NoTrailersFrameUnchanged(t *testing.T) {
+	req := wghttp.WitHttpRequest{Method: "GET", URI: "/", Body: []byte("x")}
+	frame := wghttp.MarshalRequest(req)
+	// 4+3 method, 4+1 uri, 4 header count, 4+1 body: no trailer block.
+	if len(frame) != 21 {
+		t.Fatalf("expected a 21-byte frame, got %d", len(frame))
+	}
+}
+
+func TestDecodeRequest_UnknownTrailerVersionRejected(t *testing.T) {
+	frame := append(wghttp.MarshalRequest(wghttp.WitHttpRequest{Method: "GET", URI: "/"}), 9)
+	if _, err := wghttp.DecodeRequest(bytes.NewReader(frame)); err == nil {
+		t.Fatal("expected error for an unknown trailer block version")
+	}
+}
+
+func TestHandleRequestWith_TrailersOverLimitReturn431(t *testing.T) {
+	withMaxHeaderBytes(t, 10)
+
+	handler := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		t.Fatal("handler must not be invoked for oversized trailers")
+	})
+	reqBytes := wghttp.MarshalRequest(wghttp.WitHttpRequest{
+		Method:   "POST",
+		URI:      "/",
+		Headers:  []wghttp.WitHttpHeader{{Name: "X-A", Value: "1"}},
+		Body:     []byte("body"),
+		Trailers: []wghttp.WitHttpHeader{{Name: "X-Checksum", Value: "abcdef"}},
+	})
+
+	resp := wghttp.UnmarshalResponse(wghttp.HandleRequestWith(handler, reqBytes))
+	if resp.Status != wghttp.StatusRequestHeaderFieldsTooLarge {
+		t.Fatalf("expected status 431, got %d", resp.Status)
+	}
+}
+
+func TestUnmarshalRequest_HugeTrailerCountTruncated(t *testing.T) {
+	frame := wghttp.MarshalRequest(wghttp.WitHttpRequest{Method: "POST", URI: "/", Body: []byte("x")})
+	// Version 1 and a count of 2^32-1, with the entries missing.
+	frame = append(frame, 1, 0xff, 0xff, 0xff, 0xff, 0)
+
+	if req := wghttp.UnmarshalRequest(frame); req.Trailers != nil {
+		t.Fatalf("expected no trailers from a truncated block, got %d", len(req.Trailers))
+	}
+	if _, err := wghttp.DecodeRequest(bytes.NewReader(frame)); err == nil {
+		t.Fatal("expected DecodeRequest to reject a truncated trailer block")
+	}
+
+	handler := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		if len(r.Trailer) != 0 {
+			t.Fatalf("expected no trailers, got %v", r.Trailer)
+		}
+	})
+	resp := wghttp.UnmarshalResponse(wghttp.HandleRequestWith(handler, frame))
+	if resp.Status != wghttp.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.Status)
+	}
+}
+
+func TestHandleRequest_TrailersAvailableAfterBody(t *testing.T) {
+	mux := wghttp.NewServeMux()
+	var before, after string
+	var declared bool
+	mux.HandleFunc("/upload", func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		_, declared = r.Trailer["X-Checksum"]
+		before = r.Trailer.Get("X-Checksum")
+		io.ReadAll(r.Body)
+		after = r.Trailer.Get("X-Checksum")
+	})
+
+	wghttp.HandleRequestWith(mux, wghttp.MarshalRequest(wghttp.WitHttpRequest{
+		Method:   "POST",
+		URI:      "/upload",
+		Body:     []byte("payload"),
+		Trailers: []wghttp.WitHttpHeader{{Name: "x-checksum", Value: "abc123"}},
+	}))
+
+	if !declared || before != "" {
+		t.Fatalf("expected X-Checksum declared without a value before reading, got declared=%v value='%s'", declared, before)
+	}
+	if after != "abc123" {
+		t.Fatalf("expected X-Checksum 'abc123' after reading, got '%s'", after)
+	}
+}
+
+// ── Status validation tests ─────────────────────────────────────────
+
+func TestHandleRequestWith_StatusValidation(t *testing.T) {
+	tests := []struct {
+		code int
+		want uint16
+	}{
+		{418, 418},
+		{599, 599},
+		{42, 500},
+		{99, 500},
+		{600, 500},
+		{9999, 500},
+	}
+	for _, tt := range tests {
+		handler := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+			w.WriteHeader(tt.code)
+		})
+		reqBytes := wghttp.MarshalRequest(wghttp.WitHttpRequest{Method: "GET", URI: "/"})
+
+		resp := wghttp.UnmarshalResponse(wghttp.HandleRequestWith(handler, reqBytes))
+		if resp.Status != tt.want {
+			t.Fatalf("WriteHeader(%d): expected status %d, got %d", tt.code, tt.want, resp.Status)
+		}
+	}
+}
+
+// ── Concurrency limit tests ─────────────────────────────────────────
+
+func TestHandleRequestWith_MaxConcurrentRequests(t *testing.T) {
+	prev := wghttp.MaxConcurrentRequests
+	wghttp.MaxConcurrentRequests = 3
+	defer func() { wghttp.MaxConcurrentRequests = prev }()
+
+	release := make(chan struct{})
+	handler := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		<-release
+		w.Write([]byte("done"))
+	})
+	reqBytes := wghttp.MarshalRequest(wghttp.WitHttpRequest{Method: "GET", URI: "/slow"})
+
+	const total = 10
+	results := make(chan wghttp.WitHttpResponse, total)
+	for i := 0; i < total; i++ {
+		go func() {
+			results <- wghttp.UnmarshalResponse(wghttp.HandleRequestWith(handler, reqBytes))
+		}()
+	}
+
+	// Admitted requests block in the handler, so the first responses to
+	// arrive are the rejected overflow.
+	for i := 0; i < total-3; i++ {
+		resp := <-results
+		if resp.Status != wghttp.StatusServiceUnavailable {
+			t.Fatalf("expected overflow request to get 503, got %d", resp.Status)
+		}
+		retryAfter := ""
+		for _, h := range resp.Headers {
+			if h.Name == "Retry-After" {
+				retryAfter = h.Value
+			}
+		}
+		if retryAfter == "" {
+			t.Fatal("expected Retry-After header on 503")
+		}
+	}
+
+	close(release)
+	for i := 0; i < 3; i++ {
+		if resp := <-results; resp.Status != 200 {
+			t.Fatalf("expected admitted request to succeed, got %d", resp.Status)
+		}
+	}
+
+	// Slots are released once the admitted requests finish.
+	resp := wghttp.UnmarshalResponse(wghttp.HandleRequestWith(handler, reqBytes))
+	if resp.Status != 200 {
+		t.Fatalf("expected request after drain to succeed, got %d", resp.Status)
+	}
+}
+
+// ── Status normalization tests ──────────────────────────────────────
+
+func TestHandleRequestWith_NoOpHandlerReturns200(t *testing.T) {
+	handler := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {})
+	reqBytes := wghttp.MarshalRequest(wghttp.WitHttpRequest{Method: "GET", URI: "/"})
+
+	resp := wghttp.UnmarshalResponse(wghttp.HandleRequestWith(handler, reqBytes))
+	if resp.Status != 200 {
+		t.Fatalf("expected status 200 from no-op handler, got %d", resp.Status)
+	}
+}
+
+func TestHandleRequestWith_ZeroStatusCoercedTo200(t *testing.T) {
+	handler := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		w.WriteHeader(0)
+	})
+	reqBytes := wghttp.MarshalRequest(wghttp.WitHttpRequest{Method: "GET", URI: "/"})
+
+	resp := wghttp.UnmarshalResponse(wghttp.HandleRequestWith(handler, reqBytes))
+	if resp.Status != 200 {
+		t.Fatalf("expected status 0 coerced to 200, got %d", resp.Status)
+	}
+}
+
+func TestHandleRequestWith_OutOfRangeStatusBecomes500(t *testing.T) {
+	handler := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		w.WriteHeader(70000)
+	})
+	reqBytes := wghttp.MarshalRequest(wghttp.WitHttpRequest{Method: "GET", URI: "/"})
+
+	resp := wghttp.UnmarshalResponse(wghttp.HandleRequestWith(handler, reqBytes))
+	if resp.Status != 500 {
+		t.Fatalf("expected status 500, got %d", resp.Status)
+	}
+}
+
+func TestJSONError_DevModeIncludesWrappedDetail(t *testing.T) {
+	err := fmt.Errorf("loading user 42: %w", errors.New("pq: connection refused"))
+	w := wghttp.NewTestResponseWriter()
+	wghttp.JSONError(w, wghttp.StatusInternalServerError, err)
+
+	if w.StatusCode() != 500 {
+		t.Fatalf("expected status 500, got %d", w.StatusCode())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("expected application/json content type, got '%s'", ct)
+	}
+	expected := `{"error":"loading user 42: pq: connection refused","code":500}`
+	if string(w.Body()) != expected {
+		t.Fatalf("expected body %s, got %s", expected, w.Body())
+	}
+}
+
+func TestJSONError_ProductionModeIsGeneric(t *testing.T) {
+	prev := wghttp.ProductionMode
+	wghttp.ProductionMode = true
+	defer func() { wghttp.ProductionMode = prev }()
+
+	err := fmt.Errorf("loading user 42: %w", errors.New("pq: connection refused"))
+	w := wghttp.NewTestResponseWriter()
+	wghttp.JSONError(w, wghttp.StatusInternalServerError, err)
+
+	expected := `{"error":"Internal Server Error","code":500}`
+	if string(w.Body()) != expected {
+		t.Fatalf("expected body %s, got %s", expected, w.Body())
+	}
+}
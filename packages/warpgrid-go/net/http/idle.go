@@ -0,0 +1,103 @@
+package http
+
+import (
+	"sync"
+	"time"
+)
+
+// idleState tracks request activity for LastRequestTime and OnIdle.
+var idleState struct {
+	mu          sync.Mutex
+	lastRequest time.Time // when the most recent request arrived
+	lastActive  time.Time // the latest arrival or completion
+	active      int       // requests in flight
+	watchers    []*idleWatcher
+}
+
+// idleWatcher is one OnIdle registration.
+type idleWatcher struct {
+	d     time.Duration
+	fn    func()
+	fired bool // already called for the current idle period
+}
+
+// LastRequestTime returns when the most recent request reached
+// HandleRequestWith (and so HandleRequest and HandleTrigger), as read
+// from Clock, or the zero time if none has.
+func LastRequestTime() time.Time {
+	idleState.mu.Lock()
+	defer idleState.mu.Unlock()
+	return idleState.lastRequest
+}
+
+// OnIdle registers fn to be called once no request has been in flight
+// for d, so the module can flush buffers, close pooled connections, or
+// tell the host it is ready to be suspended or scaled to zero. fn is
+// called once per idle period: a new request starts another, after
+// which fn can fire again. The period runs from the last request's
+// completion, or from registration if there has been no request.
+//
+// A suspended Wasm instance runs no timers, so idleness is evaluated
+// when CheckIdle is called, which the host does through the
+// warpgrid_http_check_idle export; elapsed time is read from Clock.
+func OnIdle(d time.Duration, fn func()) {
+	idleState.mu.Lock()
+	defer idleState.mu.Unlock()
+	if idleState.lastActive.IsZero() {
+		idleState.lastActive = now()
+	}
+	idleState.watchers = append(idleState.watchers, &idleWatcher{d: d, fn: fn})
+}
+
+// CheckIdle calls the OnIdle callbacks whose duration has passed since
+// the module was last active and that have not yet fired for this idle
+// period. It does nothing while a request is in flight. Callbacks run
+// in registration order, on the caller's goroutine.
+func CheckIdle() {
+	idleState.mu.Lock()
+	var due []func()
+	if idleState.active == 0 {
+		idle := now().Sub(idleState.lastActive)
+		for _, w := range idleState.watchers {
+			if !w.fired && idle >= w.d {
+				w.fired = true
+				due = append(due, w.fn)
+			}
+		}
+	}
+	idleState.mu.Unlock()
+
+	for _, fn := range due {
+		fn()
+	}
+}
+
+// ResetIdle removes all OnIdle registrations and forgets past requests.
+// Exposed for testing.
+func ResetIdle() {
+	idleState.mu.Lock()
+	defer idleState.mu.Unlock()
+	idleState.lastRequest = time.Time{}
+	idleState.lastActive = time.Time{}
+	idleState.watchers = nil
+}
+
+// trackRequest records a request's arrival, ending any idle period, and
+// returns the function to call when it completes.
+func trackRequest() (done func()) {
+	idleState.mu.Lock()
+	t := now()
+	idleState.lastRequest, idleState.lastActive = t, t
+	idleState.active++
+	for _, w := range idleState.watchers {
+		w.fired = false
+	}
+	idleState.mu.Unlock()
+
+	return func() {
+		idleState.mu.Lock()
+		idleState.lastActive = now()
+		idleState.active--
+		idleState.mu.Unlock()
+	}
+}
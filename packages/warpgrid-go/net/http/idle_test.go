@@ -0,0 +1,140 @@
+package http_test
+
+import (
+	"testing"
+	"time"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/net/http"
+	"github.com/anthropics/warpgrid/packages/warpgrid-go/net/http/wghttptest"
+)
+
+// ── Idle tracking tests ─────────────────────────────────────────────
+
+// manualClock is a Clock that only moves when advanced.
+type manualClock struct{ t time.Time }
+
+func (c *manualClock) now() time.Time          { return c.t }
+func (c *manualClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// idleClock installs a manualClock and clears idle tracking around the
+// test.
+func idleClock(t *testing.T) *manualClock {
+	t.Helper()
+	old := wghttp.CurrentConfig()
+	clock := &manualClock{t: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	wghttp.Clock = clock.now
+	wghttp.ResetIdle()
+	t.Cleanup(func() {
+		wghttp.Configure(old)
+		wghttp.ResetIdle()
+	})
+	return clock
+}
+
+var noopHandler = wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {})
+
+func TestLastRequestTime(t *testing.T) {
+	clock := idleClock(t)
+
+	if got := wghttp.LastRequestTime(); !got.IsZero() {
+		t.Fatalf("expected zero time before any request, got %v", got)
+	}
+	clock.advance(time.Minute)
+	wghttptest.Get(noopHandler, "/")
+
+	if got := wghttp.LastRequestTime(); !got.Equal(clock.t) {
+		t.Fatalf("expected %v, got %v", clock.t, got)
+	}
+}
+
+func TestOnIdle_FiresAfterInactivity(t *testing.T) {
+	clock := idleClock(t)
+	fired := 0
+	wghttp.OnIdle(5*time.Minute, func() { fired++ })
+
+	wghttptest.Get(noopHandler, "/")
+	clock.advance(4 * time.Minute)
+	wghttp.CheckIdle()
+	if fired != 0 {
+		t.Fatalf("expected no callback before 5m idle, got %d", fired)
+	}
+
+	clock.advance(time.Minute)
+	wghttp.CheckIdle()
+	if fired != 1 {
+		t.Fatalf("expected one callback after 5m idle, got %d", fired)
+	}
+
+	clock.advance(time.Hour)
+	wghttp.CheckIdle()
+	if fired != 1 {
+		t.Fatalf("expected the callback once per idle period, got %d", fired)
+	}
+}
+
+func TestOnIdle_ResetsOnNewRequest(t *testing.T) {
+	clock := idleClock(t)
+	fired := 0
+	wghttp.OnIdle(5*time.Minute, func() { fired++ })
+
+	clock.advance(5 * time.Minute)
+	wghttp.CheckIdle()
+	if fired != 1 {
+		t.Fatalf("expected a callback 5m after registration, got %d", fired)
+	}
+
+	wghttptest.Get(noopHandler, "/")
+	clock.advance(3 * time.Minute)
+	wghttp.CheckIdle()
+	if fired != 1 {
+		t.Fatalf("expected the request to restart the idle period, got %d", fired)
+	}
+
+	clock.advance(2 * time.Minute)
+	wghttp.CheckIdle()
+	if fired != 2 {
+		t.Fatalf("expected the callback again after a new idle period, got %d", fired)
+	}
+}
+
+func TestOnIdle_CountsFromRequestCompletion(t *testing.T) {
+	clock := idleClock(t)
+	fired := 0
+	wghttp.OnIdle(time.Minute, func() { fired++ })
+
+	slow := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		clock.advance(10 * time.Minute)
+		wghttp.CheckIdle()
+	})
+	wghttptest.Get(slow, "/")
+	if fired != 0 {
+		t.Fatalf("expected no callback while a request is in flight, got %d", fired)
+	}
+
+	clock.advance(59 * time.Second)
+	wghttp.CheckIdle()
+	if fired != 0 {
+		t.Fatalf("expected idle time to count from completion, got %d", fired)
+	}
+	clock.advance(time.Second)
+	wghttp.CheckIdle()
+	if fired != 1 {
+		t.Fatalf("expected a callback 1m after completion, got %d", fired)
+	}
+}
+
+func TestOnIdle_SeveralDurations(t *testing.T) {
+	clock := idleClock(t)
+	var order []string
+	wghttp.OnIdle(time.Minute, func() { order = append(order, "short") })
+	wghttp.OnIdle(10*time.Minute, func() { order = append(order, "long") })
+
+	clock.advance(2 * time.Minute)
+	wghttp.CheckIdle()
+	clock.advance(10 * time.Minute)
+	wghttp.CheckIdle()
+
+	if len(order) != 2 || order[0] != "short" || order[1] != "long" {
+		t.Fatalf("expected [short long], got %v", order)
+	}
+}
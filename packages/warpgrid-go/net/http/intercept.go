@@ -0,0 +1,83 @@
+package http
+
+// InterceptorRejectStatus is the status of the response
+// HandleRequestWith sends when a request interceptor returns an error.
+// It defaults to 400 Bad Request; an interceptor verifying a host
+// signature might prefer 401 or 403. Zero or an invalid code means the
+// default.
+var InterceptorRejectStatus = StatusBadRequest
+
+// InterceptorErrorHook, when set, receives the error from a request
+// interceptor that rejected req, so it can be logged. In ProductionMode
+// it is the only place the error goes: the response body is then the
+// status text alone, since interceptor errors may carry internal
+// detail.
+var InterceptorErrorHook func(req *WitHttpRequest, err error)
+
+var (
+	requestInterceptors  []func(*WitHttpRequest) error
+	responseInterceptors []func(*WitHttpResponse)
+)
+
+// RegisterRequestInterceptor adds fn to the functions HandleRequestWith
+// runs on every request before converting and routing it, in
+// registration order, for concerns that belong at the bridge boundary
+// rather than in middleware, such as decrypting the payload or
+// verifying a signature the host attached. fn may modify the request.
+//
+// If fn returns an error, the remaining interceptors and the handler
+// are skipped and the request is answered with InterceptorRejectStatus
+// and the error text, or only the status text in ProductionMode; the
+// error is passed to InterceptorErrorHook, and response interceptors
+// still run. Like
+// ListenAndServe, it should be called before serving begins.
+func RegisterRequestInterceptor(fn func(*WitHttpRequest) error) {
+	requestInterceptors = append(requestInterceptors, fn)
+}
+
+// RegisterResponseInterceptor adds fn to the functions
+// HandleRequestWith runs, in registration order, on every final
+// response it returns: those from the handler, including a mux's 404s,
+// as well as the server's own error responses. Interim responses are
+// not intercepted. fn may modify the response. Like ListenAndServe, it
+// should be called before serving begins.
+func RegisterResponseInterceptor(fn func(*WitHttpResponse)) {
+	responseInterceptors = append(responseInterceptors, fn)
+}
+
+// ResetInterceptors removes all registered interceptors and
+// InterceptorErrorHook. Exposed for testing.
+func ResetInterceptors() {
+	requestInterceptors = nil
+	responseInterceptors = nil
+	InterceptorErrorHook = nil
+}
+
+// interceptRequest runs the request interceptors on req, returning the
+// rejection response if one fails.
+func interceptRequest(req *WitHttpRequest) (WitHttpResponse, bool) {
+	for _, fn := range requestInterceptors {
+		if err := fn(req); err != nil {
+			status := InterceptorRejectStatus
+			if status < 100 || status > 599 {
+				status = StatusBadRequest
+			}
+			if InterceptorErrorHook != nil {
+				InterceptorErrorHook(req, err)
+			}
+			message := "request rejected: " + err.Error()
+			if ProductionMode {
+				message = StatusText(status)
+			}
+			return errorResponse(message, status), false
+		}
+	}
+	return WitHttpResponse{}, true
+}
+
+// interceptResponse runs the response interceptors on resp.
+func interceptResponse(resp *WitHttpResponse) {
+	for _, fn := range responseInterceptors {
+		fn(resp)
+	}
+}
@@ -0,0 +1,169 @@
+package http_test
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/net/http"
+	"github.com/anthropics/warpgrid/packages/warpgrid-go/net/http/wghttptest"
+)
+
+// ── Interceptor tests ───────────────────────────────────────────────
+
+// interceptedMux returns a mux serving only /hello, which echoes the
+// request body, and clears the interceptors when the test ends.
+func interceptedMux(t *testing.T) *wghttp.ServeMux {
+	t.Helper()
+	t.Cleanup(wghttp.ResetInterceptors)
+	mux := wghttp.NewServeMux()
+	mux.HandleFunc("/hello", func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	})
+	return mux
+}
+
+func serveIntercepted(mux *wghttp.ServeMux, req wghttp.WitHttpRequest) wghttp.WitHttpResponse {
+	return wghttp.UnmarshalResponse(wghttp.HandleRequestWith(mux, wghttp.MarshalRequest(req)))
+}
+
+func TestInterceptors_RunForMatchedAndUnmatchedRoutes(t *testing.T) {
+	mux := interceptedMux(t)
+	var seen []string
+	wghttp.RegisterRequestInterceptor(func(req *wghttp.WitHttpRequest) error {
+		seen = append(seen, req.URI)
+		return nil
+	})
+	wghttp.RegisterResponseInterceptor(func(resp *wghttp.WitHttpResponse) {
+		resp.Headers = append(resp.Headers, wghttp.WitHttpHeader{Name: "X-Signed", Value: "yes"})
+	})
+
+	hello := serveIntercepted(mux, wghttp.WitHttpRequest{Method: "GET", URI: "/hello"})
+	missing := serveIntercepted(mux, wghttp.WitHttpRequest{Method: "GET", URI: "/missing"})
+
+	if len(seen) != 2 || seen[0] != "/hello" || seen[1] != "/missing" {
+		t.Fatalf("expected request interceptor to see both requests, got %v", seen)
+	}
+	if hello.Status != 200 || missing.Status != 404 {
+		t.Fatalf("expected statuses 200 and 404, got %d and %d", hello.Status, missing.Status)
+	}
+	for _, resp := range []wghttp.WitHttpResponse{hello, missing} {
+		if wghttptest.ResponseHeader(resp).Get("X-Signed") != "yes" {
+			t.Fatalf("expected X-Signed on a %d response, got %v", resp.Status, resp.Headers)
+		}
+	}
+}
+
+func TestInterceptors_MutateRequestAndResponse(t *testing.T) {
+	mux := interceptedMux(t)
+	wghttp.RegisterRequestInterceptor(func(req *wghttp.WitHttpRequest) error {
+		// Stand-in for decryption: the host sent the body reversed.
+		for i, j := 0, len(req.Body)-1; i < j; i, j = i+1, j-1 {
+			req.Body[i], req.Body[j] = req.Body[j], req.Body[i]
+		}
+		return nil
+	})
+	wghttp.RegisterResponseInterceptor(func(resp *wghttp.WitHttpResponse) {
+		resp.Body = append([]byte("sealed:"), resp.Body...)
+	})
+
+	resp := serveIntercepted(mux, wghttp.WitHttpRequest{Method: "POST", URI: "/hello", Body: []byte("olleh")})
+
+	if string(resp.Body) != "sealed:hello" {
+		t.Fatalf("expected body 'sealed:hello', got '%s'", resp.Body)
+	}
+}
+
+func TestInterceptors_RejectionShortCircuits(t *testing.T) {
+	mux := interceptedMux(t)
+	defer wghttp.Configure(wghttp.CurrentConfig())
+	wghttp.InterceptorRejectStatus = wghttp.StatusUnauthorized
+
+	var later, responded bool
+	wghttp.RegisterRequestInterceptor(func(req *wghttp.WitHttpRequest) error {
+		return errors.New("bad host signature")
+	})
+	wghttp.RegisterRequestInterceptor(func(req *wghttp.WitHttpRequest) error {
+		later = true
+		return nil
+	})
+	wghttp.RegisterResponseInterceptor(func(resp *wghttp.WitHttpResponse) {
+		responded = true
+	})
+
+	resp := serveIntercepted(mux, wghttp.WitHttpRequest{Method: "POST", URI: "/hello", Body: []byte("x")})
+
+	if resp.Status != wghttp.StatusUnauthorized {
+		t.Fatalf("expected status 401, got %d", resp.Status)
+	}
+	if string(resp.Body) != "request rejected: bad host signature\n" {
+		t.Fatalf("expected rejection body, got '%s'", resp.Body)
+	}
+	if later {
+		t.Fatal("expected later request interceptors to be skipped")
+	}
+	if !responded {
+		t.Fatal("expected response interceptors to run on the rejection")
+	}
+}
+
+func TestInterceptors_DefaultRejectStatus(t *testing.T) {
+	mux := interceptedMux(t)
+	wghttp.RegisterRequestInterceptor(func(req *wghttp.WitHttpRequest) error {
+		return errors.New("nope")
+	})
+
+	resp := serveIntercepted(mux, wghttp.WitHttpRequest{Method: "GET", URI: "/hello"})
+	if resp.Status != wghttp.StatusBadRequest {
+		t.Fatalf("expected default status 400, got %d", resp.Status)
+	}
+}
+
+func TestInterceptors_ProductionModeHidesRejectionError(t *testing.T) {
+	mux := interceptedMux(t)
+	defer wghttp.Configure(wghttp.CurrentConfig())
+	wghttp.ProductionMode = true
+	wghttp.InterceptorRejectStatus = wghttp.StatusForbidden
+
+	var hooked error
+	var hookedURI string
+	wghttp.InterceptorErrorHook = func(req *wghttp.WitHttpRequest, err error) {
+		hooked, hookedURI = err, req.URI
+	}
+	wghttp.RegisterRequestInterceptor(func(req *wghttp.WitHttpRequest) error {
+		return errors.New("key 7f3a expired at vault.internal")
+	})
+
+	resp := serveIntercepted(mux, wghttp.WitHttpRequest{Method: "GET", URI: "/hello"})
+
+	if resp.Status != wghttp.StatusForbidden {
+		t.Fatalf("expected status 403, got %d", resp.Status)
+	}
+	if string(resp.Body) != "Forbidden\n" {
+		t.Fatalf("expected only the status text, got '%s'", resp.Body)
+	}
+	if hooked == nil || hooked.Error() != "key 7f3a expired at vault.internal" || hookedURI != "/hello" {
+		t.Fatalf("expected the hook to receive the error and request, got %v for '%s'", hooked, hookedURI)
+	}
+}
+
+func TestInterceptors_ResponseInterceptorSeesServerErrors(t *testing.T) {
+	mux := interceptedMux(t)
+	defer wghttp.Configure(wghttp.CurrentConfig())
+	wghttp.MaxHeaderBytes = 16
+	var statuses []uint16
+	wghttp.RegisterResponseInterceptor(func(resp *wghttp.WitHttpResponse) {
+		statuses = append(statuses, resp.Status)
+	})
+
+	serveIntercepted(mux, wghttp.WitHttpRequest{
+		Method:  "GET",
+		URI:     "/hello",
+		Headers: []wghttp.WitHttpHeader{{Name: "X-Large", Value: "more than sixteen bytes"}},
+	})
+
+	if len(statuses) != 1 || statuses[0] != wghttp.StatusRequestHeaderFieldsTooLarge {
+		t.Fatalf("expected the interceptor to see the 431, got %v", statuses)
+	}
+}
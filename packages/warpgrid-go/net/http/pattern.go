@@ -0,0 +1,177 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// isWildcardPattern reports whether the pattern path has {name}
+// segments.
+func isWildcardPattern(path string) bool {
+	return strings.IndexByte(path, '{') >= 0
+}
+
+// validateWildcards checks the {name} and {name...} segments of a
+// pattern path.
+func validateWildcards(path string) error {
+	if !isWildcardPattern(path) {
+		return nil
+	}
+	segments := strings.Split(path[1:], "/")
+	seen := make(map[string]bool)
+	for i, seg := range segments {
+		if !strings.ContainsAny(seg, "{}") {
+			continue
+		}
+		if len(seg) < 3 || seg[0] != '{' || seg[len(seg)-1] != '}' {
+			return errors.New("a wildcard must be a whole segment")
+		}
+		name, rest := strings.CutSuffix(seg[1:len(seg)-1], "...")
+		if rest && i != len(segments)-1 {
+			return errors.New("{name...} must be the last segment")
+		}
+		if !isWildcardName(name) {
+			return errors.New("bad wildcard name " + seg)
+		}
+		if seen[name] {
+			return errors.New("duplicate wildcard name " + name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// wildcardShape returns a pattern path with its wildcard names
+// removed, so "/users/{id}" and "/users/{name}", which match exactly
+// the same paths, have the same shape.
+func wildcardShape(path string) string {
+	if !isWildcardPattern(path) {
+		return path
+	}
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, "{") {
+			if strings.HasSuffix(seg, "...}") {
+				segments[i] = "{...}"
+			} else {
+				segments[i] = "{}"
+			}
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// isWildcardName reports whether name is a valid Go identifier.
+func isWildcardName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case '0' <= c && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// matchWildcard matches path against a wildcard pattern path, returning
+// the wildcard values and the number of literal segments matched.
+func matchWildcard(pattern, path string) (values map[string]string, literals int, ok bool) {
+	pSegs := strings.Split(pattern[1:], "/")
+	segs := strings.Split(path[1:], "/")
+	for i, p := range pSegs {
+		if p == "" || p[0] != '{' {
+			if i >= len(segs) || segs[i] != p {
+				return nil, 0, false
+			}
+			literals++
+			continue
+		}
+		name := p[1 : len(p)-1]
+		if rest, ok := strings.CutSuffix(name, "..."); ok {
+			if i >= len(segs) {
+				return nil, 0, false
+			}
+			if values == nil {
+				values = make(map[string]string)
+			}
+			values[rest] = strings.Join(segs[i:], "/")
+			return values, literals, true
+		}
+		if i >= len(segs) || segs[i] == "" {
+			return nil, 0, false
+		}
+		if values == nil {
+			values = make(map[string]string)
+		}
+		values[name] = segs[i]
+	}
+	if len(segs) != len(pSegs) {
+		return nil, 0, false
+	}
+	return values, literals, true
+}
+
+// patternKey is the context key under which ServeMux stores the
+// matched pattern.
+type patternKey struct{}
+
+// setRoute annotates r in place with the matched pattern and wildcard
+// values, as net/http's ServeMux sets Request.Pattern, so middleware
+// wrapping the mux can read them once the mux returns.
+func setRoute(r *Request, pattern string, values map[string]string) {
+	r.ctx = context.WithValue(r.Context(), patternKey{}, pattern)
+	r.pathValues = values
+}
+
+// MatchedPattern returns the ServeMux pattern that matched r, such as
+// "/users/{id}" for a request to "/users/42", or "/static/" for a
+// prefix route. A pattern registered with a method includes it, as in
+// "GET /users/{id}". Metrics and logging middleware can use it as a
+// low-cardinality route label. The mux records it on the request it
+// was given before calling the handler, so middleware wrapping the mux
+// can read it after the mux returns. ok is false when r was not routed
+// by a ServeMux.
+func MatchedPattern(r *Request) (string, bool) {
+	pattern, ok := r.Context().Value(patternKey{}).(string)
+	return pattern, ok
+}
+
+// pathSuffixKey is the context key under which ServeMux stores the
+// part of the path after a matched prefix pattern.
+type pathSuffixKey struct{}
+
+// setPathSuffix records on r, in place like setRoute, the part of the
+// path a prefix pattern did not consume. An empty suffix is only
+// recorded over one set by an outer mux, to spare the context
+// allocation on every other request.
+func setPathSuffix(r *Request, suffix string) {
+	if suffix == "" && r.PathSuffix() == "" {
+		return
+	}
+	r.ctx = context.WithValue(r.Context(), pathSuffixKey{}, suffix)
+}
+
+// PathSuffix returns the part of the request path after the prefix
+// pattern that matched r, so a handler registered on "/api/" and
+// called for "/api/users/123" gets "users/123" and can route the rest
+// itself. The suffix is taken from the decoded r.URL.Path as the mux
+// saw it. PathSuffix returns "" when r was not routed by a prefix
+// pattern, including when the path equals the pattern exactly; for
+// wildcard patterns use a final {name...} and PathValue instead. Under
+// nested muxes it reflects the innermost one that routed r.
+func (r *Request) PathSuffix() string {
+	suffix, _ := r.Context().Value(pathSuffixKey{}).(string)
+	return suffix
+}
+
+// PathValue returns the value of the named wildcard in the ServeMux
+// pattern that matched r, or "" if there is none, matching
+// net/http.Request.PathValue.
+func (r *Request) PathValue(name string) string {
+	return r.pathValues[name]
+}
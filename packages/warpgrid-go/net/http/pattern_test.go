@@ -0,0 +1,238 @@
+package http_test
+
+import (
+	"errors"
+	"testing"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/net/http"
+)
+
+// ── Wildcard pattern tests ──────────────────────────────────────────
+
+func TestServeMux_WildcardPathValue(t *testing.T) {
+	mux := wghttp.NewServeMux()
+	var id, file string
+	mux.HandleFunc("/users/{id}", func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		id = r.PathValue("id")
+	})
+	mux.HandleFunc("/files/{path...}", func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		file = r.PathValue("path")
+	})
+
+	mux.ServeHTTP(wghttp.NewTestResponseWriter(), wghttp.NewRequest(wghttp.MethodGet, "/users/42", nil))
+	if id != "42" {
+		t.Fatalf("expected id '42', got '%s'", id)
+	}
+	mux.ServeHTTP(wghttp.NewTestResponseWriter(), wghttp.NewRequest(wghttp.MethodGet, "/files/a/b/c.txt", nil))
+	if file != "a/b/c.txt" {
+		t.Fatalf("expected path 'a/b/c.txt', got '%s'", file)
+	}
+}
+
+func TestServeMux_WildcardDoesNotMatchOtherShapes(t *testing.T) {
+	mux := wghttp.NewServeMux()
+	mux.HandleFunc("/users/{id}", func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		t.Fatalf("handler must not match %s", r.URL.Path)
+	})
+
+	for _, path := range []string{"/users/", "/users/42/posts", "/users"} {
+		w := wghttp.NewTestResponseWriter()
+		mux.ServeHTTP(w, wghttp.NewRequest(wghttp.MethodGet, path, nil))
+		if w.StatusCode() != wghttp.StatusNotFound {
+			t.Fatalf("%s: expected status 404, got %d", path, w.StatusCode())
+		}
+	}
+}
+
+func TestServeMux_ExactBeatsWildcard(t *testing.T) {
+	mux := wghttp.NewServeMux()
+	var hit string
+	mux.HandleFunc("/users/{id}", func(w wghttp.ResponseWriter, r *wghttp.Request) { hit = "wildcard" })
+	mux.HandleFunc("/users/me", func(w wghttp.ResponseWriter, r *wghttp.Request) { hit = "exact" })
+
+	mux.ServeHTTP(wghttp.NewTestResponseWriter(), wghttp.NewRequest(wghttp.MethodGet, "/users/me", nil))
+	if hit != "exact" {
+		t.Fatalf("expected exact pattern to win, got %s", hit)
+	}
+}
+
+func TestServeMux_InvalidWildcardPatterns(t *testing.T) {
+	noop := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {})
+	for _, p := range []string{"/users/{}", "/users/x{id}", "/{rest...}/tail", "/{a}/{a}", "/{1id}"} {
+		mux := wghttp.NewServeMux()
+		if err := mux.TryHandle(p, noop); !errors.Is(err, wghttp.ErrInvalidPattern) {
+			t.Fatalf("%q: expected ErrInvalidPattern, got %v", p, err)
+		}
+	}
+}
+
+// ── MatchedPattern tests ────────────────────────────────────────────
+
+func TestMatchedPattern_WildcardRoute(t *testing.T) {
+	mux := wghttp.NewServeMux()
+	var pattern string
+	var ok bool
+	mux.HandleFunc("/users/{id}", func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		pattern, ok = wghttp.MatchedPattern(r)
+	})
+
+	mux.ServeHTTP(wghttp.NewTestResponseWriter(), wghttp.NewRequest(wghttp.MethodGet, "/users/42", nil))
+
+	if !ok || pattern != "/users/{id}" {
+		t.Fatalf("expected pattern '/users/{id}', got '%s' (ok=%v)", pattern, ok)
+	}
+}
+
+func TestMatchedPattern_PrefixAndMethodRoutes(t *testing.T) {
+	mux := wghttp.NewServeMux()
+	var got []string
+	record := func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		p, _ := wghttp.MatchedPattern(r)
+		got = append(got, p)
+	}
+	mux.HandleFunc("/static/", record)
+	mux.HandleFunc("POST /orders", record)
+
+	mux.ServeHTTP(wghttp.NewTestResponseWriter(), wghttp.NewRequest(wghttp.MethodGet, "/static/css/app.css", nil))
+	mux.ServeHTTP(wghttp.NewTestResponseWriter(), wghttp.NewRequest(wghttp.MethodPost, "/orders", nil))
+
+	if len(got) != 2 || got[0] != "/static/" || got[1] != "POST /orders" {
+		t.Fatalf("expected ['/static/' 'POST /orders'], got %v", got)
+	}
+}
+
+func TestMatchedPattern_VisibleToOuterMiddleware(t *testing.T) {
+	mux := wghttp.NewServeMux()
+	mux.HandleFunc("/users/{id}", func(w wghttp.ResponseWriter, r *wghttp.Request) {})
+
+	req := wghttp.NewRequest(wghttp.MethodGet, "/users/7", nil)
+	mux.ServeHTTP(wghttp.NewTestResponseWriter(), req)
+
+	if p, ok := wghttp.MatchedPattern(req); !ok || p != "/users/{id}" {
+		t.Fatalf("expected outer request to carry '/users/{id}', got '%s' (ok=%v)", p, ok)
+	}
+}
+
+func TestMatchedPattern_Unrouted(t *testing.T) {
+	if _, ok := wghttp.MatchedPattern(wghttp.NewRequest(wghttp.MethodGet, "/", nil)); ok {
+		t.Fatal("expected ok=false for a request not routed by a ServeMux")
+	}
+}
+
+// ── PathSuffix tests ────────────────────────────────────────────────
+
+func TestPathSuffix_PrefixRoute(t *testing.T) {
+	mux := wghttp.NewServeMux()
+	var suffix string
+	mux.HandleFunc("/api/", func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		suffix = r.PathSuffix()
+	})
+
+	mux.ServeHTTP(wghttp.NewTestResponseWriter(), wghttp.NewRequest(wghttp.MethodGet, "/api/users/123", nil))
+
+	if suffix != "users/123" {
+		t.Fatalf("expected suffix 'users/123', got '%s'", suffix)
+	}
+}
+
+func TestPathSuffix_ExactAndWildcardRoutesEmpty(t *testing.T) {
+	mux := wghttp.NewServeMux()
+	var got []string
+	record := func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		got = append(got, r.PathSuffix())
+	}
+	mux.HandleFunc("/api/", record)
+	mux.HandleFunc("/users/{id}", record)
+
+	mux.ServeHTTP(wghttp.NewTestResponseWriter(), wghttp.NewRequest(wghttp.MethodGet, "/api/", nil))
+	mux.ServeHTTP(wghttp.NewTestResponseWriter(), wghttp.NewRequest(wghttp.MethodGet, "/users/7", nil))
+
+	if len(got) != 2 || got[0] != "" || got[1] != "" {
+		t.Fatalf("expected empty suffixes, got %q", got)
+	}
+}
+
+func TestPathSuffix_NestedMux(t *testing.T) {
+	inner := wghttp.NewServeMux()
+	var got []string
+	record := func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		got = append(got, r.PathSuffix())
+	}
+	inner.HandleFunc("/api/users/", record)
+	inner.HandleFunc("/api/health", record)
+	outer := wghttp.NewServeMux()
+	outer.Handle("/api/", inner)
+
+	outer.ServeHTTP(wghttp.NewTestResponseWriter(), wghttp.NewRequest(wghttp.MethodGet, "/api/users/123", nil))
+	outer.ServeHTTP(wghttp.NewTestResponseWriter(), wghttp.NewRequest(wghttp.MethodGet, "/api/health", nil))
+
+	if len(got) != 2 || got[0] != "123" || got[1] != "" {
+		t.Fatalf("expected innermost suffixes ['123' ''], got %q", got)
+	}
+}
+
+func TestPathSuffix_Unrouted(t *testing.T) {
+	if s := wghttp.NewRequest(wghttp.MethodGet, "/api/x", nil).PathSuffix(); s != "" {
+		t.Fatalf("expected empty suffix for an unrouted request, got '%s'", s)
+	}
+}
+
+func TestServeMux_WildcardTieIsDeterministic(t *testing.T) {
+	for i := 0; i < 200; i++ {
+		mux := wghttp.NewServeMux()
+		mux.HandleFunc("GET /a/{x}", func(w wghttp.ResponseWriter, r *wghttp.Request) {
+			w.Write([]byte("method"))
+		})
+		mux.HandleFunc("/{y}/b", func(w wghttp.ResponseWriter, r *wghttp.Request) {
+			w.Write([]byte("any"))
+		})
+
+		w := wghttp.NewTestResponseWriter()
+		mux.ServeHTTP(w, wghttp.NewRequest(wghttp.MethodGet, "/a/b", nil))
+		if string(w.Body()) != "method" {
+			t.Fatalf("run %d: expected 'GET /a/{x}' to win the tie, got '%s'", i, w.Body())
+		}
+	}
+}
+
+func TestServeMux_EquivalentWildcardIsDuplicate(t *testing.T) {
+	mux := wghttp.NewServeMux()
+	noop := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {})
+	if err := mux.TryHandle("/users/{id}", noop); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, p := range []string{"/users/{name}", "/users/{id}"} {
+		if err := mux.TryHandle(p, noop); !errors.Is(err, wghttp.ErrDuplicatePattern) {
+			t.Fatalf("%q: expected ErrDuplicatePattern, got %v", p, err)
+		}
+	}
+	if err := mux.TryHandle("/files/{path...}", noop); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mux.TryHandle("/files/{rest...}", noop); !errors.Is(err, wghttp.ErrDuplicatePattern) {
+		t.Fatalf("expected ErrDuplicatePattern for '/files/{rest...}', got %v", err)
+	}
+	// Different methods, or a catch-all versus a single segment, do not
+	// match the same requests.
+	for _, p := range []string{"POST /users/{name}", "/users/{name...}", "/files/{path}"} {
+		if err := mux.TryHandle(p, noop); err != nil {
+			t.Fatalf("%q: unexpected error: %v", p, err)
+		}
+	}
+}
+
+func TestServeMux_ReplaceEquivalentWildcard(t *testing.T) {
+	mux := wghttp.NewServeMux()
+	mux.HandleFunc("/users/{id}", func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		w.Write([]byte("id=" + r.PathValue("id")))
+	})
+	mux.Replace("/users/{name}", wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		w.Write([]byte("name=" + r.PathValue("name")))
+	}))
+
+	w := wghttp.NewTestResponseWriter()
+	mux.ServeHTTP(w, wghttp.NewRequest(wghttp.MethodGet, "/users/42", nil))
+	if string(w.Body()) != "name=42" {
+		t.Fatalf("expected the replacement to serve the route, got '%s'", w.Body())
+	}
+}
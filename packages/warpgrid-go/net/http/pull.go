@@ -0,0 +1,72 @@
+package http
+
+import "sync"
+
+// Pulled responses let the host drain a large response in fixed-size
+// chunks instead of reading it from guest memory in one shot. The host
+// calls warpgrid_http_handle_request_pull, which serves the request and
+// keeps the serialized response under a handle, then calls
+// warpgrid_http_response_read with that handle and a buffer until it
+// reports done. HandleRequestPull and ReadResponseChunk are the same
+// protocol for native code and tests.
+
+// pulledResponses holds the responses awaiting ReadResponseChunk,
+// keyed by handle. Handle 0 is never issued.
+var pulledResponses = struct {
+	sync.Mutex
+	next    uint32
+	pending map[uint32][]byte
+}{pending: make(map[uint32][]byte)}
+
+// HandleRequestPull serves reqBytes like HandleRequest but, rather than
+// returning the serialized response, retains it for ReadResponseChunk.
+// It returns the handle to read it with and its total size in bytes.
+func HandleRequestPull(reqBytes []byte) (handle uint32, size int) {
+	return RetainResponse(HandleRequest(reqBytes))
+}
+
+// RetainResponse keeps the serialized response resp for
+// ReadResponseChunk and returns its handle and size.
+func RetainResponse(resp []byte) (handle uint32, size int) {
+	pulledResponses.Lock()
+	defer pulledResponses.Unlock()
+	for {
+		pulledResponses.next++
+		handle = pulledResponses.next
+		if _, taken := pulledResponses.pending[handle]; handle != 0 && !taken {
+			break
+		}
+	}
+	pulledResponses.pending[handle] = resp
+	return handle, len(resp)
+}
+
+// ReadResponseChunk copies the next bytes of the response retained
+// under handle into chunk and reports how many were copied and whether
+// the response is now fully read. Once done is reported the response is
+// released and the handle becomes invalid; an unknown handle reads as
+// (0, true).
+func ReadResponseChunk(handle uint32, chunk []byte) (n int, done bool) {
+	pulledResponses.Lock()
+	defer pulledResponses.Unlock()
+	rest, ok := pulledResponses.pending[handle]
+	if !ok {
+		return 0, true
+	}
+	n = copy(chunk, rest)
+	rest = rest[n:]
+	if len(rest) == 0 {
+		delete(pulledResponses.pending, handle)
+		return n, true
+	}
+	pulledResponses.pending[handle] = rest
+	return n, false
+}
+
+// ReleaseResponse discards the response retained under handle without
+// reading the rest of it, for a host that abandons the transfer.
+func ReleaseResponse(handle uint32) {
+	pulledResponses.Lock()
+	delete(pulledResponses.pending, handle)
+	pulledResponses.Unlock()
+}
@@ -0,0 +1,100 @@
+package http_test
+
+import (
+	"bytes"
+	"testing"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/net/http"
+)
+
+// ── Pulled response tests ───────────────────────────────────────────
+
+// drain reads the response under handle in chunkSize pieces, as the
+// host does through warpgrid_http_response_read.
+func drain(t *testing.T, handle uint32, chunkSize int) ([]byte, int) {
+	t.Helper()
+	var out []byte
+	chunk := make([]byte, chunkSize)
+	for calls := 1; ; calls++ {
+		n, done := wghttp.ReadResponseChunk(handle, chunk)
+		out = append(out, chunk[:n]...)
+		if done {
+			return out, calls
+		}
+		if n != chunkSize {
+			t.Fatalf("expected a full %d-byte chunk before done, got %d", chunkSize, n)
+		}
+	}
+}
+
+func TestHandleRequestPull_ReassemblesLargeResponse(t *testing.T) {
+	body := bytes.Repeat([]byte("0123456789abcdef"), 5000) // 80000 bytes
+	wghttp.RegisterAndReturn(wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(body)
+	}))
+	defer wghttp.UnregisterTrigger(wghttp.DefaultTrigger)
+
+	reqBytes := wghttp.MarshalRequest(wghttp.WitHttpRequest{Method: "GET", URI: "/blob"})
+	handle, size := wghttp.HandleRequestPull(reqBytes)
+	if handle == 0 {
+		t.Fatal("expected a non-zero handle")
+	}
+
+	data, calls := drain(t, handle, 4096)
+
+	if len(data) != size {
+		t.Fatalf("expected %d bytes, got %d", size, len(data))
+	}
+	if want := (size + 4095) / 4096; calls != want {
+		t.Fatalf("expected %d reads of 4KB, got %d", want, calls)
+	}
+	if !bytes.Equal(data, wghttp.HandleRequest(reqBytes)) {
+		t.Fatal("expected pulled bytes to match the one-shot response")
+	}
+	resp := wghttp.UnmarshalResponse(data)
+	if resp.Status != 200 || !bytes.Equal(resp.Body, body) {
+		t.Fatalf("expected the original 200 body, got status %d and %d bytes", resp.Status, len(resp.Body))
+	}
+}
+
+func TestReadResponseChunk_HandleReleasedWhenDone(t *testing.T) {
+	handle, _ := wghttp.RetainResponse([]byte("tiny"))
+
+	data, _ := drain(t, handle, 4096)
+	if string(data) != "tiny" {
+		t.Fatalf("expected 'tiny', got '%s'", data)
+	}
+
+	if n, done := wghttp.ReadResponseChunk(handle, make([]byte, 16)); n != 0 || !done {
+		t.Fatalf("expected a released handle to read as (0, true), got (%d, %v)", n, done)
+	}
+}
+
+func TestRetainResponse_HandlesAreIndependent(t *testing.T) {
+	a, _ := wghttp.RetainResponse([]byte("aaaa"))
+	b, _ := wghttp.RetainResponse([]byte("bbbb"))
+	if a == b {
+		t.Fatal("expected distinct handles")
+	}
+
+	chunk := make([]byte, 2)
+	wghttp.ReadResponseChunk(a, chunk)
+	gotB, _ := drain(t, b, 2)
+	gotA, _ := drain(t, a, 2)
+
+	if string(gotA) != "aa" || string(gotB) != "bbbb" {
+		t.Fatalf("expected interleaved reads to stay separate, got '%s' and '%s'", gotA, gotB)
+	}
+}
+
+func TestReleaseResponse_DiscardsRemainder(t *testing.T) {
+	handle, _ := wghttp.RetainResponse([]byte("abandoned"))
+	wghttp.ReadResponseChunk(handle, make([]byte, 3))
+
+	wghttp.ReleaseResponse(handle)
+
+	if n, done := wghttp.ReadResponseChunk(handle, make([]byte, 16)); n != 0 || !done {
+		t.Fatalf("expected released handle to read as (0, true), got (%d, %v)", n, done)
+	}
+}
@@ -0,0 +1,47 @@
+package http
+
+import (
+	"net/url"
+	"strings"
+)
+
+// QueryParam is one key/value pair of a URL query.
+type QueryParam struct {
+	Key   string
+	Value string
+}
+
+// RawQueryValues parses r.URL.RawQuery into its key/value pairs in the
+// order they were sent, keeping repeated keys, for schemes such as
+// request signing that hash the query in its canonical order, which
+// the url.Values map from r.URL.Query() loses.
+//
+// "key=" and a bare "key" both yield an empty Value, and empty pairs
+// (as in "a=1&&b=2") are skipped. Keys and values are unescaped as
+// url.ParseQuery does, with '+' as a space; a pair with an invalid
+// escape is kept as sent rather than dropped, so no pair goes missing
+// from a signature. Use r.URL.RawQuery itself when the exact bytes are
+// needed.
+func RawQueryValues(r *Request) []QueryParam {
+	if r.URL == nil || r.URL.RawQuery == "" {
+		return nil
+	}
+	var params []QueryParam
+	for _, pair := range strings.Split(r.URL.RawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(pair, "=")
+		params = append(params, QueryParam{Key: unescapeQuery(key), Value: unescapeQuery(value)})
+	}
+	return params
+}
+
+// unescapeQuery is url.QueryUnescape, returning s unchanged when it has
+// an invalid escape.
+func unescapeQuery(s string) string {
+	if u, err := url.QueryUnescape(s); err == nil {
+		return u
+	}
+	return s
+}
@@ -0,0 +1,50 @@
+package http_test
+
+import (
+	"testing"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/net/http"
+)
+
+// ── RawQueryValues tests ────────────────────────────────────────────
+
+func TestRawQueryValues_PreservesOrderAndDuplicates(t *testing.T) {
+	req := wghttp.NewRequest(wghttp.MethodGet, "/sign?z=1&a=2&z=3&m=4", nil)
+
+	got := wghttp.RawQueryValues(req)
+	want := []wghttp.QueryParam{{"z", "1"}, {"a", "2"}, {"z", "3"}, {"m", "4"}}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("pair %d: expected %v, got %v", i, want[i], got[i])
+		}
+	}
+
+	// The Query map groups repeated keys and has no order.
+	if q := req.URL.Query(); len(q) != 3 || len(q["z"]) != 2 {
+		t.Fatalf("expected Query to group the two z values, got %v", q)
+	}
+}
+
+func TestRawQueryValues_EmptyAndMissingValues(t *testing.T) {
+	req := wghttp.NewRequest(wghttp.MethodGet, "/?key=&flag&&name=a+b%21&bad=%zz", nil)
+
+	got := wghttp.RawQueryValues(req)
+	want := []wghttp.QueryParam{{"key", ""}, {"flag", ""}, {"name", "a b!"}, {"bad", "%zz"}}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("pair %d: expected %v, got %v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestRawQueryValues_NoQuery(t *testing.T) {
+	if got := wghttp.RawQueryValues(wghttp.NewRequest(wghttp.MethodGet, "/", nil)); got != nil {
+		t.Fatalf("expected nil for a request without a query, got %v", got)
+	}
+}
@@ -0,0 +1,74 @@
+package http
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+
+	"github.com/anthropics/warpgrid/packages/warpgrid-go/internal/randsrc"
+)
+
+// RequestIDHeader is the header RequestID reads and echoes.
+const RequestIDHeader = "X-Request-Id"
+
+// requestIDKey is the context key under which RequestID stores the ID.
+type requestIDKey struct{}
+
+// RequestID is middleware that gives every request an ID for tracing
+// across the mesh. It reuses the incoming X-Request-Id header when
+// present and otherwise generates a random UUID-formatted ID. The ID is
+// stored in the request context (see RequestIDFromContext) and echoed
+// in the response's X-Request-Id header before next runs.
+func RequestID(next Handler) Handler {
+	return HandlerFunc(func(w ResponseWriter, r *Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// SetRandSource replaces the random source behind generated request
+// IDs with fn, which must be safe for concurrent use; nil restores the
+// default. The source is shared with the net package's balancers and
+// backoff, so a deterministic source makes them all reproducible, which
+// is what tests use it for.
+func SetRandSource(fn func() uint64) {
+	randsrc.Set(fn)
+}
+
+// RequestIDFromContext returns the request ID stored by RequestID.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok
+}
+
+// newRequestID returns a version 4 UUID string.
+//
+// Randomness comes from the shared random source (see SetRandSource),
+// which reads crypto/rand, mapped by TinyGo to the WASI random_get
+// call. Where that is unavailable, as on hosts that do not implement
+// random_get, it falls back to the current time and a process-wide
+// counter. Such IDs are unique within the instance but predictable, so
+// they must not be used as secrets.
+func newRequestID() string {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], randsrc.Uint64())
+	binary.BigEndian.PutUint64(b[8:], randsrc.Uint64())
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+
+	var s [36]byte
+	hex.Encode(s[0:8], b[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], b[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], b[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], b[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], b[10:])
+	return string(s[:])
+}
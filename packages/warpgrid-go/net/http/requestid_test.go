@@ -0,0 +1,79 @@
+package http_test
+
+import (
+	"regexp"
+	"testing"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/net/http"
+)
+
+// ── RequestID middleware tests ──────────────────────────────────────
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func serveWithRequestID(req *wghttp.Request) (w wghttp.ResponseWriter, ctxID string, ok bool) {
+	rw := wghttp.NewTestResponseWriter()
+	handler := wghttp.RequestID(wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		ctxID, ok = wghttp.RequestIDFromContext(r.Context())
+	}))
+	handler.ServeHTTP(rw, req)
+	return rw, ctxID, ok
+}
+
+func TestRequestID_PassesThroughProvidedID(t *testing.T) {
+	req := wghttp.NewRequest(wghttp.MethodGet, "/", nil)
+	req.Header.Set("x-request-id", "trace-abc-123")
+
+	w, id, ok := serveWithRequestID(req)
+	if !ok || id != "trace-abc-123" {
+		t.Fatalf("expected context ID 'trace-abc-123', got '%s' (ok=%v)", id, ok)
+	}
+	if got := w.Header().Get("X-Request-Id"); got != "trace-abc-123" {
+		t.Fatalf("expected echoed ID 'trace-abc-123', got '%s'", got)
+	}
+}
+
+func TestRequestID_GeneratesIDWhenAbsent(t *testing.T) {
+	w, id, ok := serveWithRequestID(wghttp.NewRequest(wghttp.MethodGet, "/", nil))
+	if !ok || !uuidPattern.MatchString(id) {
+		t.Fatalf("expected generated UUID in context, got '%s' (ok=%v)", id, ok)
+	}
+	if got := w.Header().Get("X-Request-Id"); got != id {
+		t.Fatalf("expected response header to echo '%s', got '%s'", id, got)
+	}
+
+	_, other, _ := serveWithRequestID(wghttp.NewRequest(wghttp.MethodGet, "/", nil))
+	if other == id {
+		t.Fatalf("expected distinct generated IDs, got '%s' twice", id)
+	}
+}
+
+func TestRequestIDFromContext_Absent(t *testing.T) {
+	req := wghttp.NewRequest(wghttp.MethodGet, "/", nil)
+	if id, ok := wghttp.RequestIDFromContext(req.Context()); ok {
+		t.Fatalf("expected no request ID, got '%s'", id)
+	}
+}
+
+func TestRequestID_DeterministicWithRandSource(t *testing.T) {
+	defer wghttp.SetRandSource(nil)
+	source := func() func() uint64 {
+		var n uint64
+		return func() uint64 {
+			n++
+			return n
+		}
+	}
+
+	wghttp.SetRandSource(source())
+	_, first, _ := serveWithRequestID(wghttp.NewRequest(wghttp.MethodGet, "/", nil))
+	wghttp.SetRandSource(source())
+	_, second, _ := serveWithRequestID(wghttp.NewRequest(wghttp.MethodGet, "/", nil))
+
+	if first != second || !uuidPattern.MatchString(first) {
+		t.Fatalf("expected the same UUID from the same source, got '%s' and '%s'", first, second)
+	}
+	if first != "00000000-0000-4001-8000-000000000002" {
+		t.Fatalf("expected UUID built from the source values, got '%s'", first)
+	}
+}
@@ -1,5 +1,19 @@
 package http
 
+import (
+	"errors"
+	"io"
+)
+
+// ErrResponseTooLarge is returned by a ResponseWriter's Write when the
+// body would exceed MaxResponseBytes.
+var ErrResponseTooLarge = errors.New("http: response body too large")
+
+// ErrBodyNotAllowed is returned by a ResponseWriter's Write when the
+// status written does not permit a body (1xx, 204, and 304), matching
+// net/http.ErrBodyNotAllowed.
+var ErrBodyNotAllowed = errors.New("http: request method or response status code does not allow body")
+
 // bufferResponseWriter captures the response in memory for later
 // serialization to the WIT wire format. Implements ResponseWriter.
 type bufferResponseWriter struct {
@@ -7,6 +21,15 @@ type bufferResponseWriter struct {
 	body        []byte
 	statusCode  int
 	wroteHeader bool
+
+	// maxBytes limits the body size when positive; overflow records
+	// that a Write exceeded it.
+	maxBytes int
+	overflow bool
+
+	// interim holds informational responses written before the final
+	// one, in order (see WriteEarlyHints).
+	interim []WitHttpResponse
 }
 
 func newBufferResponseWriter() *bufferResponseWriter {
@@ -20,14 +43,89 @@ func (w *bufferResponseWriter) Header() Header {
 	return w.header
 }
 
+// Write appends data to the body. With a size limit, a write that does
+// not fit stores the bytes that do and fails with ErrResponseTooLarge,
+// reporting the short count as io.Writer requires.
+//
+// As in net/http, writes after a status that forbids a body (1xx, 204,
+// 304) are dropped with ErrBodyNotAllowed, keeping the response
+// protocol-compliant.
 func (w *bufferResponseWriter) Write(data []byte) (int, error) {
 	if !w.wroteHeader {
 		w.wroteHeader = true
 	}
+	if !bodyAllowed(w.statusCode) {
+		return 0, ErrBodyNotAllowed
+	}
+	if w.maxBytes > 0 && len(w.body)+len(data) > w.maxBytes {
+		n := w.maxBytes - len(w.body)
+		w.body = append(w.body, data[:n]...)
+		w.overflow = true
+		return n, ErrResponseTooLarge
+	}
 	w.body = append(w.body, data...)
 	return len(data), nil
 }
 
+// ReadFrom implements io.ReaderFrom, reading src directly into the body
+// as net/http's response writer does. When src reports its remaining
+// length (as *bytes.Reader and *strings.Reader do), the body grows once
+// to fit rather than repeatedly as with many small Writes. The
+// MaxResponseBytes limit applies as for Write.
+func (w *bufferResponseWriter) ReadFrom(src io.Reader) (int64, error) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+	}
+	if !bodyAllowed(w.statusCode) {
+		return 0, ErrBodyNotAllowed
+	}
+	if l, ok := src.(interface{ Len() int }); ok {
+		// One spare byte lets the final Read report io.EOF without
+		// forcing another growth.
+		w.grow(l.Len() + 1)
+	}
+
+	var total int64
+	for {
+		if len(w.body) == cap(w.body) {
+			w.grow(512)
+		}
+		limit := cap(w.body)
+		if w.maxBytes > 0 && limit > w.maxBytes+1 {
+			limit = w.maxBytes + 1
+		}
+		n, err := src.Read(w.body[len(w.body):limit])
+		if w.maxBytes > 0 && len(w.body)+n > w.maxBytes {
+			n = w.maxBytes - len(w.body)
+			w.body = w.body[:w.maxBytes]
+			w.overflow = true
+			return total + int64(n), ErrResponseTooLarge
+		}
+		w.body = w.body[:len(w.body)+n]
+		total += int64(n)
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// grow ensures the body has room for at least n more bytes, allowing
+// one byte past maxBytes so ReadFrom can detect overflow.
+func (w *bufferResponseWriter) grow(n int) {
+	if w.maxBytes > 0 && len(w.body)+n > w.maxBytes+1 {
+		n = w.maxBytes + 1 - len(w.body)
+	}
+	if n <= cap(w.body)-len(w.body) {
+		return
+	}
+	body := make([]byte, len(w.body), len(w.body)+n)
+	copy(body, w.body)
+	w.body = body
+}
+
 func (w *bufferResponseWriter) WriteHeader(statusCode int) {
 	if w.wroteHeader {
 		return
@@ -36,11 +134,53 @@ func (w *bufferResponseWriter) WriteHeader(statusCode int) {
 	w.statusCode = statusCode
 }
 
+// bodyAllowed reports whether a response with the given status may
+// carry a body.
+func bodyAllowed(status int) bool {
+	switch {
+	case status >= 100 && status <= 199:
+		return false
+	case status == StatusNoContent, status == StatusNotModified:
+		return false
+	}
+	return true
+}
+
+// writeInterim records an informational response. It is ignored once
+// the final response has started, as the client would already have
+// been sent its status line.
+func (w *bufferResponseWriter) writeInterim(statusCode int, header Header) {
+	if w.wroteHeader {
+		return
+	}
+	w.interim = append(w.interim, WitHttpResponse{
+		Status:  uint16(statusCode),
+		Headers: goHeadersToWitHeaders(header),
+	})
+}
+
+// reset discards everything written so far so that a fresh response
+// (such as a 500 after a recovered panic) can be written. Interim
+// responses are kept, since they are not part of the final response.
+func (w *bufferResponseWriter) reset() {
+	w.header = make(Header)
+	w.body = nil
+	w.statusCode = StatusOK
+	w.wroteHeader = false
+	w.overflow = false
+}
+
 // StatusCode returns the captured status code.
 func (w *bufferResponseWriter) StatusCode() int {
 	return w.statusCode
 }
 
+// InterimResponses returns the informational responses written before
+// the final one, such as those from WriteEarlyHints.
+func (w *bufferResponseWriter) InterimResponses() []WitHttpResponse {
+	return w.interim
+}
+
 // Body returns the captured response body bytes.
 func (w *bufferResponseWriter) Body() []byte {
 	return w.body
@@ -0,0 +1,45 @@
+package http
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SetRetryAfter sets the Retry-After header on w to d in the
+// delay-seconds form, rounding up to a whole second so clients never
+// retry early. A non-positive d is written as "0".
+func SetRetryAfter(w ResponseWriter, d time.Duration) {
+	secs := int64(0)
+	if d > 0 {
+		secs = int64((d + time.Second - 1) / time.Second)
+	}
+	w.Header().Set("Retry-After", strconv.FormatInt(secs, 10))
+}
+
+// ParseRetryAfter parses a Retry-After header value in either of its
+// forms (RFC 9110 section 10.2.3): delay-seconds ("120") or an
+// HTTP-date ("Wed, 21 Oct 2026 07:28:00 GMT"), which is converted to a
+// delay relative to now. A date in the past yields zero. ok is false
+// when the value is empty or malformed.
+func ParseRetryAfter(header string, now time.Time) (time.Duration, bool) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0, false
+	}
+	if header[0] >= '0' && header[0] <= '9' {
+		secs, err := strconv.ParseInt(header, 10, 64)
+		if err != nil || secs > int64(time.Duration(1<<63-1)/time.Second) {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	t, err := ParseTime(header)
+	if err != nil {
+		return 0, false
+	}
+	if d := t.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
@@ -0,0 +1,65 @@
+package http_test
+
+import (
+	"testing"
+	"time"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/net/http"
+)
+
+// ── Retry-After tests ───────────────────────────────────────────────
+
+func TestSetRetryAfter_RoundsUpToSeconds(t *testing.T) {
+	cases := map[time.Duration]string{
+		30 * time.Second:        "30",
+		1500 * time.Millisecond: "2",
+		0:                       "0",
+		-time.Second:            "0",
+	}
+	for d, want := range cases {
+		w := wghttp.NewTestResponseWriter()
+		wghttp.SetRetryAfter(w, d)
+		if got := w.Header().Get("Retry-After"); got != want {
+			t.Fatalf("%v: expected Retry-After '%s', got '%s'", d, want, got)
+		}
+	}
+}
+
+func TestParseRetryAfter_Seconds(t *testing.T) {
+	d, ok := wghttp.ParseRetryAfter(" 120 ", time.Now())
+	if !ok || d != 120*time.Second {
+		t.Fatalf("expected 120s, got %v (ok=%v)", d, ok)
+	}
+}
+
+func TestParseRetryAfter_HTTPDate(t *testing.T) {
+	now := time.Date(2026, 10, 21, 7, 27, 0, 0, time.UTC)
+
+	d, ok := wghttp.ParseRetryAfter("Wed, 21 Oct 2026 07:28:00 GMT", now)
+	if !ok || d != time.Minute {
+		t.Fatalf("expected 1m, got %v (ok=%v)", d, ok)
+	}
+
+	d, ok = wghttp.ParseRetryAfter("Wed, 21 Oct 2026 07:00:00 GMT", now)
+	if !ok || d != 0 {
+		t.Fatalf("expected 0 for a past date, got %v (ok=%v)", d, ok)
+	}
+}
+
+func TestParseRetryAfter_Malformed(t *testing.T) {
+	for _, v := range []string{"", "soon", "-5", "1.5", "12abc", "Wed, 21 Oct 2026"} {
+		if d, ok := wghttp.ParseRetryAfter(v, time.Now()); ok {
+			t.Fatalf("%q: expected ok=false, got %v", v, d)
+		}
+	}
+}
+
+func TestRetryAfter_RoundTrip(t *testing.T) {
+	w := wghttp.NewTestResponseWriter()
+	wghttp.SetRetryAfter(w, 45*time.Second)
+
+	d, ok := wghttp.ParseRetryAfter(w.Header().Get("Retry-After"), time.Now())
+	if !ok || d != 45*time.Second {
+		t.Fatalf("expected 45s, got %v (ok=%v)", d, ok)
+	}
+}
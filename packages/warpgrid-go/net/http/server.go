@@ -1,27 +1,212 @@
 package http
 
-import "sync"
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Errors returned by ServeMux.TryHandle. Handle panics with the same
+// errors, matching net/http.
+var (
+	// ErrInvalidPattern reports an empty pattern or one whose path (after
+	// an optional method) does not begin with '/'. The overlay mux matches
+	// paths only, so host-qualified patterns are not supported.
+	ErrInvalidPattern = errors.New("http: invalid pattern")
+
+	// ErrDuplicatePattern reports a pattern that already has a handler.
+	ErrDuplicatePattern = errors.New("http: multiple registrations")
+
+	// ErrNilHandler reports a nil handler passed to a registration method.
+	ErrNilHandler = errors.New("http: nil handler")
+)
 
 // ServeMux is an HTTP request multiplexer matching registered patterns
 // against the request URL path. Exact matches take priority; trailing-
 // slash patterns match as prefixes (longest match wins).
+//
+// As in net/http, a pattern may start with a method and a space, as in
+// "GET /users/" or "POST /users": such a pattern only matches requests
+// with that method, and takes precedence over a pattern without one for
+// the same path. A request whose path matches only patterns for other
+// methods is answered with 405 Method Not Allowed and an Allow header.
+//
+// A path segment of the form {name} matches any single non-empty
+// segment, and a final {name...} matches the rest of the path, as in
+// "/users/{id}" or "/files/{path...}". The matched values are available
+// from Request.PathValue, the pattern from MatchedPattern, and the
+// remainder of a prefix match from Request.PathSuffix. Wildcard
+// patterns match whole paths (a trailing slash does not make them
+// prefixes) and rank between exact and prefix patterns; among several
+// matching wildcard patterns, the one with the most literal segments
+// wins.
 type ServeMux struct {
-	mu       sync.RWMutex
-	handlers map[string]Handler
+	// RedirectTrailingSlash enables net/http's trailing-slash redirect:
+	// a request for "/foo" with no "/foo" pattern, when "/foo/" is
+	// registered, is answered with a 301 to "/foo/". It is off by
+	// default, in which case such a request is routed by prefix match
+	// as usual (and 404s unless a shorter prefix pattern matches). Set
+	// it before the mux starts serving.
+	RedirectTrailingSlash bool
+
+	// AutoHead answers HEAD requests for a path that has a GET handler
+	// but no HEAD handler by running the GET handler and discarding the
+	// body. Headers are kept, and Content-Length is set to the size of
+	// the discarded body unless the handler set it. An explicit HEAD
+	// pattern still takes precedence. Set it before the mux starts
+	// serving.
+	AutoHead bool
+
+	mu sync.RWMutex
+	// handlers maps a pattern path to its handlers by method; the
+	// empty method holds the handler for a pattern without one.
+	handlers map[string]map[string]Handler
 }
 
 // NewServeMux creates a new ServeMux.
 func NewServeMux() *ServeMux {
 	return &ServeMux{
-		handlers: make(map[string]Handler),
+		handlers: make(map[string]map[string]Handler),
 	}
 }
 
 // Handle registers the handler for the given pattern.
+//
+// Handle panics if the pattern is invalid or already registered, matching
+// net/http. Use TryHandle to receive the error instead, or Replace to
+// deliberately swap an existing registration.
 func (mux *ServeMux) Handle(pattern string, handler Handler) {
+	if err := mux.TryHandle(pattern, handler); err != nil {
+		panic(err)
+	}
+}
+
+// TryHandle registers the handler for the given pattern, returning an
+// error instead of panicking when the pattern is empty, does not begin
+// with '/' (after an optional method), is already registered, or the
+// handler is nil. Patterns differing only in wildcard names, such as
+// "/users/{id}" and "/users/{name}", match the same requests, so the
+// second is reported as a duplicate too.
+func (mux *ServeMux) TryHandle(pattern string, handler Handler) error {
+	method, path, err := parsePattern(pattern, handler)
+	if err != nil {
+		return err
+	}
+
 	mux.mu.Lock()
 	defer mux.mu.Unlock()
-	mux.handlers[pattern] = handler
+	if existing, exists := mux.equivalent(method, path); exists {
+		if existing != path {
+			return fmt.Errorf("%w for %s: matches the same paths as %s", ErrDuplicatePattern, pattern, existing)
+		}
+		return fmt.Errorf("%w for %s", ErrDuplicatePattern, pattern)
+	}
+	mux.register(method, path, handler)
+	return nil
+}
+
+// Replace registers the handler for the given pattern, overwriting any
+// existing registration. It is the documented escape hatch for tests and
+// hot-reload code that legitimately re-register a route; application
+// code should use Handle so accidental double registration is caught.
+// A registration differing only in wildcard names is replaced as well.
+// Replace still panics on an invalid pattern or nil handler.
+func (mux *ServeMux) Replace(pattern string, handler Handler) {
+	method, path, err := parsePattern(pattern, handler)
+	if err != nil {
+		panic(err)
+	}
+
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	if existing, exists := mux.equivalent(method, path); exists && existing != path {
+		delete(mux.handlers[existing], method)
+		if len(mux.handlers[existing]) == 0 {
+			delete(mux.handlers, existing)
+		}
+	}
+	mux.register(method, path, handler)
+}
+
+// equivalent returns the registered pattern path for method that
+// matches the same paths as path, differing at most in wildcard names;
+// the caller holds mu.
+func (mux *ServeMux) equivalent(method, path string) (string, bool) {
+	if _, exists := mux.handlers[path][method]; exists {
+		return path, true
+	}
+	if !isWildcardPattern(path) {
+		return "", false
+	}
+	shape := wildcardShape(path)
+	for existing, byMethod := range mux.handlers {
+		if _, exists := byMethod[method]; exists && wildcardShape(existing) == shape {
+			return existing, true
+		}
+	}
+	return "", false
+}
+
+// register stores handler; the caller holds mu.
+func (mux *ServeMux) register(method, path string, handler Handler) {
+	byMethod := mux.handlers[path]
+	if byMethod == nil {
+		byMethod = make(map[string]Handler)
+		mux.handlers[path] = byMethod
+	}
+	byMethod[method] = handler
+}
+
+// cleanPath returns the canonical form of p: rooted, with "." and ".."
+// elements resolved and repeated slashes collapsed. A trailing slash is
+// preserved.
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	np := path.Clean(p)
+	if p[len(p)-1] == '/' && np != "/" {
+		np += "/"
+	}
+	return np
+}
+
+// parsePattern splits a pattern into its optional method and its path,
+// and checks the registration arguments shared by Handle, TryHandle,
+// and Replace.
+func parsePattern(pattern string, handler Handler) (method, path string, err error) {
+	if pattern == "" {
+		return "", "", fmt.Errorf("%w: empty pattern", ErrInvalidPattern)
+	}
+	path = pattern
+	if i := strings.IndexAny(pattern, " \t"); i >= 0 {
+		method, path = pattern[:i], strings.TrimLeft(pattern[i+1:], " \t")
+		for j := 0; j < len(method); j++ {
+			if !isTokenByte(method[j]) {
+				return "", "", fmt.Errorf("%w %q: bad method", ErrInvalidPattern, pattern)
+			}
+		}
+	}
+	if path == "" || path[0] != '/' {
+		return "", "", fmt.Errorf("%w %q: must begin with '/'", ErrInvalidPattern, pattern)
+	}
+	if err := validateWildcards(path); err != nil {
+		return "", "", fmt.Errorf("%w %q: %v", ErrInvalidPattern, pattern, err)
+	}
+	if handler == nil {
+		return "", "", fmt.Errorf("%w for %s", ErrNilHandler, pattern)
+	}
+	return method, path, nil
 }
 
 // HandleFunc registers the handler function for the given pattern.
@@ -30,39 +215,236 @@ func (mux *ServeMux) HandleFunc(pattern string, handler func(ResponseWriter, *Re
 }
 
 // ServeHTTP dispatches the request to the handler whose pattern
-// matches the request URL path.
+// matches the request URL path. A panic in the matched handler is
+// recovered and routed through PanicHandler.
+//
+// Patterns are matched against the decoded URL.Path, so an escaped
+// slash ("/users/a%2Fb") matches as "/users/a/b"; the original form
+// remains available from r.URL.EscapedPath. As in net/http, a path
+// containing "." or ".." elements or repeated slashes is answered with
+// a 301 redirect to its cleaned form instead of being routed, so
+// "/static/../admin" can never reach a "/static/" handler. See
+// RedirectTrailingSlash for the optional "/foo" to "/foo/" redirect.
 func (mux *ServeMux) ServeHTTP(w ResponseWriter, r *Request) {
+	defer recoverHandler(w, r)
+
+	path := r.URL.Path
+	if clean := cleanPath(path); clean != path {
+		u := *r.URL
+		u.Path, u.RawPath = clean, ""
+		w.Header().Set("Location", u.RequestURI())
+		w.WriteHeader(StatusMovedPermanently)
+		return
+	}
+
+	if mux.RedirectTrailingSlash && mux.needsSlashRedirect(path) {
+		u := *r.URL
+		u.Path, u.RawPath = path+"/", ""
+		w.Header().Set("Location", u.RequestURI())
+		w.WriteHeader(StatusMovedPermanently)
+		return
+	}
+
+	m := mux.match(r.Method, path)
+	if m.handler != nil {
+		setRoute(r, m.pattern, m.values)
+		var suffix string
+		if m.prefix != "" {
+			suffix = path[len(m.prefix):]
+		}
+		setPathSuffix(r, suffix)
+	}
+	switch {
+	case m.handler != nil && m.viaGet:
+		serveHead(m.handler, w, r)
+	case m.handler != nil:
+		m.handler.ServeHTTP(w, r)
+	case len(m.allow) > 0:
+		w.Header().Set("Allow", strings.Join(m.allow, ", "))
+		renderError(w, r, "Method Not Allowed", StatusMethodNotAllowed)
+	default:
+		if DebugRouting && !ProductionMode {
+			w.Header().Set(RouteMatchedHeader, "false")
+		}
+		renderError(w, r, "404 page not found", StatusNotFound)
+	}
+}
+
+// needsSlashRedirect reports whether path has no pattern of its own but
+// path+"/" is registered.
+func (mux *ServeMux) needsSlashRedirect(path string) bool {
+	if path == "" || path[len(path)-1] == '/' {
+		return false
+	}
 	mux.mu.RLock()
 	defer mux.mu.RUnlock()
+	_, exact := mux.handlers[path]
+	_, slash := mux.handlers[path+"/"]
+	return !exact && slash
+}
 
-	path := r.URL.Path
+// routeMatch is the result of looking up a request in the mux.
+type routeMatch struct {
+	handler Handler
+	pattern string            // registered pattern, including any method
+	values  map[string]string // wildcard values, for PathValue
+	prefix  string            // matched pattern path, for a prefix match
+	viaGet  bool              // GET handler answering HEAD under AutoHead
+	allow   []string          // methods allowed when only the method missed
+}
+
+// match finds the handler for method and path. Exact patterns win over
+// wildcard patterns, which win over trailing-slash prefix patterns.
+// When no handler is found but the path matched patterns for other
+// methods, allow lists those methods, sorted. The read lock is released
+// before the handler runs, so handlers may inspect or modify the mux
+// themselves.
+func (mux *ServeMux) match(method, path string) routeMatch {
+	mux.mu.RLock()
+	defer mux.mu.RUnlock()
+
+	var allow []string
 
 	// Exact match first
-	if h, ok := mux.handlers[path]; ok {
-		h.ServeHTTP(w, r)
-		return
+	if byMethod, ok := mux.handlers[path]; ok && !isWildcardPattern(path) {
+		if m, ok := mux.pick(byMethod, method, path); ok {
+			return m
+		}
+		allow = mux.allowed(byMethod)
+	}
+
+	// Wildcard match: most literal segments wins, ties broken by
+	// pattern path order so the choice is deterministic. best.pattern
+	// may carry a method, so the winning path is tracked separately.
+	var best routeMatch
+	var bestPath string
+	bestLiterals := -1
+	for pattern, byMethod := range mux.handlers {
+		if !isWildcardPattern(pattern) {
+			continue
+		}
+		values, literals, ok := matchWildcard(pattern, path)
+		if !ok || literals < bestLiterals || (literals == bestLiterals && pattern > bestPath) {
+			continue
+		}
+		if m, ok := mux.pick(byMethod, method, pattern); ok {
+			m.values = values
+			best, bestPath, bestLiterals = m, pattern, literals
+		} else if allow == nil {
+			allow = mux.allowed(byMethod)
+		}
+	}
+	if best.handler != nil {
+		return best
 	}
 
 	// Prefix match: trailing-slash patterns, longest match wins
 	var bestPattern string
-	var bestHandler Handler
-	for pattern, handler := range mux.handlers {
-		if len(pattern) > 0 && pattern[len(pattern)-1] == '/' {
+	for pattern, byMethod := range mux.handlers {
+		if len(pattern) > 0 && pattern[len(pattern)-1] == '/' && !isWildcardPattern(pattern) {
 			if len(path) >= len(pattern) && path[:len(pattern)] == pattern {
 				if len(pattern) > len(bestPattern) {
-					bestPattern = pattern
-					bestHandler = handler
+					if m, ok := mux.pick(byMethod, method, pattern); ok {
+						bestPattern = pattern
+						m.prefix = pattern
+						best = m
+					} else if allow == nil {
+						allow = mux.allowed(byMethod)
+					}
 				}
 			}
 		}
 	}
+	if best.handler != nil {
+		return best
+	}
+	return routeMatch{allow: allow}
+}
 
-	if bestHandler != nil {
-		bestHandler.ServeHTTP(w, r)
-		return
+// pick selects the handler for method among the handlers registered
+// for path: an exact method match, then GET for HEAD under AutoHead,
+// then the method-less pattern.
+func (mux *ServeMux) pick(byMethod map[string]Handler, method, path string) (routeMatch, bool) {
+	if h, ok := byMethod[method]; ok && method != "" {
+		return routeMatch{handler: h, pattern: method + " " + path}, true
+	}
+	if mux.AutoHead && method == MethodHead {
+		if h, ok := byMethod[MethodGet]; ok {
+			return routeMatch{handler: h, pattern: MethodGet + " " + path, viaGet: true}, true
+		}
+	}
+	if h, ok := byMethod[""]; ok {
+		return routeMatch{handler: h, pattern: path}, true
+	}
+	return routeMatch{}, false
+}
+
+// allowed returns the sorted methods registered in byMethod, including
+// HEAD when AutoHead derives it from GET.
+func (mux *ServeMux) allowed(byMethod map[string]Handler) []string {
+	var methods []string
+	for m := range byMethod {
+		methods = append(methods, m)
+	}
+	if _, hasHead := byMethod[MethodHead]; !hasHead && mux.AutoHead {
+		if _, hasGet := byMethod[MethodGet]; hasGet {
+			methods = append(methods, MethodHead)
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// serveHead runs a GET handler for a HEAD request, discarding the body
+// but keeping its headers. Content-Length is set to the size of the
+// discarded body unless the handler set it, and a missing Content-Type
+// is sniffed from its first 512 bytes, so the headers match those of
+// the GET response. A JSON body longer than that is not recognized as
+// JSON, since judging it would mean buffering all of it.
+func serveHead(h Handler, w ResponseWriter, r *Request) {
+	hw := &headResponseWriter{ResponseWriter: w, status: StatusOK}
+	h.ServeHTTP(hw, r)
+	if hw.written > 0 && w.Header().Get("Content-Length") == "" {
+		w.Header().Set("Content-Length", strconv.Itoa(hw.written))
+	}
+	sniffContentType(w.Header(), hw.status, hw.head)
+}
+
+// headResponseWriter discards the body written by a GET handler that is
+// answering a HEAD request, counting it and keeping only the leading
+// bytes needed to sniff it.
+type headResponseWriter struct {
+	ResponseWriter
+	head        []byte
+	written     int
+	status      int
+	wroteHeader bool
+}
+
+func (w *headResponseWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = statusCode, true
 	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
 
-	Error(w, "404 page not found", StatusNotFound)
+func (w *headResponseWriter) Write(data []byte) (int, error) {
+	w.wroteHeader = true
+	if room := sniffLen - len(w.head); room > 0 {
+		if room > len(data) {
+			room = len(data)
+		}
+		w.head = append(w.head, data[:room]...)
+	}
+	w.written += len(data)
+	w.ResponseWriter.Write(nil) // commit the implicit 200
+	return len(data), nil
+}
+
+func (w *headResponseWriter) writeInterim(statusCode int, header Header) {
+	if iw, ok := w.ResponseWriter.(interimWriter); ok {
+		iw.writeInterim(statusCode, header)
+	}
 }
 
 // DefaultServeMux is the default ServeMux used by HandleFunc and
@@ -80,8 +462,191 @@ func Handle(pattern string, handler Handler) {
 	DefaultServeMux.Handle(pattern, handler)
 }
 
-// registeredHandler holds the handler set by ListenAndServe/Register.
-var registeredHandler Handler
+// ErrorRenderer writes the error responses the mux generates itself,
+// such as 404 for an unmatched path. The default, TextErrorRenderer,
+// writes plain text via Error. Set it to JSONErrorRenderer, or to a
+// custom function, to give these responses the same shape as the
+// application's own errors.
+var ErrorRenderer = TextErrorRenderer
+
+// TextErrorRenderer renders errors as plain text using Error.
+func TextErrorRenderer(w ResponseWriter, r *Request, message string, code int) {
+	Error(w, message, code)
+}
+
+// JSONErrorRenderer renders errors as {"error":"...","code":N} using
+// ErrorJSON, the same shape JSONError gives handler errors.
+func JSONErrorRenderer(w ResponseWriter, r *Request, message string, code int) {
+	ErrorJSON(w, message, code)
+}
+
+// renderError writes an error response as an HTML page when the
+// client prefers one (see SetErrorPageRenderer), or else through
+// ErrorRenderer.
+func renderError(w ResponseWriter, r *Request, message string, code int) {
+	if writeErrorPage(w, r, message, code) {
+		return
+	}
+	render := ErrorRenderer
+	if render == nil {
+		render = TextErrorRenderer
+	}
+	render(w, r, message, code)
+}
+
+// DefaultMaxHeaderBytes is the default for MaxHeaderBytes (1 MB).
+const DefaultMaxHeaderBytes = 1 << 20
+
+// MaxHeaderBytes caps the total size, in bytes, of the header and
+// trailer names and values in an inbound request. Larger blocks are
+// rejected with 431 Request Header Fields Too Large before the handler
+// runs and before the headers are allocated. Zero or negative means
+// DefaultMaxHeaderBytes.
+var MaxHeaderBytes = DefaultMaxHeaderBytes
+
+// MaxResponseBytes caps the size, in bytes, of a response body. Once a
+// handler's writes would exceed it, Write stores only what fits and
+// returns ErrResponseTooLarge, and the response is replaced by a 500
+// rather than sent truncated. Zero or negative means no limit. The
+// wghttp bridge's equivalent is MaxResponseBodySize.
+var MaxResponseBytes int
+
+// ProductionMode controls how much internal detail error responses
+// expose. When false (the default), the 500 body for a recovered panic
+// includes the panic value, matching the wghttp bridge. When true, the
+// body is a generic message so internal state never leaks to clients.
+var ProductionMode bool
+
+// RouteMatchedHeader is the debug header ServeMux sets on a 404 it
+// produced itself because no pattern matched (see DebugRouting).
+const RouteMatchedHeader = "X-WG-Route-Matched"
+
+// DebugRouting makes ServeMux mark its own 404 responses with
+// "X-WG-Route-Matched: false", so an unmatched route can be told apart
+// from a 404 written by a handler. The header is never sent when
+// ProductionMode is set.
+var DebugRouting bool
+
+// PanicHandler writes the response for a request whose handler panicked.
+// It receives the value passed to panic. The response writer has been
+// reset when possible, so the handler starts from a clean response.
+// Replace it to customise logging or the error body; the default is
+// DefaultPanicHandler.
+var PanicHandler = DefaultPanicHandler
+
+// DefaultPanicHandler replies with 500 Internal Server Error. The body
+// includes the panic value unless ProductionMode is set.
+func DefaultPanicHandler(w ResponseWriter, r *Request, recovered any) {
+	msg := "internal server error"
+	if !ProductionMode {
+		msg = fmt.Sprintf("internal server error: %v", recovered)
+	}
+	if writeErrorPage(w, r, msg, StatusInternalServerError) {
+		return
+	}
+	Error(w, msg, StatusInternalServerError)
+}
+
+// recoverHandler recovers a panic raised while serving r and routes it
+// through PanicHandler. It must be called directly via defer.
+func recoverHandler(w ResponseWriter, r *Request) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	if rw, ok := w.(interface{ reset() }); ok {
+		rw.reset()
+	}
+	handlePanic := PanicHandler
+	if handlePanic == nil {
+		handlePanic = DefaultPanicHandler
+	}
+	handlePanic(w, r, v)
+}
+
+// DefaultTrigger is the name of the HTTP trigger that ListenAndServe
+// registers and HandleRequest dispatches to.
+const DefaultTrigger = "http"
+
+// triggers holds the handlers registered per trigger name. It is
+// guarded by handlerMu so requests may be dispatched while a handler is
+// being registered.
+var (
+	handlerMu sync.RWMutex
+	triggers  = map[string]Handler{}
+)
+
+// RegisterTrigger registers handler for the named trigger, replacing
+// any handler already registered under that name. A module can serve
+// several triggers, for example the HTTP trigger alongside a cron or
+// event trigger; the host selects one by name on each invocation (see
+// HandleTrigger). If handler is nil, DefaultServeMux is used.
+func RegisterTrigger(name string, handler Handler) {
+	setTrigger(name, handler)
+}
+
+// UnregisterTrigger removes the handler registered for name, if any.
+func UnregisterTrigger(name string) {
+	handlerMu.Lock()
+	delete(triggers, name)
+	handlerMu.Unlock()
+}
+
+// triggerHandler returns the handler registered for name, or nil.
+func triggerHandler(name string) Handler {
+	handlerMu.RLock()
+	defer handlerMu.RUnlock()
+	return triggers[name]
+}
+
+// setTrigger registers handler for name, defaulting to DefaultServeMux.
+func setTrigger(name string, handler Handler) Handler {
+	if handler == nil {
+		handler = DefaultServeMux
+	}
+	handlerMu.Lock()
+	triggers[name] = handler
+	handlerMu.Unlock()
+	return handler
+}
+
+// MaxConcurrentRequests limits how many requests HandleRequest and
+// HandleRequestWith run at once. Requests beyond the limit are not
+// admitted: they get 503 Service Unavailable with a Retry-After header,
+// so a burst cannot exhaust the Wasm instance's memory. Zero (the
+// default) means unlimited. Set it before serving begins.
+var MaxConcurrentRequests int
+
+// inFlight counts requests currently admitted by HandleRequestWith.
+var inFlight atomic.Int64
+
+// RetryAfterSeconds is the Retry-After value sent with the 503 response
+// for requests rejected by MaxConcurrentRequests.
+const RetryAfterSeconds = 1
+
+// overloadedResponse is returned for requests over the concurrency limit.
+func overloadedResponse() WitHttpResponse {
+	return WitHttpResponse{
+		Status: StatusServiceUnavailable,
+		Headers: []WitHttpHeader{
+			{Name: "Content-Type", Value: "text/plain; charset=utf-8"},
+			{Name: "Retry-After", Value: strconv.Itoa(RetryAfterSeconds)},
+		},
+		Body: []byte("too many concurrent requests"),
+	}
+}
+
+// errorResponse is a response written by Error, for failures found
+// before there is a Request to hand a renderer.
+func errorResponse(message string, code int) WitHttpResponse {
+	w := newBufferResponseWriter()
+	Error(w, message, code)
+	return WitHttpResponse{
+		Status:  uint16(w.statusCode),
+		Headers: goHeadersToWitHeaders(w.header),
+		Body:    w.body,
+	}
+}
 
 // ListenAndServe registers the handler with the WarpGrid trigger system.
 //
@@ -89,26 +654,20 @@ var registeredHandler Handler
 // The addr parameter is accepted for API compatibility but has no effect;
 // WarpGrid manages the listener configuration externally.
 //
-// If handler is nil, DefaultServeMux is used.
+// If handler is nil, DefaultServeMux is used. The handler is registered
+// as DefaultTrigger; use RegisterTrigger for additional triggers.
 //
 // Returns nil immediately. The WarpGrid runtime invokes the registered
 // handler for each inbound HTTP request via HandleRequest.
 func ListenAndServe(addr string, handler Handler) error {
-	if handler == nil {
-		handler = DefaultServeMux
-	}
-	registeredHandler = handler
+	setTrigger(DefaultTrigger, handler)
 	return nil
 }
 
 // RegisterAndReturn stores the handler and returns it. This is a test
 // helper that allows verifying handler registration without blocking.
 func RegisterAndReturn(handler Handler) Handler {
-	if handler == nil {
-		handler = DefaultServeMux
-	}
-	registeredHandler = handler
-	return registeredHandler
+	return setTrigger(DefaultTrigger, handler)
 }
 
 // HandleRequest processes a serialized WIT HTTP request through the
@@ -116,46 +675,206 @@ func RegisterAndReturn(handler Handler) Handler {
 //
 // This is the entry point called by the WASI export bridge. If no
 // handler has been registered (ListenAndServe not yet called), it
-// returns a 503 Service Unavailable response.
+// returns a 503 Service Unavailable response. It is equivalent to
+// HandleTrigger(DefaultTrigger, reqBytes).
 func HandleRequest(reqBytes []byte) []byte {
-	if registeredHandler == nil {
+	return HandleTrigger(DefaultTrigger, reqBytes)
+}
+
+// HandleTrigger processes a serialized WIT HTTP request through the
+// handler registered for the named trigger. If no handler is registered
+// under that name, it returns a 503 Service Unavailable response.
+func HandleTrigger(name string, reqBytes []byte) []byte {
+	handler := triggerHandler(name)
+	if handler == nil {
+		body := "no handler registered"
+		if name != DefaultTrigger {
+			body = fmt.Sprintf("no handler registered for trigger %q", name)
+		}
 		return MarshalResponse(WitHttpResponse{
 			Status: StatusServiceUnavailable,
 			Headers: []WitHttpHeader{
 				{Name: "Content-Type", Value: "text/plain; charset=utf-8"},
 			},
-			Body: []byte("no handler registered"),
+			Body: []byte(body),
 		})
 	}
-	return HandleRequestWith(registeredHandler, reqBytes)
+	return HandleRequestWith(handler, reqBytes)
 }
 
 // HandleRequestWith processes a serialized WIT HTTP request through
 // the given handler and returns the serialized WIT response.
+//
+// When MaxConcurrentRequests is set and already reached, the request
+// is answered with 503 and Retry-After. Requests whose headers exceed
+// MaxHeaderBytes are answered with 431, and requests with a malformed
+// URI (such as bad percent-encoding) with 400, without invoking the
+// handler.
+//
+// Panics in the handler are recovered and converted to a response by
+// PanicHandler (500 by default), so a failing handler never crashes
+// the Wasm module. A response body over MaxResponseBytes is likewise
+// replaced by a 500.
+//
+// A request sent with "Connection: close" gets "Connection: close" on
+// its response, so the host does not keep the stream open.
+//
+// A response with a body but no Content-Type gets one from
+// DetectContentType; see DisableContentSniffing.
+//
+// Interim responses written with WriteEarlyHints are not sent: the
+// host reads a single response frame, so only the final response is
+// returned.
+//
+// The returned status is always a valid HTTP status code: a handler
+// that leaves it at zero (for example by calling WriteHeader(0)) gets
+// 200, and any other code outside 100-599, the classes HTTP defines,
+// becomes 500. Invalid codes are coerced rather than rejected with a
+// panic as net/http does, so the client still gets the handler's
+// headers along with a status that reports the bug.
+//
+// Interceptors registered with RegisterRequestInterceptor run on the
+// decoded request before it is converted and routed, and those
+// registered with RegisterResponseInterceptor on every final response,
+// including the 503 and 431 above, whichever handler serves the
+// request.
+//
+// Every request counts as activity for LastRequestTime and OnIdle.
 func HandleRequestWith(handler Handler, reqBytes []byte) []byte {
+	defer trackRequest()()
+	resp := serveRequest(handler, reqBytes)
+	interceptResponse(&resp)
+	return MarshalResponse(resp)
+}
+
+// serveRequest implements HandleRequestWith up to serialization.
+func serveRequest(handler Handler, reqBytes []byte) WitHttpResponse {
+	if limit := MaxConcurrentRequests; limit > 0 {
+		if inFlight.Add(1) > int64(limit) {
+			inFlight.Add(-1)
+			return overloadedResponse()
+		}
+		defer inFlight.Add(-1)
+	}
+
+	if limit := maxHeaderBytes(); headerBytes(reqBytes, limit) > limit {
+		return WitHttpResponse{
+			Status: StatusRequestHeaderFieldsTooLarge,
+			Headers: []WitHttpHeader{
+				{Name: "Content-Type", Value: "text/plain; charset=utf-8"},
+			},
+			Body: []byte("request header fields too large"),
+		}
+	}
+
 	witReq := UnmarshalRequest(reqBytes)
-	req := witRequestToGoRequest(witReq)
+	if resp, ok := interceptRequest(&witReq); !ok {
+		return resp
+	}
+	req, err := witRequestToGoRequest(witReq)
+	if err != nil {
+		return errorResponse("400 Bad Request: "+err.Error(), StatusBadRequest)
+	}
 
 	w := newBufferResponseWriter()
-	handler.ServeHTTP(w, req)
+	w.maxBytes = MaxResponseBytes
+	serveRecovered(handler, w, req)
+	if w.overflow {
+		w.reset()
+		w.maxBytes = 0
+		renderError(w, req, "response too large", StatusInternalServerError)
+	}
+	if req.Close {
+		CloseConnection(w)
+	}
 
-	resp := WitHttpResponse{
-		Status:  uint16(w.statusCode),
+	sniffContentType(w.header, w.statusCode, w.body)
+
+	return WitHttpResponse{
+		Status:  normalizeStatus(w.statusCode),
 		Headers: goHeadersToWitHeaders(w.header),
 		Body:    w.body,
 	}
-	return MarshalResponse(resp)
+}
+
+// normalizeStatus maps a handler's status code to one the host
+// accepts: zero (never meaningfully set) becomes 200 and codes outside
+// 100-599 become 500.
+func normalizeStatus(code int) uint16 {
+	switch {
+	case code == 0:
+		return StatusOK
+	case code < 100 || code > 599:
+		return StatusInternalServerError
+	}
+	return uint16(code)
+}
+
+// maxHeaderBytes returns the effective MaxHeaderBytes limit.
+func maxHeaderBytes() int {
+	if MaxHeaderBytes <= 0 {
+		return DefaultMaxHeaderBytes
+	}
+	return MaxHeaderBytes
+}
+
+// serveRecovered invokes handler, recovering any panic into w.
+func serveRecovered(handler Handler, w ResponseWriter, r *Request) {
+	defer recoverHandler(w, r)
+	handler.ServeHTTP(w, r)
 }
 
 // witRequestToGoRequest converts a WIT HTTP request to a Go Request.
-func witRequestToGoRequest(wit WitHttpRequest) *Request {
+// It fails if the URI is not a valid request URI, for example when it
+// contains malformed percent-encoding such as "%zz".
+func witRequestToGoRequest(wit WitHttpRequest) (*Request, error) {
+	if _, err := url.ParseRequestURI(wit.URI); err != nil {
+		return nil, fmt.Errorf("malformed request URI %q", wit.URI)
+	}
 	req := NewRequest(wit.Method, wit.URI, wit.Body)
 	for _, h := range wit.Headers {
 		req.Header.Add(h.Name, h.Value)
 	}
-	return req
+	if host := req.Header.Get("Host"); host != "" {
+		req.Host = host
+	}
+	req.Close = hasToken(req.Header, "Connection", "close")
+	if len(wit.Trailers) > 0 {
+		req.Trailer = make(Header, len(wit.Trailers))
+		for _, t := range wit.Trailers {
+			req.Trailer[CanonicalHeaderKey(t.Name)] = nil
+		}
+		req.Body = &trailerBody{ReadCloser: req.Body, trailer: req.Trailer, trailers: wit.Trailers}
+	}
+	return req, nil
+}
+
+// trailerBody is a request body that fills in the request's trailer
+// values once it has been read to EOF.
+type trailerBody struct {
+	io.ReadCloser
+	trailer  Header
+	trailers []WitHttpHeader
+	done     bool
 }
 
+func (b *trailerBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF && !b.done {
+		b.done = true
+		for _, t := range b.trailers {
+			b.trailer.Add(t.Name, t.Value)
+		}
+	}
+	return n, err
+}
+
+// DeterministicHeaders emits response headers sorted by name, keeping
+// the order of each header's values, instead of in Go map order, so
+// tests and caches see byte-stable responses. It is off by default to
+// save the sort on every response.
+var DeterministicHeaders bool
+
 // goHeadersToWitHeaders converts Go Header map to WIT header list.
 func goHeadersToWitHeaders(h Header) []WitHttpHeader {
 	var headers []WitHttpHeader
@@ -164,5 +883,8 @@ func goHeadersToWitHeaders(h Header) []WitHttpHeader {
 			headers = append(headers, WitHttpHeader{Name: name, Value: value})
 		}
 	}
+	if DeterministicHeaders {
+		sort.SliceStable(headers, func(i, j int) bool { return headers[i].Name < headers[j].Name })
+	}
 	return headers
 }
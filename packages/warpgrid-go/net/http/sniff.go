@@ -0,0 +1,112 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// sniffLen is how many leading bytes DetectContentType examines, as in
+// net/http.
+const sniffLen = 512
+
+// DisableContentSniffing stops the server from filling in a missing
+// Content-Type. By default, as in net/http, a response with a body but
+// no Content-Type header gets one from DetectContentType, unless it has
+// a Content-Encoding. A handler can opt a single response out by
+// setting the header to nil: w.Header()["Content-Type"] = nil.
+var DisableContentSniffing bool
+
+// DetectContentType returns the MIME type of data, a subset of the
+// WHATWG sniffing algorithm net/http.DetectContentType implements,
+// small enough for the overlay: HTML and XML documents, PDF, the common
+// image formats, gzip, zip, and WebAssembly are recognized by their
+// leading bytes. A valid JSON object or array, judged on the whole of
+// data, is "application/json". Anything else is plain text when it
+// contains no binary control bytes and "application/octet-stream"
+// otherwise. Only the first 512 bytes are examined, except for JSON.
+func DetectContentType(data []byte) string {
+	head := data
+	if len(head) > sniffLen {
+		head = head[:sniffLen]
+	}
+	for _, sig := range exactSignatures {
+		if bytes.HasPrefix(head, sig.prefix) {
+			return sig.contentType
+		}
+	}
+	if len(head) >= 16 && bytes.Equal(head[:4], []byte("RIFF")) && bytes.Equal(head[8:14], []byte("WEBPVP")) {
+		return "image/webp"
+	}
+
+	text := bytes.TrimLeft(head, "\t\n\x0c\r ")
+	if isHTML(text) {
+		return "text/html; charset=utf-8"
+	}
+	if bytes.HasPrefix(text, []byte("<?xml")) {
+		return "text/xml; charset=utf-8"
+	}
+	if len(text) > 0 && (text[0] == '{' || text[0] == '[') && json.Valid(data) {
+		return "application/json"
+	}
+	for _, b := range head {
+		if isBinaryByte(b) {
+			return "application/octet-stream"
+		}
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// exactSignatures are the formats identified by a fixed prefix.
+var exactSignatures = []struct {
+	prefix      []byte
+	contentType string
+}{
+	{[]byte("%PDF-"), "application/pdf"},
+	{[]byte("\x89PNG\r\n\x1a\n"), "image/png"},
+	{[]byte("GIF87a"), "image/gif"},
+	{[]byte("GIF89a"), "image/gif"},
+	{[]byte("\xff\xd8\xff"), "image/jpeg"},
+	{[]byte("\x1f\x8b\x08"), "application/x-gzip"},
+	{[]byte("PK\x03\x04"), "application/zip"},
+	{[]byte("\x00asm"), "application/wasm"},
+}
+
+// htmlTags are the tags that mark a document as HTML when it starts
+// with one, compared case-insensitively.
+var htmlTags = []string{
+	"<!DOCTYPE HTML", "<HTML", "<HEAD", "<SCRIPT", "<IFRAME", "<H1", "<DIV",
+	"<FONT", "<TABLE", "<A", "<STYLE", "<TITLE", "<B", "<BODY", "<BR", "<P", "<!--",
+}
+
+// isHTML reports whether text starts with one of htmlTags followed by a
+// space or '>', the tag-terminating bytes of the WHATWG algorithm.
+func isHTML(text []byte) bool {
+	for _, tag := range htmlTags {
+		if len(text) <= len(tag) || !bytes.EqualFold(text[:len(tag)], []byte(tag)) {
+			continue
+		}
+		if c := text[len(tag)]; c == ' ' || c == '>' {
+			return true
+		}
+	}
+	return false
+}
+
+// isBinaryByte reports whether b never appears in text, per the WHATWG
+// binary data byte set.
+func isBinaryByte(b byte) bool {
+	return b <= 0x08 || b == 0x0b || (b >= 0x0e && b <= 0x1a) || (b >= 0x1c && b <= 0x1f)
+}
+
+// sniffContentType sets Content-Type on h from body when the response
+// has a body and the handler set neither Content-Type nor
+// Content-Encoding, unless DisableContentSniffing is set.
+func sniffContentType(h Header, status int, body []byte) {
+	if DisableContentSniffing || len(body) == 0 || !bodyAllowed(status) {
+		return
+	}
+	if _, ok := h["Content-Type"]; ok || h.Get("Content-Encoding") != "" {
+		return
+	}
+	h.Set("Content-Type", DetectContentType(body))
+}
@@ -0,0 +1,94 @@
+package http_test
+
+import (
+	"testing"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/net/http"
+)
+
+// ── DetectContentType tests ─────────────────────────────────────────
+
+func TestDetectContentType_CommonSignatures(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"html", "<!DOCTYPE html><html><body>hi</body></html>", "text/html; charset=utf-8"},
+		{"html after whitespace", "\n  <p>hello</p>", "text/html; charset=utf-8"},
+		{"xml", "<?xml version=\"1.0\"?><a/>", "text/xml; charset=utf-8"},
+		{"json object", `{"name":"warpgrid"}`, "application/json"},
+		{"json array", ` [1, 2, 3]`, "application/json"},
+		{"bracketed text", "[INFO] started", "text/plain; charset=utf-8"},
+		{"png", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", "image/png"},
+		{"gif", "GIF89a...", "image/gif"},
+		{"jpeg", "\xff\xd8\xff\xe0\x00\x10JFIF", "image/jpeg"},
+		{"pdf", "%PDF-1.7", "application/pdf"},
+		{"wasm", "\x00asm\x01\x00\x00\x00", "application/wasm"},
+		{"plain text", "hello, world", "text/plain; charset=utf-8"},
+		{"empty", "", "text/plain; charset=utf-8"},
+		{"binary", "\x01\x02\x03\x04", "application/octet-stream"},
+	}
+	for _, c := range cases {
+		if got := wghttp.DetectContentType([]byte(c.data)); got != c.want {
+			t.Fatalf("%s: expected '%s', got '%s'", c.name, c.want, got)
+		}
+	}
+}
+
+// ── Content-Type sniffing tests ─────────────────────────────────────
+
+// serveBody runs a handler writing body, after letting setup adjust
+// the headers, and returns the response's Content-Type values.
+func serveBody(setup func(wghttp.Header), body string) []string {
+	mux := wghttp.NewServeMux()
+	mux.HandleFunc("/", func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		setup(w.Header())
+		w.Write([]byte(body))
+	})
+	resp := wghttp.UnmarshalResponse(wghttp.HandleRequestWith(mux, wghttp.MarshalRequest(wghttp.WitHttpRequest{
+		Method: "GET",
+		URI:    "/",
+	})))
+	var values []string
+	for _, h := range resp.Headers {
+		if h.Name == "Content-Type" {
+			values = append(values, h.Value)
+		}
+	}
+	return values
+}
+
+func TestHandleRequest_SniffsMissingContentType(t *testing.T) {
+	noop := func(wghttp.Header) {}
+	if got := serveBody(noop, "<html><body>hi</body></html>"); len(got) != 1 || got[0] != "text/html; charset=utf-8" {
+		t.Fatalf("expected sniffed HTML, got %v", got)
+	}
+	if got := serveBody(noop, "\x89PNG\r\n\x1a\n\x00\x00"); len(got) != 1 || got[0] != "image/png" {
+		t.Fatalf("expected sniffed PNG, got %v", got)
+	}
+}
+
+func TestHandleRequest_ExplicitContentTypeNeverOverridden(t *testing.T) {
+	got := serveBody(func(h wghttp.Header) { h.Set("Content-Type", "text/csv") }, "<html>not really</html>")
+	if len(got) != 1 || got[0] != "text/csv" {
+		t.Fatalf("expected the handler's text/csv, got %v", got)
+	}
+	got = serveBody(func(h wghttp.Header) { h["Content-Type"] = nil }, "<html></html>")
+	if len(got) != 0 {
+		t.Fatalf("expected a nil Content-Type to suppress sniffing, got %v", got)
+	}
+	got = serveBody(func(h wghttp.Header) { h.Set("Content-Encoding", "gzip") }, "\x1f\x8b\x08\x00")
+	if len(got) != 0 {
+		t.Fatalf("expected no sniffing for an encoded body, got %v", got)
+	}
+}
+
+func TestHandleRequest_SniffingDisabled(t *testing.T) {
+	defer wghttp.Configure(wghttp.CurrentConfig())
+	wghttp.DisableContentSniffing = true
+
+	if got := serveBody(func(wghttp.Header) {}, "<html></html>"); len(got) != 0 {
+		t.Fatalf("expected no Content-Type with sniffing disabled, got %v", got)
+	}
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Clock returns the current time for ServerTiming, for the dates
+// SetDeprecation and AddWarning write, and for idle tracking (see
+// OnIdle); nil means time.Now. Tests set it to a fake clock to get
+// predictable durations and dates.
+var Clock func() time.Time
+
+// now reads Clock, falling back to time.Now.
+func now() time.Time {
+	if Clock != nil {
+		return Clock()
+	}
+	return time.Now()
+}
+
+// ServerTiming returns middleware that measures how long the wrapped
+// handler takes and reports it in a Server-Timing response header as
+// "name;dur=<ms>", so browser dev tools and tracing proxies can show
+// it. Because responses are buffered, the header is added after the
+// handler returns and still reaches the client.
+//
+// The entry is added rather than set, so nesting several ServerTiming
+// middlewares under different names reports each of them. ServerTiming
+// panics if name is not a valid token, which the header syntax requires.
+func ServerTiming(name string) Middleware {
+	if name == "" {
+		panic("http: empty ServerTiming metric name")
+	}
+	for i := 0; i < len(name); i++ {
+		if !isTokenByte(name[i]) {
+			panic(fmt.Sprintf("http: invalid ServerTiming metric name %q", name))
+		}
+	}
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w ResponseWriter, r *Request) {
+			start := now()
+			next.ServeHTTP(w, r)
+			w.Header().Add("Server-Timing", name+";dur="+formatMillis(now().Sub(start)))
+		})
+	}
+}
+
+// formatMillis formats d in milliseconds with up to microsecond
+// precision, as Server-Timing durations are written.
+func formatMillis(d time.Duration) string {
+	return strconv.FormatFloat(float64(d.Microseconds())/1000, 'f', -1, 64)
+}
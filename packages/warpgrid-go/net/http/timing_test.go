@@ -0,0 +1,78 @@
+package http_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/net/http"
+)
+
+// ── ServerTiming tests ──────────────────────────────────────────────
+
+// fakeClock returns a Clock that advances by step on every reading.
+func fakeClock(step time.Duration) func() time.Time {
+	t := time.Unix(0, 0)
+	return func() time.Time {
+		t = t.Add(step)
+		return t
+	}
+}
+
+func TestServerTiming_AddsDurationHeader(t *testing.T) {
+	handler := wghttp.ServerTiming("app")(wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		time.Sleep(2 * time.Millisecond)
+		w.Write([]byte("ok"))
+	}))
+	w := wghttp.NewTestResponseWriter()
+	handler.ServeHTTP(w, wghttp.NewRequest(wghttp.MethodGet, "/", nil))
+
+	got := w.Header().Get("Server-Timing")
+	dur, ok := strings.CutPrefix(got, "app;dur=")
+	if !ok {
+		t.Fatalf("expected Server-Timing 'app;dur=<ms>', got '%s'", got)
+	}
+	ms, err := strconv.ParseFloat(dur, 64)
+	if err != nil || ms < 2 || ms > 10000 {
+		t.Fatalf("expected a plausible duration of at least 2ms, got '%s'", dur)
+	}
+}
+
+func TestServerTiming_UsesClock(t *testing.T) {
+	defer wghttp.Configure(wghttp.CurrentConfig())
+	wghttp.Clock = fakeClock(1500 * time.Microsecond)
+
+	handler := wghttp.ServerTiming("db")(wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {}))
+	w := wghttp.NewTestResponseWriter()
+	handler.ServeHTTP(w, wghttp.NewRequest(wghttp.MethodGet, "/", nil))
+
+	if got := w.Header().Get("Server-Timing"); got != "db;dur=1.5" {
+		t.Fatalf("expected 'db;dur=1.5', got '%s'", got)
+	}
+}
+
+func TestServerTiming_NestedMiddlewaresAppend(t *testing.T) {
+	defer wghttp.Configure(wghttp.CurrentConfig())
+	wghttp.Clock = fakeClock(time.Millisecond)
+
+	var handler wghttp.Handler = wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {})
+	handler = wghttp.ServerTiming("inner")(handler)
+	handler = wghttp.ServerTiming("outer")(handler)
+	w := wghttp.NewTestResponseWriter()
+	handler.ServeHTTP(w, wghttp.NewRequest(wghttp.MethodGet, "/", nil))
+
+	got := w.Header().Values("Server-Timing")
+	if len(got) != 2 || got[0] != "inner;dur=1" || got[1] != "outer;dur=3" {
+		t.Fatalf("expected [inner;dur=1 outer;dur=3], got %v", got)
+	}
+}
+
+func TestServerTiming_InvalidNamePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for a name that is not a token")
+		}
+	}()
+	wghttp.ServerTiming("bad name")
+}
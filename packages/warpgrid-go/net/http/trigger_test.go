@@ -0,0 +1,62 @@
+package http_test
+
+import (
+	"testing"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/net/http"
+)
+
+// ── Trigger registration tests ──────────────────────────────────────
+
+func TestHandleTrigger_DispatchesByName(t *testing.T) {
+	wghttp.RegisterTrigger("web", wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		w.Write([]byte("web"))
+	}))
+	wghttp.RegisterTrigger("cron", wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		w.Write([]byte("cron:" + r.URL.Path))
+	}))
+	defer wghttp.UnregisterTrigger("web")
+	defer wghttp.UnregisterTrigger("cron")
+
+	reqBytes := wghttp.MarshalRequest(wghttp.WitHttpRequest{Method: "POST", URI: "/nightly"})
+
+	web := wghttp.UnmarshalResponse(wghttp.HandleTrigger("web", reqBytes))
+	if string(web.Body) != "web" {
+		t.Fatalf("expected web trigger, got '%s'", web.Body)
+	}
+	cron := wghttp.UnmarshalResponse(wghttp.HandleTrigger("cron", reqBytes))
+	if string(cron.Body) != "cron:/nightly" {
+		t.Fatalf("expected cron trigger, got '%s'", cron.Body)
+	}
+}
+
+func TestHandleTrigger_UnknownNameReturns503(t *testing.T) {
+	reqBytes := wghttp.MarshalRequest(wghttp.WitHttpRequest{Method: "GET", URI: "/"})
+
+	resp := wghttp.UnmarshalResponse(wghttp.HandleTrigger("queue", reqBytes))
+	if resp.Status != wghttp.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d", resp.Status)
+	}
+	if string(resp.Body) != `no handler registered for trigger "queue"` {
+		t.Fatalf("unexpected body: %s", resp.Body)
+	}
+}
+
+func TestListenAndServe_RegistersDefaultTrigger(t *testing.T) {
+	wghttp.ListenAndServe(":8080", wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		w.Write([]byte("default"))
+	}))
+	defer wghttp.UnregisterTrigger(wghttp.DefaultTrigger)
+	wghttp.RegisterTrigger("events", wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		w.Write([]byte("events"))
+	}))
+	defer wghttp.UnregisterTrigger("events")
+
+	reqBytes := wghttp.MarshalRequest(wghttp.WitHttpRequest{Method: "GET", URI: "/"})
+	viaRequest := wghttp.UnmarshalResponse(wghttp.HandleRequest(reqBytes))
+	viaTrigger := wghttp.UnmarshalResponse(wghttp.HandleTrigger(wghttp.DefaultTrigger, reqBytes))
+	if string(viaRequest.Body) != "default" || string(viaTrigger.Body) != "default" {
+		t.Fatalf("expected default trigger from both entry points, got '%s' and '%s'",
+			viaRequest.Body, viaTrigger.Body)
+	}
+}
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Hijacker is implemented by response writers that can hand the
+// underlying connection to the handler, matching net/http.Hijacker
+// except that the host, not the guest, owns any buffering. After
+// Hijack the writer must not be used again; the returned conn carries
+// the raw byte stream in both directions.
+//
+// The buffered writer HandleRequestWith uses does not implement it:
+// hijacking needs a host that exposes a duplex stream to the guest.
+type Hijacker interface {
+	Hijack() (net.Conn, error)
+}
+
+var (
+	// ErrBadHandshake is returned by Upgrade when the request is not a
+	// valid WebSocket opening handshake.
+	ErrBadHandshake = errors.New("http: invalid websocket handshake")
+
+	// ErrNotHijacker is returned by Upgrade when the response writer
+	// cannot hand over the connection.
+	ErrNotHijacker = errors.New("http: response writer does not support hijacking")
+)
+
+// websocketGUID is the fixed suffix hashed with Sec-WebSocket-Key
+// (RFC 6455 §1.3).
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Upgrade completes a WebSocket opening handshake (RFC 6455 §4.2) and
+// returns the connection for the handler to speak the WebSocket
+// protocol over.
+//
+// The request must be a GET with "Connection: Upgrade", "Upgrade:
+// websocket", "Sec-WebSocket-Version: 13", and a Sec-WebSocket-Key
+// that base64-decodes to 16 bytes. Otherwise Upgrade replies with 400
+// (or 426 listing the supported version) and returns an error wrapping
+// ErrBadHandshake. If w does not implement Hijacker it replies with 500
+// and returns ErrNotHijacker. On success it writes the 101 Switching
+// Protocols response with the computed Sec-WebSocket-Accept, and any
+// headers the handler already set on w, then hijacks the connection.
+func Upgrade(w ResponseWriter, r *Request) (net.Conn, error) {
+	if r.Method != MethodGet {
+		return nil, rejectHandshake(w, "method must be GET", StatusMethodNotAllowed)
+	}
+	if !hasToken(r.Header, "Connection", "upgrade") {
+		return nil, rejectHandshake(w, "missing Connection: upgrade", StatusBadRequest)
+	}
+	if !hasToken(r.Header, "Upgrade", "websocket") {
+		return nil, rejectHandshake(w, "missing Upgrade: websocket", StatusBadRequest)
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, rejectHandshake(w, "unsupported Sec-WebSocket-Version", StatusUpgradeRequired)
+	}
+	key := strings.TrimSpace(r.Header.Get("Sec-WebSocket-Key"))
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, rejectHandshake(w, "invalid Sec-WebSocket-Key", StatusBadRequest)
+	}
+
+	hj, ok := w.(Hijacker)
+	if !ok {
+		Error(w, "websocket upgrade not supported", StatusInternalServerError)
+		return nil, ErrNotHijacker
+	}
+
+	h := w.Header()
+	h.Set("Upgrade", "websocket")
+	h.Set("Connection", "Upgrade")
+	h.Set("Sec-WebSocket-Accept", websocketAccept(key))
+	w.WriteHeader(StatusSwitchingProtocols)
+	return hj.Hijack()
+}
+
+// rejectHandshake replies with code and returns an error describing
+// why the handshake was refused.
+func rejectHandshake(w ResponseWriter, reason string, code int) error {
+	Error(w, "websocket: "+reason, code)
+	return fmt.Errorf("%w: %s", ErrBadHandshake, reason)
+}
+
+// websocketAccept computes the Sec-WebSocket-Accept value for key.
+func websocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,122 @@
+package http_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/net/http"
+)
+
+// ── WebSocket upgrade tests ─────────────────────────────────────────
+
+// hijackableWriter is a test response writer backed by a net.Pipe.
+type hijackableWriter struct {
+	capturedResponse
+	conn     net.Conn
+	hijacked bool
+}
+
+func (w *hijackableWriter) Hijack() (net.Conn, error) {
+	w.hijacked = true
+	return w.conn, nil
+}
+
+func newHijackableWriter(t *testing.T) *hijackableWriter {
+	t.Helper()
+	guest, host := net.Pipe()
+	t.Cleanup(func() { guest.Close(); host.Close() })
+	return &hijackableWriter{capturedResponse: wghttp.NewTestResponseWriter(), conn: guest}
+}
+
+// handshakeRequest returns a valid RFC 6455 opening handshake using
+// the specification's sample nonce.
+func handshakeRequest() *wghttp.Request {
+	req := wghttp.NewRequest(wghttp.MethodGet, "/chat", nil)
+	req.Header.Set("Connection", "keep-alive, Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	return req
+}
+
+func TestUpgrade_ComputesAcceptKey(t *testing.T) {
+	w := newHijackableWriter(t)
+
+	conn, err := wghttp.Upgrade(w, handshakeRequest())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if conn != w.conn || !w.hijacked {
+		t.Fatal("expected the hijacked connection to be returned")
+	}
+	if w.StatusCode() != wghttp.StatusSwitchingProtocols {
+		t.Fatalf("expected status 101, got %d", w.StatusCode())
+	}
+	// RFC 6455 §1.3 example.
+	if got := w.Header().Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("expected accept key s3pPLMBiTxaQ9kYGzzhZRbK+xOo=, got '%s'", got)
+	}
+	if w.Header().Get("Upgrade") != "websocket" || w.Header().Get("Connection") != "Upgrade" {
+		t.Fatalf("expected upgrade headers, got %v", w.Header())
+	}
+}
+
+func TestUpgrade_ValidatesHandshakeHeaders(t *testing.T) {
+	cases := []struct {
+		name   string
+		mutate func(r *wghttp.Request)
+		status int
+	}{
+		{"post", func(r *wghttp.Request) { r.Method = wghttp.MethodPost }, wghttp.StatusMethodNotAllowed},
+		{"no connection upgrade", func(r *wghttp.Request) { r.Header.Set("Connection", "keep-alive") }, wghttp.StatusBadRequest},
+		{"wrong upgrade", func(r *wghttp.Request) { r.Header.Set("Upgrade", "h2c") }, wghttp.StatusBadRequest},
+		{"old version", func(r *wghttp.Request) { r.Header.Set("Sec-WebSocket-Version", "8") }, wghttp.StatusUpgradeRequired},
+		{"missing key", func(r *wghttp.Request) { r.Header.Del("Sec-WebSocket-Key") }, wghttp.StatusBadRequest},
+		{"short key", func(r *wghttp.Request) { r.Header.Set("Sec-WebSocket-Key", "c2hvcnQ=") }, wghttp.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := newHijackableWriter(t)
+			req := handshakeRequest()
+			tc.mutate(req)
+
+			conn, err := wghttp.Upgrade(w, req)
+
+			if !errors.Is(err, wghttp.ErrBadHandshake) || conn != nil {
+				t.Fatalf("expected ErrBadHandshake, got conn=%v err=%v", conn, err)
+			}
+			if w.hijacked {
+				t.Fatal("expected no hijack on a bad handshake")
+			}
+			if w.StatusCode() != tc.status {
+				t.Fatalf("expected status %d, got %d", tc.status, w.StatusCode())
+			}
+		})
+	}
+}
+
+func TestUpgrade_AdvertisesSupportedVersion(t *testing.T) {
+	w := newHijackableWriter(t)
+	req := handshakeRequest()
+	req.Header.Set("Sec-WebSocket-Version", "8")
+
+	wghttp.Upgrade(w, req)
+
+	if got := w.Header().Get("Sec-WebSocket-Version"); got != "13" {
+		t.Fatalf("expected Sec-WebSocket-Version: 13 on 426, got '%s'", got)
+	}
+}
+
+func TestUpgrade_BufferedWriterNotHijackable(t *testing.T) {
+	w := wghttp.NewTestResponseWriter()
+
+	_, err := wghttp.Upgrade(w, handshakeRequest())
+
+	if !errors.Is(err, wghttp.ErrNotHijacker) {
+		t.Fatalf("expected ErrNotHijacker, got %v", err)
+	}
+	if w.StatusCode() != wghttp.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", w.StatusCode())
+	}
+}
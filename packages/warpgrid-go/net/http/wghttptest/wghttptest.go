@@ -0,0 +1,62 @@
+// Package wghttptest provides utilities for testing handlers written
+// against the WarpGrid net/http overlay.
+//
+// Do drives a request through the same path the WarpGrid host uses:
+// the request is marshaled to the WIT wire format, dispatched through
+// HandleRequestWith (including header conversion and panic recovery),
+// and the serialized response is decoded back. Tests therefore see
+// exactly what the host would receive, without touching the wire
+// helpers directly.
+package wghttptest
+
+import (
+	"sort"
+
+	"github.com/anthropics/warpgrid/packages/warpgrid-go/net/http"
+)
+
+// Do sends a request with the given method, URI, headers, and body to
+// handler via the WIT round-trip and returns the decoded response.
+// headers may be nil.
+func Do(handler http.Handler, method, uri string, headers http.Header, body []byte) http.WitHttpResponse {
+	req := http.WitHttpRequest{
+		Method:  method,
+		URI:     uri,
+		Headers: witHeaders(headers),
+		Body:    body,
+	}
+	respBytes := http.HandleRequestWith(handler, http.MarshalRequest(req))
+	return http.UnmarshalResponse(respBytes)
+}
+
+// Get is shorthand for Do(handler, "GET", uri, nil, nil).
+func Get(handler http.Handler, uri string) http.WitHttpResponse {
+	return Do(handler, http.MethodGet, uri, nil, nil)
+}
+
+// ResponseHeader collects the headers of resp into a Header so tests
+// can look them up case-insensitively with Get and Values.
+func ResponseHeader(resp http.WitHttpResponse) http.Header {
+	h := make(http.Header)
+	for _, wh := range resp.Headers {
+		h.Add(wh.Name, wh.Value)
+	}
+	return h
+}
+
+// witHeaders flattens h into WIT header pairs in a stable order.
+func witHeaders(h http.Header) []http.WitHttpHeader {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var headers []http.WitHttpHeader
+	for _, name := range names {
+		for _, value := range h[name] {
+			headers = append(headers, http.WitHttpHeader{Name: name, Value: value})
+		}
+	}
+	return headers
+}
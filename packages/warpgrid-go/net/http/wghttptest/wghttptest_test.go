@@ -0,0 +1,55 @@
+package wghttptest_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	wghttp "github.com/anthropics/warpgrid/packages/warpgrid-go/net/http"
+	"github.com/anthropics/warpgrid/packages/warpgrid-go/net/http/wghttptest"
+)
+
+// ── Do tests ────────────────────────────────────────────────────────
+
+func TestDo_NormalResponse(t *testing.T) {
+	handler := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Echo-Method", r.Method)
+		w.Header().Set("X-Echo-Token", r.Header.Get("x-token"))
+		w.WriteHeader(wghttp.StatusCreated)
+		w.Write([]byte("got " + string(body)))
+	})
+
+	headers := wghttp.Header{}
+	headers.Set("X-Token", "abc")
+	resp := wghttptest.Do(handler, wghttp.MethodPost, "/items?id=7", headers, []byte("payload"))
+
+	if resp.Status != wghttp.StatusCreated {
+		t.Fatalf("expected status 201, got %d", resp.Status)
+	}
+	if string(resp.Body) != "got payload" {
+		t.Fatalf("expected body 'got payload', got '%s'", resp.Body)
+	}
+	h := wghttptest.ResponseHeader(resp)
+	if h.Get("x-echo-method") != "POST" {
+		t.Fatalf("expected X-Echo-Method 'POST', got '%s'", h.Get("X-Echo-Method"))
+	}
+	if h.Get("X-Echo-Token") != "abc" {
+		t.Fatalf("expected X-Echo-Token 'abc', got '%s'", h.Get("X-Echo-Token"))
+	}
+}
+
+func TestDo_PanickingHandlerReturns500(t *testing.T) {
+	handler := wghttp.HandlerFunc(func(w wghttp.ResponseWriter, r *wghttp.Request) {
+		panic("boom")
+	})
+
+	resp := wghttptest.Get(handler, "/explode")
+
+	if resp.Status != wghttp.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", resp.Status)
+	}
+	if !strings.Contains(string(resp.Body), "boom") {
+		t.Fatalf("expected panic value in body, got '%s'", resp.Body)
+	}
+}
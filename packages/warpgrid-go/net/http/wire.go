@@ -1,6 +1,16 @@
 package http
 
-import "encoding/binary"
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrHeaderTooLarge is returned by DecodeRequest when the request's
+// header names and values together exceed MaxHeaderBytes.
+var ErrHeaderTooLarge = errors.New("http: request header fields too large")
 
 // WIT type equivalents matching crates/warpgrid-host/wit/http-types.wit.
 
@@ -11,11 +21,15 @@ type WitHttpHeader struct {
 }
 
 // WitHttpRequest mirrors the WIT http-request record.
+//
+// Trailers holds the trailer fields the client sent after a chunked
+// body; Request.Trailer exposes them once the body has been read.
 type WitHttpRequest struct {
-	Method  string
-	URI     string
-	Headers []WitHttpHeader
-	Body    []byte
+	Method   string
+	URI      string
+	Headers  []WitHttpHeader
+	Body     []byte
+	Trailers []WitHttpHeader
 }
 
 // WitHttpResponse mirrors the WIT http-response record.
@@ -33,6 +47,15 @@ type WitHttpResponse struct {
 //   u32: header_count
 //     for each: u32: name_len, bytes: name, u32: value_len, bytes: value
 //   u32: body_len,   bytes: body
+//   optional trailer block:
+//     u8:  version (1)
+//     u32: trailer_count
+//       for each: u32: name_len, bytes: name, u32: value_len, bytes: value
+//
+// The trailer block is present only when the request has trailers, so
+// frames without them are unchanged. The version byte leaves room for
+// later extensions after the body; decoders reject versions they do not
+// know.
 //
 // Response format (little-endian):
 //   u16: status
@@ -46,6 +69,12 @@ func MarshalRequest(req WitHttpRequest) []byte {
 	for _, h := range req.Headers {
 		size += 4 + len(h.Name) + 4 + len(h.Value)
 	}
+	if len(req.Trailers) > 0 {
+		size += 1 + 4
+		for _, t := range req.Trailers {
+			size += 4 + len(t.Name) + 4 + len(t.Value)
+		}
+	}
 
 	buf := make([]byte, 0, size)
 	buf = appendString(buf, req.Method)
@@ -56,11 +85,35 @@ func MarshalRequest(req WitHttpRequest) []byte {
 		buf = appendString(buf, h.Value)
 	}
 	buf = appendBytes(buf, req.Body)
+	if len(req.Trailers) > 0 {
+		buf = append(buf, requestTrailerVersion)
+		buf = appendU32(buf, uint32(len(req.Trailers)))
+		for _, t := range req.Trailers {
+			buf = appendString(buf, t.Name)
+			buf = appendString(buf, t.Value)
+		}
+	}
 	return buf
 }
 
+// requestTrailerVersion is the version byte that introduces a request
+// frame's trailer block.
+const requestTrailerVersion = 1
+
+// smallRequestLimit is the frame size up to which UnmarshalRequest
+// takes its fast path.
+const smallRequestLimit = 4 << 10
+
 // UnmarshalRequest deserializes a WitHttpRequest from the wire format.
+//
+// Frames up to 4KB, the common case, take a fast path: the method, URI,
+// and every header name and value are sliced from one string holding a
+// copy of the frame's head, instead of being allocated one by one.
+// The result is identical; the strings merely share storage.
 func UnmarshalRequest(data []byte) WitHttpRequest {
+	if len(data) <= smallRequestLimit {
+		return unmarshalSmallRequest(data)
+	}
 	offset := 0
 	var req WitHttpRequest
 
@@ -69,6 +122,9 @@ func UnmarshalRequest(data []byte) WitHttpRequest {
 
 	headerCount, off := readU32(data, offset)
 	offset = off
+	if !fitsEntries(data, offset, headerCount) {
+		panic("http: request header count exceeds frame")
+	}
 	req.Headers = make([]WitHttpHeader, headerCount)
 	for i := uint32(0); i < headerCount; i++ {
 		req.Headers[i].Name, offset = readString(data, offset)
@@ -76,9 +132,79 @@ func UnmarshalRequest(data []byte) WitHttpRequest {
 	}
 
 	req.Body, offset = readBytes(data, offset)
+	req.Trailers = readTrailers(data, offset)
+	return req
+}
+
+// readTrailers reads the optional trailer block at offset, the end of a
+// request frame's body. A frame that ends at offset, or whose block has
+// an unknown version or is too short for the count it declares, has no
+// trailers.
+func readTrailers(data []byte, offset int) []WitHttpHeader {
+	if offset+5 > len(data) || data[offset] != requestTrailerVersion {
+		return nil
+	}
+	count, offset := readU32(data, offset+1)
+	if !fitsEntries(data, offset, count) {
+		return nil
+	}
+	trailers := make([]WitHttpHeader, count)
+	for i := range trailers {
+		trailers[i].Name, offset = readString(data, offset)
+		trailers[i].Value, offset = readString(data, offset)
+	}
+	return trailers
+}
+
+// fitsEntries reports whether count header entries, each at least two
+// length prefixes, fit in data after offset. It guards allocations
+// sized from counts read out of an untrusted frame.
+func fitsEntries(data []byte, offset int, count uint32) bool {
+	return offset <= len(data) && uint64(count)*8 <= uint64(len(data)-offset)
+}
+
+// unmarshalSmallRequest implements UnmarshalRequest's fast path.
+func unmarshalSmallRequest(data []byte) WitHttpRequest {
+	// Walk the length prefixes to find where the body starts, then copy
+	// everything before it into a single string.
+	offset := skipField(data, 0)
+	offset = skipField(data, offset)
+	headerCount, offset := readU32(data, offset)
+	for i := uint32(0); i < headerCount; i++ {
+		offset = skipField(data, offset)
+		offset = skipField(data, offset)
+	}
+	head := string(data[:offset])
+
+	var req WitHttpRequest
+	offset = 0
+	req.Method, offset = sliceString(data, head, offset)
+	req.URI, offset = sliceString(data, head, offset)
+	offset += 4
+	req.Headers = make([]WitHttpHeader, headerCount)
+	for i := range req.Headers {
+		req.Headers[i].Name, offset = sliceString(data, head, offset)
+		req.Headers[i].Value, offset = sliceString(data, head, offset)
+	}
+	req.Body, offset = readBytes(data, offset)
+	req.Trailers = readTrailers(data, offset)
 	return req
 }
 
+// skipField returns the offset just past the length-prefixed field at
+// offset.
+func skipField(data []byte, offset int) int {
+	length, off := readU32(data, offset)
+	return off + int(length)
+}
+
+// sliceString is readString returning a substring of head, which holds
+// a copy of data up to at least the end of the field.
+func sliceString(data []byte, head string, offset int) (string, int) {
+	length, off := readU32(data, offset)
+	return head[off : off+int(length)], off + int(length)
+}
+
 // MarshalResponse serializes a WitHttpResponse to the wire format.
 func MarshalResponse(resp WitHttpResponse) []byte {
 	size := 2 + 4 + 4 + len(resp.Body)
@@ -118,6 +244,273 @@ func UnmarshalResponse(data []byte) WitHttpResponse {
 	return resp
 }
 
+// ── Streaming codec ─────────────────────────────────────────────────
+
+// DecodeRequest reads a WitHttpRequest in the wire format from r.
+//
+// Unlike UnmarshalRequest, the frame does not need to be in one
+// contiguous buffer: fields are read incrementally as the host delivers
+// them, and each field's storage grows only as bytes actually arrive,
+// so a bogus length prefix cannot force a huge up-front allocation.
+// A frame that ends early yields an error wrapping io.ErrUnexpectedEOF,
+// and a header block larger than MaxHeaderBytes yields ErrHeaderTooLarge;
+// trailers count toward the same limit. After the body, DecodeRequest
+// reads on until r reports io.EOF to find out whether a trailer block
+// follows, so r must end with the frame.
+func DecodeRequest(r io.Reader) (WitHttpRequest, error) {
+	var req WitHttpRequest
+	var err error
+
+	if req.Method, err = decodeString(r); err != nil {
+		return WitHttpRequest{}, err
+	}
+	if req.URI, err = decodeString(r); err != nil {
+		return WitHttpRequest{}, err
+	}
+
+	headerCount, err := decodeU32(r)
+	if err != nil {
+		return WitHttpRequest{}, err
+	}
+	limit := maxHeaderBytes()
+	total := 0
+	for i := uint32(0); i < headerCount; i++ {
+		var h WitHttpHeader
+		if h.Name, err = decodeLimitedString(r, limit-total); err != nil {
+			return WitHttpRequest{}, err
+		}
+		total += len(h.Name)
+		if h.Value, err = decodeLimitedString(r, limit-total); err != nil {
+			return WitHttpRequest{}, err
+		}
+		total += len(h.Value)
+		req.Headers = append(req.Headers, h)
+	}
+	if req.Headers == nil {
+		req.Headers = []WitHttpHeader{}
+	}
+
+	if req.Body, err = decodeBytes(r); err != nil {
+		return WitHttpRequest{}, err
+	}
+
+	var version [1]byte
+	if _, err := io.ReadFull(r, version[:]); err == io.EOF {
+		return req, nil
+	} else if err != nil {
+		return WitHttpRequest{}, err
+	}
+	if version[0] != requestTrailerVersion {
+		return WitHttpRequest{}, fmt.Errorf("http: unknown request trailer block version %d", version[0])
+	}
+	trailerCount, err := decodeU32(r)
+	if err != nil {
+		return WitHttpRequest{}, err
+	}
+	for i := uint32(0); i < trailerCount; i++ {
+		var t WitHttpHeader
+		if t.Name, err = decodeLimitedString(r, limit-total); err != nil {
+			return WitHttpRequest{}, err
+		}
+		total += len(t.Name)
+		if t.Value, err = decodeLimitedString(r, limit-total); err != nil {
+			return WitHttpRequest{}, err
+		}
+		total += len(t.Value)
+		req.Trailers = append(req.Trailers, t)
+	}
+	return req, nil
+}
+
+// PeekRequest decodes reqBytes as a request frame without converting
+// it to a Request or invoking any handler, for diagnostics that need to
+// see the fields exactly as the host sent them, such as when debugging
+// header mangling. It uses DecodeRequest's bounds-checked decoding, so a
+// truncated or oversized frame is an error rather than a panic, and
+// bytes left over after the body or trailer block are reported as
+// malformed too.
+func PeekRequest(reqBytes []byte) (WitHttpRequest, error) {
+	r := bytes.NewReader(reqBytes)
+	req, err := DecodeRequest(r)
+	if err != nil {
+		return WitHttpRequest{}, err
+	}
+	if r.Len() > 0 {
+		return WitHttpRequest{}, fmt.Errorf("http: %d trailing bytes after request frame", r.Len())
+	}
+	return req, nil
+}
+
+// EncodeResponse writes resp to w in the wire format, producing the
+// same bytes as MarshalResponse. The body is written straight from
+// resp.Body without being copied into an intermediate frame.
+func EncodeResponse(w io.Writer, resp WitHttpResponse) error {
+	var scratch [4]byte
+
+	binary.LittleEndian.PutUint16(scratch[:2], resp.Status)
+	if _, err := w.Write(scratch[:2]); err != nil {
+		return err
+	}
+	if err := encodeU32(w, &scratch, uint32(len(resp.Headers))); err != nil {
+		return err
+	}
+	for _, h := range resp.Headers {
+		if err := encodeString(w, &scratch, h.Name); err != nil {
+			return err
+		}
+		if err := encodeString(w, &scratch, h.Value); err != nil {
+			return err
+		}
+	}
+	if err := encodeU32(w, &scratch, uint32(len(resp.Body))); err != nil {
+		return err
+	}
+	if len(resp.Body) > 0 {
+		if _, err := w.Write(resp.Body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeU32(r io.Reader) (uint32, error) {
+	var b [4]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, frameError(err)
+	}
+	return binary.LittleEndian.Uint32(b[:]), nil
+}
+
+func decodeString(r io.Reader) (string, error) {
+	b, err := decodeBytes(r)
+	return string(b), err
+}
+
+// decodeLimitedString is decodeString but fails with ErrHeaderTooLarge,
+// before reading the payload, when the length prefix exceeds max.
+func decodeLimitedString(r io.Reader, max int) (string, error) {
+	length, err := decodeU32(r)
+	if err != nil {
+		return "", err
+	}
+	if int64(length) > int64(max) {
+		return "", ErrHeaderTooLarge
+	}
+	b, err := decodeBytesLen(r, length)
+	return string(b), err
+}
+
+func decodeBytes(r io.Reader) ([]byte, error) {
+	length, err := decodeU32(r)
+	if err != nil {
+		return nil, err
+	}
+	return decodeBytesLen(r, length)
+}
+
+func decodeBytesLen(r io.Reader, length uint32) ([]byte, error) {
+	if length == 0 {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, r, int64(length))
+	if n < int64(length) {
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, frameError(err)
+	}
+	return buf.Bytes(), nil
+}
+
+// frameError wraps premature end-of-stream conditions so callers can
+// match them with errors.Is(err, io.ErrUnexpectedEOF).
+func frameError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("http: truncated wire frame: %w", io.ErrUnexpectedEOF)
+	}
+	return err
+}
+
+func encodeU32(w io.Writer, scratch *[4]byte, v uint32) error {
+	binary.LittleEndian.PutUint32(scratch[:], v)
+	_, err := w.Write(scratch[:])
+	return err
+}
+
+func encodeString(w io.Writer, scratch *[4]byte, s string) error {
+	if err := encodeU32(w, scratch, uint32(len(s))); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, s)
+	return err
+}
+
+// headerBytes returns the total length of the header names and values
+// in an encoded request frame, trailers included, reading only the
+// length prefixes. It
+// stops counting once the total exceeds limit. Length prefixes are
+// trusted even when the frame is truncated, so a bogus prefix is
+// rejected here rather than allocated later.
+func headerBytes(data []byte, limit int) int {
+	offset := 0
+	skip := func() (int, bool) {
+		if offset+4 > len(data) {
+			return 0, false
+		}
+		length := binary.LittleEndian.Uint32(data[offset:])
+		offset += 4
+		if int64(length) > int64(len(data)-offset) {
+			offset = len(data)
+		} else {
+			offset += int(length)
+		}
+		if int64(length) > int64(limit) {
+			return limit + 1, true
+		}
+		return int(length), true
+	}
+
+	if _, ok := skip(); !ok { // method
+		return 0
+	}
+	if _, ok := skip(); !ok { // uri
+		return 0
+	}
+	if offset+4 > len(data) {
+		return 0
+	}
+	count := binary.LittleEndian.Uint32(data[offset:])
+	offset += 4
+
+	total := 0
+	fields := func(count uint32) bool {
+		for i := uint64(0); i < 2*uint64(count); i++ {
+			if total > limit {
+				return false
+			}
+			n, ok := skip()
+			if !ok {
+				return false
+			}
+			total += n
+		}
+		return true
+	}
+	if !fields(count) {
+		return total
+	}
+
+	// Skip the body; the trailer block after it counts too.
+	if _, ok := skip(); !ok || offset+5 > len(data) || data[offset] != requestTrailerVersion {
+		return total
+	}
+	count = binary.LittleEndian.Uint32(data[offset+1:])
+	offset += 5
+	fields(count)
+	return total
+}
+
 // ── Encoding helpers ────────────────────────────────────────────────
 
 func appendU16(buf []byte, v uint16) []byte {
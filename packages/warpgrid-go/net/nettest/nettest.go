@@ -0,0 +1,226 @@
+// Package nettest provides fakes for testing code built on the WarpGrid
+// net and dns packages: a TCP echo server whose misbehaviour can be
+// configured (slow to serve, connection resets, refused connections)
+// and a ControllableBackend resolver whose answers, errors, and latency
+// are set per hostname.
+//
+// It is intended for tests only and runs on standard Go; it does not
+// use the WASI shims.
+package nettest
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ── Fake server ─────────────────────────────────────────────────────
+
+// ServerConfig controls how a Server treats its connections.
+type ServerConfig struct {
+	// Addr is the address to listen on. Defaults to "127.0.0.1:0".
+	Addr string
+
+	// AcceptDelay postpones accepting each connection. The kernel still
+	// completes the TCP handshake, so Dial returns promptly, but the
+	// first echoed byte arrives no earlier than AcceptDelay, which
+	// simulates a slow or overloaded backend.
+	AcceptDelay time.Duration
+
+	// ResetAfter, when positive, echoes that many bytes on each
+	// connection and then resets it (RST rather than FIN), so the
+	// client sees a partial read followed by a connection reset.
+	ResetAfter int
+
+	// Refuse closes the listener immediately after binding, so every
+	// dial to Addr is refused while the port number stays known.
+	Refuse bool
+}
+
+// Server is a TCP echo server with configurable failure modes.
+type Server struct {
+	cfg  ServerConfig
+	ln   net.Listener
+	addr string
+
+	mu       sync.Mutex
+	accepted int
+	conns    []net.Conn
+	done     chan struct{}
+}
+
+// StartServer starts a Server for the duration of the test; it is
+// closed automatically by t.Cleanup.
+func StartServer(t testing.TB, cfg ServerConfig) *Server {
+	t.Helper()
+	s, err := NewServer(cfg)
+	if err != nil {
+		t.Fatalf("nettest: failed to start server: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// NewServer starts a Server. Callers must Close it.
+func NewServer(cfg ServerConfig) (*Server, error) {
+	if cfg.Addr == "" {
+		cfg.Addr = "127.0.0.1:0"
+	}
+	ln, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{cfg: cfg, ln: ln, addr: ln.Addr().String(), done: make(chan struct{})}
+	if cfg.Refuse {
+		ln.Close()
+		close(s.done)
+		return s, nil
+	}
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns the server's host:port address.
+func (s *Server) Addr() string { return s.addr }
+
+// Port returns the port component of Addr.
+func (s *Server) Port() string {
+	_, port, _ := net.SplitHostPort(s.addr)
+	return port
+}
+
+// Accepted returns the number of connections accepted so far.
+func (s *Server) Accepted() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.accepted
+}
+
+// Close stops the server and closes all accepted connections.
+func (s *Server) Close() error {
+	err := s.ln.Close()
+	<-s.done
+	s.mu.Lock()
+	for _, c := range s.conns {
+		c.Close()
+	}
+	s.conns = nil
+	s.mu.Unlock()
+	if s.cfg.Refuse || errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+func (s *Server) serve() {
+	defer close(s.done)
+	for {
+		if s.cfg.AcceptDelay > 0 {
+			time.Sleep(s.cfg.AcceptDelay)
+		}
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.accepted++
+		s.conns = append(s.conns, conn)
+		s.mu.Unlock()
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	if s.cfg.ResetAfter <= 0 {
+		io.Copy(conn, conn)
+		return
+	}
+
+	io.Copy(conn, io.LimitReader(conn, int64(s.cfg.ResetAfter)))
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		tcp.SetLinger(0) // Close now sends RST
+	}
+}
+
+// ── Controllable resolver backend ───────────────────────────────────
+
+// ControllableBackend is a dns.ResolverBackend whose answers are set
+// per hostname. Unknown hostnames fail with a "HostNotFound" error. It
+// is safe for concurrent use.
+type ControllableBackend struct {
+	mu      sync.Mutex
+	records map[string][]net.IP
+	errs    map[string]error
+	delay   time.Duration
+	calls   []string
+}
+
+// NewControllableBackend returns an empty ControllableBackend.
+func NewControllableBackend() *ControllableBackend {
+	return &ControllableBackend{
+		records: make(map[string][]net.IP),
+		errs:    make(map[string]error),
+	}
+}
+
+// Set makes hostname resolve to ips, replacing any earlier answer or
+// error. It panics if an entry is not a valid IP address.
+func (b *ControllableBackend) Set(hostname string, ips ...string) {
+	parsed := make([]net.IP, len(ips))
+	for i, s := range ips {
+		if parsed[i] = net.ParseIP(s); parsed[i] == nil {
+			panic("nettest: invalid IP " + s)
+		}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.records[hostname] = parsed
+	delete(b.errs, hostname)
+}
+
+// SetError makes lookups of hostname fail with err.
+func (b *ControllableBackend) SetError(hostname string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.errs[hostname] = err
+	delete(b.records, hostname)
+}
+
+// SetDelay makes every lookup take at least d.
+func (b *ControllableBackend) SetDelay(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.delay = d
+}
+
+// Resolve implements dns.ResolverBackend.
+func (b *ControllableBackend) Resolve(hostname string) ([]net.IP, error) {
+	b.mu.Lock()
+	b.calls = append(b.calls, hostname)
+	delay := b.delay
+	ips, ok := b.records[hostname]
+	err := b.errs[hostname]
+	b.mu.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("HostNotFound: " + hostname)
+	}
+	return append([]net.IP(nil), ips...), nil
+}
+
+// Calls returns the hostnames looked up so far, in order.
+func (b *ControllableBackend) Calls() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.calls...)
+}
@@ -0,0 +1,131 @@
+package nettest_test
+
+import (
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	wgdns "github.com/anthropics/warpgrid/packages/warpgrid-go/dns"
+	wgnet "github.com/anthropics/warpgrid/packages/warpgrid-go/net"
+	"github.com/anthropics/warpgrid/packages/warpgrid-go/net/nettest"
+)
+
+// ── Server tests ────────────────────────────────────────────────────
+
+func TestServer_Echoes(t *testing.T) {
+	s := nettest.StartServer(t, nettest.ServerConfig{})
+
+	conn, err := net.Dial("tcp", s.Addr())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	conn.Write([]byte("ping"))
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "ping" {
+		t.Fatalf("expected echo 'ping', got %q (%v)", buf, err)
+	}
+	if s.Accepted() != 1 {
+		t.Fatalf("expected 1 accepted connection, got %d", s.Accepted())
+	}
+}
+
+func TestServer_AcceptDelayPostponesFirstByte(t *testing.T) {
+	const delay = 150 * time.Millisecond
+	s := nettest.StartServer(t, nettest.ServerConfig{AcceptDelay: delay})
+
+	start := time.Now()
+	conn, err := net.Dial("tcp", s.Addr())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	conn.Write([]byte("x"))
+	buf := make([]byte, 1)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Fatalf("expected echo after at least %v, got %v", delay, elapsed)
+	}
+}
+
+func TestServer_ResetAfterNBytes(t *testing.T) {
+	s := nettest.StartServer(t, nettest.ServerConfig{ResetAfter: 4})
+
+	conn, err := net.Dial("tcp", s.Addr())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	conn.Write([]byte("abcdefgh"))
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	data, err := io.ReadAll(conn)
+	if string(data) != "abcd" {
+		t.Fatalf("expected partial read 'abcd', got %q", data)
+	}
+	if !errors.Is(err, syscall.ECONNRESET) {
+		t.Fatalf("expected connection reset, got %v", err)
+	}
+}
+
+func TestServer_RefuseRejectsDials(t *testing.T) {
+	s := nettest.StartServer(t, nettest.ServerConfig{Refuse: true})
+
+	if _, err := net.DialTimeout("tcp", s.Addr(), time.Second); err == nil {
+		t.Fatal("expected dial to a refusing server to fail")
+	}
+	if s.Port() == "" {
+		t.Fatal("expected refusing server to report its port")
+	}
+}
+
+// ── ControllableBackend tests ───────────────────────────────────────
+
+func TestControllableBackend_AnswersErrorsAndCalls(t *testing.T) {
+	b := nettest.NewControllableBackend()
+	b.Set("db.warp.local", "10.0.0.1", "fd00::1")
+	boom := errors.New("SERVFAIL")
+	b.SetError("flaky.warp.local", boom)
+
+	ips, err := b.Resolve("db.warp.local")
+	if err != nil || len(ips) != 2 {
+		t.Fatalf("expected 2 IPs, got %v (%v)", ips, err)
+	}
+	if _, err := b.Resolve("flaky.warp.local"); !errors.Is(err, boom) {
+		t.Fatalf("expected configured error, got %v", err)
+	}
+	if _, err := b.Resolve("unknown.warp.local"); err == nil {
+		t.Fatal("expected error for unknown host")
+	}
+
+	calls := b.Calls()
+	if len(calls) != 3 || calls[0] != "db.warp.local" || calls[2] != "unknown.warp.local" {
+		t.Fatalf("unexpected calls: %v", calls)
+	}
+}
+
+func TestControllableBackend_DrivesDialerFailover(t *testing.T) {
+	up := nettest.StartServer(t, nettest.ServerConfig{})
+	// Bind then release 127.0.0.2 on the same port so it refuses.
+	nettest.StartServer(t, nettest.ServerConfig{Addr: "127.0.0.2:" + up.Port(), Refuse: true})
+
+	b := nettest.NewControllableBackend()
+	b.Set("api.warp.local", "127.0.0.2", "127.0.0.1")
+	d := wgnet.NewDialer(wgdns.NewResolver(b))
+
+	conn, err := d.Dial("tcp", net.JoinHostPort("api.warp.local", up.Port()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	conn.Close()
+	if stats := d.Stats(); stats.Failovers != 1 {
+		t.Fatalf("expected 1 failover, got %d", stats.Failovers)
+	}
+}
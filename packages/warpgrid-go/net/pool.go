@@ -0,0 +1,252 @@
+package net
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultProbeTimeout is the liveness probe deadline used when
+// Pool.ProbeIdle is set and ProbeTimeout is zero.
+const DefaultProbeTimeout = time.Millisecond
+
+// Pool keeps idle connections for reuse, keyed by network and address.
+// Callers take a connection with Get and hand it back with Put when
+// the exchange is complete; a connection that failed mid-use should be
+// closed instead.
+//
+// Behind the WarpGrid proxy an idle connection can be dropped
+// server-side while the pool still holds it. IdleTimeout bounds how
+// long a connection may sit idle: a background reaper closes older
+// ones, and Get never hands them out. ProbeIdle additionally checks
+// each pooled connection is still open before returning it.
+type Pool struct {
+	// Dial opens new connections when no idle one is available.
+	Dial func(ctx context.Context, network, address string) (net.Conn, error)
+
+	// IdleTimeout is how long a connection may sit idle before it is
+	// closed. Zero or negative keeps idle connections indefinitely and
+	// runs no reaper.
+	IdleTimeout time.Duration
+
+	// MaxIdlePerHost caps the idle connections kept per network and
+	// address; Put closes connections beyond it. Zero means no limit.
+	MaxIdlePerHost int
+
+	// ProbeIdle checks that a pooled connection is still open before
+	// Get returns it. Go answers a zero-byte read without touching the
+	// socket, so the probe reads one byte under a ProbeTimeout
+	// deadline: a timeout means the peer is quiet and the connection is
+	// live, while EOF, an error, or unsolicited data means it is
+	// discarded.
+	ProbeIdle bool
+
+	// ProbeTimeout is the probe's read deadline; zero means
+	// DefaultProbeTimeout.
+	ProbeTimeout time.Duration
+
+	// Now returns the current time for idle accounting; nil means
+	// time.Now.
+	Now func() time.Time
+
+	// NewTicker starts the reaper's ticker, returning its channel and a
+	// function that stops it; nil means a time.Ticker. The reaper ticks
+	// every IdleTimeout/2.
+	NewTicker func(d time.Duration) (<-chan time.Time, func())
+
+	mu         sync.Mutex
+	idle       map[poolKey][]idleConn
+	stopReaper chan struct{}
+}
+
+type poolKey struct {
+	network, address string
+}
+
+type idleConn struct {
+	conn  net.Conn
+	since time.Time
+}
+
+// NewPool returns a Pool that dials new connections with d.
+func NewPool(d *Dialer) *Pool {
+	return &Pool{Dial: d.DialContext}
+}
+
+// Get returns an idle connection to address on network, or dials a new
+// one. Idle connections past IdleTimeout, or failing the liveness probe
+// when ProbeIdle is set, are closed and skipped.
+func (p *Pool) Get(ctx context.Context, network, address string) (net.Conn, error) {
+	key := poolKey{network, address}
+	for {
+		conn, since, ok := p.popIdle(key)
+		if !ok {
+			break
+		}
+		if p.expired(since, p.now()) || (p.ProbeIdle && !p.probe(conn)) {
+			conn.Close()
+			continue
+		}
+		return conn, nil
+	}
+	if p.Dial == nil {
+		return nil, errors.New("net: Pool.Dial is nil")
+	}
+	return p.Dial(ctx, network, address)
+}
+
+// Put returns conn, connected to address on network, to the pool for
+// reuse, starting the reaper if it is not running.
+func (p *Pool) Put(network, address string, conn net.Conn) {
+	key := poolKey{network, address}
+
+	p.mu.Lock()
+	if p.MaxIdlePerHost > 0 && len(p.idle[key]) >= p.MaxIdlePerHost {
+		p.mu.Unlock()
+		conn.Close()
+		return
+	}
+	if p.idle == nil {
+		p.idle = make(map[poolKey][]idleConn)
+	}
+	p.idle[key] = append(p.idle[key], idleConn{conn: conn, since: p.now()})
+	if p.IdleTimeout > 0 && p.stopReaper == nil {
+		p.stopReaper = make(chan struct{})
+		go p.reap(p.stopReaper)
+	}
+	p.mu.Unlock()
+}
+
+// IdleCount returns the number of idle connections held.
+func (p *Pool) IdleCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := 0
+	for _, conns := range p.idle {
+		n += len(conns)
+	}
+	return n
+}
+
+// CloseIdle closes every idle connection and stops the reaper. The pool
+// remains usable; a later Put starts a new reaper.
+func (p *Pool) CloseIdle() {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	if p.stopReaper != nil {
+		close(p.stopReaper)
+		p.stopReaper = nil
+	}
+	p.mu.Unlock()
+
+	for _, conns := range idle {
+		for _, ic := range conns {
+			ic.conn.Close()
+		}
+	}
+}
+
+// popIdle removes and returns the most recently returned idle
+// connection for key, which is the most likely to still be open.
+func (p *Pool) popIdle(key poolKey) (net.Conn, time.Time, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	conns := p.idle[key]
+	if len(conns) == 0 {
+		return nil, time.Time{}, false
+	}
+	ic := conns[len(conns)-1]
+	if len(conns) == 1 {
+		delete(p.idle, key)
+	} else {
+		p.idle[key] = conns[:len(conns)-1]
+	}
+	return ic.conn, ic.since, true
+}
+
+// reap closes expired idle connections on every tick until stop is
+// closed.
+func (p *Pool) reap(stop <-chan struct{}) {
+	interval := p.IdleTimeout / 2
+	if interval <= 0 {
+		interval = p.IdleTimeout
+	}
+	var ticks <-chan time.Time
+	var stopTicker func()
+	if p.NewTicker != nil {
+		ticks, stopTicker = p.NewTicker(interval)
+	} else {
+		t := time.NewTicker(interval)
+		ticks, stopTicker = t.C, t.Stop
+	}
+	defer stopTicker()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticks:
+			p.closeExpired()
+		}
+	}
+}
+
+// closeExpired closes the idle connections past IdleTimeout.
+func (p *Pool) closeExpired() {
+	now := p.now()
+	var dead []net.Conn
+
+	p.mu.Lock()
+	for key, conns := range p.idle {
+		kept := conns[:0]
+		for _, ic := range conns {
+			if p.expired(ic.since, now) {
+				dead = append(dead, ic.conn)
+			} else {
+				kept = append(kept, ic)
+			}
+		}
+		if len(kept) == 0 {
+			delete(p.idle, key)
+		} else {
+			p.idle[key] = kept
+		}
+	}
+	p.mu.Unlock()
+
+	for _, c := range dead {
+		c.Close()
+	}
+}
+
+func (p *Pool) expired(since, now time.Time) bool {
+	return p.IdleTimeout > 0 && now.Sub(since) >= p.IdleTimeout
+}
+
+// probe reports whether conn still looks open. See Pool.ProbeIdle.
+func (p *Pool) probe(conn net.Conn) bool {
+	timeout := p.ProbeTimeout
+	if timeout <= 0 {
+		timeout = DefaultProbeTimeout
+	}
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return false
+	}
+	var b [1]byte
+	_, err := conn.Read(b[:])
+	var ne net.Error
+	if !errors.As(err, &ne) || !ne.Timeout() {
+		return false
+	}
+	return conn.SetReadDeadline(time.Time{}) == nil
+}
+
+func (p *Pool) now() time.Time {
+	if p.Now != nil {
+		return p.Now()
+	}
+	return time.Now()
+}
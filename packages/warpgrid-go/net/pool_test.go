@@ -0,0 +1,227 @@
+package net_test
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	wgdns "github.com/anthropics/warpgrid/packages/warpgrid-go/dns"
+	wgnet "github.com/anthropics/warpgrid/packages/warpgrid-go/net"
+)
+
+// ── Helpers ─────────────────────────────────────────────────────────
+
+// trackedConn records whether it has been closed.
+type trackedConn struct {
+	net.Conn
+	closed atomic.Bool
+}
+
+func (c *trackedConn) Close() error {
+	c.closed.Store(true)
+	return c.Conn.Close()
+}
+
+// pipeConn returns a pooled-side conn and the peer end of a net.Pipe.
+func pipeConn(t *testing.T) (*trackedConn, net.Conn) {
+	t.Helper()
+	client, server := net.Pipe()
+	t.Cleanup(func() { client.Close(); server.Close() })
+	return &trackedConn{Conn: client}, server
+}
+
+// manualClock is a settable clock for Pool.Now.
+type manualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// manualTicker is a Pool.NewTicker whose ticks the test sends.
+type manualTicker struct {
+	c       chan time.Time
+	stopped atomic.Bool
+}
+
+func (m *manualTicker) NewTicker(time.Duration) (<-chan time.Time, func()) {
+	return m.c, func() { m.stopped.Store(true) }
+}
+
+func waitUntil(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// ── Pool tests ──────────────────────────────────────────────────────
+
+func TestPool_ReusesIdleConn(t *testing.T) {
+	conn, _ := pipeConn(t)
+	dials := 0
+	pool := &wgnet.Pool{Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+		dials++
+		return nil, nil
+	}}
+
+	pool.Put("tcp", "db:5432", conn)
+	got, err := pool.Get(context.Background(), "tcp", "db:5432")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != net.Conn(conn) || dials != 0 {
+		t.Fatalf("expected the idle conn to be reused without dialing, got dials=%d", dials)
+	}
+}
+
+func TestPool_ReaperClosesIdleConnsAfterTimeout(t *testing.T) {
+	conn, _ := pipeConn(t)
+	clock := &manualClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	ticker := &manualTicker{c: make(chan time.Time)}
+	pool := &wgnet.Pool{
+		IdleTimeout: time.Minute,
+		Now:         clock.Now,
+		NewTicker:   ticker.NewTicker,
+	}
+	defer pool.CloseIdle()
+
+	pool.Put("tcp", "db:5432", conn)
+
+	clock.Advance(30 * time.Second)
+	ticker.c <- clock.Now()
+	ticker.c <- clock.Now() // the second send completes only after the first tick is handled
+	if conn.closed.Load() || pool.IdleCount() != 1 {
+		t.Fatal("expected conn to survive a tick before IdleTimeout")
+	}
+
+	clock.Advance(31 * time.Second)
+	ticker.c <- clock.Now()
+	waitUntil(t, "idle conn to be reaped", conn.closed.Load)
+	if n := pool.IdleCount(); n != 0 {
+		t.Fatalf("expected no idle conns after reaping, got %d", n)
+	}
+}
+
+func TestPool_CloseIdleStopsReaper(t *testing.T) {
+	conn, _ := pipeConn(t)
+	ticker := &manualTicker{c: make(chan time.Time)}
+	pool := &wgnet.Pool{IdleTimeout: time.Minute, NewTicker: ticker.NewTicker}
+
+	pool.Put("tcp", "db:5432", conn)
+	pool.CloseIdle()
+
+	if !conn.closed.Load() {
+		t.Fatal("expected CloseIdle to close idle conns")
+	}
+	waitUntil(t, "reaper ticker to stop", ticker.stopped.Load)
+}
+
+func TestPool_GetSkipsExpiredConn(t *testing.T) {
+	conn, _ := pipeConn(t)
+	fresh, _ := pipeConn(t)
+	clock := &manualClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	pool := &wgnet.Pool{
+		IdleTimeout: time.Minute,
+		Now:         clock.Now,
+		NewTicker:   (&manualTicker{c: make(chan time.Time)}).NewTicker,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return fresh, nil
+		},
+	}
+	defer pool.CloseIdle()
+
+	pool.Put("tcp", "db:5432", conn)
+	clock.Advance(2 * time.Minute)
+
+	got, err := pool.Get(context.Background(), "tcp", "db:5432")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != net.Conn(fresh) || !conn.closed.Load() {
+		t.Fatal("expected expired conn to be closed and a new one dialed")
+	}
+}
+
+func TestPool_ProbeDiscardsDeadConn(t *testing.T) {
+	dead, peer := pipeConn(t)
+	peer.Close() // server dropped the connection while it sat idle
+	fresh, _ := pipeConn(t)
+	pool := &wgnet.Pool{
+		ProbeIdle: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return fresh, nil
+		},
+	}
+
+	pool.Put("tcp", "db:5432", dead)
+	got, err := pool.Get(context.Background(), "tcp", "db:5432")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got == net.Conn(dead) {
+		t.Fatal("expected dead pooled conn not to be returned")
+	}
+	if got != net.Conn(fresh) || !dead.closed.Load() {
+		t.Fatal("expected dead conn closed and a new one dialed")
+	}
+}
+
+func TestPool_ProbeKeepsLiveConn(t *testing.T) {
+	live, _ := pipeConn(t)
+	pool := &wgnet.Pool{ProbeIdle: true}
+
+	pool.Put("tcp", "db:5432", live)
+	got, err := pool.Get(context.Background(), "tcp", "db:5432")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != net.Conn(live) || live.closed.Load() {
+		t.Fatal("expected live conn to pass the probe and be reused")
+	}
+}
+
+func TestPool_MaxIdlePerHost(t *testing.T) {
+	first, _ := pipeConn(t)
+	second, _ := pipeConn(t)
+	pool := &wgnet.Pool{MaxIdlePerHost: 1}
+
+	pool.Put("tcp", "db:5432", first)
+	pool.Put("tcp", "db:5432", second)
+
+	if n := pool.IdleCount(); n != 1 {
+		t.Fatalf("expected 1 idle conn, got %d", n)
+	}
+	if !second.closed.Load() {
+		t.Fatal("expected conn beyond MaxIdlePerHost to be closed")
+	}
+}
+
+func TestNewPool_DialsThroughDialer(t *testing.T) {
+	addr, cleanup := startEchoServer(t)
+	defer cleanup()
+
+	pool := wgnet.NewPool(wgnet.NewDialer(wgdns.NewResolver(mockResolverFunc(nil))))
+	conn, err := pool.Get(context.Background(), "tcp", addr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	conn.Close()
+}
@@ -0,0 +1,174 @@
+package net
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// ErrProxyAuthRequired is returned, wrapped in a *net.OpError, when the
+// CONNECT proxy answers 407 Proxy Authentication Required: no
+// credentials were configured, or the proxy rejected them.
+var ErrProxyAuthRequired = errors.New("net: proxy authentication required")
+
+// ProxyConfig routes a Dialer's TCP connections through an HTTP CONNECT
+// proxy.
+type ProxyConfig struct {
+	// Address is the proxy's host:port. A hostname is resolved through
+	// the Dialer's resolver, with the usual failover, Balancer, and
+	// DialTrace hooks.
+	Address string
+
+	// Username and Password, when Username is set, are sent with every
+	// CONNECT as Basic credentials in Proxy-Authorization.
+	Username string
+	Password string
+}
+
+// maxProxyResponseBytes bounds the CONNECT response head, so a
+// misbehaving proxy cannot make the dial buffer without limit.
+const maxProxyResponseBytes = 16 << 10
+
+// isTCPNetwork reports whether network is one a CONNECT proxy can
+// tunnel.
+func isTCPNetwork(network string) bool {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		return true
+	}
+	return false
+}
+
+// dialProxy connects to d.Proxy and asks it to tunnel to address, which
+// is sent as given: resolving the target is left to the proxy.
+func (d *Dialer) dialProxy(ctx context.Context, network, address string) (net.Conn, error) {
+	conn, err := d.dialTarget(ctx, "tcp", d.Proxy.Address)
+	if err != nil {
+		return nil, err
+	}
+	tunnel, err := d.connect(ctx, conn, address)
+	if err != nil {
+		conn.Close()
+		return nil, &net.OpError{
+			Op:   "dial",
+			Net:  network,
+			Addr: hostAddr{network, address},
+			Err:  err,
+		}
+	}
+	return tunnel, nil
+}
+
+// connect performs the CONNECT handshake for address on conn, bounded
+// by ctx and ConnectTimeout, and returns the tunneled connection.
+func (d *Dialer) connect(ctx context.Context, conn net.Conn, address string) (net.Conn, error) {
+	deadline, ok := ctx.Deadline()
+	if d.ConnectTimeout > 0 {
+		if t := time.Now().Add(d.ConnectTimeout); !ok || t.Before(deadline) {
+			deadline, ok = t, true
+		}
+	}
+	if ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	// Unblock the handshake if ctx is cancelled mid-way.
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	var req strings.Builder
+	req.WriteString("CONNECT " + address + " HTTP/1.1\r\nHost: " + address + "\r\n")
+	if d.Proxy.Username != "" {
+		creds := base64.StdEncoding.EncodeToString([]byte(d.Proxy.Username + ":" + d.Proxy.Password))
+		req.WriteString("Proxy-Authorization: Basic " + creds + "\r\n")
+	}
+	req.WriteString("\r\n")
+	if _, err := conn.Write([]byte(req.String())); err != nil {
+		return nil, proxyError(ctx, err)
+	}
+
+	br := bufio.NewReader(conn)
+	status, err := readProxyResponse(br)
+	if err != nil {
+		return nil, proxyError(ctx, err)
+	}
+	switch {
+	case status.code == 407:
+		return nil, ErrProxyAuthRequired
+	case status.code < 200 || status.code > 299:
+		return nil, fmt.Errorf("net: proxy refused CONNECT: %s", status.line)
+	}
+	if br.Buffered() > 0 {
+		// The target spoke first and its bytes arrived with the
+		// proxy's response; keep them.
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// proxyError reports ctx's error in place of the I/O error a cancelled
+// handshake produces.
+func proxyError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// proxyStatus is the status line of a CONNECT response.
+type proxyStatus struct {
+	line string
+	code int
+}
+
+// readProxyResponse reads a CONNECT response head from br, returning
+// its status line and discarding the header fields.
+func readProxyResponse(br *bufio.Reader) (proxyStatus, error) {
+	var status proxyStatus
+	total := 0
+	for first := true; ; first = false {
+		line, err := br.ReadString('\n')
+		total += len(line)
+		if total > maxProxyResponseBytes {
+			return status, errors.New("net: proxy response too large")
+		}
+		if err != nil {
+			return status, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if first {
+			status.line = line
+			proto, rest, _ := strings.Cut(line, " ")
+			code, _, _ := strings.Cut(rest, " ")
+			if !strings.HasPrefix(proto, "HTTP/1.") || len(code) != 3 {
+				return status, fmt.Errorf("net: malformed proxy response %q", line)
+			}
+			for _, c := range code {
+				if c < '0' || c > '9' {
+					return status, fmt.Errorf("net: malformed proxy response %q", line)
+				}
+				status.code = status.code*10 + int(c-'0')
+			}
+			continue
+		}
+		if line == "" {
+			return status, nil
+		}
+	}
+}
+
+// bufferedConn is a net.Conn whose first reads drain bytes already
+// buffered from it.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
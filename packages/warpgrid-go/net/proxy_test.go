@@ -0,0 +1,165 @@
+package net_test
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	wgdns "github.com/anthropics/warpgrid/packages/warpgrid-go/dns"
+	wgnet "github.com/anthropics/warpgrid/packages/warpgrid-go/net"
+)
+
+// ── Proxy tests ─────────────────────────────────────────────────────
+
+// fakeProxy is a CONNECT proxy that tunnels every request to backend,
+// whatever target it names, and records the targets it was asked for.
+// With a non-empty auth it answers 407 unless Proxy-Authorization
+// carries exactly that value.
+type fakeProxy struct {
+	addr    string
+	backend string
+	auth    string
+
+	mu      sync.Mutex
+	targets []string
+}
+
+func startFakeProxy(t *testing.T, backend, auth string) *fakeProxy {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start proxy: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	p := &fakeProxy{addr: ln.Addr().String(), backend: backend, auth: auth}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go p.serve(conn)
+		}
+	}()
+	return p
+}
+
+func (p *fakeProxy) serve(conn net.Conn) {
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+	req, err := http.ReadRequest(br)
+	if err != nil || req.Method != http.MethodConnect {
+		io.WriteString(conn, "HTTP/1.1 400 Bad Request\r\n\r\n")
+		return
+	}
+	p.mu.Lock()
+	p.targets = append(p.targets, req.Host)
+	p.mu.Unlock()
+
+	if p.auth != "" && req.Header.Get("Proxy-Authorization") != p.auth {
+		io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\nProxy-Authenticate: Basic realm=\"warp\"\r\n\r\n")
+		return
+	}
+	upstream, err := net.Dial("tcp", p.backend)
+	if err != nil {
+		io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+		return
+	}
+	defer upstream.Close()
+	io.WriteString(conn, "HTTP/1.1 200 Connection Established\r\n\r\n")
+	go io.Copy(upstream, br)
+	io.Copy(conn, upstream)
+}
+
+func (p *fakeProxy) requested() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.targets...)
+}
+
+// proxyDialer returns a Dialer using p whose resolver fails every
+// lookup, so any attempt to resolve the target shows up as an error.
+func proxyDialer(p *fakeProxy, user, pass string) *wgnet.Dialer {
+	backend := mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		return nil, errors.New("unexpected lookup of " + hostname)
+	})
+	dialer := wgnet.NewDialer(wgdns.NewResolver(backend))
+	dialer.Proxy = &wgnet.ProxyConfig{Address: p.addr, Username: user, Password: pass}
+	return dialer
+}
+
+func TestDial_ProxyTunnelsData(t *testing.T) {
+	addr, cleanup := startEchoServer(t)
+	defer cleanup()
+	proxy := startFakeProxy(t, addr, "")
+
+	conn, err := proxyDialer(proxy, "", "").Dial("tcp", "db.internal:5432")
+	if err != nil {
+		t.Fatalf("expected tunneled dial to succeed, got %v", err)
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatalf("write through tunnel: %v", err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatalf("read through tunnel: %v", err)
+	}
+	if string(buf) != "ping" {
+		t.Fatalf("expected echo %q, got %q", "ping", buf)
+	}
+	if got := proxy.requested(); len(got) != 1 || got[0] != "db.internal:5432" {
+		t.Fatalf("expected CONNECT for the unresolved target, got %v", got)
+	}
+}
+
+func TestDial_ProxySendsBasicAuth(t *testing.T) {
+	addr, cleanup := startEchoServer(t)
+	defer cleanup()
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte("warp:s3cret"))
+	proxy := startFakeProxy(t, addr, auth)
+
+	conn, err := proxyDialer(proxy, "warp", "s3cret").Dial("tcp", "db.internal:5432")
+	if err != nil {
+		t.Fatalf("expected authenticated dial to succeed, got %v", err)
+	}
+	conn.Close()
+}
+
+func TestDial_ProxyAuthRequired(t *testing.T) {
+	addr, cleanup := startEchoServer(t)
+	defer cleanup()
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte("warp:s3cret"))
+	proxy := startFakeProxy(t, addr, auth)
+
+	for _, creds := range [][2]string{{"", ""}, {"warp", "wrong"}} {
+		_, err := proxyDialer(proxy, creds[0], creds[1]).Dial("tcp", "db.internal:5432")
+		if !errors.Is(err, wgnet.ErrProxyAuthRequired) {
+			t.Fatalf("expected ErrProxyAuthRequired for %v, got %v", creds, err)
+		}
+		var opErr *net.OpError
+		if !errors.As(err, &opErr) || opErr.Addr.String() != "db.internal:5432" {
+			t.Fatalf("expected *net.OpError for the target, got %#v", err)
+		}
+	}
+}
+
+func TestDial_ProxyRefusal(t *testing.T) {
+	proxy := startFakeProxy(t, "127.0.0.1:1", "")
+
+	_, err := proxyDialer(proxy, "", "").Dial("tcp", "db.internal:5432")
+	if err == nil {
+		t.Fatal("expected error for a 502 from the proxy")
+	}
+	if errors.Is(err, wgnet.ErrProxyAuthRequired) {
+		t.Fatalf("expected a non-auth error, got %v", err)
+	}
+}
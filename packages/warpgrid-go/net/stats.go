@@ -0,0 +1,137 @@
+package net
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DialerStats is a point-in-time snapshot of a Dialer's counters.
+type DialerStats struct {
+	// Dials is the number of Dial/DialContext calls.
+	Dials uint64
+
+	// DNSLookups is the number of hostnames sent to the resolver.
+	// IP literals and Unix socket paths do not count.
+	DNSLookups uint64
+
+	// Failovers is the number of times a failed connection attempt
+	// caused the next resolved address to be tried.
+	Failovers uint64
+
+	// Hosts holds per-host dial outcomes, keyed by the host component
+	// of the dialed address (or the socket path for Unix networks).
+	// Only the 1024 most recently dialed hosts are kept, so a dialer
+	// reaching many hosts does not grow without bound; the counts of a
+	// host dropped to make room start over if it is dialed again.
+	Hosts map[string]HostStats
+}
+
+// HostStats counts the outcomes of dials to a single host.
+type HostStats struct {
+	Successes uint64
+	Failures  uint64
+}
+
+// maxStatsHosts bounds how many hosts DialerStats.Hosts tracks.
+const maxStatsHosts = 1024
+
+// dialerStats holds the live counters behind Dialer.Stats.
+type dialerStats struct {
+	dials      atomic.Uint64
+	dnsLookups atomic.Uint64
+	failovers  atomic.Uint64
+
+	mu    sync.Mutex
+	hosts map[string]*hostStats
+	// clock orders dials so the least recently dialed host can be
+	// evicted once maxStatsHosts are tracked.
+	clock uint64
+}
+
+// hostStats is a host's counters and when it was last dialed.
+type hostStats struct {
+	HostStats
+	lastDial uint64
+}
+
+// Stats returns a snapshot of the dialer's counters. It is safe to call
+// concurrently with dials.
+func (d *Dialer) Stats() DialerStats {
+	s := &d.stats
+	snap := DialerStats{
+		Dials:      s.dials.Load(),
+		DNSLookups: s.dnsLookups.Load(),
+		Failovers:  s.failovers.Load(),
+		Hosts:      make(map[string]HostStats),
+	}
+	s.mu.Lock()
+	for host, hs := range s.hosts {
+		snap.Hosts[host] = hs.HostStats
+	}
+	s.mu.Unlock()
+	return snap
+}
+
+// ResetStats zeroes all of the dialer's counters.
+func (d *Dialer) ResetStats() {
+	s := &d.stats
+	s.dials.Store(0)
+	s.dnsLookups.Store(0)
+	s.failovers.Store(0)
+	s.mu.Lock()
+	s.hosts = nil
+	s.mu.Unlock()
+}
+
+// recordResult tallies the outcome of one dial to address.
+func (s *dialerStats) recordResult(network, address string, ok bool) {
+	host := hostKey(network, address)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.hosts == nil {
+		s.hosts = make(map[string]*hostStats)
+	}
+	hs := s.hosts[host]
+	if hs == nil {
+		if len(s.hosts) >= maxStatsHosts {
+			s.evictOldest()
+		}
+		hs = &hostStats{}
+		s.hosts[host] = hs
+	}
+	s.clock++
+	hs.lastDial = s.clock
+	if ok {
+		hs.Successes++
+	} else {
+		hs.Failures++
+	}
+}
+
+// evictOldest drops the least recently dialed host; the caller holds
+// mu.
+func (s *dialerStats) evictOldest() {
+	var oldest string
+	var oldestDial uint64
+	for host, hs := range s.hosts {
+		if oldest == "" || hs.lastDial < oldestDial {
+			oldest, oldestDial = host, hs.lastDial
+		}
+	}
+	delete(s.hosts, oldest)
+}
+
+// hostKey returns the host component of address, or the socket path
+// for Unix networks, as used to key per-host stats and limits.
+func hostKey(network, address string) string {
+	if isUnixNetwork(network) {
+		return strings.TrimPrefix(address, "unix://")
+	}
+	if h, _, err := net.SplitHostPort(address); err == nil {
+		return h
+	}
+	return address
+}
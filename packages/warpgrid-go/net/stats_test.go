@@ -0,0 +1,117 @@
+package net_test
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	wgdns "github.com/anthropics/warpgrid/packages/warpgrid-go/dns"
+	wgnet "github.com/anthropics/warpgrid/packages/warpgrid-go/net"
+)
+
+// ── Dialer stats tests ──────────────────────────────────────────────
+
+func TestDialerStats_CountsDialsLookupsAndFailovers(t *testing.T) {
+	addr, cleanup := startEchoServer(t)
+	defer cleanup()
+	_, port, _ := net.SplitHostPort(addr)
+
+	resolver := wgdns.NewResolver(mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		switch hostname {
+		case "api.warp.local":
+			// 127.0.0.2 refuses, so the dial fails over to 127.0.0.1.
+			return []net.IP{net.ParseIP("127.0.0.2"), net.ParseIP("127.0.0.1")}, nil
+		case "down.warp.local":
+			return []net.IP{net.ParseIP("127.0.0.2")}, nil
+		}
+		return nil, errors.New("HostNotFound: " + hostname)
+	}))
+	d := wgnet.NewDialer(resolver)
+
+	for _, target := range []string{"api.warp.local", "api.warp.local", "127.0.0.1"} {
+		conn, err := d.Dial("tcp", net.JoinHostPort(target, port))
+		if err != nil {
+			t.Fatalf("dial %s: unexpected error: %v", target, err)
+		}
+		conn.Close()
+	}
+	for _, target := range []string{"down.warp.local", "missing.warp.local"} {
+		if _, err := d.Dial("tcp", net.JoinHostPort(target, port)); err == nil {
+			t.Fatalf("dial %s: expected error", target)
+		}
+	}
+
+	stats := d.Stats()
+	if stats.Dials != 5 {
+		t.Fatalf("expected 5 dials, got %d", stats.Dials)
+	}
+	if stats.DNSLookups != 4 {
+		t.Fatalf("expected 4 DNS lookups, got %d", stats.DNSLookups)
+	}
+	if stats.Failovers != 2 {
+		t.Fatalf("expected 2 failovers, got %d", stats.Failovers)
+	}
+
+	expected := map[string]wgnet.HostStats{
+		"api.warp.local":     {Successes: 2},
+		"127.0.0.1":          {Successes: 1},
+		"down.warp.local":    {Failures: 1},
+		"missing.warp.local": {Failures: 1},
+	}
+	if len(stats.Hosts) != len(expected) {
+		t.Fatalf("expected %d hosts, got %v", len(expected), stats.Hosts)
+	}
+	for host, want := range expected {
+		if got := stats.Hosts[host]; got != want {
+			t.Fatalf("host %s: expected %+v, got %+v", host, want, got)
+		}
+	}
+}
+
+func TestDialerStats_ResetStats(t *testing.T) {
+	addr, cleanup := startEchoServer(t)
+	defer cleanup()
+
+	d := wgnet.NewDialer(wgdns.NewResolver(mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		return nil, errors.New("unused")
+	})))
+	conn, err := d.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	conn.Close()
+
+	d.ResetStats()
+	stats := d.Stats()
+	if stats.Dials != 0 || stats.DNSLookups != 0 || stats.Failovers != 0 || len(stats.Hosts) != 0 {
+		t.Fatalf("expected zeroed stats after reset, got %+v", stats)
+	}
+}
+
+func TestDialerStats_HostsBounded(t *testing.T) {
+	resolver := wgdns.NewResolver(mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		return nil, errors.New("HostNotFound: " + hostname)
+	}))
+	d := wgnet.NewDialer(resolver)
+
+	dial := func(i int) {
+		d.Dial("tcp", net.JoinHostPort(fmt.Sprintf("h%d.warp.local", i), "5432"))
+	}
+	for i := 0; i < 1024; i++ {
+		dial(i)
+	}
+	dial(0) // h0 is now the most recently dialed
+	dial(1024)
+
+	hosts := d.Stats().Hosts
+	if len(hosts) != 1024 {
+		t.Fatalf("expected 1024 tracked hosts, got %d", len(hosts))
+	}
+	if _, ok := hosts["h1.warp.local"]; ok {
+		t.Fatal("expected the least recently dialed host to be evicted")
+	}
+	if hosts["h0.warp.local"].Failures != 2 || hosts["h1024.warp.local"].Failures != 1 {
+		t.Fatalf("expected recent hosts kept, got h0=%v h1024=%v", hosts["h0.warp.local"], hosts["h1024.warp.local"])
+	}
+}
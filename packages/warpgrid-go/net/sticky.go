@@ -0,0 +1,74 @@
+package net
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// stickyTable remembers, per hostname, the address the last successful
+// dial connected to, for Dialer.StickyTTL.
+type stickyTable struct {
+	mu      sync.Mutex
+	entries map[string]stickyEntry
+}
+
+// stickyEntry is a remembered address and when it stops applying.
+type stickyEntry struct {
+	addr  net.IPAddr
+	until time.Time
+}
+
+// prefer moves host's remembered address to the front of addrs when it
+// is still current and among them, keeping the order of the rest.
+func (s *stickyTable) prefer(host string, addrs []net.IPAddr) []net.IPAddr {
+	s.mu.Lock()
+	entry, ok := s.entries[host]
+	if ok && !time.Now().Before(entry.until) {
+		delete(s.entries, host)
+		ok = false
+	}
+	s.mu.Unlock()
+	if !ok {
+		return addrs
+	}
+	for i, addr := range addrs {
+		if addr.IP.Equal(entry.addr.IP) && addr.Zone == entry.addr.Zone {
+			if i == 0 {
+				return addrs
+			}
+			out := make([]net.IPAddr, 0, len(addrs))
+			out = append(out, addr)
+			out = append(out, addrs[:i]...)
+			return append(out, addrs[i+1:]...)
+		}
+	}
+	return addrs
+}
+
+// remember records that a dial to host connected to conn's remote
+// address, for ttl from now.
+func (s *stickyTable) remember(host string, conn net.Conn, ttl time.Duration) {
+	var addr net.IPAddr
+	switch ra := conn.RemoteAddr().(type) {
+	case *net.TCPAddr:
+		addr = net.IPAddr{IP: ra.IP, Zone: ra.Zone}
+	case *net.UDPAddr:
+		addr = net.IPAddr{IP: ra.IP, Zone: ra.Zone}
+	default:
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.entries == nil {
+		s.entries = make(map[string]stickyEntry)
+	}
+	s.entries[host] = stickyEntry{addr: addr, until: time.Now().Add(ttl)}
+}
+
+// forget drops host's remembered address after every address failed.
+func (s *stickyTable) forget(host string) {
+	s.mu.Lock()
+	delete(s.entries, host)
+	s.mu.Unlock()
+}
@@ -0,0 +1,123 @@
+package net_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	wgdns "github.com/anthropics/warpgrid/packages/warpgrid-go/dns"
+	wgnet "github.com/anthropics/warpgrid/packages/warpgrid-go/net"
+)
+
+// ── StickyTTL tests ─────────────────────────────────────────────────
+
+// listenOn starts listeners on the same port at each of the given
+// loopback addresses, returning the port and the listeners. Accepted
+// connections are left open until the client closes them.
+func listenOn(t *testing.T, ips ...string) (string, []net.Listener) {
+	t.Helper()
+	port := "0"
+	var listeners []net.Listener
+	t.Cleanup(func() {
+		for _, ln := range listeners {
+			ln.Close()
+		}
+	})
+	for _, ip := range ips {
+		ln, err := net.Listen("tcp", net.JoinHostPort(ip, port))
+		if err != nil {
+			t.Skipf("cannot listen on %s: %v", ip, err)
+		}
+		_, port, _ = net.SplitHostPort(ln.Addr().String())
+		go func() {
+			for {
+				if _, err := ln.Accept(); err != nil {
+					return
+				}
+			}
+		}()
+		listeners = append(listeners, ln)
+	}
+	return port, listeners
+}
+
+// stickyDialer returns a round-robin Dialer resolving every name to
+// 127.0.0.1 and 127.0.0.2, so without affinity successive dials start
+// at alternating addresses.
+func stickyDialer(ttl time.Duration) *wgnet.Dialer {
+	backend := mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("127.0.0.2")}, nil
+	})
+	dialer := wgnet.NewDialer(wgdns.NewResolver(backend))
+	dialer.Balancer = &wgnet.RoundRobin{}
+	dialer.StickyTTL = ttl
+	return dialer
+}
+
+// dialRemote dials host and returns the IP the connection reached.
+func dialRemote(t *testing.T, d *wgnet.Dialer, port string) string {
+	t.Helper()
+	conn, err := d.Dial("tcp", net.JoinHostPort("sticky.warp.local", port))
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+	host, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+	return host
+}
+
+func TestDial_StickyTTLReusesFirstChoice(t *testing.T) {
+	port, _ := listenOn(t, "127.0.0.1", "127.0.0.2")
+	dialer := stickyDialer(time.Minute)
+
+	first := dialRemote(t, dialer, port)
+	for i := 0; i < 4; i++ {
+		if got := dialRemote(t, dialer, port); got != first {
+			t.Fatalf("dial %d: expected sticky %s, got %s", i, first, got)
+		}
+	}
+}
+
+func TestDial_WithoutStickyTTLBalancerRotates(t *testing.T) {
+	port, _ := listenOn(t, "127.0.0.1", "127.0.0.2")
+	dialer := stickyDialer(0)
+
+	if a, b := dialRemote(t, dialer, port), dialRemote(t, dialer, port); a == b {
+		t.Fatalf("expected round robin to alternate addresses, got %s twice", a)
+	}
+}
+
+func TestDial_StickyTTLExpires(t *testing.T) {
+	port, _ := listenOn(t, "127.0.0.1", "127.0.0.2")
+	dialer := stickyDialer(50 * time.Millisecond)
+
+	first := dialRemote(t, dialer, port)
+	time.Sleep(80 * time.Millisecond)
+	// The balancer's next pick is the other address.
+	if got := dialRemote(t, dialer, port); got == first {
+		t.Fatalf("expected a re-pick after the TTL, got %s again", got)
+	}
+}
+
+func TestDial_StickyAddressFailureRepicks(t *testing.T) {
+	port, listeners := listenOn(t, "127.0.0.1", "127.0.0.2")
+	dialer := stickyDialer(time.Minute)
+
+	if got := dialRemote(t, dialer, port); got != "127.0.0.1" {
+		t.Fatalf("expected the first pick 127.0.0.1, got %s", got)
+	}
+	listeners[0].Close()
+
+	if got := dialRemote(t, dialer, port); got != "127.0.0.2" {
+		t.Fatalf("expected failover to 127.0.0.2, got %s", got)
+	}
+	// 127.0.0.2 is now sticky, even where the balancer would start at .1.
+	for i := 0; i < 3; i++ {
+		if got := dialRemote(t, dialer, port); got != "127.0.0.2" {
+			t.Fatalf("dial %d: expected the new sticky 127.0.0.2, got %s", i, got)
+		}
+	}
+	if got := dialer.Stats().Failovers; got != 1 {
+		t.Fatalf("expected only the one failover, got %d", got)
+	}
+}
@@ -0,0 +1,64 @@
+package net
+
+import (
+	"context"
+	"net"
+)
+
+// DialTrace is a set of hooks run at stages of a Dialer's dial, for
+// attributing connection latency to DNS or to connecting, in the
+// manner of net/http/httptrace.ClientTrace. Attach one to a dial's
+// context with WithDialTrace. Hooks run synchronously at the moment
+// the stage begins or ends, so calling time.Now in a hook timestamps
+// it. Any hook may be nil.
+//
+// With Happy Eyeballs (Dialer.FallbackDelay) connection attempts
+// overlap, so ConnectStart and ConnectDone may be called concurrently.
+type DialTrace struct {
+	// DNSStart is called before the hostname is resolved. It is not
+	// called for IP literals or Unix socket paths.
+	DNSStart func(DNSStartInfo)
+
+	// DNSDone is called once resolution finishes, with the addresses
+	// found or the error. With RetryOnEmptyDNS it covers every retry.
+	DNSDone func(DNSDoneInfo)
+
+	// ConnectStart is called before each connection attempt, with the
+	// resolved "host:port" (or socket path) being dialed.
+	ConnectStart func(network, addr string)
+
+	// ConnectDone is called when that attempt completes, with its
+	// error if it failed.
+	ConnectDone func(network, addr string, err error)
+}
+
+// DNSStartInfo is passed to DialTrace.DNSStart.
+type DNSStartInfo struct {
+	Host string
+}
+
+// DNSDoneInfo is passed to DialTrace.DNSDone.
+type DNSDoneInfo struct {
+	// Addrs are the resolved addresses, in the order they will be
+	// tried before any Balancer or Happy Eyeballs reordering.
+	Addrs []net.IPAddr
+
+	// Err is the resolution error, if any.
+	Err error
+}
+
+// dialTraceKey is the context key under which WithDialTrace stores a
+// *DialTrace.
+type dialTraceKey struct{}
+
+// WithDialTrace returns a copy of ctx carrying trace, so dials made
+// with it through a Dialer's DialContext report to trace's hooks.
+func WithDialTrace(ctx context.Context, trace *DialTrace) context.Context {
+	return context.WithValue(ctx, dialTraceKey{}, trace)
+}
+
+// ContextDialTrace returns the DialTrace attached to ctx, or nil.
+func ContextDialTrace(ctx context.Context) *DialTrace {
+	trace, _ := ctx.Value(dialTraceKey{}).(*DialTrace)
+	return trace
+}
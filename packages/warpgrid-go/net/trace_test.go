@@ -0,0 +1,133 @@
+package net_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	wgdns "github.com/anthropics/warpgrid/packages/warpgrid-go/dns"
+	wgnet "github.com/anthropics/warpgrid/packages/warpgrid-go/net"
+)
+
+// ── DialTrace tests ─────────────────────────────────────────────────
+
+// traceLog records each hook call as a line, with the time it ran.
+type traceLog struct {
+	mu     sync.Mutex
+	events []string
+	times  []time.Time
+}
+
+func (l *traceLog) add(format string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, fmt.Sprintf(format, args...))
+	l.times = append(l.times, time.Now())
+}
+
+func (l *traceLog) trace() *wgnet.DialTrace {
+	return &wgnet.DialTrace{
+		DNSStart: func(info wgnet.DNSStartInfo) { l.add("DNSStart %s", info.Host) },
+		DNSDone: func(info wgnet.DNSDoneInfo) {
+			var addrs []string
+			for _, a := range info.Addrs {
+				addrs = append(addrs, a.String())
+			}
+			l.add("DNSDone %v err=%v", addrs, info.Err != nil)
+		},
+		ConnectStart: func(network, addr string) { l.add("ConnectStart %s %s", network, addr) },
+		ConnectDone: func(network, addr string, err error) {
+			l.add("ConnectDone %s %s err=%v", network, addr, err != nil)
+		},
+	}
+}
+
+func TestDialTrace_SuccessfulDial(t *testing.T) {
+	addr, cleanup := startEchoServer(t)
+	defer cleanup()
+	_, port, _ := net.SplitHostPort(addr)
+
+	backend := mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		time.Sleep(5 * time.Millisecond)
+		return []net.IP{net.ParseIP("127.0.0.1")}, nil
+	})
+	dialer := wgnet.NewDialer(wgdns.NewResolver(backend))
+
+	var log traceLog
+	ctx := wgnet.WithDialTrace(context.Background(), log.trace())
+	conn, err := dialer.DialContext(ctx, "tcp", "db.warp.local:"+port)
+	if err != nil {
+		t.Fatalf("DialContext failed: %v", err)
+	}
+	conn.Close()
+
+	want := []string{
+		"DNSStart db.warp.local",
+		"DNSDone [127.0.0.1] err=false",
+		"ConnectStart tcp 127.0.0.1:" + port,
+		"ConnectDone tcp 127.0.0.1:" + port + " err=false",
+	}
+	if strings.Join(log.events, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("expected hooks\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(log.events, "\n"))
+	}
+	if dns := log.times[1].Sub(log.times[0]); dns < 5*time.Millisecond {
+		t.Fatalf("expected DNSStart to DNSDone to span the lookup, got %v", dns)
+	}
+	for i := 1; i < len(log.times); i++ {
+		if log.times[i].Before(log.times[i-1]) {
+			t.Fatalf("expected hook times in order, got %v", log.times)
+		}
+	}
+}
+
+func TestDialTrace_DNSFailure(t *testing.T) {
+	backend := mockResolverFunc(func(hostname string) ([]net.IP, error) {
+		return nil, errors.New("HostNotFound: " + hostname)
+	})
+	dialer := wgnet.NewDialer(wgdns.NewResolver(backend))
+
+	var log traceLog
+	ctx := wgnet.WithDialTrace(context.Background(), log.trace())
+	if _, err := dialer.DialContext(ctx, "tcp", "missing.warp.local:5432"); err == nil {
+		t.Fatal("expected error for a failed lookup")
+	}
+
+	want := []string{
+		"DNSStart missing.warp.local",
+		"DNSDone [] err=true",
+	}
+	if strings.Join(log.events, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("expected hooks\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(log.events, "\n"))
+	}
+}
+
+func TestDialTrace_IPLiteralSkipsDNSHooks(t *testing.T) {
+	addr, cleanup := startEchoServer(t)
+	defer cleanup()
+
+	var log traceLog
+	ctx := wgnet.WithDialTrace(context.Background(), &wgnet.DialTrace{
+		DNSStart:     func(wgnet.DNSStartInfo) { log.add("DNSStart") },
+		ConnectStart: func(network, addr string) { log.add("ConnectStart %s", addr) },
+	})
+	conn, err := wgnet.NewDialer(wgdns.NewResolver(nil)).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		t.Fatalf("DialContext failed: %v", err)
+	}
+	conn.Close()
+
+	if len(log.events) != 1 || log.events[0] != "ConnectStart "+addr {
+		t.Fatalf("expected only ConnectStart %s, got %v", addr, log.events)
+	}
+}
+
+func TestContextDialTrace_NoneAttached(t *testing.T) {
+	if trace := wgnet.ContextDialTrace(context.Background()); trace != nil {
+		t.Fatalf("expected nil trace, got %+v", trace)
+	}
+}